@@ -5,16 +5,19 @@ go 1.17
 require (
 	github.com/aws/aws-sdk-go-v2/config v1.11.0
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.22.0
+	github.com/blevesearch/vellum v1.0.7
 	github.com/blugelabs/bluge v0.1.9
 	github.com/blugelabs/bluge_segment_api v0.2.0
 	github.com/blugelabs/query_string v0.3.0
 	github.com/bwmarrin/snowflake v0.3.0
+	github.com/cbroglie/mustache v1.4.0
 	github.com/getsentry/sentry-go v0.13.0
 	github.com/gin-contrib/cors v1.3.1
 	github.com/gin-gonic/gin v1.7.7
 	github.com/go-ego/gse v0.70.0
 	github.com/goccy/go-json v0.9.6
 	github.com/joho/godotenv v1.4.0
+	github.com/klauspost/compress v1.15.2
 	github.com/minio/minio-go/v7 v7.0.21
 	github.com/robfig/cron/v3 v3.0.0
 	github.com/rs/zerolog v1.26.1
@@ -49,7 +52,6 @@ require (
 	github.com/blevesearch/mmap-go v1.0.3 // indirect
 	github.com/blevesearch/segment v0.9.0 // indirect
 	github.com/blevesearch/snowballstem v0.9.0 // indirect
-	github.com/blevesearch/vellum v1.0.7 // indirect
 	github.com/blugelabs/ice v0.2.0 // indirect
 	github.com/bmizerany/assert v0.0.0-20160611221934-b7ed37b82869 // indirect
 	github.com/caio/go-tdigest v3.1.0+incompatible // indirect
@@ -67,7 +69,6 @@ require (
 	github.com/gopherjs/gopherjs v0.0.0-20181017120253-0766667cb4d1 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/jtolds/gls v4.20.0+incompatible // indirect
-	github.com/klauspost/compress v1.15.2 // indirect
 	github.com/klauspost/cpuid v1.3.1 // indirect
 	github.com/leodido/go-urn v1.2.0 // indirect
 	github.com/mattn/go-isatty v0.0.14 // indirect
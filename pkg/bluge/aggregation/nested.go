@@ -0,0 +1,157 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package aggregation
+
+import (
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/search"
+	"github.com/blugelabs/bluge/search/aggregations"
+)
+
+// NestedAggregation aggregates the documents joined to the matching documents,
+// the nested objects of a path for nested or the documents of the nested objects for reverse_nested.
+// The joined documents are searched after the search by ResolveNested.
+type NestedAggregation struct {
+	joinField string
+	query     func(ids []string) bluge.Query
+
+	aggregations map[string]search.Aggregation
+}
+
+// NewNestedAggregation returns a NestedAggregation of the nested objects of the path,
+// the nested objects are hidden documents with the path and the _id of their document in the path and parent fields
+func NewNestedAggregation(path, pathField, parentField string) *NestedAggregation {
+	return &NestedAggregation{
+		joinField: "_id",
+		query: func(ids []string) bluge.Query {
+			filter := bluge.NewBooleanQuery().AddMust(bluge.NewTermQuery(path).SetField(pathField))
+			return bluge.NewBooleanQuery().AddMust(termsQuery(parentField, ids), filter)
+		},
+		aggregations: make(map[string]search.Aggregation),
+	}
+}
+
+// NewReverseNestedAggregation returns a NestedAggregation of the documents of the nested objects
+func NewReverseNestedAggregation(parentField string) *NestedAggregation {
+	return &NestedAggregation{
+		joinField: parentField,
+		query: func(ids []string) bluge.Query {
+			return termsQuery("_id", ids)
+		},
+		aggregations: make(map[string]search.Aggregation),
+	}
+}
+
+func termsQuery(field string, terms []string) bluge.Query {
+	if len(terms) == 0 {
+		return bluge.NewMatchNoneQuery()
+	}
+	q := bluge.NewBooleanQuery()
+	for _, term := range terms {
+		q.AddShould(bluge.NewTermQuery(term).SetField(field))
+	}
+	return q
+}
+
+func (a *NestedAggregation) Fields() []string {
+	return []string{a.joinField}
+}
+
+func (a *NestedAggregation) Calculator() search.Calculator {
+	return &NestedCalculator{
+		agg: a,
+		ids: make(map[string]struct{}),
+	}
+}
+
+func (a *NestedAggregation) AddAggregation(name string, aggregation search.Aggregation) {
+	a.aggregations[name] = aggregation
+}
+
+type NestedCalculator struct {
+	agg *NestedAggregation
+
+	ids    map[string]struct{}
+	idList []string
+	bucket *search.Bucket
+}
+
+func (c *NestedCalculator) Consume(d *search.DocumentMatch) {
+	for _, id := range d.DocValues(c.agg.joinField) {
+		c.add(string(id))
+	}
+}
+
+func (c *NestedCalculator) add(id string) {
+	if _, ok := c.ids[id]; ok {
+		return
+	}
+	c.ids[id] = struct{}{}
+	c.idList = append(c.idList, id)
+}
+
+func (c *NestedCalculator) Merge(other search.Calculator) {
+	if other, ok := other.(*NestedCalculator); ok {
+		for _, id := range other.idList {
+			c.add(id)
+		}
+	}
+}
+
+func (c *NestedCalculator) Finish() {
+}
+
+// Bucket returns the aggregations of the joined documents, it is nil before ResolveNested
+func (c *NestedCalculator) Bucket() *search.Bucket {
+	return c.bucket
+}
+
+// DocCount returns the number of joined documents
+func (c *NestedCalculator) DocCount() int64 {
+	if c.bucket == nil {
+		return 0
+	}
+	return int64(c.bucket.Count())
+}
+
+// ResolveNested searches the joined documents of the nested aggregations in the bucket and its sub buckets
+func ResolveNested(bucket *search.Bucket, searchFn func(req bluge.SearchRequest) (search.DocumentMatchIterator, error)) error {
+	for _, calculator := range bucket.Aggregations() {
+		switch calculator := calculator.(type) {
+		case *NestedCalculator:
+			req := bluge.NewTopNSearch(0, calculator.agg.query(calculator.idList))
+			req.AddAggregation("count", aggregations.CountMatches())
+			for name, agg := range calculator.agg.aggregations {
+				req.AddAggregation(name, agg)
+			}
+			dmi, err := searchFn(req)
+			if err != nil {
+				return err
+			}
+			calculator.bucket = dmi.Aggregations()
+			if err := ResolveNested(calculator.bucket, searchFn); err != nil {
+				return err
+			}
+		case search.BucketCalculator:
+			for _, subBucket := range calculator.Buckets() {
+				if err := ResolveNested(subBucket, searchFn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package profile
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/search"
+	"github.com/blugelabs/bluge/search/searcher"
+
+	zincquery "github.com/zinclabs/zinc/pkg/bluge/query"
+)
+
+// QueryTimings are the timing types of the breakdown of a query clause
+var QueryTimings = []string{"create_weight", "build_scorer", "next_doc", "advance", "match", "score", "shallow_advance", "compute_max_score", "set_min_competitive_score"}
+
+// AggregationTimings are the timing types of the breakdown of an aggregation
+var AggregationTimings = []string{"initialize", "build_leaf_collector", "collect", "post_collection", "build_aggregation", "reduce"}
+
+// FetchTimings are the timing types of the breakdown of the fetch phase
+var FetchTimings = []string{"load_stored_fields"}
+
+// FetchPhases are the sub phases of the fetch phase
+var FetchPhases = []string{"FetchSourcePhase", "FetchFieldsPhase", "HighlightPhase", "InnerHitsPhase"}
+
+// Node is the profile of a query clause or an aggregation, its children are the profiles of
+// the clauses of a boolean query or the sub aggregations
+type Node struct {
+	Type        string
+	Description string
+	Children    []*Node
+	timings     []string
+	breakdown   map[string]*int64 // the times in nanoseconds and the counts of the timings
+}
+
+func newNode(typ, description string, timings []string) *Node {
+	node := &Node{Type: typ, Description: description, timings: timings, breakdown: make(map[string]*int64, len(timings)*2)}
+	for _, timing := range timings {
+		node.breakdown[timing] = new(int64)
+		node.breakdown[timing+"_count"] = new(int64)
+	}
+	return node
+}
+
+// add records a timing, it can be called by concurrent searches of the readers
+func (n *Node) add(timing string, start time.Time) {
+	atomic.AddInt64(n.breakdown[timing], int64(time.Since(start)))
+	atomic.AddInt64(n.breakdown[timing+"_count"], 1)
+}
+
+// Breakdown returns the times in nanoseconds and the counts of the timings
+func (n *Node) Breakdown() map[string]int64 {
+	breakdown := make(map[string]int64, len(n.breakdown))
+	for k, v := range n.breakdown {
+		breakdown[k] = atomic.LoadInt64(v)
+	}
+	return breakdown
+}
+
+// Time returns the total time of the timings in nanoseconds
+func (n *Node) Time() int64 {
+	var total int64
+	for _, timing := range n.timings {
+		total += atomic.LoadInt64(n.breakdown[timing])
+	}
+	return total
+}
+
+// Profiler is the profile of a search request
+type Profiler struct {
+	Queries      []*Node // the query of each reader
+	RewriteTime  time.Duration
+	Aggregations []*Node
+	Collect      time.Duration // the search of the readers, it includes the times of the queries and aggregations
+	Fetch        *Node         // the loading of the hits, its children are the fetch phases
+}
+
+// NewProfiler creates the profiler of a search request of the readers
+func NewProfiler(readers int, rewriteTime time.Duration) *Profiler {
+	fetch := newNode("fetch", "", FetchTimings)
+	for _, phase := range FetchPhases {
+		fetch.Children = append(fetch.Children, newNode(phase, "", []string{"process"}))
+	}
+	return &Profiler{Queries: make([]*Node, readers), RewriteTime: rewriteTime, Fetch: fetch}
+}
+
+// AddCollect records the time of the search since start, the profiler may be nil
+func (p *Profiler) AddCollect(start time.Time) {
+	if p != nil {
+		p.Collect += time.Since(start)
+	}
+}
+
+// AddFetch records a fetch timing or the time of a fetch phase since start, the profiler may be nil
+func (p *Profiler) AddFetch(timing string, start time.Time) {
+	if p == nil {
+		return
+	}
+	for _, child := range p.Fetch.Children {
+		if child.Type == timing {
+			child.add("process", start)
+			return
+		}
+	}
+	p.Fetch.add(timing, start)
+}
+
+// NewQuery wraps the query to profile the searchers built from it,
+// the clauses of boolean queries are profiled as the children of the node
+func NewQuery(q bluge.Query) (bluge.Query, *Node) {
+	node := newNode(zincquery.TypeName(q), zincquery.Describe(q), QueryTimings)
+	if bq, ok := q.(*bluge.BooleanQuery); ok {
+		nbq := bluge.NewBooleanQuery().SetBoost(bq.Boost())
+		if bq.MinShould() > 0 {
+			nbq.SetMinShould(bq.MinShould())
+		}
+		for _, clause := range bq.Musts() {
+			wq, child := NewQuery(clause)
+			nbq.AddMust(wq)
+			node.Children = append(node.Children, child)
+		}
+		for _, clause := range bq.Shoulds() {
+			wq, child := NewQuery(clause)
+			nbq.AddShould(wq)
+			node.Children = append(node.Children, child)
+		}
+		for _, clause := range bq.MustNots() {
+			wq, child := NewQuery(clause)
+			nbq.AddMustNot(wq)
+			node.Children = append(node.Children, child)
+		}
+		q = nbq
+	}
+	return &profileQuery{query: q, node: node}, node
+}
+
+type profileQuery struct {
+	query bluge.Query
+	node  *Node
+}
+
+func (q *profileQuery) Searcher(i search.Reader, options search.SearcherOptions) (search.Searcher, error) {
+	start := time.Now()
+	s, err := q.query.Searcher(i, options)
+	q.node.add("build_scorer", start)
+	if err != nil {
+		return nil, err
+	}
+	// the boolean query drops the clauses matching none
+	if _, ok := s.(*searcher.MatchNoneSearcher); ok {
+		return s, nil
+	}
+	return &profileSearcher{Searcher: s, node: q.node}, nil
+}
+
+// profileSearcher times the iteration of the documents, the counts of next_doc and advance are the visited documents
+type profileSearcher struct {
+	search.Searcher
+	node *Node
+}
+
+func (s *profileSearcher) Next(ctx *search.Context) (*search.DocumentMatch, error) {
+	start := time.Now()
+	defer s.node.add("next_doc", start)
+	return s.Searcher.Next(ctx)
+}
+
+func (s *profileSearcher) Advance(ctx *search.Context, number uint64) (*search.DocumentMatch, error) {
+	start := time.Now()
+	defer s.node.add("advance", start)
+	return s.Searcher.Advance(ctx, number)
+}
+
+// NewAggregation wraps the aggregation to profile its calculators
+func NewAggregation(name string, agg search.Aggregation) (search.Aggregation, *Node) {
+	node := newNode(zincquery.TypeName(agg), name, AggregationTimings)
+	return &profileAggregation{Aggregation: agg, node: node}, node
+}
+
+type profileAggregation struct {
+	search.Aggregation
+	node *Node
+}
+
+func (a *profileAggregation) Calculator() search.Calculator {
+	start := time.Now()
+	calculator := a.Aggregation.Calculator()
+	a.node.add("initialize", start)
+	return &profileCalculator{Calculator: calculator, node: a.node}
+}
+
+type profileCalculator struct {
+	search.Calculator
+	node *Node
+}
+
+func (c *profileCalculator) Consume(d *search.DocumentMatch) {
+	start := time.Now()
+	c.Calculator.Consume(d)
+	c.node.add("collect", start)
+}
+
+func (c *profileCalculator) Finish() {
+	start := time.Now()
+	c.Calculator.Finish()
+	c.node.add("build_aggregation", start)
+}
+
+func (c *profileCalculator) Merge(other search.Calculator) {
+	start := time.Now()
+	if v, ok := other.(*profileCalculator); ok {
+		other = v.Calculator
+	}
+	c.Calculator.Merge(other)
+	c.node.add("reduce", start)
+}
+
+// Unwrap restores the calculators of the profiled aggregations of the bucket,
+// the responses of the aggregations assert the types of their calculators
+func Unwrap(bucket *search.Bucket) {
+	calculators := bucket.Aggregations()
+	for name, calculator := range calculators {
+		if v, ok := calculator.(*profileCalculator); ok {
+			calculators[name] = v.Calculator
+		}
+	}
+}
@@ -0,0 +1,162 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package query
+
+import (
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/search"
+)
+
+// BoostingQuery matches the documents of the positive query, the score of the documents
+// also matching the negative query is multiplied by the negative boost
+type BoostingQuery struct {
+	positive      bluge.Query
+	negative      bluge.Query
+	negativeBoost float64
+	boost         float64
+}
+
+// NewBoostingQuery returns a BoostingQuery, negativeBoost should be between 0 and 1.0 to demote the documents
+func NewBoostingQuery(positive, negative bluge.Query, negativeBoost float64) *BoostingQuery {
+	return &BoostingQuery{
+		positive:      positive,
+		negative:      negative,
+		negativeBoost: negativeBoost,
+		boost:         1.0,
+	}
+}
+
+// SetBoost multiplies the score of all the matching documents
+func (q *BoostingQuery) SetBoost(boost float64) *BoostingQuery {
+	q.boost = boost
+	return q
+}
+
+func (q *BoostingQuery) Boost() float64 {
+	return q.boost
+}
+
+func (q *BoostingQuery) Positive() bluge.Query {
+	return q.positive
+}
+
+func (q *BoostingQuery) Searcher(i search.Reader, options search.SearcherOptions) (search.Searcher, error) {
+	positive, err := q.positive.Searcher(i, options)
+	if err != nil {
+		return nil, err
+	}
+	negative, err := q.negative.Searcher(i, options)
+	if err != nil {
+		_ = positive.Close()
+		return nil, err
+	}
+
+	return &boostingSearcher{
+		positive:      positive,
+		negative:      negative,
+		negativeBoost: q.negativeBoost,
+		boost:         q.boost,
+		explain:       options.Explain,
+	}, nil
+}
+
+// boostingSearcher follows the positive searcher and advances the negative searcher to the same document
+type boostingSearcher struct {
+	positive      search.Searcher
+	negative      search.Searcher
+	negativeBoost float64
+	boost         float64
+	explain       bool
+
+	negativeMatch *search.DocumentMatch // the negative searcher is positioned on it
+	negativeDone  bool
+}
+
+func (s *boostingSearcher) Next(ctx *search.Context) (*search.DocumentMatch, error) {
+	match, err := s.positive.Next(ctx)
+	if err != nil || match == nil {
+		return nil, err
+	}
+	return s.score(ctx, match)
+}
+
+func (s *boostingSearcher) Advance(ctx *search.Context, number uint64) (*search.DocumentMatch, error) {
+	match, err := s.positive.Advance(ctx, number)
+	if err != nil || match == nil {
+		return nil, err
+	}
+	return s.score(ctx, match)
+}
+
+// score demotes the match if the negative searcher matches the same document
+func (s *boostingSearcher) score(ctx *search.Context, match *search.DocumentMatch) (*search.DocumentMatch, error) {
+	if !s.negativeDone && (s.negativeMatch == nil || s.negativeMatch.Number < match.Number) {
+		if s.negativeMatch != nil {
+			ctx.DocumentMatchPool.Put(s.negativeMatch)
+		}
+		var err error
+		if s.negativeMatch, err = s.negative.Advance(ctx, match.Number); err != nil {
+			return nil, err
+		}
+		s.negativeDone = s.negativeMatch == nil
+	}
+
+	var explanations []*search.Explanation
+	if s.explain && match.Explanation != nil {
+		explanations = append(explanations, match.Explanation)
+	}
+	if !s.negativeDone && s.negativeMatch.Number == match.Number {
+		match.Score *= s.negativeBoost
+		if s.explain {
+			explanations = append(explanations, search.NewExplanation(s.negativeBoost, "negative boost"))
+		}
+	}
+	if s.boost != 1.0 {
+		match.Score *= s.boost
+		if s.explain {
+			explanations = append(explanations, search.NewExplanation(s.boost, "boost"))
+		}
+	}
+	if s.explain {
+		match.Explanation = search.NewExplanation(match.Score, "product of:", explanations...)
+	}
+
+	return match, nil
+}
+
+func (s *boostingSearcher) Close() error {
+	err := s.positive.Close()
+	if err2 := s.negative.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (s *boostingSearcher) Count() uint64 {
+	return s.positive.Count()
+}
+
+func (s *boostingSearcher) Min() int {
+	return 0
+}
+
+func (s *boostingSearcher) Size() int {
+	return s.positive.Size() + s.negative.Size()
+}
+
+func (s *boostingSearcher) DocumentMatchPoolSize() int {
+	return s.positive.DocumentMatchPoolSize() + s.negative.DocumentMatchPoolSize() + 1
+}
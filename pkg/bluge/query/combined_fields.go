@@ -0,0 +1,359 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/search"
+	"github.com/blugelabs/bluge/search/similarity"
+	segment "github.com/blugelabs/bluge_segment_api"
+)
+
+const (
+	bm25B  = 0.75
+	bm25K1 = 1.2
+)
+
+// CombinedFieldsQuery searches the terms over several text fields as if they were indexed into one
+// combined field, the documents are scored with BM25F
+type CombinedFieldsQuery struct {
+	terms       []string
+	fields      []string
+	weights     []float64
+	operator    bluge.MatchQueryOperator
+	minShould   int
+	boost       float64
+	lengthField string
+}
+
+// NewCombinedFieldsQuery returns a CombinedFieldsQuery for the analyzed terms of the query
+func NewCombinedFieldsQuery(terms []string) *CombinedFieldsQuery {
+	return &CombinedFieldsQuery{
+		terms:    terms,
+		operator: bluge.MatchQueryOperatorOr,
+		boost:    1.0,
+	}
+}
+
+// AddField adds a field to the combined field, the term frequencies and the length of the field are multiplied by the weight
+func (q *CombinedFieldsQuery) AddField(field string, weight float64) *CombinedFieldsQuery {
+	q.fields = append(q.fields, field)
+	q.weights = append(q.weights, weight)
+	return q
+}
+
+// SetOperator sets if all the terms (and) or any of the terms (or) must match
+func (q *CombinedFieldsQuery) SetOperator(operator bluge.MatchQueryOperator) *CombinedFieldsQuery {
+	q.operator = operator
+	return q
+}
+
+// SetMinShould sets the minimum number of terms that must match with the or operator
+func (q *CombinedFieldsQuery) SetMinShould(minShould int) *CombinedFieldsQuery {
+	q.minShould = minShould
+	return q
+}
+
+// SetLengthField sets the FieldLengthField of the documents, the length of the combined field is the sum
+// of the lengths of all the fields. Without it only the fields containing the term are counted.
+func (q *CombinedFieldsQuery) SetLengthField(field string) *CombinedFieldsQuery {
+	q.lengthField = field
+	return q
+}
+
+func (q *CombinedFieldsQuery) SetBoost(boost float64) *CombinedFieldsQuery {
+	q.boost = boost
+	return q
+}
+
+func (q *CombinedFieldsQuery) Boost() float64 {
+	return q.boost
+}
+
+func (q *CombinedFieldsQuery) Fields() []string {
+	return q.fields
+}
+
+func (q *CombinedFieldsQuery) Terms() []string {
+	return q.terms
+}
+
+func (q *CombinedFieldsQuery) Searcher(i search.Reader, options search.SearcherOptions) (search.Searcher, error) {
+	boolQuery := bluge.NewBooleanQuery().SetBoost(q.boost)
+	for _, term := range q.terms {
+		termQuery := &combinedTermQuery{term: term, fields: q.fields, weights: q.weights, lengthField: q.lengthField}
+		if q.operator == bluge.MatchQueryOperatorAnd {
+			boolQuery.AddMust(termQuery)
+		} else {
+			boolQuery.AddShould(termQuery)
+		}
+	}
+	if q.operator == bluge.MatchQueryOperatorOr && q.minShould > 0 {
+		boolQuery.SetMinShould(q.minShould)
+	}
+	return boolQuery.Searcher(i, options)
+}
+
+// combinedTermQuery matches a term in any of the fields and scores it with the combined statistics of the fields
+type combinedTermQuery struct {
+	term        string
+	fields      []string
+	weights     []float64
+	lengthField string
+}
+
+func (q *combinedTermQuery) Searcher(i search.Reader, options search.SearcherOptions) (search.Searcher, error) {
+	s := &combinedTermSearcher{reader: i, term: q.term, explain: options.Explain}
+	if q.lengthField != "" {
+		dvReadable, ok := i.(search.DocumentValueReadable)
+		if !ok {
+			return nil, fmt.Errorf("combined_fields: reader doesn't support doc values")
+		}
+		dvReader, err := dvReadable.DocumentValueReader([]string{q.lengthField})
+		if err != nil {
+			return nil, err
+		}
+		s.docValues = dvReader
+		s.weights = make(map[string]float64, len(q.fields))
+		for n, field := range q.fields {
+			s.weights[field] += q.weights[n]
+		}
+	}
+	var docCount, docFreq uint64
+	var sumTotalTermFreq float64
+	for n, field := range q.fields {
+		stats, err := i.CollectionStats(field)
+		if err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+		if stats.DocumentCount() > docCount {
+			docCount = stats.DocumentCount()
+		}
+		sumTotalTermFreq += q.weights[n] * float64(stats.SumTotalTermFrequency())
+
+		postings, err := i.PostingsIterator([]byte(q.term), field, true, true, options.IncludeTermVectors)
+		if err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+		if postings.Count() > docFreq {
+			docFreq = postings.Count()
+		}
+		s.cursors = append(s.cursors, &postingsCursor{postings: postings, weight: q.weights[n]})
+	}
+
+	if docFreq > docCount {
+		docFreq = docCount
+	}
+	s.docFreq = docFreq
+	s.docCount = docCount
+	s.idf = similarity.NewBM25Similarity().Idf(docFreq, docCount)
+	// the average length of the combined field, like the document length it is summed over all the fields
+	if docCount > 0 {
+		s.avgDocLen = sumTotalTermFreq / float64(docCount)
+	}
+	return s, nil
+}
+
+// postingsCursor keeps the current posting of a field, the posting is copied as the iterator may reuse it
+type postingsCursor struct {
+	postings  segment.PostingsIterator
+	weight    float64
+	number    uint64
+	freq      int
+	norm      float64
+	locations []search.FieldTermLocation
+	started   bool
+	pending   bool // positioned on a posting which is not returned yet
+	done      bool
+}
+
+func (c *postingsCursor) set(p segment.Posting, term string) {
+	c.started = true
+	if p == nil {
+		c.done = true
+		c.pending = false
+		return
+	}
+	c.number = p.Number()
+	c.freq = p.Frequency()
+	c.norm = p.Norm()
+	c.locations = c.locations[:0]
+	for _, l := range p.Locations() {
+		c.locations = append(c.locations, search.FieldTermLocation{
+			Field:    l.Field(),
+			Term:     term,
+			Location: search.Location{Pos: l.Pos(), Start: l.Start(), End: l.End()},
+		})
+	}
+	c.pending = true
+}
+
+type combinedTermSearcher struct {
+	reader    search.Reader
+	term      string
+	cursors   []*postingsCursor
+	docValues segment.DocumentValueReader // lengths of the fields, see FieldLengthField
+	weights   map[string]float64          // weights of the fields by name
+	docFreq   uint64
+	docCount  uint64
+	idf       float64
+	avgDocLen float64
+	explain   bool
+}
+
+func (s *combinedTermSearcher) Next(ctx *search.Context) (*search.DocumentMatch, error) {
+	for _, c := range s.cursors {
+		if !c.done && !c.pending {
+			p, err := c.postings.Next()
+			if err != nil {
+				return nil, err
+			}
+			c.set(p, s.term)
+		}
+	}
+	return s.match(ctx)
+}
+
+func (s *combinedTermSearcher) Advance(ctx *search.Context, number uint64) (*search.DocumentMatch, error) {
+	for _, c := range s.cursors {
+		if c.done || (c.pending && c.number >= number) {
+			continue
+		}
+		var p segment.Posting
+		var err error
+		if c.started && c.number >= number {
+			p, err = c.postings.Next()
+		} else {
+			p, err = c.postings.Advance(number)
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.set(p, s.term)
+	}
+	return s.match(ctx)
+}
+
+// match combines the postings of the fields on the lowest document number
+func (s *combinedTermSearcher) match(ctx *search.Context) (*search.DocumentMatch, error) {
+	var number uint64
+	found := false
+	for _, c := range s.cursors {
+		if c.pending && (!found || c.number < number) {
+			number = c.number
+			found = true
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+
+	rv := ctx.DocumentMatchPool.Get()
+	rv.SetReader(s.reader)
+	rv.Number = number
+	docLen, ok, err := s.docLength(number)
+	if err != nil {
+		return nil, err
+	}
+	var freq float64
+	for _, c := range s.cursors {
+		if !c.pending || c.number != number {
+			continue
+		}
+		freq += c.weight * float64(c.freq)
+		if !ok {
+			docLen += c.weight * float64(math.Float32bits(float32(c.norm)))
+		}
+		rv.FieldTermLocations = append(rv.FieldTermLocations, c.locations...)
+		c.pending = false
+	}
+
+	weight := s.idf
+	normInverse := 1.0
+	if s.avgDocLen > 0 {
+		normInverse = 1 / (bm25K1 * ((1 - bm25B) + bm25B*docLen/s.avgDocLen))
+	}
+	rv.Score = weight - weight/(1+freq*normInverse)
+	if s.explain {
+		rv.Explanation = search.NewExplanation(rv.Score,
+			fmt.Sprintf("score(freq=%g), computed as idf * tf from combined fields:", freq),
+			search.NewExplanation(s.idf, "idf, computed as log(1 + (N - n + 0.5) / (n + 0.5)) from:",
+				search.NewExplanation(float64(s.docFreq), "n, number of documents containing term"),
+				search.NewExplanation(float64(s.docCount), "N, total number of documents with field")),
+			search.NewExplanation(freq, "freq, weighted occurrences of term within document"),
+			search.NewExplanation(docLen, "dl, weighted length of fields"),
+			search.NewExplanation(s.avgDocLen, "avgdl, average weighted length of fields"))
+	}
+	return rv, nil
+}
+
+// docLength returns the weighted length of all the fields of the document from the FieldLengthField,
+// it reports false if the document doesn't have it, then the norms of the postings are used
+func (s *combinedTermSearcher) docLength(number uint64) (float64, bool, error) {
+	if s.docValues == nil {
+		return 0, false, nil
+	}
+	var docLen float64
+	found := false
+	err := s.docValues.VisitDocumentValues(number, func(field string, term []byte) {
+		name, n, ok := decodeFieldLength(term)
+		if !ok {
+			return
+		}
+		found = true
+		docLen += s.weights[name] * float64(n)
+	})
+	return docLen, found, err
+}
+
+func (s *combinedTermSearcher) Close() error {
+	var err error
+	for _, c := range s.cursors {
+		if err2 := c.postings.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+func (s *combinedTermSearcher) Count() uint64 {
+	var count uint64
+	for _, c := range s.cursors {
+		count += c.postings.Count()
+	}
+	return count
+}
+
+func (s *combinedTermSearcher) Min() int {
+	return 0
+}
+
+func (s *combinedTermSearcher) Size() int {
+	size := 0
+	for _, c := range s.cursors {
+		size += c.postings.Size()
+	}
+	return size
+}
+
+func (s *combinedTermSearcher) DocumentMatchPoolSize() int {
+	return 1
+}
@@ -0,0 +1,127 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/blugelabs/bluge"
+)
+
+// Describe returns the query in the style of the lucene query syntax, the clauses of boolean queries are
+// prefixed with + for must and - for must not, the queries without a syntax are described by their type
+func Describe(q bluge.Query) string {
+	switch q := q.(type) {
+	case *bluge.BooleanQuery:
+		clauses := make([]string, 0, len(q.Musts())+len(q.Shoulds())+len(q.MustNots()))
+		for _, clause := range q.Musts() {
+			clauses = append(clauses, "+"+describeClause(clause))
+		}
+		for _, clause := range q.Shoulds() {
+			clauses = append(clauses, describeClause(clause))
+		}
+		for _, clause := range q.MustNots() {
+			clauses = append(clauses, "-"+describeClause(clause))
+		}
+		s := strings.Join(clauses, " ")
+		if q.MinShould() > 0 {
+			s = fmt.Sprintf("(%s)~%d", s, q.MinShould())
+		}
+		if q.Boost() != 1 {
+			s = fmt.Sprintf("(%s)^%v", s, q.Boost())
+		}
+		return s
+	case *bluge.TermQuery:
+		return boosted(fielded(q.Field(), q.Term()), q.Boost())
+	case *bluge.MatchQuery:
+		return boosted(fielded(q.Field(), q.Match()), q.Boost())
+	case *bluge.MatchPhraseQuery:
+		return boosted(fielded(q.Field(), "\""+q.Phrase()+"\""), q.Boost())
+	case *bluge.PrefixQuery:
+		return boosted(fielded(q.Field(), q.Prefix()+"*"), q.Boost())
+	case *bluge.WildcardQuery:
+		return boosted(fielded(q.Field(), q.Wildcard()), q.Boost())
+	case *bluge.RegexpQuery:
+		return boosted(fielded(q.Field(), "/"+q.Regexp()+"/"), q.Boost())
+	case *bluge.FuzzyQuery:
+		return boosted(fielded(q.Field(), fmt.Sprintf("%s~%d", q.Term(), q.Fuzziness())), q.Boost())
+	case *bluge.NumericRangeQuery:
+		min, minInclusive := q.Min()
+		max, maxInclusive := q.Max()
+		return boosted(describeRange(q.Field(), fmt.Sprint(min), fmt.Sprint(max), minInclusive, maxInclusive), q.Boost())
+	case *bluge.DateRangeQuery:
+		start, startInclusive := q.Start()
+		end, endInclusive := q.End()
+		return boosted(describeRange(q.Field(), start.Format(time.RFC3339Nano), end.Format(time.RFC3339Nano), startInclusive, endInclusive), q.Boost())
+	case *bluge.TermRangeQuery:
+		min, minInclusive := q.Min()
+		max, maxInclusive := q.Max()
+		return boosted(describeRange(q.Field(), min, max, minInclusive, maxInclusive), q.Boost())
+	case *bluge.MatchAllQuery:
+		return boosted("*:*", q.Boost())
+	case *bluge.MatchNoneQuery:
+		return "MatchNoDocsQuery"
+	}
+	return TypeName(q)
+}
+
+// TypeName returns the name of the type of the query
+func TypeName(q interface{}) string {
+	t := reflect.TypeOf(q)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// describeClause groups the clauses of a boolean query with more than one clause
+func describeClause(q bluge.Query) string {
+	s := Describe(q)
+	if bq, ok := q.(*bluge.BooleanQuery); ok && bq.Boost() == 1 && bq.MinShould() == 0 &&
+		len(bq.Musts())+len(bq.Shoulds())+len(bq.MustNots()) > 1 {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+func describeRange(field, min, max string, minInclusive, maxInclusive bool) string {
+	open, end := "{", "}"
+	if minInclusive {
+		open = "["
+	}
+	if maxInclusive {
+		end = "]"
+	}
+	return fielded(field, fmt.Sprintf("%s%s TO %s%s", open, min, max, end))
+}
+
+// fielded prefixes the value with the field, the values of the default field are not prefixed
+func fielded(field, value string) string {
+	if field == "" {
+		return value
+	}
+	return field + ":" + value
+}
+
+func boosted(s string, boost float64) string {
+	if boost != 1 {
+		return fmt.Sprintf("%s^%v", s, boost)
+	}
+	return s
+}
@@ -0,0 +1,143 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package query
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+	segment "github.com/blugelabs/bluge_segment_api"
+)
+
+// FieldLengthField keeps the analyzed length of the included fields of the document as doc values,
+// combined_fields reads it for the length of the fields which don't contain the term. It consumes the
+// fields when the document is analyzed, like the composite fields, each term is the name of a field and
+// its length separated by \x00.
+type FieldLengthField struct {
+	name    string
+	include map[string]struct{}
+	lengths map[string]int
+	terms   []fieldLengthTerm
+}
+
+// NewFieldLengthField returns a FieldLengthField keeping the length of the fields
+func NewFieldLengthField(name string, fields []string) *FieldLengthField {
+	include := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		include[field] = struct{}{}
+	}
+	return &FieldLengthField{name: name, include: include, lengths: make(map[string]int, len(include))}
+}
+
+func (f *FieldLengthField) Consume(field bluge.Field) {
+	if _, ok := f.include[field.Name()]; ok {
+		f.lengths[field.Name()] += field.Length()
+		f.terms = nil
+	}
+}
+
+func (f *FieldLengthField) Name() string {
+	return f.name
+}
+
+func (f *FieldLengthField) Length() int {
+	return len(f.lengths)
+}
+
+// EachTerm visits the terms sorted by field name, the segment visits them more than once
+func (f *FieldLengthField) EachTerm(vt segment.VisitTerm) {
+	if f.terms == nil {
+		names := make([]string, 0, len(f.lengths))
+		for name := range f.lengths {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		f.terms = make([]fieldLengthTerm, len(names))
+		for i, name := range names {
+			f.terms[i] = fieldLengthTerm(name + "\x00" + strconv.Itoa(f.lengths[name]))
+		}
+	}
+	for _, term := range f.terms {
+		vt(term)
+	}
+}
+
+func (f *FieldLengthField) Value() []byte {
+	return nil
+}
+
+func (f *FieldLengthField) Index() bool {
+	return false
+}
+
+func (f *FieldLengthField) Store() bool {
+	return false
+}
+
+func (f *FieldLengthField) IndexDocValues() bool {
+	return true
+}
+
+func (f *FieldLengthField) Analyze(int) int {
+	return 0
+}
+
+func (f *FieldLengthField) AnalyzedTokenFrequencies() analysis.TokenFrequencies {
+	return nil
+}
+
+func (f *FieldLengthField) PositionIncrementGap() int {
+	return 0
+}
+
+func (f *FieldLengthField) Size() int {
+	size := len(f.name)
+	for name := range f.include {
+		size += len(name)
+	}
+	for _, term := range f.terms {
+		size += len(term)
+	}
+	return size + len(f.lengths)*16
+}
+
+type fieldLengthTerm []byte
+
+func (t fieldLengthTerm) Term() []byte {
+	return t
+}
+
+func (t fieldLengthTerm) Frequency() int {
+	return 1
+}
+
+func (t fieldLengthTerm) EachLocation(segment.VisitLocation) {}
+
+// decodeFieldLength returns the field name and its length of a term of FieldLengthField
+func decodeFieldLength(term []byte) (string, int, bool) {
+	i := bytes.LastIndexByte(term, 0)
+	if i < 0 {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(string(term[i+1:]))
+	if err != nil {
+		return "", 0, false
+	}
+	return string(term[:i]), n, true
+}
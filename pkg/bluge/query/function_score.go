@@ -0,0 +1,365 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/search"
+	segment "github.com/blugelabs/bluge_segment_api"
+)
+
+// ScoreFunction computes the score of a function for a document
+type ScoreFunction interface {
+	// Fields returns the fields whose doc values are needed by the function
+	Fields() []string
+	// Score returns the score of the function, score is the score of the query and values are the doc values of the fields
+	Score(score float64, values map[string][][]byte) (float64, error)
+}
+
+// FunctionClause is a function of the function score query, the function applies to the documents matching the filter,
+// a nil filter matches all documents and a nil function scores 1 so only the weight applies
+type FunctionClause struct {
+	Filter   bluge.Query
+	Function ScoreFunction
+	Weight   float64
+}
+
+// FunctionScoreQuery modifies the scores of the documents matching the query with the functions
+type FunctionScoreQuery struct {
+	query     bluge.Query
+	functions []*FunctionClause
+	scoreMode string
+	boostMode string
+	maxBoost  float64
+	minScore  *float64
+	boost     float64
+}
+
+// NewFunctionScoreQuery returns a FunctionScoreQuery, the function scores are multiplied together and with the query score by default
+func NewFunctionScoreQuery(query bluge.Query) *FunctionScoreQuery {
+	return &FunctionScoreQuery{
+		query:     query,
+		scoreMode: "multiply",
+		boostMode: "multiply",
+		maxBoost:  math.MaxFloat64,
+		boost:     1.0,
+	}
+}
+
+func (q *FunctionScoreQuery) AddFunction(clause *FunctionClause) *FunctionScoreQuery {
+	q.functions = append(q.functions, clause)
+	return q
+}
+
+// SetScoreMode sets how the function scores are combined: multiply, sum, avg, first, max or min
+func (q *FunctionScoreQuery) SetScoreMode(mode string) *FunctionScoreQuery {
+	q.scoreMode = mode
+	return q
+}
+
+// SetBoostMode sets how the function score is combined with the query score: multiply, replace, sum, avg, max or min
+func (q *FunctionScoreQuery) SetBoostMode(mode string) *FunctionScoreQuery {
+	q.boostMode = mode
+	return q
+}
+
+// SetMaxBoost restricts the function score
+func (q *FunctionScoreQuery) SetMaxBoost(maxBoost float64) *FunctionScoreQuery {
+	q.maxBoost = maxBoost
+	return q
+}
+
+// SetMinScore excludes the documents whose final score is lower
+func (q *FunctionScoreQuery) SetMinScore(minScore float64) *FunctionScoreQuery {
+	q.minScore = &minScore
+	return q
+}
+
+func (q *FunctionScoreQuery) SetBoost(boost float64) *FunctionScoreQuery {
+	q.boost = boost
+	return q
+}
+
+func (q *FunctionScoreQuery) Boost() float64 {
+	return q.boost
+}
+
+func (q *FunctionScoreQuery) Query() bluge.Query {
+	return q.query
+}
+
+func (q *FunctionScoreQuery) Searcher(i search.Reader, options search.SearcherOptions) (search.Searcher, error) {
+	s := &functionScoreSearcher{
+		query:   q,
+		explain: options.Explain,
+		filters: make([]search.Searcher, len(q.functions)),
+		current: make([]*search.DocumentMatch, len(q.functions)),
+		done:    make([]bool, len(q.functions)),
+	}
+
+	var err error
+	if s.searcher, err = q.query.Searcher(i, options); err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	seen := make(map[string]struct{})
+	for n, clause := range q.functions {
+		if clause.Filter != nil {
+			// filters only select the documents, they don't need scores
+			filterOptions := options
+			filterOptions.Score = "none"
+			filterOptions.Explain = false
+			filterOptions.IncludeTermVectors = false
+			if s.filters[n], err = clause.Filter.Searcher(i, filterOptions); err != nil {
+				_ = s.Close()
+				return nil, err
+			}
+		}
+		if clause.Function != nil {
+			for _, field := range clause.Function.Fields() {
+				if _, ok := seen[field]; !ok {
+					seen[field] = struct{}{}
+					fields = append(fields, field)
+				}
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		dvReadable, ok := i.(search.DocumentValueReadable)
+		if !ok {
+			_ = s.Close()
+			return nil, fmt.Errorf("function_score: reader doesn't support doc values")
+		}
+		if s.docValues, err = dvReadable.DocumentValueReader(fields); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+type functionScoreSearcher struct {
+	query     *FunctionScoreQuery
+	searcher  search.Searcher
+	filters   []search.Searcher
+	current   []*search.DocumentMatch // the current matches of the filters
+	done      []bool
+	docValues segment.DocumentValueReader
+	explain   bool
+}
+
+func (s *functionScoreSearcher) Next(ctx *search.Context) (*search.DocumentMatch, error) {
+	for {
+		match, err := s.searcher.Next(ctx)
+		if err != nil || match == nil {
+			return nil, err
+		}
+		if ok, err := s.score(ctx, match); err != nil || ok {
+			return match, err
+		}
+		ctx.DocumentMatchPool.Put(match)
+	}
+}
+
+func (s *functionScoreSearcher) Advance(ctx *search.Context, number uint64) (*search.DocumentMatch, error) {
+	match, err := s.searcher.Advance(ctx, number)
+	if err != nil || match == nil {
+		return nil, err
+	}
+	if ok, err := s.score(ctx, match); err != nil || ok {
+		return match, err
+	}
+	ctx.DocumentMatchPool.Put(match)
+	return s.Next(ctx)
+}
+
+// score computes the final score of the match, it reports false if the score is lower than the min score
+func (s *functionScoreSearcher) score(ctx *search.Context, match *search.DocumentMatch) (bool, error) {
+	values := make(map[string][][]byte)
+	if s.docValues != nil {
+		err := s.docValues.VisitDocumentValues(match.Number, func(field string, term []byte) {
+			values[field] = append(values[field], append([]byte(nil), term...))
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	q := s.query
+	queryScore := match.Score
+	var scores, weights []float64
+	for n, clause := range q.functions {
+		ok, err := s.matchFilter(ctx, n, match.Number)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			continue
+		}
+		score := 1.0
+		if clause.Function != nil {
+			if score, err = clause.Function.Score(queryScore, values); err != nil {
+				return false, err
+			}
+		}
+		scores = append(scores, score*clause.Weight)
+		weights = append(weights, clause.Weight)
+		if q.scoreMode == "first" {
+			break
+		}
+	}
+
+	functionScore := 1.0
+	if len(scores) > 0 {
+		switch q.scoreMode {
+		case "sum", "avg":
+			functionScore = 0
+			totalWeight := 0.0
+			for n, score := range scores {
+				functionScore += score
+				totalWeight += weights[n]
+			}
+			if q.scoreMode == "avg" && totalWeight != 0 {
+				functionScore /= totalWeight
+			}
+		case "first":
+			functionScore = scores[0]
+		case "max":
+			functionScore = scores[0]
+			for _, score := range scores {
+				functionScore = math.Max(functionScore, score)
+			}
+		case "min":
+			functionScore = scores[0]
+			for _, score := range scores {
+				functionScore = math.Min(functionScore, score)
+			}
+		default:
+			for _, score := range scores {
+				functionScore *= score
+			}
+		}
+	}
+	functionScore = math.Min(functionScore, q.maxBoost)
+
+	var score float64
+	switch q.boostMode {
+	case "replace":
+		score = functionScore
+	case "sum":
+		score = queryScore + functionScore
+	case "avg":
+		score = (queryScore + functionScore) / 2
+	case "max":
+		score = math.Max(queryScore, functionScore)
+	case "min":
+		score = math.Min(queryScore, functionScore)
+	default:
+		score = queryScore * functionScore
+	}
+	score *= q.boost
+
+	if s.explain {
+		var children []*search.Explanation
+		if match.Explanation != nil {
+			children = append(children, match.Explanation)
+		}
+		children = append(children, search.NewExplanation(functionScore,
+			fmt.Sprintf("function score, score mode [%s], computed from %d matching functions", q.scoreMode, len(scores))))
+		if q.boost != 1.0 {
+			children = append(children, search.NewExplanation(q.boost, "boost"))
+		}
+		match.Explanation = search.NewExplanation(score, fmt.Sprintf("function score, boost mode [%s], product of:", q.boostMode), children...)
+	}
+	match.Score = score
+
+	return q.minScore == nil || score >= *q.minScore, nil
+}
+
+// matchFilter reports if the document matches the filter of the function, the filter searchers move forward only
+func (s *functionScoreSearcher) matchFilter(ctx *search.Context, n int, number uint64) (bool, error) {
+	filter := s.filters[n]
+	if filter == nil {
+		return true, nil
+	}
+	if s.done[n] {
+		return false, nil
+	}
+	if s.current[n] == nil || s.current[n].Number < number {
+		if s.current[n] != nil {
+			ctx.DocumentMatchPool.Put(s.current[n])
+		}
+		var err error
+		if s.current[n], err = filter.Advance(ctx, number); err != nil {
+			return false, err
+		}
+		if s.current[n] == nil {
+			s.done[n] = true
+			return false, nil
+		}
+	}
+	return s.current[n].Number == number, nil
+}
+
+func (s *functionScoreSearcher) Close() error {
+	var err error
+	if s.searcher != nil {
+		err = s.searcher.Close()
+	}
+	for _, filter := range s.filters {
+		if filter == nil {
+			continue
+		}
+		if err2 := filter.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+func (s *functionScoreSearcher) Count() uint64 {
+	return s.searcher.Count()
+}
+
+func (s *functionScoreSearcher) Min() int {
+	return s.searcher.Min()
+}
+
+func (s *functionScoreSearcher) Size() int {
+	size := s.searcher.Size()
+	for _, filter := range s.filters {
+		if filter != nil {
+			size += filter.Size()
+		}
+	}
+	return size
+}
+
+func (s *functionScoreSearcher) DocumentMatchPoolSize() int {
+	size := s.searcher.DocumentMatchPoolSize() + 1
+	for _, filter := range s.filters {
+		if filter != nil {
+			size += filter.DocumentMatchPoolSize()
+		}
+	}
+	return size
+}
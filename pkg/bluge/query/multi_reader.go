@@ -0,0 +1,60 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package query
+
+import (
+	"sync"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/search"
+	"github.com/blugelabs/bluge/search/searcher"
+)
+
+// MultiReaderQuery searches each reader of bluge.MultiSearch with its own query,
+// the queries are in the order of the readers, which are searched in order
+type MultiReaderQuery struct {
+	queries []bluge.Query
+
+	lock    sync.Mutex
+	readers map[search.Reader]int
+}
+
+func NewMultiReaderQuery(queries []bluge.Query) *MultiReaderQuery {
+	return &MultiReaderQuery{
+		queries: queries,
+		readers: make(map[search.Reader]int),
+	}
+}
+
+// Queries returns the queries of the readers
+func (q *MultiReaderQuery) Queries() []bluge.Query {
+	return q.queries
+}
+
+func (q *MultiReaderQuery) Searcher(i search.Reader, options search.SearcherOptions) (search.Searcher, error) {
+	q.lock.Lock()
+	n, ok := q.readers[i]
+	if !ok {
+		n = len(q.readers)
+		q.readers[i] = n
+	}
+	q.lock.Unlock()
+
+	if n >= len(q.queries) {
+		return searcher.NewMatchNoneSearcher(i, options)
+	}
+	return q.queries[n].Searcher(i, options)
+}
@@ -0,0 +1,246 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/search"
+	"github.com/blugelabs/bluge/search/searcher"
+	"github.com/blugelabs/bluge/search/similarity"
+	segment "github.com/blugelabs/bluge_segment_api"
+)
+
+// NestedQuery matches the documents whose nested objects of the path match the query,
+// the nested objects are hidden documents with the path and the _id of their document in the path and parent fields
+type NestedQuery struct {
+	path        string
+	pathField   string
+	parentField string
+	query       bluge.Query
+	scoreMode   string
+	boost       float64
+}
+
+// NewNestedQuery returns a NestedQuery, the score of a document is the average score of its matching nested objects by default
+func NewNestedQuery(path, pathField, parentField string, query bluge.Query) *NestedQuery {
+	return &NestedQuery{
+		path:        path,
+		pathField:   pathField,
+		parentField: parentField,
+		query:       query,
+		scoreMode:   "avg",
+		boost:       1.0,
+	}
+}
+
+// SetScoreMode sets how the scores of the matching nested objects are combined: avg, max, min, sum or none
+func (q *NestedQuery) SetScoreMode(mode string) *NestedQuery {
+	q.scoreMode = mode
+	return q
+}
+
+func (q *NestedQuery) SetBoost(boost float64) *NestedQuery {
+	q.boost = boost
+	return q
+}
+
+func (q *NestedQuery) Boost() float64 {
+	return q.boost
+}
+
+func (q *NestedQuery) Path() string {
+	return q.path
+}
+
+// ChildQuery returns the query of the nested objects, it only matches the hidden documents of the path
+func (q *NestedQuery) ChildQuery() bluge.Query {
+	filter := bluge.NewBooleanQuery().SetBoost(0).AddMust(bluge.NewTermQuery(q.path).SetField(q.pathField))
+	return bluge.NewBooleanQuery().AddMust(q.query, filter)
+}
+
+func (q *NestedQuery) Searcher(i search.Reader, options search.SearcherOptions) (search.Searcher, error) {
+	dvReadable, ok := i.(search.DocumentValueReadable)
+	if !ok {
+		return nil, fmt.Errorf("nested: reader doesn't support doc values")
+	}
+
+	childOptions := options
+	childOptions.Explain = false
+	childOptions.IncludeTermVectors = false
+	childSearcher, err := q.ChildQuery().Searcher(i, childOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer childSearcher.Close()
+
+	dvReader, err := dvReadable.DocumentValueReader([]string{q.parentField})
+	if err != nil {
+		return nil, err
+	}
+
+	// collect the scores of the matching nested objects by their documents
+	scores := make(map[string]*nestedScore)
+	var parents []string
+	ctx := search.NewSearchContext(childSearcher.DocumentMatchPoolSize(), 0)
+	match, err := childSearcher.Next(ctx)
+	for err == nil && match != nil {
+		var parent string
+		err = dvReader.VisitDocumentValues(match.Number, func(field string, term []byte) {
+			parent = string(term)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if parent != "" {
+			score, ok := scores[parent]
+			if !ok {
+				score = &nestedScore{min: math.MaxFloat64}
+				scores[parent] = score
+				parents = append(parents, parent)
+			}
+			score.add(match.Score)
+		}
+		ctx.DocumentMatchPool.Put(match)
+		match, err = childSearcher.Next(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(parents) == 0 {
+		return searcher.NewMatchNoneSearcher(i, options)
+	}
+
+	idOptions := options
+	idOptions.Score = "none"
+	idOptions.Explain = false
+	idSearcher, err := searcher.NewMultiTermSearcher(i, parents, "_id", 1.0,
+		similarity.ConstantScorer(0), similarity.NewCompositeSumScorer(), idOptions, false)
+	if err != nil {
+		return nil, err
+	}
+	idReader, err := dvReadable.DocumentValueReader([]string{"_id"})
+	if err != nil {
+		_ = idSearcher.Close()
+		return nil, err
+	}
+
+	return &nestedSearcher{
+		query:    q,
+		searcher: idSearcher,
+		idReader: idReader,
+		scores:   scores,
+		explain:  options.Explain,
+	}, nil
+}
+
+// nestedScore is the combined score of the matching nested objects of a document
+type nestedScore struct {
+	count int
+	sum   float64
+	min   float64
+	max   float64
+}
+
+func (s *nestedScore) add(score float64) {
+	s.count++
+	s.sum += score
+	s.min = math.Min(s.min, score)
+	s.max = math.Max(s.max, score)
+}
+
+func (s *nestedScore) score(mode string) float64 {
+	switch mode {
+	case "max":
+		return s.max
+	case "min":
+		return s.min
+	case "sum":
+		return s.sum
+	case "none":
+		return 0
+	default:
+		return s.sum / float64(s.count)
+	}
+}
+
+type nestedSearcher struct {
+	query    *NestedQuery
+	searcher search.Searcher
+	idReader segment.DocumentValueReader
+	scores   map[string]*nestedScore
+	explain  bool
+}
+
+func (s *nestedSearcher) Next(ctx *search.Context) (*search.DocumentMatch, error) {
+	match, err := s.searcher.Next(ctx)
+	if err != nil || match == nil {
+		return nil, err
+	}
+	return match, s.score(match)
+}
+
+func (s *nestedSearcher) Advance(ctx *search.Context, number uint64) (*search.DocumentMatch, error) {
+	match, err := s.searcher.Advance(ctx, number)
+	if err != nil || match == nil {
+		return nil, err
+	}
+	return match, s.score(match)
+}
+
+// score sets the score of the document from the scores of its matching nested objects
+func (s *nestedSearcher) score(match *search.DocumentMatch) error {
+	var id string
+	err := s.idReader.VisitDocumentValues(match.Number, func(field string, term []byte) {
+		id = string(term)
+	})
+	if err != nil {
+		return err
+	}
+	nested, ok := s.scores[id]
+	if !ok {
+		match.Score = 0
+		return nil
+	}
+	match.Score = nested.score(s.query.scoreMode) * s.query.boost
+	if s.explain {
+		match.Explanation = search.NewExplanation(match.Score,
+			fmt.Sprintf("score mode [%s] of %d matching nested objects of path [%s], boost %v", s.query.scoreMode, nested.count, s.query.path, s.query.boost))
+	}
+	return nil
+}
+
+func (s *nestedSearcher) Close() error {
+	return s.searcher.Close()
+}
+
+func (s *nestedSearcher) Count() uint64 {
+	return s.searcher.Count()
+}
+
+func (s *nestedSearcher) Min() int {
+	return s.searcher.Min()
+}
+
+func (s *nestedSearcher) Size() int {
+	return s.searcher.Size()
+}
+
+func (s *nestedSearcher) DocumentMatchPoolSize() int {
+	return s.searcher.DocumentMatchPoolSize()
+}
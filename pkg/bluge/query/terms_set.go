@@ -0,0 +1,324 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/numeric"
+	"github.com/blugelabs/bluge/search"
+	segment "github.com/blugelabs/bluge_segment_api"
+)
+
+// MinimumShouldMatchScript computes the number of terms that must match for a document
+type MinimumShouldMatchScript interface {
+	// Fields returns the fields whose doc values are needed by the script
+	Fields() []string
+	// Execute returns the number of terms that must match, values are the doc values of the fields
+	Execute(values map[string][][]byte) (float64, error)
+}
+
+// TermsSetQuery matches the documents containing a minimum number of the terms,
+// the minimum is read from a numeric field or computed by a script for each document
+type TermsSetQuery struct {
+	terms     []string
+	field     string
+	msmField  string
+	msmScript MinimumShouldMatchScript
+	boost     float64
+}
+
+// NewTermsSetQuery returns a TermsSetQuery for the terms of the field
+func NewTermsSetQuery(field string, terms []string) *TermsSetQuery {
+	return &TermsSetQuery{
+		terms: terms,
+		field: field,
+		boost: 1.0,
+	}
+}
+
+// SetMinimumShouldMatchField sets the numeric field containing the number of terms that must match
+func (q *TermsSetQuery) SetMinimumShouldMatchField(field string) *TermsSetQuery {
+	q.msmField = field
+	return q
+}
+
+// SetMinimumShouldMatchScript sets the script computing the number of terms that must match
+func (q *TermsSetQuery) SetMinimumShouldMatchScript(script MinimumShouldMatchScript) *TermsSetQuery {
+	q.msmScript = script
+	return q
+}
+
+func (q *TermsSetQuery) SetBoost(boost float64) *TermsSetQuery {
+	q.boost = boost
+	return q
+}
+
+func (q *TermsSetQuery) Boost() float64 {
+	return q.boost
+}
+
+func (q *TermsSetQuery) Field() string {
+	return q.field
+}
+
+func (q *TermsSetQuery) Terms() []string {
+	return q.terms
+}
+
+func (q *TermsSetQuery) Searcher(i search.Reader, options search.SearcherOptions) (search.Searcher, error) {
+	var fields []string
+	if q.msmScript != nil {
+		fields = q.msmScript.Fields()
+	} else if q.msmField != "" {
+		fields = []string{q.msmField}
+	}
+	s := &termsSetSearcher{
+		reader:    i,
+		msmField:  q.msmField,
+		msmScript: q.msmScript,
+		boost:     q.boost,
+		explain:   options.Explain,
+	}
+	if len(fields) > 0 {
+		dvReadable, ok := i.(search.DocumentValueReadable)
+		if !ok {
+			return nil, fmt.Errorf("terms_set: reader doesn't support doc values")
+		}
+		dvReader, err := dvReadable.DocumentValueReader(fields)
+		if err != nil {
+			return nil, err
+		}
+		s.docValues = dvReader
+	}
+
+	seen := make(map[string]struct{}, len(q.terms))
+	for _, term := range q.terms {
+		if _, ok := seen[term]; ok {
+			continue
+		}
+		seen[term] = struct{}{}
+		searcher, err := bluge.NewTermQuery(term).SetField(q.field).Searcher(i, options)
+		if err != nil {
+			_ = s.Close()
+			return nil, err
+		}
+		s.cursors = append(s.cursors, &searcherCursor{searcher: searcher})
+	}
+	return s, nil
+}
+
+// searcherCursor keeps the current match of a sub searcher
+type searcherCursor struct {
+	searcher search.Searcher
+	match    *search.DocumentMatch
+	number   uint64
+	started  bool
+	done     bool
+}
+
+func (c *searcherCursor) set(match *search.DocumentMatch) {
+	c.started = true
+	c.match = match
+	if match == nil {
+		c.done = true
+		return
+	}
+	c.number = match.Number
+}
+
+type termsSetSearcher struct {
+	reader    search.Reader
+	cursors   []*searcherCursor
+	msmField  string
+	msmScript MinimumShouldMatchScript
+	docValues segment.DocumentValueReader
+	boost     float64
+	explain   bool
+}
+
+func (s *termsSetSearcher) Next(ctx *search.Context) (*search.DocumentMatch, error) {
+	for {
+		for _, c := range s.cursors {
+			if !c.done && c.match == nil {
+				match, err := c.searcher.Next(ctx)
+				if err != nil {
+					return nil, err
+				}
+				c.set(match)
+			}
+		}
+		match, ok, err := s.next(ctx)
+		if err != nil || match == nil || ok {
+			return match, err
+		}
+		ctx.DocumentMatchPool.Put(match)
+	}
+}
+
+func (s *termsSetSearcher) Advance(ctx *search.Context, number uint64) (*search.DocumentMatch, error) {
+	for _, c := range s.cursors {
+		if c.done || (c.match != nil && c.number >= number) {
+			continue
+		}
+		if c.match != nil {
+			ctx.DocumentMatchPool.Put(c.match)
+			c.match = nil
+		}
+		var match *search.DocumentMatch
+		var err error
+		if c.started && c.number >= number {
+			match, err = c.searcher.Next(ctx)
+		} else {
+			match, err = c.searcher.Advance(ctx, number)
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.set(match)
+	}
+	match, ok, err := s.next(ctx)
+	if err != nil || match == nil || ok {
+		return match, err
+	}
+	ctx.DocumentMatchPool.Put(match)
+	return s.Next(ctx)
+}
+
+// next combines the matches on the lowest document number and reports if enough terms matched
+func (s *termsSetSearcher) next(ctx *search.Context) (*search.DocumentMatch, bool, error) {
+	var rv *search.DocumentMatch
+	for _, c := range s.cursors {
+		if c.match != nil && (rv == nil || c.number < rv.Number) {
+			rv = c.match
+		}
+	}
+	if rv == nil {
+		return nil, false, nil
+	}
+
+	number := rv.Number
+	matched := 0
+	var explanations []*search.Explanation
+	score := 0.0
+	for _, c := range s.cursors {
+		if c.match == nil || c.number != number {
+			continue
+		}
+		matched++
+		score += c.match.Score
+		if s.explain && c.match.Explanation != nil {
+			explanations = append(explanations, c.match.Explanation)
+		}
+		if c.match != rv {
+			rv.FieldTermLocations = append(rv.FieldTermLocations, c.match.FieldTermLocations...)
+			ctx.DocumentMatchPool.Put(c.match)
+		}
+		c.match = nil
+	}
+
+	required, err := s.minimumShouldMatch(number)
+	if err != nil {
+		return rv, false, err
+	}
+	if required < 1 {
+		required = 1
+	}
+	if matched < required {
+		return rv, false, nil
+	}
+
+	rv.Score = score * s.boost
+	if s.explain {
+		rv.Explanation = search.NewExplanation(rv.Score,
+			fmt.Sprintf("sum of %d matching terms, minimum should match %d:", matched, required), explanations...)
+	}
+	return rv, true, nil
+}
+
+func (s *termsSetSearcher) minimumShouldMatch(number uint64) (int, error) {
+	values := make(map[string][][]byte)
+	if s.docValues != nil {
+		err := s.docValues.VisitDocumentValues(number, func(field string, term []byte) {
+			values[field] = append(values[field], append([]byte(nil), term...))
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if s.msmScript != nil {
+		v, err := s.msmScript.Execute(values)
+		if err != nil {
+			return 0, err
+		}
+		return int(v), nil
+	}
+
+	// numeric doc values are indexed with several shifts, the exact value has shift 0
+	for _, term := range values[s.msmField] {
+		prefixCoded := numeric.PrefixCoded(term)
+		if shift, err := prefixCoded.Shift(); err != nil || shift != 0 {
+			continue
+		}
+		v, err := prefixCoded.Int64()
+		if err != nil {
+			return 0, err
+		}
+		return int(numeric.Int64ToFloat64(v)), nil
+	}
+	// the document doesn't have the field, it can't match
+	return len(s.cursors) + 1, nil
+}
+
+func (s *termsSetSearcher) Close() error {
+	var err error
+	for _, c := range s.cursors {
+		if err2 := c.searcher.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+func (s *termsSetSearcher) Count() uint64 {
+	var count uint64
+	for _, c := range s.cursors {
+		count += c.searcher.Count()
+	}
+	return count
+}
+
+func (s *termsSetSearcher) Min() int {
+	return 0
+}
+
+func (s *termsSetSearcher) Size() int {
+	size := 0
+	for _, c := range s.cursors {
+		size += c.searcher.Size()
+	}
+	return size
+}
+
+func (s *termsSetSearcher) DocumentMatchPoolSize() int {
+	size := 1
+	for _, c := range s.cursors {
+		size += c.searcher.DocumentMatchPoolSize()
+	}
+	return size
+}
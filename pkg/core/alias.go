@@ -0,0 +1,402 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog/log"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/query"
+	"github.com/zinclabs/zinc/pkg/zutils"
+)
+
+// aliases are the index aliases by alias name and index name, they are persisted in the _alias system index
+var aliases = struct {
+	lock sync.RWMutex
+	list map[string]map[string]*meta.Alias
+}{list: make(map[string]map[string]*meta.Alias)}
+
+// LoadAliases loads the aliases from the _alias system index, aliases of missing indexes are skipped
+func LoadAliases() error {
+	reader, err := ZINC_SYSTEM_INDEX_LIST["_alias"].Writer.Reader()
+	if err != nil {
+		return fmt.Errorf("core.LoadAliases: error accessing reader: %s", err.Error())
+	}
+	defer reader.Close()
+
+	dmi, err := reader.Search(context.Background(), bluge.NewAllMatches(bluge.NewMatchAllQuery()))
+	if err != nil {
+		return fmt.Errorf("core.LoadAliases: error executing search: %s", err.Error())
+	}
+
+	aliases.lock.Lock()
+	defer aliases.lock.Unlock()
+	next, err := dmi.Next()
+	for err == nil && next != nil {
+		alias := new(meta.Alias)
+		err = next.VisitStoredFields(func(field string, value []byte) bool {
+			if field == "_source" {
+				json.Unmarshal(value, alias)
+			}
+			return true
+		})
+		if err != nil {
+			log.Printf("core.LoadAliases: error accessing stored fields: %s", err.Error())
+		}
+		if _, ok := GetIndex(alias.Index); ok {
+			if aliases.list[alias.Alias] == nil {
+				aliases.list[alias.Alias] = make(map[string]*meta.Alias)
+			}
+			aliases.list[alias.Alias][alias.Index] = alias
+		}
+		next, err = dmi.Next()
+	}
+
+	return err
+}
+
+// IsAlias checks if the name is an alias
+func IsAlias(name string) bool {
+	aliases.lock.RLock()
+	defer aliases.lock.RUnlock()
+	_, ok := aliases.list[name]
+	return ok
+}
+
+// ListAliases returns the aliases of the indexes by index name sorted by alias name,
+// names filters the aliases by name or pattern with * wildcards, all aliases are returned if it is empty
+func ListAliases(indexes []*Index, names []string) map[string][]*meta.Alias {
+	aliases.lock.RLock()
+	defer aliases.lock.RUnlock()
+
+	list := make(map[string][]*meta.Alias)
+	for _, index := range indexes {
+		for name, byIndex := range aliases.list {
+			alias, ok := byIndex[index.Name]
+			if !ok || !matchAliasName(names, name) {
+				continue
+			}
+			list[index.Name] = append(list[index.Name], alias)
+		}
+		sort.Slice(list[index.Name], func(i, j int) bool { return list[index.Name][i].Alias < list[index.Name][j].Alias })
+	}
+	return list
+}
+
+func matchAliasName(names []string, name string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, pattern := range names {
+		if pattern == "_all" || zutils.MatchWildcard(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// aliasChange is an add or remove action of UpdateAliases with its indexes resolved
+type aliasChange struct {
+	add     bool
+	indexes []*Index
+	names   []string
+	action  *meta.AliasAction
+}
+
+// UpdateAliases applies the add and remove actions of the _aliases API in order,
+// nothing is changed if one of the actions is invalid
+func UpdateAliases(actions []map[string]*meta.AliasAction) error {
+	changes := make([]aliasChange, 0, len(actions))
+	for _, item := range actions {
+		if len(item) != 1 {
+			return errors.New(errors.ErrorTypeIllegalArgumentException, "[aliases] an action must have exactly one of [add, remove]")
+		}
+		for op, action := range item {
+			if op != "add" && op != "remove" {
+				return errors.New(errors.ErrorTypeIllegalArgumentException, "[aliases] unknown action ["+op+"]")
+			}
+			if action == nil {
+				return errors.New(errors.ErrorTypeParsingException, "[aliases] action ["+op+"] should be an object")
+			}
+			change, err := newAliasChange(op == "add", action)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	aliases.lock.Lock()
+	defer aliases.lock.Unlock()
+
+	list := make(map[string]map[string]*meta.Alias, len(aliases.list))
+	for name, byIndex := range aliases.list {
+		list[name] = make(map[string]*meta.Alias, len(byIndex))
+		for indexName, alias := range byIndex {
+			list[name][indexName] = alias
+		}
+	}
+
+	type aliasKey struct{ alias, index string }
+	touched := make(map[aliasKey]struct{})
+	for _, change := range changes {
+		if change.add {
+			for _, name := range change.names {
+				if list[name] == nil {
+					list[name] = make(map[string]*meta.Alias)
+				}
+				for _, index := range change.indexes {
+					list[name][index.Name] = &meta.Alias{
+						Alias:        name,
+						Index:        index.Name,
+						Filter:       change.action.Filter,
+						IsWriteIndex: change.action.IsWriteIndex,
+					}
+					touched[aliasKey{name, index.Name}] = struct{}{}
+				}
+			}
+			continue
+		}
+
+		for _, pattern := range change.names {
+			removed := false
+			for name, byIndex := range list {
+				if !zutils.MatchWildcard(pattern, name) {
+					continue
+				}
+				for _, index := range change.indexes {
+					if _, ok := byIndex[index.Name]; ok {
+						delete(byIndex, index.Name)
+						touched[aliasKey{name, index.Name}] = struct{}{}
+						removed = true
+					}
+				}
+				if len(byIndex) == 0 {
+					delete(list, name)
+				}
+			}
+			if !removed && !strings.Contains(pattern, "*") {
+				return errors.New(errors.ErrorTypeAliasesNotFoundException, "aliases ["+pattern+"] missing")
+			}
+		}
+	}
+
+	for key := range touched {
+		var writeIndexes []string
+		for indexName, alias := range list[key.alias] {
+			if alias.IsWriteIndex != nil && *alias.IsWriteIndex {
+				writeIndexes = append(writeIndexes, indexName)
+			}
+		}
+		if len(writeIndexes) > 1 {
+			sort.Strings(writeIndexes)
+			return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf(
+				"alias [%s] has more than one write index [%s]", key.alias, strings.Join(writeIndexes, ","),
+			))
+		}
+	}
+
+	for key := range touched {
+		var err error
+		if alias, ok := list[key.alias][key.index]; ok {
+			err = storeAlias(alias)
+		} else {
+			err = deleteAlias(key.alias, key.index)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	aliases.list = list
+
+	return nil
+}
+
+// newAliasChange validates the action and resolves its indexes
+func newAliasChange(add bool, action *meta.AliasAction) (aliasChange, error) {
+	change := aliasChange{add: add, action: action}
+
+	indexNames := action.Indices
+	if action.Index != "" {
+		indexNames = append(indexNames, action.Index)
+	}
+	if len(indexNames) == 0 {
+		return change, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: One of [index] or [indices] is required;")
+	}
+	change.names = action.Aliases
+	if action.Alias != "" {
+		change.names = append(change.names, action.Alias)
+	}
+	if len(change.names) == 0 {
+		return change, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: One of [alias] or [aliases] is required;")
+	}
+
+	var err error
+	if change.indexes, err = MatchIndexes(indexNames); err != nil {
+		return change, err
+	}
+	if len(change.indexes) == 0 {
+		return change, errors.New(errors.ErrorTypeIndexNotFoundException, "no such index ["+strings.Join(indexNames, ",")+"]")
+	}
+	if !add {
+		return change, nil
+	}
+
+	for _, name := range change.names {
+		if strings.Contains(name, "*") || strings.Contains(name, ",") {
+			return change, errors.New(errors.ErrorTypeInvalidAliasNameException, "Invalid alias name ["+name+"], must not contain [*] or [,]")
+		}
+		if strings.HasPrefix(name, "_") || strings.HasPrefix(name, "-") {
+			return change, errors.New(errors.ErrorTypeInvalidAliasNameException, "Invalid alias name ["+name+"], must not start with [_] or [-]")
+		}
+		if _, ok := GetIndex(name); ok {
+			return change, errors.New(errors.ErrorTypeInvalidAliasNameException, "Invalid alias name ["+name+"], an index exists with the same name as the alias")
+		}
+	}
+	if action.Filter != nil {
+		for _, index := range change.indexes {
+			if _, err := query.Query(action.Filter, index.CachedMappings, index.CachedAnalyzers); err != nil {
+				return change, errors.New(errors.ErrorTypeIllegalArgumentException, "failed to parse filter for alias ["+change.names[0]+"]").Cause(err)
+			}
+		}
+	}
+
+	return change, nil
+}
+
+// DeleteIndexAliases removes the aliases of the deleted index
+func DeleteIndexAliases(indexName string) error {
+	aliases.lock.Lock()
+	defer aliases.lock.Unlock()
+
+	for name, byIndex := range aliases.list {
+		if _, ok := byIndex[indexName]; !ok {
+			continue
+		}
+		if err := deleteAlias(name, indexName); err != nil {
+			return err
+		}
+		delete(byIndex, indexName)
+		if len(byIndex) == 0 {
+			delete(aliases.list, name)
+		}
+	}
+	return nil
+}
+
+func storeAlias(alias *meta.Alias) error {
+	bdoc := bluge.NewDocument(alias.Index + "/" + alias.Alias)
+	bdoc.AddField(bluge.NewKeywordField("alias", alias.Alias).StoreValue().Sortable())
+	bdoc.AddField(bluge.NewKeywordField("index", alias.Index).StoreValue().Sortable())
+
+	docByteVal, _ := json.Marshal(alias)
+	bdoc.AddField(bluge.NewDateTimeField("@timestamp", time.Now()).StoreValue().Sortable().Aggregatable())
+	bdoc.AddField(bluge.NewStoredOnlyField("_source", docByteVal))
+	bdoc.AddField(bluge.NewCompositeFieldExcluding("_all", nil))
+
+	err := ZINC_SYSTEM_INDEX_LIST["_alias"].Writer.Update(bdoc.ID(), bdoc)
+	if err != nil {
+		return fmt.Errorf("alias: error updating document: %s", err.Error())
+	}
+	return nil
+}
+
+func deleteAlias(name, indexName string) error {
+	bdoc := bluge.NewDocument(indexName + "/" + name)
+	bdoc.AddField(bluge.NewCompositeFieldExcluding("_all", nil))
+	err := ZINC_SYSTEM_INDEX_LIST["_alias"].Writer.Delete(bdoc.ID())
+	if err != nil {
+		return fmt.Errorf("alias: error deleting alias: %s", err.Error())
+	}
+	return nil
+}
+
+// ResolveIndex returns the index of a single index operation on the name, an alias must have exactly one index.
+// Names which are neither an index nor an alias are returned as is.
+func ResolveIndex(name string) (string, error) {
+	aliases.lock.RLock()
+	defer aliases.lock.RUnlock()
+
+	byIndex, ok := aliases.list[name]
+	if !ok {
+		return name, nil
+	}
+	if len(byIndex) > 1 {
+		return name, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf(
+			"alias [%s] has more than one index associated with it [%s], can't execute a single index op", name, strings.Join(aliasIndexes(byIndex), ", "),
+		))
+	}
+	for indexName := range byIndex {
+		return indexName, nil
+	}
+	return name, nil
+}
+
+// ResolveWriteIndex returns the index the writes to the name go, it is the write index of an alias
+// or its only index if it isn't explicitly disabled. Names which are not an alias are returned as is.
+func ResolveWriteIndex(name string) (string, error) {
+	aliases.lock.RLock()
+	defer aliases.lock.RUnlock()
+
+	byIndex, ok := aliases.list[name]
+	if !ok {
+		return name, nil
+	}
+	for indexName, alias := range byIndex {
+		if alias.IsWriteIndex != nil && *alias.IsWriteIndex {
+			return indexName, nil
+		}
+		if len(byIndex) == 1 && alias.IsWriteIndex == nil {
+			return indexName, nil
+		}
+	}
+	return name, errors.New(errors.ErrorTypeIllegalArgumentException, "no write index is defined for alias ["+name+"]. "+
+		"The write index may be explicitly disabled using is_write_index=false or the alias points to multiple indices "+
+		"without one being designated as a write index")
+}
+
+func aliasIndexes(byIndex map[string]*meta.Alias) []string {
+	names := make([]string, 0, len(byIndex))
+	for indexName := range byIndex {
+		names = append(names, indexName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FilteredQuery applies the filter of the aliases to the query DSL, a nil query matches all documents
+func FilteredQuery(q, filter map[string]interface{}) map[string]interface{} {
+	if filter == nil {
+		return q
+	}
+	if q == nil {
+		q = map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+	return map[string]interface{}{"bool": map[string]interface{}{
+		"must":   []interface{}{q},
+		"filter": []interface{}{filter},
+	}}
+}
@@ -0,0 +1,242 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	blugeindex "github.com/blugelabs/bluge/index"
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/startup"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/query"
+)
+
+// DeleteByQuery deletes all the documents matching the query
+func (index *Index) DeleteByQuery(req *meta.ByQueryRequest) (*meta.ByQueryResponse, error) {
+	resp, deleted, err := index.byQuery(req, false, func(batch *blugeindex.Batch, doc *Document) error {
+		index.DeleteBatch(batch, doc.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Deleted = deleted
+
+	return resp, nil
+}
+
+// UpdateByQuery reindexes all the documents matching the query from their _source,
+// so that they pick up the current mappings and analyzers of the index
+func (index *Index) UpdateByQuery(req *meta.ByQueryRequest) (*meta.ByQueryResponse, error) {
+	if req.Script != nil {
+		return nil, errors.New(errors.ErrorTypeNotImplemented, "[update_by_query] script doesn't support")
+	}
+
+	resp, updated, err := index.byQuery(req, true, func(batch *blugeindex.Batch, doc *Document) error {
+		data := make(map[string]interface{})
+		if err := json.Unmarshal(doc.Source, &data); err != nil {
+			return errors.New(errors.ErrorTypeParsingException, err.Error())
+		}
+		// @timestamp is not kept in _source, restore it to keep the original time
+		if _, ok := data["@timestamp"]; !ok && !doc.Timestamp.IsZero() {
+			data["@timestamp"] = doc.Timestamp.Format(time.RFC3339Nano)
+		}
+		bdoc, nested, err := index.BuildBlugeDocumentFromJSON(doc.ID, data)
+		if err != nil {
+			return errors.New(errors.ErrorTypeIllegalArgumentException, err.Error())
+		}
+		SetDocumentVersion(bdoc, doc.Version+1, index.NextSeqNo())
+		index.UpdateBatch(batch, bdoc, nested)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Updated = updated
+
+	return resp, nil
+}
+
+// byQuery iterates all the documents matching the query and calls fn for each of them.
+// fn adds its change to the batch, the batch is persisted every startup.LoadBatchSize() documents.
+// The documents are checked again with the index locked before their batch is written, a document
+// changed since the search is a version conflict. It returns the number of changed documents.
+func (index *Index) byQuery(req *meta.ByQueryRequest, loadSource bool, fn func(batch *blugeindex.Batch, doc *Document) error) (*meta.ByQueryResponse, int64, error) {
+	startTime := time.Now()
+
+	q, err := query.Query(req.Query, index.CachedMappings, index.CachedAnalyzers)
+	if err != nil {
+		return nil, 0, err
+	}
+	q = query.RootQuery(q, index.CachedMappings)
+
+	reader, err := index.Writer.Reader()
+	if err != nil {
+		return nil, 0, fmt.Errorf("core.byQuery: error accessing reader: %s", err.Error())
+	}
+	defer reader.Close()
+
+	dmi, err := reader.Search(context.Background(), bluge.NewAllMatches(q))
+	if err != nil {
+		return nil, 0, fmt.Errorf("core.byQuery: error executing search: %s", err.Error())
+	}
+
+	resp := &meta.ByQueryResponse{Failures: make([]*meta.ByQueryFailure, 0)}
+	batchSize := startup.LoadBatchSize()
+	batch := blugeindex.NewBatch()
+	pending := make([]*Document, 0, batchSize)
+	var changed int64
+	aborted := false
+	persist := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		defer func() {
+			pending = pending[:0]
+		}()
+
+		index.lock.Lock()
+		defer index.lock.Unlock()
+
+		current, err := index.Writer.Reader()
+		if err != nil {
+			return fmt.Errorf("core.byQuery: error accessing reader: %s", err.Error())
+		}
+		defer current.Close()
+
+		documentsInBatch := 0
+		for _, doc := range pending {
+			stored, err := GetDocumentFromReader(current, doc.ID)
+			if err != nil {
+				return err
+			}
+			if stored == nil || stored.SeqNo != doc.SeqNo {
+				resp.VersionConflicts++
+				if req.Conflicts != "proceed" {
+					resp.Failures = append(resp.Failures, &meta.ByQueryFailure{
+						Index:  index.Name,
+						ID:     doc.ID,
+						Cause:  byQueryConflictError(doc, stored),
+						Status: http.StatusConflict,
+					})
+					aborted = true
+					break
+				}
+				continue
+			}
+			if err := fn(batch, doc); err != nil {
+				resp.Failures = append(resp.Failures, &meta.ByQueryFailure{
+					Index:  index.Name,
+					ID:     doc.ID,
+					Cause:  err,
+					Status: http.StatusBadRequest,
+				})
+				continue
+			}
+			documentsInBatch++
+		}
+		if documentsInBatch == 0 {
+			return nil
+		}
+
+		if err := index.Writer.Batch(batch); err != nil {
+			return fmt.Errorf("core.byQuery: error updating batch: %s", err.Error())
+		}
+		batch.Reset()
+		changed += int64(documentsInBatch)
+		resp.Batches++
+		return nil
+	}
+
+	next, err := dmi.Next()
+	for err == nil && next != nil && !aborted {
+		if req.MaxDocs > 0 && resp.Total >= int64(req.MaxDocs) {
+			break
+		}
+
+		doc := &Document{Index: index.Name, Version: 1}
+		err = next.VisitStoredFields(func(field string, value []byte) bool {
+			switch field {
+			case "_id":
+				doc.ID = string(value)
+			case "_version":
+				if v, err := bluge.DecodeNumericFloat64(value); err == nil {
+					doc.Version = int64(v)
+				}
+			case "_seq_no":
+				if v, err := bluge.DecodeNumericFloat64(value); err == nil {
+					doc.SeqNo = int64(v)
+				}
+			case "@timestamp":
+				if loadSource {
+					doc.Timestamp, _ = bluge.DecodeDateTime(value)
+				}
+			case "_source":
+				if loadSource {
+					doc.Source = append([]byte(nil), value...)
+				}
+			default:
+			}
+			return true
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("core.byQuery: error accessing stored fields: %s", err.Error())
+		}
+
+		resp.Total++
+		pending = append(pending, doc)
+		if len(pending) >= batchSize {
+			if err := persist(); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		next, err = dmi.Next()
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("core.byQuery: error iterating results: %s", err.Error())
+	}
+
+	if err := persist(); err != nil {
+		return nil, 0, err
+	}
+	index.ReloadDocsCount()
+
+	resp.Took = int(time.Since(startTime).Milliseconds())
+
+	return resp, changed, nil
+}
+
+// byQueryConflictError is the version conflict of a document changed or deleted since the search
+func byQueryConflictError(doc, stored *Document) error {
+	if stored == nil {
+		return errors.New(errors.ErrorTypeVersionConflictEngineException, fmt.Sprintf(
+			"[%s]: version conflict, document was deleted since the search", doc.ID,
+		))
+	}
+	return errors.New(errors.ErrorTypeVersionConflictEngineException, fmt.Sprintf(
+		"[%s]: version conflict, required seqNo [%d], current document has seqNo [%d]", doc.ID, doc.SeqNo, stored.SeqNo,
+	))
+}
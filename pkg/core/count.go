@@ -0,0 +1,71 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blugelabs/bluge"
+
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/query"
+)
+
+// Count returns the number of documents matching the query in the indexes, see MatchIndexFilters
+func Count(indexNames []string, q map[string]interface{}) (*meta.CountResponse, error) {
+	indexes, filters, err := MatchIndexFilters(indexNames)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &meta.CountResponse{Shards: meta.Shards{Total: len(indexes), Successful: len(indexes)}}
+	for i, index := range indexes {
+		n, err := index.Count(FilteredQuery(q, filters[i]))
+		if err != nil {
+			return nil, err
+		}
+		resp.Count += n
+	}
+
+	return resp, nil
+}
+
+// Count returns the number of documents matching the query, a nil query matches all documents
+func (index *Index) Count(q map[string]interface{}) (int64, error) {
+	if q == nil && len(index.CachedMappings.NestedPaths()) == 0 {
+		return index.LoadDocsCount()
+	}
+
+	bq, err := query.Query(q, index.CachedMappings, index.CachedAnalyzers)
+	if err != nil {
+		return 0, err
+	}
+	bq = query.RootQuery(bq, index.CachedMappings)
+
+	reader, err := index.Writer.Reader()
+	if err != nil {
+		return 0, fmt.Errorf("core.index.Count: error accessing reader: %s", err.Error())
+	}
+	defer reader.Close()
+
+	dmi, err := reader.Search(context.Background(), bluge.NewTopNSearch(0, bq).WithStandardAggregations())
+	if err != nil {
+		return 0, fmt.Errorf("core.index.Count: error executing search: %s", err.Error())
+	}
+
+	return int64(dmi.Aggregations().Count()), nil
+}
@@ -0,0 +1,177 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/search"
+
+	zincquery "github.com/zinclabs/zinc/pkg/bluge/query"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	parser "github.com/zinclabs/zinc/pkg/uquery/v2"
+)
+
+// Explain computes the score of the document for the query, the filter is the filter of the alias
+// the index is searched through. The explanation of the response is nil when the document doesn't exist.
+func (index *Index) Explain(docID string, query *meta.ZincQuery, filter map[string]interface{}) (*meta.ExplainResponse, error) {
+	query.Explain = true
+	searchRequest, err := parser.ParseMultiQueryDSL(query, index.CachedMappings, []parser.IndexMappings{
+		{Mappings: index.CachedMappings, Analyzers: index.CachedAnalyzers, Filter: filter},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := index.Writer.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("core.Explain: error accessing reader: %s", err.Error())
+	}
+	defer reader.Close()
+
+	resp := &meta.ExplainResponse{Index: index.Name, ID: docID}
+	dmi, err := reader.Search(context.Background(), bluge.NewTopNSearch(1, bluge.NewTermQuery(docID).SetField("_id")))
+	if err != nil {
+		return nil, fmt.Errorf("core.Explain: error executing search: %s", err.Error())
+	}
+	doc, err := dmi.Next()
+	if err != nil {
+		return nil, fmt.Errorf("core.Explain: error accessing document: %s", err.Error())
+	}
+	if doc == nil {
+		return resp, nil
+	}
+
+	dmi, err = reader.Search(context.Background(), &explainRequest{SearchRequest: searchRequest, number: doc.Number})
+	if err != nil {
+		return nil, fmt.Errorf("core.Explain: error executing search: %s", err.Error())
+	}
+	match, err := dmi.Next()
+	if err != nil {
+		return nil, fmt.Errorf("core.Explain: error accessing document: %s", err.Error())
+	}
+	if match == nil {
+		resp.Explanation = &meta.Explanation{Description: "no matching query", Details: []*meta.Explanation{}}
+		return resp, nil
+	}
+	resp.Matched = true
+	resp.Explanation = explanation(match.Explanation)
+	return resp, nil
+}
+
+// explanation converts the explanation of the score of a document match
+func explanation(e *search.Explanation) *meta.Explanation {
+	if e == nil {
+		return nil
+	}
+	rv := &meta.Explanation{Value: e.Value, Description: e.Message, Details: make([]*meta.Explanation, 0, len(e.Children))}
+	for _, child := range e.Children {
+		if child != nil {
+			rv.Details = append(rv.Details, explanation(child))
+		}
+	}
+	return rv
+}
+
+// explainRequest searches the request for a single document, the document is scored like the hits of the request
+type explainRequest struct {
+	bluge.SearchRequest
+	number uint64 // the number of the document in the reader
+}
+
+func (r *explainRequest) Collector() search.Collector {
+	return &explainCollector{number: r.number}
+}
+
+// explainCollector advances the searcher to the document, it doesn't collect the aggregations
+type explainCollector struct {
+	number uint64
+}
+
+func (c *explainCollector) Collect(ctx context.Context, aggs search.Aggregations, searcher search.Collectible) (search.DocumentMatchIterator, error) {
+	defer searcher.Close()
+
+	sctx := search.NewSearchContext(searcher.DocumentMatchPoolSize(), 0)
+	var match *search.DocumentMatch
+	var err error
+	if s, ok := searcher.(search.Searcher); ok {
+		match, err = s.Advance(sctx, c.number)
+	} else {
+		match, err = searcher.Next(sctx)
+		for err == nil && match != nil && match.Number < c.number {
+			match, err = searcher.Next(sctx)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if match != nil && match.Number != c.number {
+		match = nil
+	}
+	return &explainIterator{match: match, bucket: search.NewBucket("", aggs)}, nil
+}
+
+func (c *explainCollector) Size() int {
+	return 0
+}
+
+func (c *explainCollector) BackingSize() int {
+	return 1
+}
+
+type explainIterator struct {
+	match  *search.DocumentMatch
+	bucket *search.Bucket
+}
+
+func (i *explainIterator) Next() (*search.DocumentMatch, error) {
+	match := i.match
+	i.match = nil
+	return match, nil
+}
+
+func (i *explainIterator) Aggregations() *search.Bucket {
+	return i.bucket
+}
+
+// ValidateQuery parses the query for the indexes without executing it, the explanations are the parsed
+// queries of the indexes or their parse errors
+func ValidateQuery(indexNames []string, q map[string]interface{}) (*meta.ValidateResponse, error) {
+	indexes, filters, err := MatchIndexFilters(indexNames)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &meta.ValidateResponse{Shards: meta.Shards{Total: len(indexes), Successful: len(indexes)}, Valid: true}
+	for i, index := range indexes {
+		query := &meta.ZincQuery{Query: q}
+		explanation := &meta.ValidateExplanation{Index: index.Name, Valid: true}
+		_, err := parser.ParseMultiQueryDSL(query, index.CachedMappings, []parser.IndexMappings{
+			{Mappings: index.CachedMappings, Analyzers: index.CachedAnalyzers, Filter: filters[i]},
+		})
+		if err != nil {
+			resp.Valid = false
+			explanation.Valid = false
+			explanation.Error = err.Error()
+		} else {
+			explanation.Explanation = zincquery.Describe(query.Rewritten)
+		}
+		resp.Explanations = append(resp.Explanations, explanation)
+	}
+	return resp, nil
+}
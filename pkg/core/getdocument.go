@@ -0,0 +1,100 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blugelabs/bluge"
+)
+
+// GetDocument loads a document by id straight from the index reader.
+// It returns nil without error when the document does not exist.
+func (index *Index) GetDocument(docID string) (*Document, error) {
+	reader, err := index.Writer.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("core.GetDocument: error accessing reader: %s", err.Error())
+	}
+	defer reader.Close()
+
+	return GetDocumentFromReader(reader, docID)
+}
+
+// GetDocuments loads a list of documents by id using a single reader snapshot.
+// Missing documents are returned as nil in the same position as the requested id.
+func (index *Index) GetDocuments(docIDs []string) ([]*Document, error) {
+	reader, err := index.Writer.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("core.GetDocuments: error accessing reader: %s", err.Error())
+	}
+	defer reader.Close()
+
+	docs := make([]*Document, len(docIDs))
+	for i, docID := range docIDs {
+		if docs[i], err = GetDocumentFromReader(reader, docID); err != nil {
+			return nil, err
+		}
+	}
+
+	return docs, nil
+}
+
+// GetDocumentFromReader loads a document by id from the given reader snapshot.
+// It returns nil without error when the document does not exist.
+func GetDocumentFromReader(reader *bluge.Reader, docID string) (*Document, error) {
+	query := bluge.NewTermQuery(docID).SetField("_id")
+	searchRequest := bluge.NewTopNSearch(1, query) // Should get just 1 result at max
+	dmi, err := reader.Search(context.Background(), searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("core.GetDocument: error executing search: %s", err.Error())
+	}
+
+	next, err := dmi.Next()
+	if err != nil {
+		return nil, fmt.Errorf("core.GetDocument: error accessing document: %s", err.Error())
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	doc := &Document{ID: docID, Version: 1}
+	err = next.VisitStoredFields(func(field string, value []byte) bool {
+		switch field {
+		case "_index":
+			doc.Index = string(value)
+		case "@timestamp":
+			doc.Timestamp, _ = bluge.DecodeDateTime(value)
+		case "_version":
+			if v, err := bluge.DecodeNumericFloat64(value); err == nil {
+				doc.Version = int64(v)
+			}
+		case "_seq_no":
+			if v, err := bluge.DecodeNumericFloat64(value); err == nil {
+				doc.SeqNo = int64(v)
+			}
+		case "_source":
+			doc.Source = append([]byte(nil), value...)
+		default:
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("core.GetDocument: error accessing stored fields: %s", err.Error())
+	}
+
+	return doc, nil
+}
@@ -21,6 +21,7 @@ import (
 	"math"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -29,30 +30,96 @@ import (
 	"github.com/goccy/go-json"
 	"github.com/rs/zerolog/log"
 
+	zincquery "github.com/zinclabs/zinc/pkg/bluge/query"
 	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 	zincanalysis "github.com/zinclabs/zinc/pkg/uquery/v2/analysis"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/query"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/suggest"
 	"github.com/zinclabs/zinc/pkg/zutils"
 	"github.com/zinclabs/zinc/pkg/zutils/flatten"
+	zincgeo "github.com/zinclabs/zinc/pkg/zutils/geo"
 )
 
-// BuildBlugeDocumentFromJSON returns the bluge document for the json document. It also updates the mapping for the fields if not found.
-// If no mappings are found, it creates te mapping for all the encountered fields. If mapping for some fields is found but not for others
-// then it creates the mapping for the missing fields.
-func (index *Index) BuildBlugeDocumentFromJSON(docID string, doc map[string]interface{}) (*bluge.Document, error) {
+// BuildBlugeDocumentFromJSON returns the bluge document for the json document and the hidden documents of its nested objects.
+// It also updates the mapping for the fields if not found. If no mappings are found, it creates te mapping for all the encountered fields.
+// If mapping for some fields is found but not for others then it creates the mapping for the missing fields.
+func (index *Index) BuildBlugeDocumentFromJSON(docID string, doc map[string]interface{}) (*bluge.Document, []*bluge.Document, error) {
 	// Pick the index mapping from the cache if it already exists
 	mappings := index.CachedMappings
 	if mappings == nil {
 		mappings = meta.NewMappings()
 	}
 
-	mappingsNeedsUpdate := false
-
 	// Create a new bluge document
 	bdoc := bluge.NewDocument(docID)
+
+	// geo_point values are objects or arrays, so they are read before the document is flattened
+	geoFields, err := index.buildGeoFields(mappings, bdoc, doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// completion values can be objects with input and weight, they are read before the document is flattened too
+	completionFields, err := index.buildCompletionFields(mappings, bdoc, doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the objects of nested fields are indexed as hidden documents, not as fields of the document
+	nestedPaths := mappings.NestedPaths()
+
 	flatDoc, _ := flatten.Flatten(doc, "")
+	skipFields := append(append(geoFields, completionFields...), nestedPaths...)
+	mappingsNeedsUpdate, err := index.buildFields(mappings, bdoc, flatDoc, skipFields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nested, nestedNeedsUpdate, err := index.buildNestedDocuments(mappings, docID, doc, nestedPaths)
+	if err != nil {
+		return nil, nil, err
+	}
+	mappingsNeedsUpdate = mappingsNeedsUpdate || nestedNeedsUpdate
+
+	if mappingsNeedsUpdate {
+		index.SetMappings(mappings)
+		StoreIndex(index)
+	}
+
+	timestamp := time.Now()
+	if v, ok := flatDoc["@timestamp"]; ok {
+		switch v := v.(type) {
+		case string:
+			if t, err := time.Parse(time.RFC3339, v); err == nil && !t.IsZero() {
+				timestamp = t
+				delete(doc, "@timestamp")
+			}
+		case float64:
+			if t := zutils.Unix(int64(v)); !t.IsZero() {
+				timestamp = t
+				delete(doc, "@timestamp")
+			}
+		default:
+			// noop
+		}
+	}
+	docByteVal, _ := json.Marshal(doc)
+	bdoc.AddField(bluge.NewDateTimeField("@timestamp", timestamp).StoreValue().Sortable().Aggregatable())
+	bdoc.AddField(bluge.NewStoredOnlyField("_index", []byte(index.Name)))
+	bdoc.AddField(bluge.NewStoredOnlyField("_source", docByteVal))
+	bdoc.AddField(bluge.NewCompositeFieldExcluding("_all", []string{"_index", "_id", "_version", "_seq_no", "_source", "@timestamp"}))
+
+	return bdoc, nested, nil
+}
+
+// buildFields adds the flattened fields to the bluge document, the fields in skipFields or under them are skipped.
+// It reports if the mappings were updated for new fields.
+func (index *Index) buildFields(mappings *meta.Mappings, bdoc *bluge.Document, flatDoc map[string]interface{}, skipFields []string) (bool, error) {
+	mappingsNeedsUpdate := false
+	var textFields []string
 	// Iterate through each field and add it to the bluge document
 	for key, value := range flatDoc {
-		if value == nil || key == "@timestamp" {
+		if value == nil || key == "@timestamp" || isSubField(skipFields, key) {
 			continue
 		}
 
@@ -87,50 +154,27 @@ func (index *Index) BuildBlugeDocumentFromJSON(docID string, doc map[string]inte
 		if !mappings.Properties[key].Index {
 			continue // not index, skip
 		}
+		if mappings.Properties[key].Type == "text" {
+			textFields = append(textFields, key)
+		}
 
 		switch v := value.(type) {
 		case []interface{}:
 			for _, v := range v {
 				if err := index.buildField(mappings, bdoc, key, v); err != nil {
-					return nil, err
+					return false, err
 				}
 			}
 		default:
 			if err := index.buildField(mappings, bdoc, key, v); err != nil {
-				return nil, err
+				return false, err
 			}
 		}
 	}
-
-	if mappingsNeedsUpdate {
-		index.SetMappings(mappings)
-		StoreIndex(index)
-	}
-
-	timestamp := time.Now()
-	if v, ok := flatDoc["@timestamp"]; ok {
-		switch v := v.(type) {
-		case string:
-			if t, err := time.Parse(time.RFC3339, v); err == nil && !t.IsZero() {
-				timestamp = t
-				delete(doc, "@timestamp")
-			}
-		case float64:
-			if t := zutils.Unix(int64(v)); !t.IsZero() {
-				timestamp = t
-				delete(doc, "@timestamp")
-			}
-		default:
-			// noop
-		}
+	if len(textFields) > 0 {
+		bdoc.AddField(zincquery.NewFieldLengthField(meta.FieldLengthField, textFields))
 	}
-	docByteVal, _ := json.Marshal(doc)
-	bdoc.AddField(bluge.NewDateTimeField("@timestamp", timestamp).StoreValue().Sortable().Aggregatable())
-	bdoc.AddField(bluge.NewStoredOnlyField("_index", []byte(index.Name)))
-	bdoc.AddField(bluge.NewStoredOnlyField("_source", docByteVal))
-	bdoc.AddField(bluge.NewCompositeFieldExcluding("_all", []string{"_index", "_id", "_source", "@timestamp"}))
-
-	return bdoc, nil
+	return mappingsNeedsUpdate, nil
 }
 
 func (index *Index) buildField(mappings *meta.Mappings, bdoc *bluge.Document, key string, value interface{}) error {
@@ -164,6 +208,10 @@ func (index *Index) buildField(mappings *meta.Mappings, bdoc *bluge.Document, ke
 	case "bool": // found using existing index mapping
 		value := value.(bool)
 		field = bluge.NewKeywordField(key, strconv.FormatBool(value))
+	case "geo_point":
+		return nil // added by buildGeoFields
+	case "completion":
+		return nil // added by buildCompletionFields
 	case "date", "time":
 		format := time.RFC3339
 		if mappings.Properties[key].Format != "" {
@@ -199,6 +247,75 @@ func (index *Index) buildField(mappings *meta.Mappings, bdoc *bluge.Document, ke
 	return nil
 }
 
+// buildGeoFields adds the geo_point fields of the mappings to the bluge document, it returns the names of the fields
+func (index *Index) buildGeoFields(mappings *meta.Mappings, bdoc *bluge.Document, doc map[string]interface{}) ([]string, error) {
+	var geoFields []string
+	for key, prop := range mappings.Properties {
+		if prop.Type != "geo_point" {
+			continue
+		}
+		geoFields = append(geoFields, key)
+		value, ok := zutils.GetPath(doc, key)
+		if !ok || value == nil || !prop.Index {
+			continue
+		}
+		points, err := zincgeo.ParsePoints(value)
+		if err != nil {
+			return nil, fmt.Errorf("field [%s] of type [geo_point] failed to parse: %s", key, err.Error())
+		}
+		for _, point := range points {
+			if err := zincgeo.Validate(point); err != nil {
+				return nil, fmt.Errorf("field [%s] of type [geo_point] failed to parse: %s", key, err.Error())
+			}
+			field := bluge.NewGeoPointField(key, point.Lon, point.Lat)
+			if prop.Store {
+				field.StoreValue()
+			}
+			if prop.Sortable {
+				field.Sortable()
+			}
+			if prop.Aggregatable {
+				field.Aggregatable()
+			}
+			bdoc.AddField(field)
+		}
+	}
+	return geoFields, nil
+}
+
+// buildCompletionFields adds the completion fields of the mappings to the bluge document, it returns the names of the fields
+func (index *Index) buildCompletionFields(mappings *meta.Mappings, bdoc *bluge.Document, doc map[string]interface{}) ([]string, error) {
+	var completionFields []string
+	for key, prop := range mappings.Properties {
+		if prop.Type != "completion" {
+			continue
+		}
+		completionFields = append(completionFields, key)
+		value, ok := zutils.GetPath(doc, key)
+		if !ok || value == nil || !prop.Index {
+			continue
+		}
+		terms, err := suggest.CompletionTerms(value, suggest.CompletionAnalyzer(index.CachedAnalyzers, prop))
+		if err != nil {
+			return nil, fmt.Errorf("field [%s] of type [completion] failed to parse: %s", key, err.Error())
+		}
+		for _, term := range terms {
+			bdoc.AddField(bluge.NewKeywordField(key, term))
+		}
+	}
+	return completionFields, nil
+}
+
+// isSubField reports if the flattened key is one of the fields or a part of them
+func isSubField(fields []string, key string) bool {
+	for _, field := range fields {
+		if key == field || strings.HasPrefix(key, field+".") {
+			return true
+		}
+	}
+	return false
+}
+
 func (index *Index) UseTemplate() error {
 	template, err := UseTemplate(index.Name)
 	if err != nil {
@@ -325,16 +442,30 @@ func (index *Index) GetStoredMapping() (*meta.Mappings, error) {
 	return mappings, nil
 }
 
+// LoadDocsCount returns the number of live documents in the index, deleted documents
+// and the hidden documents of nested objects are not counted
 func (index *Index) LoadDocsCount() (int64, error) {
-	query := bluge.NewMatchAllQuery()
-	searchRequest := bluge.NewTopNSearch(0, query).WithStandardAggregations()
-	reader, _ := index.Writer.Reader()
-	dmi, err := reader.Search(context.Background(), searchRequest)
+	reader, err := index.Writer.Reader()
+	if err != nil {
+		return 0, fmt.Errorf("core.index.LoadDocsCount: error accessing reader: %s", err.Error())
+	}
+	defer reader.Close()
+
+	if len(index.CachedMappings.NestedPaths()) > 0 {
+		q := query.RootQuery(bluge.NewMatchAllQuery(), index.CachedMappings)
+		dmi, err := reader.Search(context.Background(), bluge.NewTopNSearch(0, q).WithStandardAggregations())
+		if err != nil {
+			return 0, fmt.Errorf("core.index.LoadDocsCount: error counting documents: %s", err.Error())
+		}
+		return int64(dmi.Aggregations().Count()), nil
+	}
+
+	count, err := reader.Count()
 	if err != nil {
-		return 0, fmt.Errorf("core.index.LoadDocsCount: error executing search: %s", err.Error())
+		return 0, fmt.Errorf("core.index.LoadDocsCount: error counting documents: %s", err.Error())
 	}
 
-	return int64(dmi.Aggregations().Count()), nil
+	return int64(count), nil
 }
 
 func (index *Index) LoadStorageSize() float64 {
@@ -363,13 +494,20 @@ func (index *Index) ReLoadStorageSize() {
 	}()
 }
 
-func (index *Index) ReduceDocsCount(n int64) {
-	atomic.AddInt64(&index.DocsCount, -n)
+// ReloadDocsCount refreshes the cached docs count from the index reader, it is called after batch writes
+func (index *Index) ReloadDocsCount() {
+	count, err := index.LoadDocsCount()
+	if err != nil {
+		log.Error().Err(err).Str("index", index.Name).Msg("core.index.ReloadDocsCount")
+	} else {
+		atomic.StoreInt64(&index.DocsCount, count)
+	}
 	index.ReLoadStorageSize()
 }
 
-func (index *Index) GainDocsCount(n int64) {
-	atomic.AddInt64(&index.DocsCount, n)
+// AddDocsCount adjusts the cached docs count after a single document write
+func (index *Index) AddDocsCount(delta int64) {
+	atomic.AddInt64(&index.DocsCount, delta)
 	index.ReLoadStorageSize()
 }
 
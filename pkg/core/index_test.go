@@ -40,7 +40,7 @@ func TestBuildBlugeDocumentFromJSON(t *testing.T) {
 				"zip":    "95035",
 			}
 
-			_, err := idx.BuildBlugeDocumentFromJSON("1", doc1)
+			_, _, err := idx.BuildBlugeDocumentFromJSON("1", doc1)
 			So(err, ShouldBeNil)
 		})
 	})
@@ -33,7 +33,7 @@ import (
 	"github.com/zinclabs/zinc/pkg/zutils"
 )
 
-var systemIndexList = []string{"_index_mapping", "_index_template", "_index", "_metadata", "_users"}
+var systemIndexList = []string{"_index_mapping", "_index_template", "_index", "_metadata", "_users", "_alias", "_script"}
 
 func LoadZincSystemIndexes() (map[string]*Index, error) {
 	indexList := make(map[string]*Index)
@@ -112,6 +112,9 @@ func LoadZincIndexesFromMeta() (map[string]*Index, error) {
 		// load index docs count
 		index.DocsCount, _ = index.LoadDocsCount()
 
+		// load index sequence number
+		index.SeqNo, _ = index.LoadSeqNo()
+
 		// load index size
 		index.ReLoadStorageSize()
 
@@ -22,43 +22,61 @@ import (
 	"time"
 
 	"github.com/blugelabs/bluge"
-	"github.com/blugelabs/bluge/analysis"
+	"github.com/blugelabs/bluge/search"
+	"github.com/goccy/go-json"
 	"github.com/rs/zerolog/log"
 
+	"github.com/zinclabs/zinc/pkg/errors"
 	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 	parser "github.com/zinclabs/zinc/pkg/uquery/v2"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/suggest"
 )
 
+// MultiSearchV2 searches the indexes, see MatchIndexFilters
 func MultiSearchV2(indexNames []string, query *meta.ZincQuery) (*meta.SearchResponse, error) {
-	var mappings *meta.Mappings
-	var analyzers map[string]*analysis.Analyzer
-	var readers []*bluge.Reader
-	readerMap := make(map[string]struct{})
-	for name, index := range ZINC_INDEX_LIST {
-		for _, indexName := range indexNames {
-			if _, ok := readerMap[name]; ok {
-				continue
-			}
-			if indexName == "" || (indexName != "" && strings.HasPrefix(name, indexName[:len(indexName)-1])) {
-				reader, _ := index.Writer.Reader()
-				readers = append(readers, reader)
-				if mappings == nil {
-					mappings = index.CachedMappings
-					analyzers = index.CachedAnalyzers
-				}
-				readerMap[name] = struct{}{}
-			}
-		}
+	indexes, filters, err := MatchIndexFilters(indexNames)
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) == 0 {
+		return nil, errors.New(errors.ErrorTypeIndexNotFoundException, "no such index ["+strings.Join(indexNames, ",")+"]")
 	}
 
-	if len(readers) == 0 {
-		return nil, fmt.Errorf("core.MultiSearchV2: error accessing reader: no index found")
+	readers := make([]*bluge.Reader, 0, len(indexes))
+	defer func() {
+		for _, reader := range readers {
+			reader.Close()
+		}
+	}()
+	for _, index := range indexes {
+		reader, err := index.Writer.Reader()
+		if err != nil {
+			return nil, fmt.Errorf("core.MultiSearchV2: error accessing reader: %s", err.Error())
+		}
+		readers = append(readers, reader)
 	}
 
-	searchRequest, err := parser.ParseQueryDSL(query, mappings, analyzers)
+	return multiSearchV2(readers, indexes, filters, query)
+}
+
+// multiSearchV2 searches the readers of the indexes, the query of each reader is parsed with the mappings
+// and analyzers of its index and restricted by its alias filter, the other parts of the request use the merged
+// mappings of the indexes
+func multiSearchV2(readers []*bluge.Reader, indexes []*Index, filters []map[string]interface{}, query *meta.ZincQuery) (*meta.SearchResponse, error) {
+	mappings, conflicts := mergeMappings(indexes)
+	indexMappings := make([]parser.IndexMappings, len(indexes))
+	indexesByName := make(map[string]*Index, len(indexes))
+	for i, index := range indexes {
+		indexMappings[i] = parser.IndexMappings{Name: index.Name, Mappings: index.CachedMappings, Analyzers: index.CachedAnalyzers, Filter: filters[i]}
+		indexesByName[index.Name] = index
+	}
+	searchRequest, err := parser.ParseMultiQueryDSL(query, mappings, indexMappings)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkConflicts(query, conflicts); err != nil {
+		return nil, err
+	}
 
 	ctx := context.Background()
 	var cancel context.CancelFunc
@@ -67,7 +85,9 @@ func MultiSearchV2(indexNames []string, query *meta.ZincQuery) (*meta.SearchResp
 		defer cancel()
 	}
 
+	start := time.Now()
 	dmi, err := bluge.MultiSearch(ctx, searchRequest, readers...)
+	query.Profiler.AddCollect(start)
 	if err != nil {
 		log.Printf("core.MultiSearchV2: error executing search: %s", err.Error())
 		if err == context.DeadlineExceeded {
@@ -80,5 +100,100 @@ func MultiSearchV2(indexNames []string, query *meta.ZincQuery) (*meta.SearchResp
 		return nil, err
 	}
 
-	return searchV2(dmi, query, mappings)
+	readersByName := make(map[string]*bluge.Reader, len(indexes))
+	for i, index := range indexes {
+		readersByName[index.Name] = readers[i]
+	}
+	resp, err := searchV2(dmi, query, mappings, indexesByName, func(req bluge.SearchRequest) (search.DocumentMatchIterator, error) {
+		return bluge.MultiSearch(ctx, req, readers...)
+	}, func(indexName string, req bluge.SearchRequest) (search.DocumentMatchIterator, error) {
+		return bluge.MultiSearch(ctx, req, readersByName[indexName])
+	})
+	if err == nil && query.Profiler != nil {
+		indexNames := make([]string, len(indexes))
+		for i, index := range indexes {
+			indexNames[i] = index.Name
+		}
+		resp.Profile = profileResponse(query.Profiler, indexNames)
+	}
+	if err != nil || query.Suggesters == nil {
+		return resp, err
+	}
+	resp.Suggest, err = suggest.Response(query.Suggesters, readers, mappings, indexes[0].CachedAnalyzers)
+	return resp, err
+}
+
+// mergeMappings merges the mappings of the indexes, it returns the fields mapped with different types,
+// the type of the first index is kept in the merged mappings
+func mergeMappings(indexes []*Index) (*meta.Mappings, map[string]string) {
+	mappings := meta.NewMappings()
+	owners := make(map[string]string)
+	conflicts := make(map[string]string)
+	for _, index := range indexes {
+		if index.CachedMappings == nil {
+			continue
+		}
+		for field, prop := range index.CachedMappings.Properties {
+			merged, ok := mappings.Properties[field]
+			if !ok {
+				mappings.Properties[field] = prop
+				owners[field] = index.Name
+				continue
+			}
+			if merged.Type != prop.Type {
+				if _, ok := conflicts[field]; !ok {
+					conflicts[field] = fmt.Sprintf("[%s] in [%s]", merged.Type, owners[field])
+				}
+				conflicts[field] += fmt.Sprintf(", [%s] in [%s]", prop.Type, index.Name)
+			}
+		}
+	}
+	return mappings, conflicts
+}
+
+// checkConflicts reports the fields with conflicting mappings used by the sort and the aggregations,
+// their values can't be compared across the indexes
+func checkConflicts(query *meta.ZincQuery, conflicts map[string]string) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	var fields []string
+	if sortFields, ok := query.Sort.([]*meta.SortField); ok {
+		for _, sortField := range sortFields {
+			fields = append(fields, sortField.Field)
+		}
+	}
+	if len(query.Aggregations) > 0 {
+		data, _ := json.Marshal(query.Aggregations)
+		var aggs interface{}
+		_ = json.Unmarshal(data, &aggs)
+		fields = append(fields, aggregationFields(aggs)...)
+	}
+	for _, field := range fields {
+		if types, ok := conflicts[field]; ok {
+			return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("field [%s] has conflicting mappings across the indexes: %s", field, types))
+		}
+	}
+	return nil
+}
+
+// aggregationFields returns the fields of the aggregations
+func aggregationFields(v interface{}) []string {
+	var fields []string
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, v := range v {
+			if field, ok := v.(string); ok && (k == "field" || k == "weight_field") {
+				fields = append(fields, field)
+				continue
+			}
+			fields = append(fields, aggregationFields(v)...)
+		}
+	case []interface{}:
+		for _, v := range v {
+			fields = append(fields, aggregationFields(v)...)
+		}
+	}
+	return fields
 }
@@ -0,0 +1,113 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/blugelabs/bluge"
+	blugeindex "github.com/blugelabs/bluge/index"
+	"github.com/goccy/go-json"
+
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/zutils"
+	"github.com/zinclabs/zinc/pkg/zutils/flatten"
+)
+
+// nestedParent identifies the nested documents of a document, it is used to delete them with the document
+type nestedParent string
+
+func (p nestedParent) Field() string {
+	return meta.NestedParentField
+}
+
+func (p nestedParent) Term() []byte {
+	return []byte(p)
+}
+
+// buildNestedDocuments returns the hidden documents of the objects of the nested fields,
+// it reports if the mappings were updated for new fields of the objects
+func (index *Index) buildNestedDocuments(mappings *meta.Mappings, docID string, doc map[string]interface{}, nestedPaths []string) ([]*bluge.Document, bool, error) {
+	var docs []*bluge.Document
+	mappingsNeedsUpdate := false
+	for _, path := range nestedPaths {
+		value, ok := zutils.GetPath(doc, path)
+		if !ok || value == nil {
+			continue
+		}
+		var objects []interface{}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			objects = []interface{}{v}
+		case []interface{}:
+			objects = v
+		default:
+			return nil, false, fmt.Errorf("object mapping for [%s] tried to parse field [%s] as object, but found a concrete value", path, path)
+		}
+
+		for offset, object := range objects {
+			object, ok := object.(map[string]interface{})
+			if !ok {
+				return nil, false, fmt.Errorf("object mapping for [%s] tried to parse field [%s] as object, but found a concrete value", path, path)
+			}
+
+			ndoc := bluge.NewDocument(docID + "\x00" + path + "\x00" + strconv.Itoa(offset))
+			flatDoc, _ := flatten.Flatten(object, path+".")
+			needsUpdate, err := index.buildFields(mappings, ndoc, flatDoc, nil)
+			if err != nil {
+				return nil, false, err
+			}
+			mappingsNeedsUpdate = mappingsNeedsUpdate || needsUpdate
+
+			source, _ := json.Marshal(object)
+			ndoc.AddField(bluge.NewKeywordField(meta.NestedPathField, path).Sortable())
+			ndoc.AddField(bluge.NewKeywordField(meta.NestedParentField, docID).StoreValue().Sortable())
+			ndoc.AddField(bluge.NewNumericField(meta.NestedOffsetField, float64(offset)).StoreValue())
+			ndoc.AddField(bluge.NewStoredOnlyField("_index", []byte(index.Name)))
+			ndoc.AddField(bluge.NewStoredOnlyField("_source", source))
+			docs = append(docs, ndoc)
+		}
+	}
+	return docs, mappingsNeedsUpdate, nil
+}
+
+// UpdateBatch adds the document and its nested documents to the batch, they replace the stored document and its nested documents
+func (index *Index) UpdateBatch(batch *blugeindex.Batch, bdoc *bluge.Document, nested []*bluge.Document) {
+	batch.Update(bdoc.ID(), bdoc)
+	if len(index.CachedMappings.NestedPaths()) > 0 {
+		batch.Delete(nestedParent(bdoc.ID().Term()))
+	}
+	for _, ndoc := range nested {
+		batch.Insert(ndoc)
+	}
+}
+
+// InsertBatch adds the new document and its nested documents to the batch
+func (index *Index) InsertBatch(batch *blugeindex.Batch, bdoc *bluge.Document, nested []*bluge.Document) {
+	batch.Insert(bdoc)
+	for _, ndoc := range nested {
+		batch.Insert(ndoc)
+	}
+}
+
+// DeleteBatch adds the delete of the document and its nested documents to the batch
+func (index *Index) DeleteBatch(batch *blugeindex.Batch, docID string) {
+	batch.Delete(bluge.Identifier(docID))
+	if len(index.CachedMappings.NestedPaths()) > 0 {
+		batch.Delete(nestedParent(docID))
+	}
+}
@@ -17,6 +17,7 @@ package core
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/goccy/go-json"
 
 	"github.com/zinclabs/zinc/pkg/directory"
+	"github.com/zinclabs/zinc/pkg/errors"
 	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 	"github.com/zinclabs/zinc/pkg/zutils"
 )
@@ -37,6 +39,9 @@ func NewIndex(name, storageType string, useNewIndexMeta int, defaultSearchAnalyz
 	if strings.HasPrefix(name, "_") {
 		return nil, fmt.Errorf("core.NewIndex: index name cannot start with _")
 	}
+	if IsAlias(name) {
+		return nil, fmt.Errorf("core.NewIndex: index name [%s] already exists as alias", name)
+	}
 
 	var dataPath string
 	var config bluge.Config
@@ -67,6 +72,7 @@ func NewIndex(name, storageType string, useNewIndexMeta int, defaultSearchAnalyz
 		Writer:      writer,
 		StorageType: storageType,
 	}
+	index.SeqNo, _ = index.LoadSeqNo()
 
 	// use template
 	if err = index.UseTemplate(); err != nil {
@@ -155,10 +161,131 @@ func DeleteIndex(name string) error {
 		return fmt.Errorf("core.DeleteIndex: error deleting template: %s", err.Error())
 	}
 
-	return nil
+	return DeleteIndexAliases(name)
 }
 
 func GetIndex(name string) (*Index, bool) {
 	index, ok := ZINC_INDEX_LIST[name]
 	return index, ok
 }
+
+// MatchIndexes returns the indexes of the index expressions sorted by name, see MatchIndexFilters
+func MatchIndexes(indexNames []string) ([]*Index, error) {
+	indexes, _, err := MatchIndexFilters(indexNames)
+	return indexes, err
+}
+
+// MatchIndexFilters returns the indexes of the index expressions sorted by name and the filter query DSL
+// of each index. The expressions can be comma separated lists of index or alias names, patterns with
+// * wildcards and exclusions starting with - which remove the matching indexes of the previous expressions.
+// Empty, * or _all matches all the indexes. Names without wildcards must exist. The filter of an index
+// is nil unless the index is only matched through filtered aliases, the filters of many aliases are OR'ed.
+func MatchIndexFilters(indexNames []string) ([]*Index, []map[string]interface{}, error) {
+	var expressions []string
+	for _, indexName := range indexNames {
+		for _, expression := range strings.Split(indexName, ",") {
+			if expression = strings.TrimSpace(expression); expression != "" {
+				expressions = append(expressions, expression)
+			}
+		}
+	}
+	if len(expressions) == 0 {
+		expressions = append(expressions, "_all")
+	}
+
+	aliases.lock.RLock()
+	defer aliases.lock.RUnlock()
+
+	matched := make(map[string]*indexMatch)
+	match := func(index *Index, filter map[string]interface{}) {
+		m, ok := matched[index.Name]
+		if !ok {
+			m = &indexMatch{index: index}
+			matched[index.Name] = m
+		}
+		if filter == nil {
+			m.unfiltered = true
+		} else {
+			m.filters = append(m.filters, filter)
+		}
+	}
+	matchAlias := func(byIndex map[string]*meta.Alias) {
+		for indexName, alias := range byIndex {
+			if index, ok := GetIndex(indexName); ok {
+				match(index, alias.Filter)
+			}
+		}
+	}
+	for i, expression := range expressions {
+		switch {
+		case expression == "_all" || expression == "*":
+			for _, index := range ZINC_INDEX_LIST {
+				match(index, nil)
+			}
+		case strings.HasPrefix(expression, "-") && i > 0:
+			for name := range matched {
+				if zutils.MatchWildcard(expression[1:], name) {
+					delete(matched, name)
+				}
+			}
+			for name, byIndex := range aliases.list {
+				if zutils.MatchWildcard(expression[1:], name) {
+					for indexName := range byIndex {
+						delete(matched, indexName)
+					}
+				}
+			}
+		case strings.Contains(expression, "*"):
+			for name, index := range ZINC_INDEX_LIST {
+				if zutils.MatchWildcard(expression, name) {
+					match(index, nil)
+				}
+			}
+			for name, byIndex := range aliases.list {
+				if zutils.MatchWildcard(expression, name) {
+					matchAlias(byIndex)
+				}
+			}
+		default:
+			if index, ok := GetIndex(expression); ok {
+				match(index, nil)
+			} else if byIndex, ok := aliases.list[expression]; ok {
+				matchAlias(byIndex)
+			} else {
+				return nil, nil, errors.New(errors.ErrorTypeIndexNotFoundException, "no such index ["+expression+"]")
+			}
+		}
+	}
+
+	indexes := make([]*Index, 0, len(matched))
+	for _, m := range matched {
+		indexes = append(indexes, m.index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].Name < indexes[j].Name })
+	filters := make([]map[string]interface{}, len(indexes))
+	for i, index := range indexes {
+		filters[i] = matched[index.Name].filter()
+	}
+	return indexes, filters, nil
+}
+
+// indexMatch is an index matched by index expressions, directly or through aliases
+type indexMatch struct {
+	index      *Index
+	filters    []map[string]interface{} // filters of the filtered aliases matching the index
+	unfiltered bool                     // the index is matched directly or through an alias without filter
+}
+
+func (m *indexMatch) filter() map[string]interface{} {
+	if m.unfiltered || len(m.filters) == 0 {
+		return nil
+	}
+	if len(m.filters) == 1 {
+		return m.filters[0]
+	}
+	should := make([]interface{}, len(m.filters))
+	for i, filter := range m.filters {
+		should[i] = filter
+	}
+	return map[string]interface{}{"bool": map[string]interface{}{"should": should, "minimum_should_match": float64(1)}}
+}
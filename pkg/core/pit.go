@@ -0,0 +1,219 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"github.com/rs/zerolog/log"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	"github.com/zinclabs/zinc/pkg/ider"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// MaxKeepAlive is the max keep alive of a point in time or a scroll
+const MaxKeepAlive = 24 * time.Hour
+
+// snapshot pins the reader snapshots of indexes, searches of the snapshot
+// don't see the changes made after it is opened
+type snapshot struct {
+	ID      string
+	Indexes []string
+
+	lock    sync.RWMutex // searches hold the read lock, closing holds the write lock
+	readers []*bluge.Reader
+	indexes []*Index                 // the indexes of the readers, their mappings and analyzers are used to parse queries
+	filters []map[string]interface{} // the alias filters of the indexes
+	expire  time.Time                // guarded by contexts.lock
+	closed  bool
+}
+
+// PointInTime is a snapshot used by search requests with pit, it is closed when the keep alive expires
+type PointInTime struct {
+	*snapshot
+}
+
+// contexts are the open points in time and scrolls, the expired ones are closed in background
+var contexts = struct {
+	lock    sync.Mutex
+	pits    map[string]*PointInTime
+	scrolls map[string]*Scroll
+	once    sync.Once
+}{pits: make(map[string]*PointInTime), scrolls: make(map[string]*Scroll)}
+
+// OpenPointInTime opens a point in time of the indexes, see MatchIndexFilters
+func OpenPointInTime(indexNames []string, keepAlive time.Duration) (*PointInTime, error) {
+	s, err := openSnapshot(indexNames, keepAlive)
+	if err != nil {
+		return nil, err
+	}
+	pit := &PointInTime{s}
+
+	contexts.lock.Lock()
+	contexts.pits[pit.ID] = pit
+	contexts.lock.Unlock()
+
+	return pit, nil
+}
+
+// GetPointInTime returns the point in time by id, expired ones are not returned
+func GetPointInTime(id string) (*PointInTime, bool) {
+	contexts.lock.Lock()
+	defer contexts.lock.Unlock()
+
+	pit, ok := contexts.pits[id]
+	if !ok || pit.expired(time.Now()) {
+		return nil, false
+	}
+	return pit, true
+}
+
+// ClosePointInTime closes the point in time and releases its readers
+func ClosePointInTime(id string) bool {
+	contexts.lock.Lock()
+	pit, ok := contexts.pits[id]
+	delete(contexts.pits, id)
+	contexts.lock.Unlock()
+
+	if !ok {
+		return false
+	}
+	pit.close()
+	return true
+}
+
+// Search searches the pinned readers, keepAlive extends the point in time if it is greater than 0
+func (pit *PointInTime) Search(query *meta.ZincQuery, keepAlive time.Duration) (*meta.SearchResponse, error) {
+	resp, err := pit.search(query, keepAlive)
+	if err != nil {
+		return nil, err
+	}
+	resp.PitID = pit.ID
+	return resp, nil
+}
+
+func openSnapshot(indexNames []string, keepAlive time.Duration) (*snapshot, error) {
+	if err := checkKeepAlive(keepAlive); err != nil {
+		return nil, err
+	}
+	indexes, filters, err := MatchIndexFilters(indexNames)
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) == 0 {
+		return nil, errors.New(errors.ErrorTypeIndexNotFoundException, "no such index ["+indexNames[0]+"]")
+	}
+
+	s := &snapshot{
+		ID:      ider.Generate(),
+		indexes: indexes,
+		filters: filters,
+		expire:  time.Now().Add(keepAlive),
+	}
+	for _, index := range indexes {
+		reader, err := index.Writer.Reader()
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+		s.readers = append(s.readers, reader)
+		s.Indexes = append(s.Indexes, index.Name)
+	}
+
+	contexts.once.Do(func() { go reapContexts() })
+
+	return s, nil
+}
+
+// search searches the pinned readers, keepAlive extends the snapshot if it is greater than 0
+func (s *snapshot) search(query *meta.ZincQuery, keepAlive time.Duration) (*meta.SearchResponse, error) {
+	if keepAlive != 0 {
+		if err := checkKeepAlive(keepAlive); err != nil {
+			return nil, err
+		}
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.closed {
+		return nil, errors.New(errors.ErrorTypeResourceNotFound, "No search context found for id ["+s.ID+"]")
+	}
+
+	if keepAlive > 0 {
+		contexts.lock.Lock()
+		s.expire = time.Now().Add(keepAlive)
+		contexts.lock.Unlock()
+	}
+
+	return multiSearchV2(s.readers, s.indexes, s.filters, query)
+}
+
+// expired checks the keep alive, the caller must hold contexts.lock
+func (s *snapshot) expired(now time.Time) bool {
+	return now.After(s.expire)
+}
+
+func (s *snapshot) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, reader := range s.readers {
+		if err := reader.Close(); err != nil {
+			log.Error().Err(err).Str("id", s.ID).Msg("core.snapshot: error closing reader")
+		}
+	}
+	s.readers = nil
+	s.closed = true
+}
+
+// reapContexts closes the expired points in time and scrolls
+func reapContexts() {
+	for range time.Tick(10 * time.Second) {
+		now := time.Now()
+		expired := make([]*snapshot, 0)
+		contexts.lock.Lock()
+		for id, pit := range contexts.pits {
+			if pit.expired(now) {
+				expired = append(expired, pit.snapshot)
+				delete(contexts.pits, id)
+			}
+		}
+		for id, scroll := range contexts.scrolls {
+			if scroll.expired(now) {
+				expired = append(expired, scroll.snapshot)
+				delete(contexts.scrolls, id)
+			}
+		}
+		contexts.lock.Unlock()
+
+		for _, s := range expired {
+			s.close()
+		}
+	}
+}
+
+func checkKeepAlive(keepAlive time.Duration) error {
+	if keepAlive <= 0 {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, "[keep_alive] must be greater than 0")
+	}
+	if keepAlive > MaxKeepAlive {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, "Keep alive for request ("+keepAlive.String()+") is too large. It must be less than ("+MaxKeepAlive.String()+").")
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/zinclabs/zinc/pkg/bluge/profile"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// profileResponse formats the profile in the shape of the Elasticsearch profile, every index is a shard
+// with the query of its reader, the collector, aggregations and fetch run across the readers and are
+// reported by the first shard
+func profileResponse(profiler *profile.Profiler, indexNames []string) *meta.Profile {
+	resp := &meta.Profile{Shards: make([]meta.ProfileShard, len(indexNames))}
+	for i, indexName := range indexNames {
+		shard := meta.ProfileShard{
+			ID:           fmt.Sprintf("[%s][%s][0]", TaskNode, indexName),
+			Aggregations: []meta.ProfileResult{},
+		}
+		search := meta.ProfileSearch{
+			Query:       []meta.ProfileResult{profileResult(profiler.Queries[i])},
+			RewriteTime: profiler.RewriteTime.Nanoseconds(),
+			Collector:   []meta.ProfileCollector{},
+		}
+		if i == 0 {
+			search.Collector = append(search.Collector, meta.ProfileCollector{
+				Name:        "SimpleTopDocsCollector",
+				Reason:      "search_top_hits",
+				TimeInNanos: profiler.Collect.Nanoseconds(),
+			})
+			for _, node := range profiler.Aggregations {
+				shard.Aggregations = append(shard.Aggregations, profileResult(node))
+			}
+			sort.Slice(shard.Aggregations, func(i, j int) bool {
+				return shard.Aggregations[i].Description < shard.Aggregations[j].Description
+			})
+			fetch := profileResult(profiler.Fetch)
+			for _, child := range fetch.Children {
+				fetch.TimeInNanos += child.TimeInNanos
+			}
+			shard.Fetch = &fetch
+		}
+		shard.Searches = []meta.ProfileSearch{search}
+		resp.Shards[i] = shard
+	}
+	return resp
+}
+
+func profileResult(node *profile.Node) meta.ProfileResult {
+	result := meta.ProfileResult{
+		Type:        node.Type,
+		Description: node.Description,
+		TimeInNanos: node.Time(),
+		Breakdown:   node.Breakdown(),
+	}
+	for _, child := range node.Children {
+		result.Children = append(result.Children, profileResult(child))
+	}
+	return result
+}
@@ -0,0 +1,609 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	blugeindex "github.com/blugelabs/bluge/index"
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/startup"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/query"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/source"
+)
+
+// ReindexAction is the task action of reindex
+const ReindexAction = "indices:data/write/reindex"
+
+const defaultReindexBatchSize = 1000
+
+// errReindexStop stops reading the sources, max_docs is reached or a failure aborts the reindex
+var errReindexStop = fmt.Errorf("core.Reindex: stop")
+
+// Reindex copies documents from the source indexes, local or on a remote zinc server,
+// into the dest index. It runs as a task, see NewTask.
+type Reindex struct {
+	req          *meta.ReindexRequest
+	sources      []string
+	dest         string                   // the dest index, the write index if dest is an alias
+	indexes      []string                 // local source indexes, sources can be aliases and patterns
+	filters      []map[string]interface{} // alias filter of each local source index
+	sourceFilter *meta.Source
+	batchSize    int
+	client       *http.Client // remote source only
+
+	lock        sync.Mutex
+	status      meta.ReindexResponse
+	read        int64 // documents read from the sources
+	throttled   time.Time
+	rethrottled chan struct{}
+}
+
+type reindexDocument struct {
+	id   string
+	data map[string]interface{}
+}
+
+// NewReindex validates the request, requestsPerSecond <= 0 means unlimited
+func NewReindex(req *meta.ReindexRequest, requestsPerSecond float64) (*Reindex, error) {
+	if req.Script != nil {
+		return nil, errors.New(errors.ErrorTypeNotImplemented, "[reindex] script doesn't support")
+	}
+	if req.Dest.Index == "" {
+		return nil, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: dest.index must be specified;")
+	}
+	switch req.Dest.OpType {
+	case "", "index", "create":
+	default:
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, "opType must be 'create' or 'index', found: ["+req.Dest.OpType+"]")
+	}
+	switch req.Conflicts {
+	case "", "abort", "proceed":
+	default:
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, "conflicts may only be \"proceed\" or \"abort\" but was ["+req.Conflicts+"]")
+	}
+
+	dest, err := ResolveWriteIndex(req.Dest.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reindex{req: req, dest: dest, batchSize: req.Source.Size, rethrottled: make(chan struct{}, 1)}
+	if r.batchSize <= 0 {
+		r.batchSize = defaultReindexBatchSize
+	}
+	if requestsPerSecond <= 0 {
+		requestsPerSecond = -1
+	}
+	r.status.RequestsPerSecond = requestsPerSecond
+	r.status.Failures = make([]*meta.ByQueryFailure, 0)
+
+	switch v := req.Source.Index.(type) {
+	case string:
+		r.sources = strings.Split(v, ",")
+	case []interface{}:
+		for _, v := range v {
+			if s, ok := v.(string); ok {
+				r.sources = append(r.sources, s)
+			}
+		}
+	}
+	if len(r.sources) == 0 || r.sources[0] == "" {
+		return nil, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: source.index must be specified;")
+	}
+
+	filter := req.Source.Source
+	if v, ok := filter.(string); ok {
+		filter = []interface{}{v}
+	}
+	if r.sourceFilter, err = source.Request(filter); err != nil {
+		return nil, err
+	}
+
+	if req.Source.Remote != nil {
+		if r.client, err = newReindexClient(req.Source.Remote); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	indexes, filters, err := MatchIndexFilters(r.sources)
+	if err != nil {
+		return nil, err
+	}
+	for i, index := range indexes {
+		if index.Name == r.dest {
+			return nil, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: reindex cannot write into an index its reading from ["+index.Name+"];")
+		}
+		r.indexes = append(r.indexes, index.Name)
+		r.filters = append(r.filters, filters[i])
+	}
+
+	return r, nil
+}
+
+func newReindexClient(remote *meta.ReindexRemote) (*http.Client, error) {
+	u, err := url.Parse(remote.Host)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, "[host] must be of the form [scheme]://[host]:[port] but was ["+remote.Host+"]")
+	}
+	if err := checkRemoteWhitelist(u); err != nil {
+		return nil, err
+	}
+
+	socketTimeout, connectTimeout := 30*time.Second, 30*time.Second
+	if remote.SocketTimeout != "" {
+		if socketTimeout, err = time.ParseDuration(remote.SocketTimeout); err != nil {
+			return nil, errors.New(errors.ErrorTypeIllegalArgumentException, "[socket_timeout] "+err.Error())
+		}
+	}
+	if remote.ConnectTimeout != "" {
+		if connectTimeout, err = time.ParseDuration(remote.ConnectTimeout); err != nil {
+			return nil, errors.New(errors.ErrorTypeIllegalArgumentException, "[connect_timeout] "+err.Error())
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout}).DialContext
+	return &http.Client{Transport: transport, Timeout: socketTimeout}, nil
+}
+
+// checkRemoteWhitelist rejects the remote hosts which are not in startup.LoadReindexRemoteWhitelist(),
+// reindex must not be able to read from any server the zinc server can reach
+func checkRemoteWhitelist(u *url.URL) error {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	host := strings.ToLower(net.JoinHostPort(u.Hostname(), port))
+	for _, pattern := range startup.LoadReindexRemoteWhitelist() {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return nil
+		}
+	}
+	return errors.New(errors.ErrorTypeIllegalArgumentException, "["+host+"] not whitelisted in ZINC_REINDEX_REMOTE_WHITELIST")
+}
+
+// remoteError reports the status of a failed remote request, the body is only used for the type and reason
+// of an ES error, other content of the remote server isn't returned to the caller
+func remoteError(host string, status int, body []byte) error {
+	msg := fmt.Sprintf("[reindex] remote [%s] responded [%d]", host, status)
+	resp := new(struct {
+		Error struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	})
+	if err := json.Unmarshal(body, resp); err == nil && resp.Error.Type != "" {
+		msg += fmt.Sprintf(" [%s]: %s", resp.Error.Type, resp.Error.Reason)
+	}
+	return errors.New(errors.ErrorTypeRuntimeException, msg)
+}
+
+// Description describes the reindex for the task API
+func (r *Reindex) Description() string {
+	from := "[" + strings.Join(r.sources, ", ") + "]"
+	if r.req.Source.Remote != nil {
+		from = "[" + r.req.Source.Remote.Host + "]" + from
+	}
+	return "reindex from " + from + " to [" + r.req.Dest.Index + "]"
+}
+
+// Status returns the progress of the reindex
+func (r *Reindex) Status() interface{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	status := r.status
+	status.Failures = append([]*meta.ByQueryFailure(nil), r.status.Failures...)
+	if wait := time.Until(r.throttled); wait > 0 {
+		status.ThrottledUntilMillis = wait.Milliseconds()
+	}
+	return &status
+}
+
+// Rethrottle changes the requests per second of the running reindex, <= 0 means unlimited
+func (r *Reindex) Rethrottle(requestsPerSecond float64) {
+	if requestsPerSecond <= 0 {
+		requestsPerSecond = -1
+	}
+	r.lock.Lock()
+	r.status.RequestsPerSecond = requestsPerSecond
+	r.lock.Unlock()
+
+	select {
+	case r.rethrottled <- struct{}{}:
+	default:
+	}
+}
+
+// Run copies the documents batch by batch until all the sources are read, max_docs is reached,
+// a failure aborts the reindex or the task is cancelled
+func (r *Reindex) Run(ctx context.Context) (interface{}, error) {
+	startTime := time.Now()
+
+	dest, ok := GetIndex(r.dest)
+	if !ok {
+		var err error
+		if dest, err = NewIndex(r.dest, "disk", UseNewIndexMeta, nil); err != nil {
+			return nil, errors.New(errors.ErrorTypeInvalidIndexNameException, err.Error())
+		}
+		if err = StoreIndex(dest); err != nil {
+			return nil, err
+		}
+	}
+
+	docs := make([]*reindexDocument, 0, r.batchSize)
+	flush := func() error {
+		if len(docs) == 0 {
+			return nil
+		}
+		batchStart := time.Now()
+		if err := r.writeBatch(dest, docs); err != nil {
+			return err
+		}
+		n := len(docs)
+		docs = docs[:0]
+		return r.throttle(ctx, n, batchStart)
+	}
+	add := func(doc *reindexDocument) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+		r.read++
+		if len(docs) >= r.batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if r.req.MaxDocs > 0 && r.read >= int64(r.req.MaxDocs) {
+			return errReindexStop
+		}
+		return nil
+	}
+
+	var err error
+	if r.client != nil {
+		for _, name := range r.sources {
+			if err = r.readRemote(ctx, name, add); err != nil {
+				break
+			}
+		}
+	} else {
+		for i, name := range r.indexes {
+			if err = r.readLocal(ctx, name, r.filters[i], add); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil || err == errReindexStop {
+		err = flush()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.status.Took = time.Since(startTime).Milliseconds()
+	switch err {
+	case nil, errReindexStop:
+	case context.Canceled:
+		r.status.Canceled = "by user request"
+	default:
+		return nil, err
+	}
+	status := r.status
+	return &status, nil
+}
+
+// readLocal reads the documents matching the query and the alias filter from a local index
+func (r *Reindex) readLocal(ctx context.Context, indexName string, filter map[string]interface{}, fn func(doc *reindexDocument) error) error {
+	index, ok := GetIndex(indexName)
+	if !ok {
+		return errors.New(errors.ErrorTypeIndexNotFoundException, "no such index ["+indexName+"]")
+	}
+
+	q, err := query.Query(FilteredQuery(r.req.Source.Query, filter), index.CachedMappings, index.CachedAnalyzers)
+	if err != nil {
+		return err
+	}
+	q = query.RootQuery(q, index.CachedMappings)
+
+	reader, err := index.Writer.Reader()
+	if err != nil {
+		return fmt.Errorf("core.Reindex: error accessing reader: %s", err.Error())
+	}
+	defer reader.Close()
+
+	dmi, err := reader.Search(ctx, bluge.NewAllMatches(q))
+	if err != nil {
+		return fmt.Errorf("core.Reindex: error executing search: %s", err.Error())
+	}
+
+	next, err := dmi.Next()
+	for err == nil && next != nil {
+		doc := new(reindexDocument)
+		var timestamp time.Time
+		err = next.VisitStoredFields(func(field string, value []byte) bool {
+			switch field {
+			case "_id":
+				doc.id = string(value)
+			case "@timestamp":
+				timestamp, _ = bluge.DecodeDateTime(value)
+			case "_source":
+				doc.data = source.Response(r.sourceFilter, value)
+			default:
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("core.Reindex: error accessing stored fields: %s", err.Error())
+		}
+
+		if doc.data == nil {
+			doc.data = make(map[string]interface{})
+		}
+		// @timestamp is not kept in _source, restore it to keep the original time
+		if _, ok := doc.data["@timestamp"]; !ok && !timestamp.IsZero() {
+			doc.data["@timestamp"] = timestamp.Format(time.RFC3339Nano)
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+
+		next, err = dmi.Next()
+	}
+	if err != nil {
+		return fmt.Errorf("core.Reindex: error iterating results: %s", err.Error())
+	}
+
+	return nil
+}
+
+// readRemote reads the documents matching the query from the /es search API of a remote zinc server,
+// the documents are read page by page with search_after on _id
+func (r *Reindex) readRemote(ctx context.Context, indexName string, fn func(doc *reindexDocument) error) error {
+	remote := r.req.Source.Remote
+	api := strings.TrimRight(remote.Host, "/") + "/es/" + url.PathEscape(indexName) + "/_search"
+	q := r.req.Source.Query
+	if q == nil {
+		q = map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+
+	// pages are read in the order of _id after the last hit of the previous page, the cost of a page
+	// doesn't grow with the number of read documents and there is no result window to exceed
+	var after []interface{}
+	for {
+		search := map[string]interface{}{
+			"query":   q,
+			"size":    r.batchSize,
+			"sort":    []string{"_id"},
+			"_source": r.req.Source.Source,
+		}
+		if after != nil {
+			search["search_after"] = after
+		}
+		body, err := json.Marshal(search)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, api, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if remote.Username != "" {
+			req.SetBasicAuth(remote.Username, remote.Password)
+		}
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errors.New(errors.ErrorTypeRuntimeException, "[reindex] error requesting remote ["+remote.Host+"]: "+err.Error())
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return errors.New(errors.ErrorTypeRuntimeException, "[reindex] error reading remote ["+remote.Host+"]: "+err.Error())
+		}
+		if resp.StatusCode != http.StatusOK {
+			return remoteError(remote.Host, resp.StatusCode, data)
+		}
+
+		result := new(meta.SearchResponse)
+		if err = json.Unmarshal(data, result); err != nil {
+			return errors.New(errors.ErrorTypeParsingException, "[reindex] error parsing remote response: "+err.Error())
+		}
+		for _, hit := range result.Hits.Hits {
+			doc := &reindexDocument{id: hit.ID, data: hit.Source}
+			if doc.data == nil {
+				doc.data = make(map[string]interface{})
+			}
+			if _, ok := doc.data["@timestamp"]; !ok && !hit.Timestamp.IsZero() {
+				doc.data["@timestamp"] = hit.Timestamp.Format(time.RFC3339Nano)
+			}
+			if err := fn(doc); err != nil {
+				return err
+			}
+		}
+
+		if len(result.Hits.Hits) < r.batchSize {
+			return nil
+		}
+		after = []interface{}{result.Hits.Hits[len(result.Hits.Hits)-1].ID}
+	}
+}
+
+// writeBatch writes the documents into the dest index, it returns errReindexStop if a failure aborts the reindex
+func (r *Reindex) writeBatch(dest *Index, docs []*reindexDocument) error {
+	dest.lock.Lock()
+	defer dest.lock.Unlock()
+
+	reader, err := dest.Writer.Reader()
+	if err != nil {
+		return fmt.Errorf("core.Reindex: error accessing reader: %s", err.Error())
+	}
+	defer func() {
+		reader.Close()
+	}()
+
+	batch := blugeindex.NewBatch()
+	pending := make(map[string]struct{})
+	persist := func() error {
+		if err := dest.Writer.Batch(batch); err != nil {
+			return fmt.Errorf("core.Reindex: error updating batch: %s", err.Error())
+		}
+		batch.Reset()
+		pending = make(map[string]struct{})
+		r.lock.Lock()
+		r.status.Batches++
+		r.lock.Unlock()
+		return nil
+	}
+
+	var abort error
+	for _, doc := range docs {
+		// the same id read from different sources, the first one must be stored before checking the next
+		if _, ok := pending[doc.id]; ok {
+			if err := persist(); err != nil {
+				return err
+			}
+			reader.Close()
+			if reader, err = dest.Writer.Reader(); err != nil {
+				return fmt.Errorf("core.Reindex: error accessing reader: %s", err.Error())
+			}
+		}
+
+		current, err := GetDocumentFromReader(reader, doc.id)
+		if err != nil {
+			return err
+		}
+
+		if r.req.Dest.OpType == "create" && current != nil {
+			r.lock.Lock()
+			r.status.Total++
+			r.status.VersionConflicts++
+			if r.req.Conflicts != "proceed" {
+				r.status.Failures = append(r.status.Failures, &meta.ByQueryFailure{
+					Index:  dest.Name,
+					ID:     doc.id,
+					Cause:  DocumentExistsError(doc.id, current),
+					Status: http.StatusConflict,
+				})
+				abort = errReindexStop
+			}
+			r.lock.Unlock()
+			if abort != nil {
+				break
+			}
+			continue
+		}
+
+		bdoc, nested, err := dest.BuildBlugeDocumentFromJSON(doc.id, doc.data)
+		if err != nil {
+			r.lock.Lock()
+			r.status.Failures = append(r.status.Failures, &meta.ByQueryFailure{
+				Index:  dest.Name,
+				ID:     doc.id,
+				Cause:  errors.New(errors.ErrorTypeMapperParsingException, err.Error()),
+				Status: http.StatusBadRequest,
+			})
+			r.lock.Unlock()
+			abort = errReindexStop
+			break
+		}
+		version, _ := CheckVersion(doc.id, current, nil)
+		SetDocumentVersion(bdoc, version, dest.NextSeqNo())
+		dest.UpdateBatch(batch, bdoc, nested)
+		pending[doc.id] = struct{}{}
+
+		r.lock.Lock()
+		r.status.Total++
+		if current == nil {
+			r.status.Created++
+		} else {
+			r.status.Updated++
+		}
+		r.lock.Unlock()
+	}
+
+	if len(pending) > 0 {
+		if err := persist(); err != nil {
+			return err
+		}
+	}
+	dest.ReloadDocsCount()
+
+	return abort
+}
+
+// throttle waits until the batch of n documents matches the requests per second
+func (r *Reindex) throttle(ctx context.Context, n int, batchStart time.Time) error {
+	waitStart := time.Now()
+	for {
+		r.lock.Lock()
+		rps := r.status.RequestsPerSecond
+		r.lock.Unlock()
+		if rps <= 0 {
+			break
+		}
+
+		deadline := batchStart.Add(time.Duration(float64(n) / rps * float64(time.Second)))
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			break
+		}
+		r.lock.Lock()
+		r.throttled = deadline
+		r.lock.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-r.rethrottled:
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+		break
+	}
+
+	r.lock.Lock()
+	r.throttled = time.Time{}
+	r.status.ThrottledMillis += time.Since(waitStart).Milliseconds()
+	r.lock.Unlock()
+	return nil
+}
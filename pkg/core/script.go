@@ -0,0 +1,157 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/searchtemplate"
+)
+
+// NewScript stores a mustache template of search requests, the source is stored as a string
+func NewScript(id string, script *meta.StoredScript) error {
+	if script == nil {
+		return errors.New(errors.ErrorTypeParsingException, "must specify [script] for stored script")
+	}
+	if script.Lang == "" {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, "must specify lang for stored script")
+	}
+	if script.Lang != "mustache" {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("stored script lang [%s] is not supported, only [mustache] is supported", script.Lang))
+	}
+	source, err := templateSource(script.Source)
+	if err != nil {
+		return err
+	}
+	if source == "" {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, "must specify source for stored script")
+	}
+	if err := searchtemplate.Parse(source); err != nil {
+		return err
+	}
+	script.Source = source
+
+	bdoc := bluge.NewDocument(id)
+	bdoc.AddField(bluge.NewKeywordField("lang", script.Lang).StoreValue())
+	docByteVal, _ := json.Marshal(script)
+	bdoc.AddField(bluge.NewDateTimeField("@timestamp", time.Now()).StoreValue().Sortable().Aggregatable())
+	bdoc.AddField(bluge.NewStoredOnlyField("_source", docByteVal))
+	bdoc.AddField(bluge.NewCompositeFieldExcluding("_all", nil))
+
+	if err := ZINC_SYSTEM_INDEX_LIST["_script"].Writer.Update(bdoc.ID(), bdoc); err != nil {
+		return fmt.Errorf("script: error updating document: %s", err.Error())
+	}
+	return nil
+}
+
+// LoadScript loads a stored script
+func LoadScript(id string) (*meta.StoredScript, bool, error) {
+	reader, err := ZINC_SYSTEM_INDEX_LIST["_script"].Writer.Reader()
+	if err != nil {
+		return nil, false, fmt.Errorf("script: error accessing reader: %s", err.Error())
+	}
+	defer reader.Close()
+
+	dmi, err := reader.Search(context.Background(), bluge.NewTopNSearch(1, bluge.NewTermQuery(id).SetField("_id")))
+	if err != nil {
+		return nil, false, fmt.Errorf("script: error executing search: %s", err.Error())
+	}
+	next, err := dmi.Next()
+	if err != nil {
+		return nil, false, fmt.Errorf("script: error accessing stored fields: %s", err.Error())
+	}
+	if next == nil {
+		return nil, false, nil
+	}
+
+	script := new(meta.StoredScript)
+	err = next.VisitStoredFields(func(field string, value []byte) bool {
+		if field == "_source" {
+			_ = json.Unmarshal(value, script)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("script: error accessing stored fields: %s", err.Error())
+	}
+	return script, true, nil
+}
+
+// DeleteScript deletes a stored script
+func DeleteScript(id string) error {
+	bdoc := bluge.NewDocument(id)
+	if err := ZINC_SYSTEM_INDEX_LIST["_script"].Writer.Delete(bdoc.ID()); err != nil {
+		return fmt.Errorf("script: error deleting script: %s", err.Error())
+	}
+	return nil
+}
+
+// RenderSearchTemplate renders the stored or inline template of the request with its params
+func RenderSearchTemplate(req *meta.SearchTemplateRequest) ([]byte, error) {
+	if req.ID != "" && req.Source != nil {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, "[id] and [source] can't be used together")
+	}
+
+	var source string
+	if req.ID != "" {
+		script, exists, err := LoadScript(req.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errors.New(errors.ErrorTypeResourceNotFound, fmt.Sprintf("unable to find script [%s] in cluster state", req.ID))
+		}
+		source, _ = script.Source.(string)
+	} else {
+		var err error
+		if source, err = templateSource(req.Source); err != nil {
+			return nil, err
+		}
+		if source == "" {
+			return nil, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: template is missing;")
+		}
+	}
+
+	rendered, err := searchtemplate.Render(source, req.Params)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid([]byte(rendered)) {
+		return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("the rendered template is not a valid JSON: %s", rendered))
+	}
+	return []byte(rendered), nil
+}
+
+// templateSource returns the template as a string, the templates given as JSON objects are marshaled
+func templateSource(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+	return "", errors.New(errors.ErrorTypeParsingException, "[source] must be a string or an object")
+}
@@ -0,0 +1,160 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/startup"
+)
+
+// Scroll is a snapshot iterated page by page with search_after, it is closed when the keep alive expires
+type Scroll struct {
+	*snapshot
+
+	request []byte        // parsing changes the query, so every page parses the request again
+	after   []interface{} // sort values of the last hit
+	page    sync.Mutex    // one page at a time
+}
+
+// OpenScroll opens a scroll of the indexes and returns the first page, see MatchIndexFilters.
+// The number of open scrolls is limited by startup.LoadMaxScrollContexts().
+func OpenScroll(indexNames []string, query *meta.ZincQuery, keepAlive time.Duration) (*meta.SearchResponse, error) {
+	switch {
+	case query.SearchAfter != nil:
+		return nil, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: [search_after] cannot be used in a scroll context;")
+	case query.PIT != nil:
+		return nil, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: using [point in time] is not allowed in a scroll context;")
+	case query.From > 0:
+		return nil, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: using [from] is not allowed in a scroll context;")
+	}
+	request, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// fail fast before opening the snapshot, the limit is checked again when the scroll is added
+	contexts.lock.Lock()
+	err = checkScrollContexts()
+	contexts.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := openSnapshot(indexNames, keepAlive)
+	if err != nil {
+		return nil, err
+	}
+	scroll := &Scroll{snapshot: s, request: request}
+
+	contexts.lock.Lock()
+	if err := checkScrollContexts(); err != nil {
+		contexts.lock.Unlock()
+		scroll.close()
+		return nil, err
+	}
+	contexts.scrolls[scroll.ID] = scroll
+	contexts.lock.Unlock()
+
+	resp, err := scroll.Next(0)
+	if err != nil {
+		ClearScroll(scroll.ID)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// checkScrollContexts fails if no more scrolls can be opened, the caller must hold contexts.lock
+func checkScrollContexts() error {
+	if max := startup.LoadMaxScrollContexts(); len(contexts.scrolls) >= max {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf(
+			"Trying to create too many scroll contexts. Must be less than or equal to: [%d]. This limit can be set by changing the [ZINC_MAX_SCROLL_CONTEXTS] environment variable.", max,
+		))
+	}
+	return nil
+}
+
+// GetScroll returns the scroll by id, expired ones are not returned
+func GetScroll(id string) (*Scroll, bool) {
+	contexts.lock.Lock()
+	defer contexts.lock.Unlock()
+
+	scroll, ok := contexts.scrolls[id]
+	if !ok || scroll.expired(time.Now()) {
+		return nil, false
+	}
+	return scroll, true
+}
+
+// ClearScroll closes the scrolls and returns the number of closed ones, no ids closes all the scrolls
+func ClearScroll(ids ...string) int {
+	closed := make([]*Scroll, 0, len(ids))
+	contexts.lock.Lock()
+	if len(ids) == 0 {
+		for id, scroll := range contexts.scrolls {
+			closed = append(closed, scroll)
+			delete(contexts.scrolls, id)
+		}
+	}
+	for _, id := range ids {
+		if scroll, ok := contexts.scrolls[id]; ok {
+			closed = append(closed, scroll)
+			delete(contexts.scrolls, id)
+		}
+	}
+	contexts.lock.Unlock()
+
+	for _, scroll := range closed {
+		scroll.close()
+	}
+	return len(closed)
+}
+
+// Next returns the next page of the scroll, keepAlive extends the scroll if it is greater than 0
+func (s *Scroll) Next(keepAlive time.Duration) (*meta.SearchResponse, error) {
+	s.page.Lock()
+	defer s.page.Unlock()
+
+	query := new(meta.ZincQuery)
+	if err := json.Unmarshal(s.request, query); err != nil {
+		return nil, err
+	}
+	hasSort := query.Sort != nil
+	query.SearchAfter = s.after
+	// the scroll is a point in time, the parser breaks the ties of the sort by _id
+	query.PIT = &meta.PointInTime{ID: s.ID}
+
+	resp, err := s.search(query, keepAlive)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(resp.Hits.Hits); n > 0 {
+		s.after = resp.Hits.Hits[n-1].Sort
+	}
+	if !hasSort {
+		for i := range resp.Hits.Hits {
+			resp.Hits.Hits[i].Sort = nil
+		}
+	}
+	resp.ScrollID = s.ID
+	return resp, nil
+}
@@ -50,30 +50,30 @@ func (index *Index) Search(iQuery *v1.ZincQuery) (*v1.SearchResponse, error) {
 	var err error
 	switch iQuery.SearchType {
 	case "alldocuments":
-		searchRequest, err = uquery.AllDocuments(iQuery)
+		searchRequest, err = uquery.AllDocuments(iQuery, index.CachedMappings)
 	case "wildcard":
-		searchRequest, err = uquery.WildcardQuery(iQuery)
+		searchRequest, err = uquery.WildcardQuery(iQuery, index.CachedMappings)
 	case "fuzzy":
-		searchRequest, err = uquery.FuzzyQuery(iQuery)
+		searchRequest, err = uquery.FuzzyQuery(iQuery, index.CachedMappings)
 	case "term":
-		searchRequest, err = uquery.TermQuery(iQuery)
+		searchRequest, err = uquery.TermQuery(iQuery, index.CachedMappings)
 	case "daterange":
-		searchRequest, err = uquery.DateRangeQuery(iQuery)
+		searchRequest, err = uquery.DateRangeQuery(iQuery, index.CachedMappings)
 	case "matchall":
-		searchRequest, err = uquery.MatchAllQuery(iQuery)
+		searchRequest, err = uquery.MatchAllQuery(iQuery, index.CachedMappings)
 	case "match":
-		searchRequest, err = uquery.MatchQuery(iQuery)
+		searchRequest, err = uquery.MatchQuery(iQuery, index.CachedMappings)
 	case "matchphrase":
-		searchRequest, err = uquery.MatchPhraseQuery(iQuery)
+		searchRequest, err = uquery.MatchPhraseQuery(iQuery, index.CachedMappings)
 	case "multiphrase":
-		searchRequest, err = uquery.MultiPhraseQuery(iQuery)
+		searchRequest, err = uquery.MultiPhraseQuery(iQuery, index.CachedMappings)
 	case "prefix":
-		searchRequest, err = uquery.PrefixQuery(iQuery)
+		searchRequest, err = uquery.PrefixQuery(iQuery, index.CachedMappings)
 	case "querystring":
-		searchRequest, err = uquery.QueryStringQuery(iQuery)
+		searchRequest, err = uquery.QueryStringQuery(iQuery, index.CachedMappings)
 	default:
 		// default use alldocuments search
-		searchRequest, err = uquery.AllDocuments(iQuery)
+		searchRequest, err = uquery.AllDocuments(iQuery, index.CachedMappings)
 	}
 
 	if err != nil {
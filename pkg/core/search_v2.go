@@ -21,13 +21,17 @@ import (
 
 	"github.com/blugelabs/bluge"
 	"github.com/blugelabs/bluge/search"
-	"github.com/blugelabs/bluge/search/highlight"
 	"github.com/rs/zerolog/log"
 
+	zincaggregation "github.com/zinclabs/zinc/pkg/bluge/aggregation"
+	"github.com/zinclabs/zinc/pkg/bluge/profile"
 	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 	parser "github.com/zinclabs/zinc/pkg/uquery/v2"
 	"github.com/zinclabs/zinc/pkg/uquery/v2/fields"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/highlight"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/sort"
 	"github.com/zinclabs/zinc/pkg/uquery/v2/source"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/suggest"
 )
 
 func (index *Index) SearchV2(query *meta.ZincQuery) (*meta.SearchResponse, error) {
@@ -50,7 +54,9 @@ func (index *Index) SearchV2(query *meta.ZincQuery) (*meta.SearchResponse, error
 		defer cancel()
 	}
 
+	start := time.Now()
 	dmi, err := reader.Search(ctx, searchRequest)
+	query.Profiler.AddCollect(start)
 	if err != nil {
 		log.Printf("index.SearchV2: error executing search: %s", err.Error())
 		if err == context.DeadlineExceeded {
@@ -63,23 +69,30 @@ func (index *Index) SearchV2(query *meta.ZincQuery) (*meta.SearchResponse, error
 		return nil, err
 	}
 
-	return searchV2(dmi, query, index.CachedMappings)
+	resp, err := searchV2(dmi, query, index.CachedMappings, map[string]*Index{index.Name: index}, func(req bluge.SearchRequest) (search.DocumentMatchIterator, error) {
+		return reader.Search(ctx, req)
+	}, func(indexName string, req bluge.SearchRequest) (search.DocumentMatchIterator, error) {
+		return reader.Search(ctx, req)
+	})
+	if err == nil && query.Profiler != nil {
+		resp.Profile = profileResponse(query.Profiler, []string{index.Name})
+	}
+	if err != nil || query.Suggesters == nil {
+		return resp, err
+	}
+	resp.Suggest, err = suggest.Response(query.Suggesters, []*bluge.Reader{reader}, index.CachedMappings, index.CachedAnalyzers)
+	return resp, err
 }
 
-func searchV2(dmi search.DocumentMatchIterator, query *meta.ZincQuery, mappings *meta.Mappings) (*meta.SearchResponse, error) {
+// searchV2 formats the response, indexes are the searched indexes by name, the hits are highlighted with
+// the mappings and analyzers of their index. searchFn searches the same readers for the nested aggregations,
+// indexSearchFn searches the reader of one index for the inner hits
+func searchV2(dmi search.DocumentMatchIterator, query *meta.ZincQuery, mappings *meta.Mappings, indexes map[string]*Index, searchFn func(req bluge.SearchRequest) (search.DocumentMatchIterator, error), indexSearchFn func(indexName string, req bluge.SearchRequest) (search.DocumentMatchIterator, error)) (*meta.SearchResponse, error) {
 	resp := &meta.SearchResponse{
 		Hits: meta.Hits{Hits: []meta.Hit{}},
 	}
 
-	// highlight
-	var highlighter *highlight.SimpleHighlighter
-	if query.Highlight != nil {
-		if len(query.Highlight.PreTags) > 0 && len(query.Highlight.PostTags) > 0 {
-			highlighter = highlight.NewHTMLHighlighterTags(query.Highlight.PreTags[0], query.Highlight.PostTags[0])
-		} else {
-			highlighter = highlight.NewHTMLHighlighter()
-		}
-	}
+	sortFields, _ := query.Sort.([]*meta.SortField)
 
 	Hits := make([]meta.Hit, 0)
 	next, err := dmi.Next()
@@ -87,12 +100,9 @@ func searchV2(dmi search.DocumentMatchIterator, query *meta.ZincQuery, mappings
 		var id string
 		var indexName string
 		var timestamp time.Time
-		var sourceData map[string]interface{}
-		var fieldsData map[string]interface{}
-		var highlightData map[string]interface{}
-		if query.Highlight != nil {
-			highlightData = make(map[string]interface{})
-		}
+		var version, seqNo int64 = 1, 0
+		var rawSource []byte
+		start := time.Now()
 		err = next.VisitStoredFields(func(field string, value []byte) bool {
 			switch field {
 			case "_id":
@@ -101,34 +111,53 @@ func searchV2(dmi search.DocumentMatchIterator, query *meta.ZincQuery, mappings
 				indexName = string(value)
 			case "@timestamp":
 				timestamp, _ = bluge.DecodeDateTime(value)
-			case "_source":
-				sourceData = source.Response(query.Source.(*meta.Source), value)
-				if query.Fields != nil {
-					fieldsData = fields.Response(query.Fields.([]*meta.Field), value, mappings)
+			case "_version":
+				if v, err := bluge.DecodeNumericFloat64(value); err == nil {
+					version = int64(v)
 				}
-			default:
-				// highlight
-				if query.Highlight != nil && query.Highlight.Fields != nil {
-					if options, ok := query.Highlight.Fields[field]; ok {
-						if v, ok := next.Locations[field]; ok {
-							if len(options.PreTags) > 0 && len(options.PostTags) > 0 {
-								highlighter := highlight.NewHTMLHighlighterTags(options.PreTags[0], options.PostTags[0])
-								highlightData[field] = highlighter.BestFragments(v, value, options.NumberOfFragments)
-							} else {
-								highlightData[field] = highlighter.BestFragments(v, value, options.NumberOfFragments)
-							}
-						}
-					}
+			case "_seq_no":
+				if v, err := bluge.DecodeNumericFloat64(value); err == nil {
+					seqNo = int64(v)
 				}
+			case "_source":
+				rawSource = append(rawSource, value...)
 			}
 
 			return true
 		})
+		query.Profiler.AddFetch("load_stored_fields", start)
 		if err != nil {
 			log.Printf("core.SearchV2: error accessing stored fields: %s", err.Error())
 			continue
 		}
 
+		var sourceData map[string]interface{}
+		var fieldsData map[string]interface{}
+		var highlightData map[string]interface{}
+		if rawSource != nil {
+			start = time.Now()
+			sourceData = source.Response(query.Source.(*meta.Source), rawSource)
+			query.Profiler.AddFetch("FetchSourcePhase", start)
+			if query.Fields != nil {
+				start = time.Now()
+				fieldsData = fields.Response(query.Fields.([]*meta.Field), rawSource, mappings)
+				query.Profiler.AddFetch("FetchFieldsPhase", start)
+			}
+		}
+		if query.Highlight != nil {
+			start = time.Now()
+			options := query.Highlight
+			if v, ok := query.IndexHighlights[indexName]; ok {
+				options = v
+			}
+			if index, ok := indexes[indexName]; ok {
+				highlightData = highlight.Response(options, rawSource, index.CachedMappings, index.CachedAnalyzers)
+			} else {
+				highlightData = highlight.Response(options, rawSource, mappings, nil)
+			}
+			query.Profiler.AddFetch("HighlightPhase", start)
+		}
+
 		hit := meta.Hit{
 			Index:     indexName,
 			Type:      "_doc",
@@ -139,6 +168,37 @@ func searchV2(dmi search.DocumentMatchIterator, query *meta.ZincQuery, mappings
 			Fields:    fieldsData,
 			Highlight: highlightData,
 		}
+		if query.Explain {
+			hit.Explanation = explanation(next.Explanation)
+		}
+		if query.Version {
+			hit.Version = version
+		}
+		if query.SeqNoPrimaryTerm {
+			hit.SeqNo = &seqNo
+			hit.PrimaryTerm = PrimaryTerm
+		}
+		if len(sortFields) > 0 {
+			hit.Sort = sort.Values(sortFields, next.SortValue, mappings)
+		}
+		start = time.Now()
+		indexInnerHits := query.InnerHits
+		if v, ok := query.IndexInnerHits[indexName]; ok {
+			indexInnerHits = v
+		}
+		for _, innerHits := range indexInnerHits {
+			innerHit, err := searchInnerHits(innerHits, indexName, id, indexSearchFn)
+			if err != nil {
+				return nil, err
+			}
+			if hit.InnerHits == nil {
+				hit.InnerHits = make(map[string]meta.InnerHit)
+			}
+			hit.InnerHits[innerHits.Name] = innerHit
+		}
+		if len(indexInnerHits) > 0 {
+			query.Profiler.AddFetch("InnerHitsPhase", start)
+		}
 		Hits = append(Hits, hit)
 
 		next, err = dmi.Next()
@@ -157,9 +217,67 @@ func searchV2(dmi search.DocumentMatchIterator, query *meta.ZincQuery, mappings
 		Hits:     Hits,
 	}
 
+	if query.Profiler != nil {
+		profile.Unwrap(dmi.Aggregations())
+	}
+	if err := zincaggregation.ResolveNested(dmi.Aggregations(), searchFn); err != nil {
+		return nil, err
+	}
 	if err := parser.FormatResponse(resp, query, dmi.Aggregations()); err != nil {
 		log.Printf("core.SearchV2: error format response: %s", err.Error())
 	}
 
 	return resp, nil
 }
+
+// searchInnerHits searches the matching nested objects of the document in its index, the other indexes
+// can have a document with the same id
+func searchInnerHits(innerHits *meta.InnerHits, indexName, id string, indexSearchFn func(indexName string, req bluge.SearchRequest) (search.DocumentMatchIterator, error)) (meta.InnerHit, error) {
+	filter := bluge.NewBooleanQuery().SetBoost(0).AddMust(bluge.NewTermQuery(id).SetField(meta.NestedParentField))
+	q := bluge.NewBooleanQuery().AddMust(innerHits.Query, filter)
+	req := bluge.NewTopNSearch(innerHits.Size, q).SetFrom(innerHits.From).WithStandardAggregations()
+	dmi, err := indexSearchFn(indexName, req)
+	if err != nil {
+		return meta.InnerHit{}, err
+	}
+
+	hits := make([]meta.Hit, 0)
+	next, err := dmi.Next()
+	for err == nil && next != nil {
+		hit := meta.Hit{
+			Type:   "_doc",
+			ID:     id,
+			Score:  next.Score,
+			Nested: &meta.NestedIdentity{Field: innerHits.Path},
+		}
+		err = next.VisitStoredFields(func(field string, value []byte) bool {
+			switch field {
+			case "_index":
+				hit.Index = string(value)
+			case meta.NestedOffsetField:
+				if v, err := bluge.DecodeNumericFloat64(value); err == nil {
+					hit.Nested.Offset = int(v)
+				}
+			case "_source":
+				hit.Source = source.Response(innerHits.Source, value)
+			}
+			return true
+		})
+		if err != nil {
+			return meta.InnerHit{}, err
+		}
+		hits = append(hits, hit)
+		next, err = dmi.Next()
+	}
+	if err != nil {
+		return meta.InnerHit{}, err
+	}
+
+	return meta.InnerHit{
+		Hits: meta.Hits{
+			Total:    meta.Total{Value: int(dmi.Aggregations().Count())},
+			MaxScore: dmi.Aggregations().Metric("max_score"),
+			Hits:     hits,
+		},
+	}, nil
+}
@@ -0,0 +1,220 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// TaskNode is the node name in task ids, zinc runs as a single node
+const TaskNode = "zinc"
+
+// TaskRunner is the work of a background task
+type TaskRunner interface {
+	Run(ctx context.Context) (interface{}, error)
+	Status() interface{} // progress of the running task
+}
+
+// completed tasks are kept for the task API until they expire, the oldest ones are evicted first
+// if there are too many of them
+var (
+	taskRetention     = 24 * time.Hour
+	maxCompletedTasks = 1000
+)
+
+// Task is a background job, like reindex. Tasks are kept in memory, the completed ones are evicted
+// after taskRetention, see evictTasks.
+type Task struct {
+	ID          int64
+	Action      string
+	Description string
+	StartTime   time.Time
+	Runner      TaskRunner
+
+	cancel    context.CancelFunc
+	cancelled int32
+	done      chan struct{}
+	endTime   time.Time
+	response  interface{}
+	err       error
+}
+
+var tasks = struct {
+	lock  sync.Mutex
+	seq   int64
+	tasks map[int64]*Task
+}{tasks: make(map[int64]*Task)}
+
+// NewTask registers the task and starts it in background
+func NewTask(action, description string, runner TaskRunner) *Task {
+	ctx, cancel := context.WithCancel(context.Background())
+	task := &Task{
+		Action:      action,
+		Description: description,
+		StartTime:   time.Now(),
+		Runner:      runner,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+
+	tasks.lock.Lock()
+	evictTasks(time.Now())
+	tasks.seq++
+	task.ID = tasks.seq
+	tasks.tasks[task.ID] = task
+	tasks.lock.Unlock()
+
+	go func() {
+		defer cancel()
+		response, err := runner.Run(ctx)
+		task.response, task.err = response, err
+		task.endTime = time.Now()
+		close(task.done)
+	}()
+
+	return task
+}
+
+// GetTask returns the task by id, like: zinc:1
+func GetTask(id string) (*Task, bool) {
+	i := strings.LastIndex(id, ":")
+	if i < 0 || id[:i] != TaskNode {
+		return nil, false
+	}
+	n, err := strconv.ParseInt(id[i+1:], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	tasks.lock.Lock()
+	evictTasks(time.Now())
+	task, ok := tasks.tasks[n]
+	tasks.lock.Unlock()
+	return task, ok
+}
+
+// ListTasks returns all the tasks, running and completed
+func ListTasks() []*Task {
+	tasks.lock.Lock()
+	defer tasks.lock.Unlock()
+
+	evictTasks(time.Now())
+	list := make([]*Task, 0, len(tasks.tasks))
+	for _, task := range tasks.tasks {
+		list = append(list, task)
+	}
+	return list
+}
+
+// evictTasks removes the completed tasks older than taskRetention and the oldest completed ones
+// above maxCompletedTasks, the caller must hold the write lock of tasks
+func evictTasks(now time.Time) {
+	completed := make([]*Task, 0)
+	for id, task := range tasks.tasks {
+		if !task.Completed() {
+			continue
+		}
+		if now.Sub(task.endTime) > taskRetention {
+			delete(tasks.tasks, id)
+			continue
+		}
+		completed = append(completed, task)
+	}
+	if len(completed) <= maxCompletedTasks {
+		return
+	}
+
+	sort.Slice(completed, func(i, j int) bool {
+		if completed[i].endTime.Equal(completed[j].endTime) {
+			return completed[i].ID < completed[j].ID
+		}
+		return completed[i].endTime.Before(completed[j].endTime)
+	})
+	for _, task := range completed[:len(completed)-maxCompletedTasks] {
+		delete(tasks.tasks, task.ID)
+	}
+}
+
+// TaskID returns the id of the task used by the API, like: zinc:1
+func (t *Task) TaskID() string {
+	return TaskNode + ":" + strconv.FormatInt(t.ID, 10)
+}
+
+// Cancel asks the task to stop, it returns immediately
+func (t *Task) Cancel() {
+	atomic.StoreInt32(&t.cancelled, 1)
+	t.cancel()
+}
+
+// Done is closed when the task is completed
+func (t *Task) Done() <-chan struct{} {
+	return t.done
+}
+
+// Completed checks if the task is completed
+func (t *Task) Completed() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Result returns the response of the task, it should be called after the task is completed
+func (t *Task) Result() (interface{}, error) {
+	<-t.done
+	return t.response, t.err
+}
+
+// Info returns the task information for the task API
+func (t *Task) Info() *meta.TaskResponse {
+	info := &meta.TaskInfo{
+		Node:              TaskNode,
+		ID:                t.ID,
+		Type:              "transport",
+		Action:            t.Action,
+		Status:            t.Runner.Status(),
+		Description:       t.Description,
+		StartTimeInMillis: t.StartTime.UnixNano() / int64(time.Millisecond),
+		Cancellable:       true,
+		Cancelled:         atomic.LoadInt32(&t.cancelled) == 1,
+	}
+
+	resp := &meta.TaskResponse{Task: info}
+	if t.Completed() {
+		resp.Completed = true
+		info.RunningTimeInNanos = t.endTime.Sub(t.StartTime).Nanoseconds()
+		resp.Response = t.response
+		if v, ok := t.err.(*errors.Error); ok {
+			resp.Error = v
+		} else if t.err != nil {
+			resp.Error = errors.New(errors.ErrorTypeRuntimeException, t.err.Error())
+		}
+	} else {
+		info.RunningTimeInNanos = time.Since(t.StartTime).Nanoseconds()
+	}
+	return resp
+}
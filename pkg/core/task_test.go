@@ -0,0 +1,63 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testTaskRunner struct{}
+
+func (testTaskRunner) Run(ctx context.Context) (interface{}, error) { return nil, nil }
+func (testTaskRunner) Status() interface{}                          { return nil }
+
+func TestEvictTasks(t *testing.T) {
+	Convey("test evict completed tasks", t, func() {
+		retention, max := taskRetention, maxCompletedTasks
+		defer func() { taskRetention, maxCompletedTasks = retention, max }()
+
+		Convey("evict after the retention", func() {
+			task := NewTask("test", "test", testTaskRunner{})
+			<-task.Done()
+			_, ok := GetTask(task.TaskID())
+			So(ok, ShouldBeTrue)
+
+			taskRetention = time.Nanosecond
+			time.Sleep(time.Millisecond)
+			_, ok = GetTask(task.TaskID())
+			So(ok, ShouldBeFalse)
+		})
+		Convey("evict the oldest above the limit", func() {
+			maxCompletedTasks = 2
+			list := make([]*Task, 0, 3)
+			for i := 0; i < 3; i++ {
+				task := NewTask("test", "test", testTaskRunner{})
+				<-task.Done()
+				list = append(list, task)
+			}
+			_, ok := GetTask(list[0].TaskID())
+			So(ok, ShouldBeFalse)
+			_, ok = GetTask(list[1].TaskID())
+			So(ok, ShouldBeTrue)
+			_, ok = GetTask(list[2].TaskID())
+			So(ok, ShouldBeTrue)
+		})
+	})
+}
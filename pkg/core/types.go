@@ -16,6 +16,7 @@
 package core
 
 import (
+	"sync"
 	"time"
 
 	"github.com/blugelabs/bluge"
@@ -63,6 +64,10 @@ func init() {
 			StoreIndex(index)
 		}
 	}
+
+	if err = LoadAliases(); err != nil {
+		log.Error().Msgf("Error loading aliases: %s", err.Error())
+	}
 }
 
 type Index struct {
@@ -77,6 +82,26 @@ type Index struct {
 	CachedAnalyzers     map[string]*analysis.Analyzer `json:"-"`
 	CachedMappings      *meta.Mappings                `json:"-"`
 	Writer              *bluge.Writer                 `json:"-"`
+	SeqNo               int64                         `json:"-"` // last sequence number of the index
+	lock                sync.Mutex                    // serializes read-check-write of single documents
+}
+
+// Document is a single stored document loaded from an index reader
+type Document struct {
+	Index     string
+	ID        string
+	Timestamp time.Time
+	Version   int64
+	SeqNo     int64
+	Source    []byte // raw _source json
+}
+
+// WriteResult is the outcome of a document write
+type WriteResult struct {
+	ID      string
+	Version int64
+	SeqNo   int64
+	Result  string // created, updated, deleted, not_found, noop
 }
 
 type IndexTemplate struct {
@@ -15,20 +15,188 @@
 
 package core
 
+import (
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/zutils/flatten"
+)
+
 // UpdateDocument inserts or updates a document in the zinc index
 func (index *Index) UpdateDocument(docID string, doc map[string]interface{}, mintedID bool) error {
-	bdoc, err := index.BuildBlugeDocumentFromJSON(docID, doc)
+	_, err := index.IndexDocument(docID, doc, mintedID, nil)
+	return err
+}
+
+// IndexDocument inserts or updates a document in the zinc index, the write is rejected
+// with a version conflict if the version control doesn't match the stored document
+func (index *Index) IndexDocument(docID string, doc map[string]interface{}, mintedID bool, vc *meta.VersionControl) (*WriteResult, error) {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	var current *Document
+	if !mintedID {
+		var err error
+		if current, err = index.GetDocument(docID); err != nil {
+			return nil, err
+		}
+	}
+
+	return index.writeDocument(docID, doc, current, vc)
+}
+
+// CreateDocument inserts a document in the zinc index, it fails with a version conflict if the document already exists
+func (index *Index) CreateDocument(docID string, doc map[string]interface{}) (*WriteResult, error) {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	current, err := index.GetDocument(docID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		return nil, DocumentExistsError(docID, current)
+	}
+
+	return index.writeDocument(docID, doc, nil, nil)
+}
+
+// UpdateDocumentPartial applies a partial update to a document, see MergeDocument
+func (index *Index) UpdateDocumentPartial(docID string, update *meta.UpdateRequest, vc *meta.VersionControl) (*WriteResult, error) {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	current, err := index.GetDocument(docID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, result, err := MergeDocument(docID, current, update)
+	if err != nil {
+		return nil, err
+	}
+	if result == "noop" {
+		if _, err := CheckVersion(docID, current, vc); err != nil {
+			return nil, err
+		}
+		return &WriteResult{ID: docID, Version: current.Version, SeqNo: current.SeqNo, Result: result}, nil
+	}
+
+	return index.writeDocument(docID, doc, current, vc)
+}
+
+// DeleteDocument deletes a document from the zinc index
+func (index *Index) DeleteDocument(docID string, vc *meta.VersionControl) (*WriteResult, error) {
+	index.lock.Lock()
+	defer index.lock.Unlock()
+
+	current, err := index.GetDocument(docID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := CheckVersion(docID, current, vc)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := bluge.NewBatch()
+	index.DeleteBatch(batch, docID)
+	if err := index.Writer.Batch(batch); err != nil {
+		return nil, err
+	}
+
+	// a missing document is not written, it doesn't take a sequence number
+	if current == nil {
+		return &WriteResult{ID: docID, Version: version, SeqNo: index.CurrentSeqNo(), Result: "not_found"}, nil
+	}
+	index.AddDocsCount(-1)
+
+	return &WriteResult{ID: docID, Version: version, SeqNo: index.NextSeqNo(), Result: "deleted"}, nil
+}
+
+// LockWrites takes the lock that serializes the read-check-write of documents, writers that check
+// the stored documents must hold it from the check until their batch is persisted
+func (index *Index) LockWrites() {
+	index.lock.Lock()
+}
+
+// UnlockWrites releases the lock taken by LockWrites
+func (index *Index) UnlockWrites() {
+	index.lock.Unlock()
+}
+
+// writeDocument writes the document with the next version and sequence number,
+// current is the stored document, nil if it doesn't exist. The caller must hold index.lock.
+func (index *Index) writeDocument(docID string, doc map[string]interface{}, current *Document, vc *meta.VersionControl) (*WriteResult, error) {
+	version, err := CheckVersion(docID, current, vc)
+	if err != nil {
+		return nil, err
+	}
+
+	bdoc, nested, err := index.BuildBlugeDocumentFromJSON(docID, doc)
 	if err != nil {
-		return err
+		return nil, errors.New(errors.ErrorTypeMapperParsingException, err.Error())
 	}
+	seqNo := index.NextSeqNo()
+	SetDocumentVersion(bdoc, version, seqNo)
 
 	// Finally update the document on disk
-	writer := index.Writer
-	if !mintedID {
-		err = writer.Update(bdoc.ID(), bdoc)
-	} else {
-		err = writer.Insert(bdoc)
-		index.GainDocsCount(1)
+	batch := bluge.NewBatch()
+	index.UpdateBatch(batch, bdoc, nested)
+	if err = index.Writer.Batch(batch); err != nil {
+		return nil, err
 	}
-	return err
+
+	ret := &WriteResult{ID: docID, Version: version, SeqNo: seqNo, Result: "updated"}
+	if current == nil {
+		ret.Result = "created"
+		index.AddDocsCount(1)
+	}
+
+	return ret, nil
+}
+
+// MergeDocument applies a partial update to the stored _source of a document, old is the stored
+// document or nil if it doesn't exist. It returns the new document and the result of the operation:
+// created, updated or noop. Nothing is written to the index.
+func MergeDocument(docID string, old *Document, update *meta.UpdateRequest) (map[string]interface{}, string, error) {
+	if update.Script != nil {
+		return nil, "", errors.New(errors.ErrorTypeNotImplemented, "[update] script doesn't support")
+	}
+	if update.Doc == nil {
+		return nil, "", errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: script or doc is missing;")
+	}
+
+	if old == nil {
+		if update.Upsert != nil {
+			return update.Upsert, "created", nil
+		}
+		if update.DocAsUpsert {
+			return update.Doc, "created", nil
+		}
+		return nil, "", errors.New(errors.ErrorTypeDocumentMissingException, "["+docID+"]: document missing")
+	}
+
+	doc := make(map[string]interface{})
+	if len(old.Source) > 0 {
+		if err := json.Unmarshal(old.Source, &doc); err != nil {
+			return nil, "", err
+		}
+	}
+
+	changed := flatten.Merge(doc, update.Doc)
+	if !changed && (update.DetectNoop == nil || *update.DetectNoop) {
+		return doc, "noop", nil
+	}
+
+	// @timestamp is not kept in _source, restore it to keep the original time
+	if _, ok := doc["@timestamp"]; !ok && !old.Timestamp.IsZero() {
+		doc["@timestamp"] = old.Timestamp.Format(time.RFC3339Nano)
+	}
+
+	return doc, "updated", nil
 }
@@ -0,0 +1,152 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/blugelabs/bluge"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// PrimaryTerm is always 1, zinc has no replicas that can be promoted
+const PrimaryTerm int64 = 1
+
+const (
+	VersionTypeInternal    = "internal"
+	VersionTypeExternal    = "external"
+	VersionTypeExternalGTE = "external_gte"
+)
+
+// CheckVersion checks the concurrency control of a write against the current document,
+// current is nil if the document doesn't exist. It returns the version for the new document.
+func CheckVersion(docID string, current *Document, vc *meta.VersionControl) (int64, error) {
+	var currentVersion int64
+	if current != nil {
+		currentVersion = current.Version
+	}
+	if vc == nil {
+		return currentVersion + 1, nil
+	}
+
+	if vc.IfSeqNo != nil || vc.IfPrimaryTerm != nil {
+		if vc.IfSeqNo == nil || vc.IfPrimaryTerm == nil {
+			return 0, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: if_seq_no and if_primary_term must be set together;")
+		}
+		if current == nil {
+			return 0, errors.New(errors.ErrorTypeVersionConflictEngineException, fmt.Sprintf(
+				"[%s]: version conflict, required seqNo [%d], primary term [%d]. but no document was found",
+				docID, *vc.IfSeqNo, *vc.IfPrimaryTerm,
+			))
+		}
+		if *vc.IfSeqNo != current.SeqNo || *vc.IfPrimaryTerm != PrimaryTerm {
+			return 0, errors.New(errors.ErrorTypeVersionConflictEngineException, fmt.Sprintf(
+				"[%s]: version conflict, required seqNo [%d], primary term [%d]. current document has seqNo [%d] and primary term [%d]",
+				docID, *vc.IfSeqNo, *vc.IfPrimaryTerm, current.SeqNo, PrimaryTerm,
+			))
+		}
+	}
+
+	switch vc.VersionType {
+	case "", VersionTypeInternal:
+		if vc.Version != nil {
+			return 0, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: internal versioning can not be used for optimistic concurrency control. Please use `if_seq_no` and `if_primary_term` instead;")
+		}
+		return currentVersion + 1, nil
+	case VersionTypeExternal, VersionTypeExternalGTE:
+		if vc.Version == nil {
+			return 0, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: an explicit version is required for version_type ["+vc.VersionType+"];")
+		}
+		if current != nil {
+			if vc.VersionType == VersionTypeExternal && *vc.Version <= currentVersion {
+				return 0, errors.New(errors.ErrorTypeVersionConflictEngineException, fmt.Sprintf(
+					"[%s]: version conflict, current version [%d] is higher or equal to the one provided [%d]",
+					docID, currentVersion, *vc.Version,
+				))
+			}
+			if vc.VersionType == VersionTypeExternalGTE && *vc.Version < currentVersion {
+				return 0, errors.New(errors.ErrorTypeVersionConflictEngineException, fmt.Sprintf(
+					"[%s]: version conflict, current version [%d] is higher than the one provided [%d]",
+					docID, currentVersion, *vc.Version,
+				))
+			}
+		}
+		return *vc.Version, nil
+	default:
+		return 0, errors.New(errors.ErrorTypeIllegalArgumentException, "No version type match ["+vc.VersionType+"]")
+	}
+}
+
+// DocumentExistsError is the version conflict of creating a document that already exists
+func DocumentExistsError(docID string, current *Document) error {
+	return errors.New(errors.ErrorTypeVersionConflictEngineException, fmt.Sprintf(
+		"[%s]: version conflict, document already exists (current version [%d])", docID, current.Version,
+	))
+}
+
+// SetDocumentVersion adds the version and sequence number fields to the bluge document
+func SetDocumentVersion(bdoc *bluge.Document, version, seqNo int64) {
+	bdoc.AddField(bluge.NewNumericField("_version", float64(version)).StoreValue())
+	bdoc.AddField(bluge.NewNumericField("_seq_no", float64(seqNo)).StoreValue().Sortable())
+}
+
+// NextSeqNo returns the next sequence number of the index
+func (index *Index) NextSeqNo() int64 {
+	return atomic.AddInt64(&index.SeqNo, 1)
+}
+
+// CurrentSeqNo returns the last sequence number of the index without allocating a new one
+func (index *Index) CurrentSeqNo() int64 {
+	return atomic.LoadInt64(&index.SeqNo)
+}
+
+// LoadSeqNo returns the max sequence number stored in the index, -1 if nothing stored
+func (index *Index) LoadSeqNo() (int64, error) {
+	reader, err := index.Writer.Reader()
+	if err != nil {
+		return -1, fmt.Errorf("core.index.LoadSeqNo: error accessing reader: %s", err.Error())
+	}
+	defer reader.Close()
+
+	query := bluge.NewMatchAllQuery()
+	searchRequest := bluge.NewTopNSearch(1, query).SortBy([]string{"-_seq_no"})
+	dmi, err := reader.Search(context.Background(), searchRequest)
+	if err != nil {
+		return -1, fmt.Errorf("core.index.LoadSeqNo: error executing search: %s", err.Error())
+	}
+
+	next, err := dmi.Next()
+	if err != nil || next == nil {
+		return -1, err
+	}
+
+	seqNo := int64(-1)
+	err = next.VisitStoredFields(func(field string, value []byte) bool {
+		if field == "_seq_no" {
+			if v, err := bluge.DecodeNumericFloat64(value); err == nil {
+				seqNo = int64(v)
+			}
+			return false
+		}
+		return true
+	})
+
+	return seqNo, err
+}
@@ -18,11 +18,21 @@ package errors
 import "fmt"
 
 const (
-	ErrorTypeParsingException         = "parsing_exception"
-	ErrorTypeXContentParseException   = "x_content_parse_exception"
-	ErrorTypeIllegalArgumentException = "illegal_argument_exception"
-	ErrorTypeNotImplemented           = "not_implemented"
-	ErrorTypeRuntimeException         = "runtime_exception"
+	ErrorTypeParsingException               = "parsing_exception"
+	ErrorTypeXContentParseException         = "x_content_parse_exception"
+	ErrorTypeIllegalArgumentException       = "illegal_argument_exception"
+	ErrorTypeNotImplemented                 = "not_implemented"
+	ErrorTypeRuntimeException               = "runtime_exception"
+	ErrorTypeIndexNotFoundException         = "index_not_found_exception"
+	ErrorTypeResourceNotFound               = "resource_not_found_exception"
+	ErrorTypeDocumentMissingException       = "document_missing_exception"
+	ErrorTypeActionRequestValidation        = "action_request_validation_exception"
+	ErrorTypeVersionConflictEngineException = "version_conflict_engine_exception"
+	ErrorTypeMapperParsingException         = "mapper_parsing_exception"
+	ErrorTypeInvalidIndexNameException      = "invalid_index_name_exception"
+	ErrorTypeScriptException                = "script_exception"
+	ErrorTypeInvalidAliasNameException      = "invalid_alias_name_exception"
+	ErrorTypeAliasesNotFoundException       = "aliases_not_found_exception"
 )
 
 type Error struct {
@@ -16,8 +16,7 @@
 package handlers
 
 import (
-	"bufio"
-	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"time"
@@ -29,8 +28,11 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
 	"github.com/zinclabs/zinc/pkg/ider"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 	"github.com/zinclabs/zinc/pkg/startup"
+	"github.com/zinclabs/zinc/pkg/zutils/ndjson"
 )
 
 func BulkHandler(c *gin.Context) {
@@ -52,183 +54,403 @@ func ESBulkHandler(c *gin.Context) {
 	ret.Took = int(time.Since(startTime) / time.Millisecond)
 	if err != nil {
 		ret.Error = err.Error()
+		c.JSON(http.StatusInternalServerError, ret)
+		return
 	}
 	c.JSON(http.StatusOK, ret)
 }
 
+// BulkHandlerWorker processes the bulk request. Every action gets its own item in the response,
+// a failed action doesn't stop the others. It only returns an error when the batch can't be persisted.
 func BulkHandlerWorker(target string, body io.ReadCloser) (*BulkResponse, error) {
 	bulkRes := &BulkResponse{Items: []map[string]*BulkResponseItem{}}
 
-	// Prepare to read the entire raw text of the body
-	scanner := bufio.NewScanner(body)
+	// Prepare to stream the body line by line
+	maxDocumentSize := startup.LoadMaxDocumentSize()
+	reader := ndjson.NewReader(body, maxDocumentSize)
 	defer body.Close()
 
 	// force set batchSize
 	batchSize := startup.LoadBatchSize()
 
-	// Set 1 MB max per line. docs at - https://pkg.go.dev/bufio#pkg-constants
-	// This is the max size of a line in a file that we will process
-	const maxCapacityPerLine = 1024 * 1024
-	buf := make([]byte, maxCapacityPerLine)
-	scanner.Buffer(buf, maxCapacityPerLine)
-
 	nextLineIsData := false
-	lastLineMetaData := make(map[string]interface{})
+	nextLineMaybeData := false // the metadata line was malformed, the next line is skipped if it is not an action
+	var action *bulkAction
 
 	batch := make(map[string]*index.Batch)
 	var indexesInThisBatch []string
 	var documentsInBatch int
+	documentsPending := make(map[string]struct{}) // documents in batch, key is index/id
+	readers := make(bulkReaders)
+	defer readers.close()
+	locks := new(bulkLocks)
+	defer locks.unlock()
+
+	// persist writes the batch to disk, the stored documents are visible to the next actions
+	persist := func() error {
+		if err := persistBatch(batch, indexesInThisBatch); err != nil {
+			return err
+		}
+		readers.close()
+		locks.unlock()
+		documentsInBatch = 0
+		documentsPending = make(map[string]struct{})
+		return nil
+	}
+
+	// prepare returns the stored document, the version of a document depends on the stored one,
+	// so pending changes of it must be persisted first. The index stays locked from the check
+	// until the batch is persisted, no other write can change the document in between.
+	prepare := func(indexName, docID string) (*core.Document, error) {
+		if DoesExistInThisRequest(indexesInThisBatch, indexName) == -1 {
+			indexesInThisBatch = append(indexesInThisBatch, indexName)
+			batch[indexName] = index.NewBatch()
+		}
+		if _, ok := documentsPending[indexName+"/"+docID]; ok {
+			if err := persist(); err != nil {
+				return nil, err
+			}
+		}
+		if !locks.held(indexName) {
+			if !locks.canLock(indexName) {
+				if err := persist(); err != nil {
+					return nil, err
+				}
+			}
+			locks.lock(indexName)
+		}
+		return readers.document(indexName, docID)
+	}
+
 	var doc map[string]interface{}
-	var err error
-	for scanner.Scan() { // Read each line
+	for { // Read each line
+		line, err := reader.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != ndjson.ErrLineTooLarge {
+			return bulkRes, err
+		}
+
 		for k := range doc {
 			delete(doc, k)
 		}
-		if err = json.Unmarshal(scanner.Bytes(), &doc); err != nil {
-			log.Error().Msgf("bulk.json.Unmarshal: err %s", err.Error())
-			continue
+		var parseErr error // the line can't be parsed, it is reported in the item of the action
+		if err == ndjson.ErrLineTooLarge {
+			parseErr = errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf(
+				"document too large, line [%d] is larger than the max document size [%d] bytes", reader.Line(), maxDocumentSize,
+			))
+		} else if err = json.Unmarshal(line, &doc); err != nil {
+			parseErr = errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("failed to parse line [%d]: %s", reader.Line(), err.Error()))
 		}
 
-		// This will process the data line in the request. Each data line is preceded by a metadata line.
-		// Docs at https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-bulk.html
-		if nextLineIsData {
-			bulkRes.Count++
-			nextLineIsData = false
-			mintedID := false
-
-			var docID = ""
-			if val, ok := lastLineMetaData["_id"]; ok && val != nil {
-				docID = val.(string)
-			}
-			if docID == "" {
-				docID = ider.Generate()
-				mintedID = true
+		if nextLineMaybeData {
+			nextLineMaybeData = false
+			if parseErr != nil || !isBulkActionLine(doc) {
+				continue
 			}
+		}
 
-			indexName := lastLineMetaData["_index"].(string)
-			operation := lastLineMetaData["operation"].(string)
-			switch operation {
-			case "index":
-				bulkRes.Items = append(bulkRes.Items, map[string]*BulkResponseItem{
-					"index": NewBulkResponseItem(bulkRes.Count, indexName, docID, "created", nil),
-				})
-			case "create":
-				bulkRes.Items = append(bulkRes.Items, map[string]*BulkResponseItem{
-					"index": NewBulkResponseItem(bulkRes.Count, indexName, docID, "created", nil),
-				})
-			case "update":
-				bulkRes.Items = append(bulkRes.Items, map[string]*BulkResponseItem{
-					"index": NewBulkResponseItem(bulkRes.Count, indexName, docID, "updated", nil),
-				})
-			default:
+		// This branch will process the metadata line in the request. Each metadata line is followed by a data line, except delete.
+		// Docs at https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-bulk.html
+		if !nextLineIsData {
+			bulkRes.Count++
+			if parseErr != nil {
+				bulkRes.addItem("index", NewBulkResponseItem(target, "", "", 0, 0, parseErr))
+				nextLineMaybeData = true
+				continue
 			}
 
-			_, exists := core.GetIndex(indexName)
-			if !exists { // If the requested indexName does not exist then create it
-				newIndex, err := core.NewIndex(indexName, "disk", core.UseNewIndexMeta, nil)
+			action = newBulkAction(doc, target)
+			if action.Operation == "delete" {
+				item, err := deleteAction(action, prepare)
 				if err != nil {
 					return bulkRes, err
 				}
-				// store index
-				if err := core.StoreIndex(newIndex); err != nil {
-					return bulkRes, err
+				if item.Error == nil {
+					core.ZINC_INDEX_LIST[action.Index].DeleteBatch(batch[action.Index], action.ID)
+					documentsPending[action.Index+"/"+action.ID] = struct{}{}
 				}
+				bulkRes.addItem(action.Operation, item)
+				continue
+			}
+
+			if action.Operation == "" {
+				// unknown action, skip its data line if there is one
+				bulkRes.addItem("index", action.item("", 0, 0, action.Err))
+				nextLineMaybeData = true
+				continue
+			}
+			nextLineIsData = true
+			continue
+		}
+
+		// This branch will process the data line of the previous metadata line.
+		nextLineIsData = false
+		itemKey := action.Operation
+		fail := func(err error) {
+			bulkRes.addItem(itemKey, action.item("", 0, 0, err))
+		}
+
+		if action.Err != nil {
+			fail(action.Err)
+			continue
+		}
+		if parseErr != nil {
+			fail(parseErr)
+			continue
+		}
+
+		mintedID := false
+		if action.ID == "" {
+			if action.Operation == "update" {
+				fail(errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: id is missing;"))
+				continue
 			}
+			action.ID = ider.Generate()
+			mintedID = true
+		}
 
-			// Since this is a bulk request, we need to check if we already created a new batch for this index. We need to create 1 batch per index.
-			if DoesExistInThisRequest(indexesInThisBatch, indexName) == -1 { // Add the list of indexes to the batch if it's not already there
-				indexesInThisBatch = append(indexesInThisBatch, indexName)
-				batch[indexName] = index.NewBatch()
+		var update *meta.UpdateRequest
+		if action.Operation == "update" {
+			update = new(meta.UpdateRequest)
+			if err := json.Unmarshal(line, update); err != nil {
+				fail(errors.New(errors.ErrorTypeParsingException, "failed to parse: "+err.Error()))
+				continue
 			}
+		}
 
-			bdoc, err := core.ZINC_INDEX_LIST[indexName].BuildBlugeDocumentFromJSON(docID, doc)
+		indexName := action.Index
+		if _, exists := core.GetIndex(indexName); !exists { // If the requested indexName does not exist then create it
+			// the index is only created if the item writes, there is no stored document to update or to check the version
+			if update != nil {
+				if _, _, err := core.MergeDocument(action.ID, nil, update); err != nil {
+					fail(err)
+					continue
+				}
+			}
+			if _, err := core.CheckVersion(action.ID, nil, action.VersionControl); err != nil {
+				fail(err)
+				continue
+			}
+			newIndex, err := core.NewIndex(indexName, "disk", core.UseNewIndexMeta, nil)
 			if err != nil {
+				fail(errors.New(errors.ErrorTypeInvalidIndexNameException, err.Error()))
+				continue
+			}
+			// store index
+			if err := core.StoreIndex(newIndex); err != nil {
+				return bulkRes, err
+			}
+		}
+
+		var current *core.Document
+		if !mintedID {
+			var err error
+			if current, err = prepare(indexName, action.ID); err != nil {
 				return bulkRes, err
 			}
+		} else if DoesExistInThisRequest(indexesInThisBatch, indexName) == -1 {
+			indexesInThisBatch = append(indexesInThisBatch, indexName)
+			batch[indexName] = index.NewBatch()
+		}
 
-			// Add the documen to the batch. We will persist the batch to the index
-			// when we have processed all documents in the request
-			if !mintedID {
-				batch[indexName].Update(bdoc.ID(), bdoc)
-			} else {
-				batch[indexName].Insert(bdoc)
+		data := doc
+		result := "created"
+		if current != nil {
+			result = "updated"
+		}
+		if update != nil {
+			var err error
+			if data, result, err = core.MergeDocument(action.ID, current, update); err != nil {
+				fail(err)
+				continue
 			}
+			if result == "noop" {
+				bulkRes.addItem(itemKey, action.item(result, current.Version, current.SeqNo, nil))
+				continue
+			}
+		}
 
-			documentsInBatch++
+		if action.Operation == "create" && current != nil {
+			fail(core.DocumentExistsError(action.ID, current))
+			continue
+		}
+		version, err := core.CheckVersion(action.ID, current, action.VersionControl)
+		if err != nil {
+			fail(err)
+			continue
+		}
 
-			// refresh index stats
-			core.ZINC_INDEX_LIST[indexName].GainDocsCount(1)
+		bdoc, nested, err := core.ZINC_INDEX_LIST[indexName].BuildBlugeDocumentFromJSON(action.ID, data)
+		if err != nil {
+			fail(errors.New(errors.ErrorTypeMapperParsingException, err.Error()))
+			continue
+		}
+		seqNo := core.ZINC_INDEX_LIST[indexName].NextSeqNo()
+		core.SetDocumentVersion(bdoc, version, seqNo)
+
+		// Add the documen to the batch. We will persist the batch to the index
+		// when we have processed all documents in the request
+		if !mintedID {
+			core.ZINC_INDEX_LIST[indexName].UpdateBatch(batch[indexName], bdoc, nested)
+		} else {
+			core.ZINC_INDEX_LIST[indexName].InsertBatch(batch[indexName], bdoc, nested)
+		}
 
-			if documentsInBatch >= batchSize {
-				for _, indexName := range indexesInThisBatch {
-					// Persist the batch to the index
-					if err := core.ZINC_INDEX_LIST[indexName].Writer.Batch(batch[indexName]); err != nil {
-						log.Error().Msgf("bulk: index updating batch err %s", err.Error())
-						return bulkRes, err
-					}
-					batch[indexName].Reset()
-				}
-				documentsInBatch = 0
-			}
+		documentsInBatch++
+		documentsPending[indexName+"/"+action.ID] = struct{}{}
+		bulkRes.addItem(itemKey, action.item(result, version, seqNo, nil))
 
-		} else { // This branch will process the metadata line in the request. Each metadata line is preceded by a data line.
-
-			for k, v := range doc {
-				if k == "index" || k == "create" || k == "update" {
-					nextLineIsData = true
-
-					lastLineMetaData["operation"] = k
-
-					if _, ok := v.(map[string]interface{}); !ok {
-						return nil, errors.New("bulk index data format error")
-					}
-
-					if v.(map[string]interface{})["_index"] != "" { // if index is specified in metadata then it overtakes the index in the query path
-						lastLineMetaData["_index"] = v.(map[string]interface{})["_index"]
-					} else {
-						lastLineMetaData["_index"] = target
-					}
-
-					lastLineMetaData["_id"] = v.(map[string]interface{})["_id"]
-				} else if k == "delete" {
-					nextLineIsData = false
-
-					lastLineMetaData["operation"] = k
-					lastLineMetaData["_index"] = v.(map[string]interface{})["_index"]
-					lastLineMetaData["_id"] = v.(map[string]interface{})["_id"]
-
-					// delete
-					indexName := lastLineMetaData["_index"].(string)
-					bdoc := bluge.NewDocument(lastLineMetaData["_id"].(string))
-					if DoesExistInThisRequest(indexesInThisBatch, indexName) == -1 {
-						indexesInThisBatch = append(indexesInThisBatch, indexName)
-						batch[indexName] = index.NewBatch()
-					}
-					batch[indexName].Delete(bdoc.ID())
-					core.ZINC_INDEX_LIST[indexName].ReduceDocsCount(1)
-
-					bulkRes.Count++
-					bulkRes.Items = append(bulkRes.Items, map[string]*BulkResponseItem{
-						"delete": NewBulkResponseItem(bulkRes.Count, indexName, bdoc.ID().Field(), "deleted", nil),
-					})
-				}
+		if documentsInBatch >= batchSize {
+			if err := persist(); err != nil {
+				return bulkRes, err
 			}
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
+	// the last action is waiting for its data line
+	if nextLineIsData {
+		bulkRes.addItem(action.Operation, action.item("", 0, 0, errors.New(
+			errors.ErrorTypeIllegalArgumentException, "The bulk request must be terminated by a newline [\\n]",
+		)))
+	}
+
+	if err := persist(); err != nil {
 		return bulkRes, err
 	}
 
-	for _, indexName := range indexesInThisBatch {
+	return bulkRes, nil
+}
+
+// deleteAction checks the delete action against the stored document, the caller adds the delete
+// to the batch if the item has no error. It only returns an error when the stored document can't be read.
+func deleteAction(action *bulkAction, prepare func(indexName, docID string) (*core.Document, error)) (*BulkResponseItem, error) {
+	if action.Err != nil {
+		return action.item("", 0, 0, action.Err), nil
+	}
+	index, exists := core.GetIndex(action.Index)
+	if !exists {
+		return action.item("", 0, 0, newIndexNotFoundError(action.Index)), nil
+	}
+
+	current, err := prepare(action.Index, action.ID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := core.CheckVersion(action.ID, current, action.VersionControl)
+	if err != nil {
+		return action.item("", 0, 0, err), nil
+	}
+
+	if current == nil {
+		return action.item("not_found", version, index.CurrentSeqNo(), nil), nil
+	}
+	return action.item("deleted", version, index.NextSeqNo(), nil), nil
+}
+
+// bulkAction is the parsed metadata line of a bulk action
+type bulkAction struct {
+	Operation      string // index, create, update, delete, empty if the action is unknown
+	Index          string
+	ID             string
+	VersionControl *meta.VersionControl
+	Err            error // the metadata line is invalid, it is reported in the item of the action
+}
+
+func newBulkAction(doc map[string]interface{}, target string) *bulkAction {
+	action := &bulkAction{Index: target}
+	if len(doc) != 1 {
+		action.Err = errors.New(errors.ErrorTypeIllegalArgumentException, "Malformed action/metadata line, expected a single action")
+		return action
+	}
+
+	for k, v := range doc {
+		switch k {
+		case "index", "create", "update", "delete":
+		default:
+			action.Err = errors.New(errors.ErrorTypeIllegalArgumentException, "Malformed action/metadata line, expected one of [create, delete, index, update] but found ["+k+"]")
+			return action
+		}
+		action.Operation = k
+
+		metaData, ok := v.(map[string]interface{})
+		if !ok {
+			action.Err = errors.New(errors.ErrorTypeParsingException, "Malformed action/metadata line, ["+k+"] should be an object")
+			return action
+		}
+		// if index is specified in metadata then it overtakes the index in the query path
+		if v, ok := metaData["_index"].(string); ok && v != "" {
+			action.Index = v
+		}
+		switch v := metaData["_id"].(type) {
+		case nil:
+		case string:
+			action.ID = v
+		default:
+			action.Err = errors.New(errors.ErrorTypeParsingException, "Malformed action/metadata line, [_id] should be a string")
+			return action
+		}
+		if action.Index == "" {
+			action.Err = errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: index is missing;")
+			return action
+		}
+		if k == "delete" && action.ID == "" {
+			action.Err = errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: id is missing;")
+			return action
+		}
+		action.VersionControl, action.Err = versionControl(map[string]interface{}{
+			"if_seq_no":       metaData["if_seq_no"],
+			"if_primary_term": metaData["if_primary_term"],
+			"version":         metaData["version"],
+			"version_type":    metaData["version_type"],
+		})
+	}
+
+	// writes to an alias go to its write index
+	if action.Err == nil {
+		if indexName, err := core.ResolveWriteIndex(action.Index); err != nil {
+			action.Err = err
+		} else {
+			action.Index = indexName
+		}
+	}
+
+	return action
+}
+
+func (action *bulkAction) item(result string, version, seqNo int64, err error) *BulkResponseItem {
+	return NewBulkResponseItem(action.Index, action.ID, result, version, seqNo, err)
+}
+
+// isBulkActionLine checks if the line looks like a metadata line
+func isBulkActionLine(doc map[string]interface{}) bool {
+	if len(doc) != 1 {
+		return false
+	}
+	for k, v := range doc {
+		switch k {
+		case "index", "create", "update", "delete":
+			_, ok := v.(map[string]interface{})
+			return ok
+		}
+	}
+	return false
+}
+
+// persistBatch writes the batch of every index to disk and resets it
+func persistBatch(batch map[string]*index.Batch, indexes []string) error {
+	for _, indexName := range indexes {
 		// Persist the batch to the index
 		if err := core.ZINC_INDEX_LIST[indexName].Writer.Batch(batch[indexName]); err != nil {
-			log.Printf("bulk: index updating batch err %s", err.Error())
-			return bulkRes, err
+			log.Error().Msgf("bulk: index updating batch err %s", err.Error())
+			return err
 		}
+		batch[indexName].Reset()
+		// refresh index stats
+		core.ZINC_INDEX_LIST[indexName].ReloadDocsCount()
 	}
-
-	return bulkRes, nil
+	return nil
 }
 
 // DoesExistInThisRequest takes a slice and looks for an element in it. If found it will
@@ -242,22 +464,89 @@ func DoesExistInThisRequest(slice []string, val string) int {
 	return -1
 }
 
-func NewBulkResponseItem(seqNo int, index, id, result string, err error) *BulkResponseItem {
-	return &BulkResponseItem{
+func NewBulkResponseItem(index, id, result string, version, seqNo int64, err error) *BulkResponseItem {
+	if _, ok := err.(*errors.Error); err != nil && !ok {
+		err = errors.New(errors.ErrorTypeRuntimeException, err.Error())
+	}
+	item := &BulkResponseItem{
 		Index:   index,
 		Type:    "_doc",
 		ID:      id,
-		Version: 1,
+		Version: version,
 		Result:  result,
 		Shards: BulkResponseItemShard{
 			Total:      1,
 			Successful: 1,
 			Failed:     0,
 		},
-		Status: 200,
-		SeqNo:  seqNo,
-		Error:  err,
+		Status:      http.StatusOK,
+		SeqNo:       seqNo,
+		PrimaryTerm: core.PrimaryTerm,
+		Error:       err,
 	}
+	if result == "not_found" {
+		item.Status = http.StatusNotFound
+	}
+	if err != nil {
+		item.Status = writeErrorStatus(err)
+		item.Shards.Successful = 0
+		item.Shards.Failed = 1
+	}
+	return item
+}
+
+// bulkReaders caches a reader snapshot per index to look up the stored documents,
+// it must be closed every time a batch is persisted
+type bulkReaders map[string]*bluge.Reader
+
+func (r bulkReaders) document(indexName, docID string) (*core.Document, error) {
+	reader, ok := r[indexName]
+	if !ok {
+		var err error
+		if reader, err = core.ZINC_INDEX_LIST[indexName].Writer.Reader(); err != nil {
+			return nil, err
+		}
+		r[indexName] = reader
+	}
+	return core.GetDocumentFromReader(reader, docID)
+}
+
+func (r bulkReaders) close() {
+	for indexName, reader := range r {
+		reader.Close()
+		delete(r, indexName)
+	}
+}
+
+// bulkLocks holds the write locks of the indexes checked in the batch. They are taken in the order
+// of the index names, concurrent bulk requests can't deadlock on the indexes of each other.
+type bulkLocks []*core.Index
+
+func (l *bulkLocks) held(indexName string) bool {
+	for _, index := range *l {
+		if index.Name == indexName {
+			return true
+		}
+	}
+	return false
+}
+
+// canLock checks the index comes after the locked ones, otherwise the batch must be persisted first
+func (l *bulkLocks) canLock(indexName string) bool {
+	return len(*l) == 0 || (*l)[len(*l)-1].Name < indexName
+}
+
+func (l *bulkLocks) lock(indexName string) {
+	index := core.ZINC_INDEX_LIST[indexName]
+	index.LockWrites()
+	*l = append(*l, index)
+}
+
+func (l *bulkLocks) unlock() {
+	for _, index := range *l {
+		index.UnlockWrites()
+	}
+	*l = (*l)[:0]
 }
 
 type BulkResponse struct {
@@ -268,16 +557,25 @@ type BulkResponse struct {
 	Items  []map[string]*BulkResponseItem `json:"items"`
 }
 
+// addItem adds the result of an action, the response has errors if any item failed
+func (r *BulkResponse) addItem(action string, item *BulkResponseItem) {
+	if item.Error != nil {
+		r.Errors = true
+	}
+	r.Items = append(r.Items, map[string]*BulkResponseItem{action: item})
+}
+
 type BulkResponseItem struct {
-	Index   string                `json:"_index"`
-	Type    string                `json:"_type"`
-	ID      string                `json:"_id"`
-	Version int64                 `json:"_version"`
-	Result  string                `json:"result"`
-	Shards  BulkResponseItemShard `json:"_shards"`
-	Status  int                   `json:"status"`
-	SeqNo   int                   `json:"seq_no"`
-	Error   error                 `json:"error,omitempty"`
+	Index       string                `json:"_index"`
+	Type        string                `json:"_type"`
+	ID          string                `json:"_id"`
+	Version     int64                 `json:"_version"`
+	Result      string                `json:"result"`
+	Shards      BulkResponseItemShard `json:"_shards"`
+	Status      int                   `json:"status"`
+	SeqNo       int64                 `json:"_seq_no"`
+	PrimaryTerm int64                 `json:"_primary_term"`
+	Error       error                 `json:"error,omitempty"`
 }
 
 type BulkResponseItemShard struct {
@@ -18,14 +18,18 @@ package handlers
 import (
 	"net/http"
 
-	"github.com/blugelabs/bluge"
 	"github.com/gin-gonic/gin"
+
 	"github.com/zinclabs/zinc/pkg/core"
 )
 
 func DeleteDocument(c *gin.Context) {
-	indexName := c.Param("target")
 	queryID := c.Param("id")
+	indexName, err := core.ResolveWriteIndex(c.Param("target"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
 
 	index, exists := core.GetIndex(indexName)
 	if !exists {
@@ -33,13 +37,25 @@ func DeleteDocument(c *gin.Context) {
 		return
 	}
 
-	bdoc := bluge.NewDocument(queryID)
-	bdoc.AddField(bluge.NewCompositeFieldExcluding("_all", nil))
-	err := index.Writer.Delete(bdoc.ID())
+	vc, err := versionControlFromQuery(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	ret, err := index.DeleteDocument(queryID, vc)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err)
-	} else {
-		core.ZINC_INDEX_LIST[indexName].ReduceDocsCount(1)
-		c.JSON(http.StatusOK, gin.H{"message": "Deleted", "index": indexName, "id": queryID})
+		writeError(c, err)
+		return
 	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Deleted",
+		"index":         indexName,
+		"id":            queryID,
+		"_version":      ret.Version,
+		"_seq_no":       ret.SeqNo,
+		"_primary_term": core.PrimaryTerm,
+		"result":        ret.Result,
+	})
 }
@@ -0,0 +1,106 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// ESUpdateDocument partially updates a document, compatible with ES update API
+func ESUpdateDocument(c *gin.Context) {
+	queryID := c.Param("id")
+	indexName, err := core.ResolveWriteIndex(c.Param("target"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	update := new(meta.UpdateRequest)
+	if err := c.BindJSON(update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	vc, err := versionControlFromQuery(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	index, exists := core.GetIndex(indexName)
+	if !exists {
+		if update.Doc == nil || (update.Upsert == nil && !update.DocAsUpsert) {
+			writeError(c, errors.New(errors.ErrorTypeDocumentMissingException, "["+queryID+"]: document missing"))
+			return
+		}
+		index, err = core.NewIndex(indexName, "disk", core.UseNewIndexMeta, nil) // Create a new index with disk storage as default
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// store index
+		core.StoreIndex(index)
+	}
+
+	ret, err := index.UpdateDocumentPartial(queryID, update, vc)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &meta.UpdateResponse{
+		Index:       indexName,
+		Type:        "_doc",
+		ID:          queryID,
+		Version:     ret.Version,
+		SeqNo:       ret.SeqNo,
+		PrimaryTerm: core.PrimaryTerm,
+		Result:      ret.Result,
+		Shards:      meta.Shards{Total: 1, Successful: 1},
+	})
+}
+
+// writeError responds the error of a document write with the status ES uses for it
+func writeError(c *gin.Context, err error) {
+	status := writeErrorStatus(err)
+	if v, ok := err.(*errors.Error); ok {
+		c.JSON(status, gin.H{"error": v, "status": status})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error(), "status": status})
+}
+
+func writeErrorStatus(err error) int {
+	if v, ok := err.(*errors.Error); ok {
+		switch v.Type {
+		case errors.ErrorTypeDocumentMissingException, errors.ErrorTypeIndexNotFoundException:
+			return http.StatusNotFound
+		case errors.ErrorTypeVersionConflictEngineException:
+			return http.StatusConflict
+		case errors.ErrorTypeActionRequestValidation, errors.ErrorTypeIllegalArgumentException, errors.ErrorTypeNotImplemented,
+			errors.ErrorTypeParsingException, errors.ErrorTypeXContentParseException, errors.ErrorTypeMapperParsingException,
+			errors.ErrorTypeInvalidIndexNameException:
+			return http.StatusBadRequest
+		}
+	}
+	return http.StatusInternalServerError
+}
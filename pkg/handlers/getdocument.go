@@ -0,0 +1,262 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/source"
+)
+
+// GetDocument returns a single document by id, HEAD requests only check the existence
+func GetDocument(c *gin.Context) {
+	queryID := c.Param("id")
+	indexName, err := core.ResolveIndex(c.Param("target"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	index, exists := core.GetIndex(indexName)
+	if !exists {
+		indexNotFound(c, indexName)
+		return
+	}
+
+	sourceFilter, err := sourceFromQuery(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	doc, err := index.GetDocument(queryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := newGetResponse(indexName, queryID, doc, sourceFilter)
+	if !resp.Found {
+		c.JSON(http.StatusNotFound, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetDocumentSource returns only the _source of a document by id
+func GetDocumentSource(c *gin.Context) {
+	queryID := c.Param("id")
+	indexName, err := core.ResolveIndex(c.Param("target"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	index, exists := core.GetIndex(indexName)
+	if !exists {
+		indexNotFound(c, indexName)
+		return
+	}
+
+	sourceFilter, err := sourceFromQuery(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	doc, err := index.GetDocument(queryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if doc == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":  errors.New(errors.ErrorTypeResourceNotFound, "Document not found ["+indexName+"]/_doc/["+queryID+"]"),
+			"status": http.StatusNotFound,
+		})
+		return
+	}
+
+	data := source.Response(sourceFilter, doc.Source)
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// MultiGetDocuments returns multiple documents by index and id in one request
+func MultiGetDocuments(c *gin.Context) {
+	defaultIndexName := c.Param("target")
+
+	var query meta.MGetRequest
+	if err := c.BindJSON(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	defaultSource, err := sourceFromQuery(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if len(query.Ids) > 0 {
+		if defaultIndexName == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errors.New(errors.ErrorTypeIllegalArgumentException, "[mget] index is missing for ids")})
+			return
+		}
+		for _, id := range query.Ids {
+			query.Docs = append(query.Docs, meta.MGetDoc{Index: defaultIndexName, ID: id})
+		}
+	}
+	if len(query.Docs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.New(errors.ErrorTypeIllegalArgumentException, "[mget] no documents to get")})
+		return
+	}
+
+	resp := &meta.MGetResponse{Docs: make([]*meta.GetResponse, 0, len(query.Docs))}
+	for _, item := range query.Docs {
+		indexName := item.Index
+		if indexName == "" {
+			indexName = defaultIndexName
+		}
+
+		sourceFilter := defaultSource
+		if item.Source != nil {
+			if sourceFilter, err = source.Request(item.Source); err != nil {
+				writeError(c, err)
+				return
+			}
+		}
+
+		indexName, err := core.ResolveIndex(indexName)
+		if err != nil {
+			resp.Docs = append(resp.Docs, &meta.GetResponse{
+				Index: indexName,
+				Type:  "_doc",
+				ID:    item.ID,
+				Error: err,
+			})
+			continue
+		}
+		index, exists := core.GetIndex(indexName)
+		if !exists {
+			resp.Docs = append(resp.Docs, &meta.GetResponse{
+				Index: indexName,
+				Type:  "_doc",
+				ID:    item.ID,
+				Error: newIndexNotFoundError(indexName),
+			})
+			continue
+		}
+
+		doc, err := index.GetDocument(item.ID)
+		if err != nil {
+			resp.Docs = append(resp.Docs, &meta.GetResponse{
+				Index: indexName,
+				Type:  "_doc",
+				ID:    item.ID,
+				Error: errors.New(errors.ErrorTypeRuntimeException, err.Error()),
+			})
+			continue
+		}
+
+		resp.Docs = append(resp.Docs, newGetResponse(indexName, item.ID, doc, sourceFilter))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func newGetResponse(indexName, docID string, doc *core.Document, sourceFilter *meta.Source) *meta.GetResponse {
+	resp := &meta.GetResponse{
+		Index: indexName,
+		Type:  "_doc",
+		ID:    docID,
+	}
+	if doc == nil {
+		return resp
+	}
+
+	resp.Found = true
+	resp.Version = doc.Version
+	resp.SeqNo = &doc.SeqNo
+	resp.PrimaryTerm = core.PrimaryTerm
+	resp.Source = source.Response(sourceFilter, doc.Source)
+	if !doc.Timestamp.IsZero() {
+		resp.Timestamp = &doc.Timestamp
+	}
+
+	return resp
+}
+
+// sourceFromQuery parse _source filtering from url params, like: ?_source=false or ?_source_includes=field1,field2
+// or ?_source_excludes=field3
+func sourceFromQuery(c *gin.Context) (*meta.Source, error) {
+	var v interface{}
+	if s, ok := c.GetQuery("_source"); ok {
+		switch strings.ToLower(s) {
+		case "true", "":
+			v = true
+		case "false":
+			v = false
+		default:
+			v = splitFields(s)
+		}
+	}
+	if s := c.Query("_source_includes"); s != "" {
+		v = splitFields(s)
+	}
+
+	sourceFilter, err := source.Request(v)
+	if err != nil {
+		return nil, err
+	}
+	if s := c.Query("_source_excludes"); s != "" {
+		for _, field := range splitFields(s) {
+			sourceFilter.Excludes = append(sourceFilter.Excludes, field.(string))
+		}
+	}
+	return sourceFilter, nil
+}
+
+func splitFields(s string) []interface{} {
+	fields := make([]interface{}, 0)
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func newIndexNotFoundError(indexName string) *errors.Error {
+	return errors.New(errors.ErrorTypeIndexNotFoundException, "no such index ["+indexName+"]")
+}
+
+func indexNotFound(c *gin.Context, indexName string) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error":  newIndexNotFoundError(indexName),
+		"status": http.StatusNotFound,
+	})
+}
@@ -18,6 +18,7 @@ package handlers
 import (
 	"net/http"
 	"sort"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 	"github.com/zinclabs/zinc/pkg/core"
@@ -31,7 +32,7 @@ func ListIndexes(c *gin.Context) {
 		item.Name = name
 		item.StorageType = value.StorageType
 		item.StorageSize = int64(value.StorageSize)
-		item.DocsCount = value.DocsCount
+		item.DocsCount = atomic.LoadInt64(&value.DocsCount)
 		if value.Settings != nil {
 			item.Settings = value.Settings
 		} else {
@@ -21,11 +21,21 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
 	"github.com/zinclabs/zinc/pkg/ider"
 )
 
 func UpdateDocument(c *gin.Context) {
-	indexName := c.Param("target")
+	indexDocument(c, c.DefaultQuery("op_type", "index"))
+}
+
+// CreateDocument indexes a document only if it doesn't exist yet
+func CreateDocument(c *gin.Context) {
+	indexDocument(c, "create")
+}
+
+// indexDocument writes the document of the request, opType is index or create
+func indexDocument(c *gin.Context, opType string) {
 	queryID := c.Param("id") // ID for the document to be updated provided in URL path
 
 	var err error
@@ -35,6 +45,26 @@ func UpdateDocument(c *gin.Context) {
 		return
 	}
 
+	indexName, err := core.ResolveWriteIndex(c.Param("target"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	vc, err := versionControlFromQuery(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	if opType != "index" && opType != "create" {
+		writeError(c, errors.New(errors.ErrorTypeIllegalArgumentException, "opType must be 'create' or 'index', found: ["+opType+"]"))
+		return
+	}
+	if opType == "create" && vc != nil {
+		writeError(c, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: create operations do not support explicit versions or concurrency control;"))
+		return
+	}
+
 	docID := ""
 	mintedID := false
 
@@ -61,11 +91,24 @@ func UpdateDocument(c *gin.Context) {
 		core.StoreIndex(index)
 	}
 
-	err = index.UpdateDocument(docID, doc, mintedID)
+	var ret *core.WriteResult
+	if opType == "create" && !mintedID {
+		ret, err = index.CreateDocument(docID, doc)
+	} else {
+		ret, err = index.IndexDocument(docID, doc, mintedID, vc)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"id": docID})
+	c.JSON(http.StatusOK, gin.H{
+		"id":            docID,
+		"_index":        indexName,
+		"_id":           docID,
+		"_version":      ret.Version,
+		"_seq_no":       ret.SeqNo,
+		"_primary_term": core.PrimaryTerm,
+		"result":        ret.Result,
+	})
 }
@@ -0,0 +1,129 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// UpdateAliases adds and removes aliases in one request, compatible with ES _aliases
+func UpdateAliases(c *gin.Context) {
+	var req meta.AliasActions
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, errors.New(errors.ErrorTypeParsingException, "[aliases] failed to parse request body").Cause(err))
+		return
+	}
+	if len(req.Actions) == 0 {
+		handleError(c, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: Must specify at least one alias action;"))
+		return
+	}
+
+	if err := core.UpdateAliases(req.Actions); err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"acknowledged": true})
+}
+
+// PutAlias adds an alias to the indexes, the body can set the filter and is_write_index of the alias
+func PutAlias(c *gin.Context) {
+	action := new(meta.AliasAction)
+	if c.Request.Body != nil && c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(action); err != nil && err != io.EOF {
+			handleError(c, errors.New(errors.ErrorTypeParsingException, "[alias] failed to parse request body").Cause(err))
+			return
+		}
+	}
+	action.Index, action.Indices = "", strings.Split(c.Param("target"), ",")
+	action.Alias, action.Aliases = "", strings.Split(c.Param("name"), ",")
+
+	if err := core.UpdateAliases([]map[string]*meta.AliasAction{{"add": action}}); err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"acknowledged": true})
+}
+
+// DeleteAlias removes the aliases from the indexes, the alias names can be patterns with * wildcards
+func DeleteAlias(c *gin.Context) {
+	action := &meta.AliasAction{
+		Indices: strings.Split(c.Param("target"), ","),
+		Aliases: strings.Split(c.Param("name"), ","),
+	}
+
+	if err := core.UpdateAliases([]map[string]*meta.AliasAction{{"remove": action}}); err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"acknowledged": true})
+}
+
+// GetAlias returns the aliases of the indexes by index, compatible with ES _alias.
+// Indexes without aliases are listed only if no alias name is requested.
+func GetAlias(c *gin.Context) {
+	indexes, err := core.MatchIndexes(strings.Split(c.Param("target"), ","))
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+	var names []string
+	if name := c.Param("name"); name != "" {
+		names = strings.Split(name, ",")
+	}
+
+	list := core.ListAliases(indexes, names)
+	if len(names) > 0 && len(list) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "alias [" + strings.Join(names, ",") + "] missing", "status": http.StatusNotFound})
+		return
+	}
+
+	resp := make(map[string]interface{})
+	for _, index := range indexes {
+		aliases, ok := list[index.Name]
+		if !ok && len(names) > 0 {
+			continue
+		}
+		data := make(map[string]interface{})
+		for _, alias := range aliases {
+			data[alias.Alias] = aliasResponse(alias)
+		}
+		resp[index.Name] = gin.H{"aliases": data}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func aliasResponse(alias *meta.Alias) map[string]interface{} {
+	data := make(map[string]interface{})
+	if alias.Filter != nil {
+		data["filter"] = alias.Filter
+	}
+	if alias.IsWriteIndex != nil {
+		data["is_write_index"] = *alias.IsWriteIndex
+	}
+	return data
+}
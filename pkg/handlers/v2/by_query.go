@@ -0,0 +1,88 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// DeleteByQuery deletes all the documents matching the query
+func DeleteByQuery(c *gin.Context) {
+	byQuery(c, (*core.Index).DeleteByQuery)
+}
+
+// UpdateByQuery reindexes all the documents matching the query
+func UpdateByQuery(c *gin.Context) {
+	byQuery(c, (*core.Index).UpdateByQuery)
+}
+
+func byQuery(c *gin.Context, fn func(*core.Index, *meta.ByQueryRequest) (*meta.ByQueryResponse, error)) {
+	req := new(meta.ByQueryRequest)
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if conflicts := c.Query("conflicts"); conflicts != "" {
+		req.Conflicts = conflicts
+	}
+	switch req.Conflicts {
+	case "", "abort", "proceed":
+	default:
+		handleError(c, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: conflicts may only be \"proceed\" or \"abort\";"))
+		return
+	}
+
+	indexes, filters, err := core.MatchIndexFilters([]string{c.Param("target")})
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	resp := &meta.ByQueryResponse{Failures: make([]*meta.ByQueryFailure, 0)}
+	for i, index := range indexes {
+		if req.MaxDocs > 0 && resp.Total >= int64(req.MaxDocs) {
+			break
+		}
+		indexReq := *req
+		indexReq.Query = core.FilteredQuery(req.Query, filters[i])
+		if req.MaxDocs > 0 {
+			indexReq.MaxDocs = req.MaxDocs - int(resp.Total)
+		}
+		ret, err := fn(index, &indexReq)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		resp.Took += ret.Took
+		resp.Total += ret.Total
+		resp.Deleted += ret.Deleted
+		resp.Updated += ret.Updated
+		resp.Batches += ret.Batches
+		resp.VersionConflicts += ret.VersionConflicts
+		resp.Failures = append(resp.Failures, ret.Failures...)
+		if ret.VersionConflicts > 0 && req.Conflicts != "proceed" {
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,428 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+)
+
+// CatIndices lists the indexes, compatible with ES _cat/indices
+func CatIndices(c *gin.Context) {
+	indexes, err := core.MatchIndexes(catTarget(c))
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	table := newCatTable(
+		&catColumn{Name: "health", Aliases: []string{"h"}, Desc: "current health status", Default: true},
+		&catColumn{Name: "status", Aliases: []string{"s"}, Desc: "open/close status", Default: true},
+		&catColumn{Name: "index", Aliases: []string{"i", "idx"}, Desc: "index name", Default: true},
+		&catColumn{Name: "pri", Aliases: []string{"p", "shards.primary"}, Desc: "number of primary shards", Default: true},
+		&catColumn{Name: "rep", Aliases: []string{"r", "shards.replica"}, Desc: "number of replica shards", Default: true},
+		&catColumn{Name: "docs.count", Aliases: []string{"dc", "docsCount"}, Desc: "available docs", Default: true},
+		&catColumn{Name: "store.size", Aliases: []string{"ss", "storeSize"}, Desc: "store size of primaries & replicas", Default: true, Bytes: true},
+		&catColumn{Name: "pri.store.size", Desc: "store size of primaries", Default: true, Bytes: true},
+		&catColumn{Name: "storage_type", Aliases: []string{"st"}, Desc: "storage type of the index"},
+	)
+	for _, index := range indexes {
+		size := int64(index.StorageSize * 1024) // StorageSize is in KB
+		table.addRow("green", "open", index.Name, int64(1), int64(0), atomic.LoadInt64(&index.DocsCount), size, size, index.StorageType)
+	}
+
+	table.render(c)
+}
+
+// CatCount counts the documents of the indexes, compatible with ES _cat/count
+func CatCount(c *gin.Context) {
+	resp, err := core.Count(catTarget(c), nil)
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	table := newCatTable(
+		&catColumn{Name: "epoch", Aliases: []string{"t", "time"}, Desc: "seconds since 1970-01-01 00:00:00", Default: true},
+		&catColumn{Name: "timestamp", Aliases: []string{"ts", "hms", "hhmmss"}, Desc: "time in HH:MM:SS", Default: true},
+		&catColumn{Name: "count", Aliases: []string{"dc", "docs.count", "docsCount"}, Desc: "the document count", Default: true},
+	)
+	now := time.Now()
+	table.addRow(now.Unix(), now.Format("15:04:05"), resp.Count)
+
+	table.render(c)
+}
+
+// CatTemplates lists the index templates, compatible with ES _cat/templates
+func CatTemplates(c *gin.Context) {
+	templates, err := core.ListTemplates("")
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	table := newCatTable(
+		&catColumn{Name: "name", Aliases: []string{"n"}, Desc: "template name", Default: true},
+		&catColumn{Name: "index_patterns", Aliases: []string{"t"}, Desc: "template index patterns", Default: true},
+		&catColumn{Name: "order", Aliases: []string{"o", "p"}, Desc: "template application order/priority number", Default: true},
+		&catColumn{Name: "version", Aliases: []string{"v"}, Desc: "version", Default: true},
+		&catColumn{Name: "composed_of", Aliases: []string{"c"}, Desc: "component templates comprising index template", Default: true},
+	)
+	name := c.Param("name")
+	for _, tpl := range templates {
+		if name != "" {
+			if ok, _ := path.Match(name, tpl.Name); !ok {
+				continue
+			}
+		}
+		var patterns string
+		var priority int64
+		if tpl.IndexTemplate != nil {
+			patterns = strings.Join(tpl.IndexTemplate.IndexPatterns, ", ")
+			priority = int64(tpl.IndexTemplate.Priority)
+		}
+		table.addRow(tpl.Name, "["+patterns+"]", priority, "", "[]")
+	}
+
+	table.render(c)
+}
+
+// CatAliases lists the aliases, compatible with ES _cat/aliases
+func CatAliases(c *gin.Context) {
+	indexes, err := core.MatchIndexes(nil)
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+	var names []string
+	if name := c.Param("name"); name != "" {
+		names = strings.Split(name, ",")
+	}
+
+	table := newCatTable(
+		&catColumn{Name: "alias", Aliases: []string{"a"}, Desc: "alias name", Default: true},
+		&catColumn{Name: "index", Aliases: []string{"i", "idx"}, Desc: "index alias points to", Default: true},
+		&catColumn{Name: "filter", Aliases: []string{"f", "fi"}, Desc: "filter", Default: true},
+		&catColumn{Name: "routing.index", Aliases: []string{"ri", "routingIndex"}, Desc: "index routing", Default: true},
+		&catColumn{Name: "routing.search", Aliases: []string{"rs", "routingSearch"}, Desc: "search routing", Default: true},
+		&catColumn{Name: "is_write_index", Aliases: []string{"w", "isWriteIndex"}, Desc: "write index", Default: true},
+	)
+	for _, aliases := range core.ListAliases(indexes, names) {
+		for _, alias := range aliases {
+			filter, isWriteIndex := "-", "-"
+			if alias.Filter != nil {
+				filter = "*"
+			}
+			if alias.IsWriteIndex != nil {
+				isWriteIndex = strconv.FormatBool(*alias.IsWriteIndex)
+			}
+			table.addRow(alias.Alias, alias.Index, filter, "-", "-", isWriteIndex)
+		}
+	}
+
+	table.render(c)
+}
+
+// CatHealth shows the health of the cluster, compatible with ES _cat/health.
+// Zinc runs as a single node with one primary shard per index and no replicas, so it is always green.
+func CatHealth(c *gin.Context) {
+	table := newCatTable(
+		&catColumn{Name: "epoch", Aliases: []string{"t", "time"}, Desc: "seconds since 1970-01-01 00:00:00", Default: true},
+		&catColumn{Name: "timestamp", Aliases: []string{"ts", "hms", "hhmmss"}, Desc: "time in HH:MM:SS", Default: true},
+		&catColumn{Name: "cluster", Aliases: []string{"cl"}, Desc: "cluster name", Default: true},
+		&catColumn{Name: "status", Aliases: []string{"st"}, Desc: "health status", Default: true},
+		&catColumn{Name: "node.total", Aliases: []string{"nt", "nodeTotal"}, Desc: "total number of nodes", Default: true},
+		&catColumn{Name: "node.data", Aliases: []string{"nd", "nodeData"}, Desc: "number of nodes that can store data", Default: true},
+		&catColumn{Name: "shards", Aliases: []string{"t", "sh", "shards.total", "shardsTotal"}, Desc: "total number of shards", Default: true},
+		&catColumn{Name: "pri", Aliases: []string{"p", "shards.primary", "shardsPrimary"}, Desc: "number of primary shards", Default: true},
+		&catColumn{Name: "relo", Aliases: []string{"r", "shards.relocating", "shardsRelocating"}, Desc: "number of relocating nodes", Default: true},
+		&catColumn{Name: "init", Aliases: []string{"i", "shards.initializing", "shardsInitializing"}, Desc: "number of initializing nodes", Default: true},
+		&catColumn{Name: "unassign", Aliases: []string{"u", "shards.unassigned", "shardsUnassigned"}, Desc: "number of unassigned shards", Default: true},
+		&catColumn{Name: "pending_tasks", Aliases: []string{"pt", "pendingTasks"}, Desc: "number of pending tasks", Default: true},
+		&catColumn{Name: "max_task_wait_time", Aliases: []string{"mtwt", "maxTaskWaitTime"}, Desc: "wait time of longest task pending", Default: true},
+		&catColumn{Name: "active_shards_percent", Aliases: []string{"asp", "activeShardsPercent"}, Desc: "active number of shards in percent", Default: true},
+	)
+	now := time.Now()
+	shards := int64(len(core.ZINC_INDEX_LIST))
+	table.addRow(now.Unix(), now.Format("15:04:05"), core.TaskNode, "green", int64(1), int64(1), shards, shards, int64(0), int64(0), int64(0), int64(0), "-", "100.0%")
+
+	table.render(c)
+}
+
+// Cat lists the _cat APIs
+func Cat(c *gin.Context) {
+	c.String(http.StatusOK, "=^.^=\n/_cat/indices\n/_cat/indices/{index}\n/_cat/count\n/_cat/count/{index}\n/_cat/templates\n/_cat/templates/{name}\n/_cat/health\n/_cat/aliases\n/_cat/aliases/{alias}\n")
+}
+
+func catTarget(c *gin.Context) []string {
+	return strings.Split(c.Param("target"), ",")
+}
+
+// catColumn is a column of a _cat table
+type catColumn struct {
+	Name    string
+	Aliases []string
+	Desc    string
+	Default bool // displayed if the h parameter is not set
+	Bytes   bool // the value is a size in bytes, formatted by the bytes parameter
+}
+
+// catTable renders the rows of a _cat API as aligned text or json,
+// the values of a row are in the order of the columns, a value is a string or an int64
+type catTable struct {
+	columns []*catColumn
+	rows    [][]interface{}
+}
+
+func newCatTable(columns ...*catColumn) *catTable {
+	return &catTable{columns: columns}
+}
+
+func (t *catTable) addRow(values ...interface{}) {
+	t.rows = append(t.rows, values)
+}
+
+// column returns the position of the column by name or alias, -1 if not found
+func (t *catTable) column(name string) int {
+	for i, col := range t.columns {
+		if col.Name == name {
+			return i
+		}
+		for _, alias := range col.Aliases {
+			if alias == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// render responds the table with the parameters of the _cat APIs: v, h, s, bytes, format and help
+func (t *catTable) render(c *gin.Context) {
+	if _, ok := c.GetQuery("help"); ok {
+		var b strings.Builder
+		for _, col := range t.columns {
+			fmt.Fprintf(&b, "%s | %s | %s\n", col.Name, strings.Join(col.Aliases, ","), col.Desc)
+		}
+		c.String(http.StatusOK, b.String())
+		return
+	}
+
+	// select columns
+	var columns []int
+	if h := c.Query("h"); h != "" {
+		for _, name := range strings.Split(h, ",") {
+			name = strings.TrimSpace(name)
+			if strings.Contains(name, "*") {
+				for i, col := range t.columns {
+					if ok, _ := path.Match(name, col.Name); ok {
+						columns = append(columns, i)
+					}
+				}
+				continue
+			}
+			i := t.column(name)
+			if i < 0 {
+				handleError(c, errors.New(errors.ErrorTypeIllegalArgumentException, "header name ["+name+"] does not exist"))
+				return
+			}
+			columns = append(columns, i)
+		}
+	} else {
+		for i, col := range t.columns {
+			if col.Default {
+				columns = append(columns, i)
+			}
+		}
+	}
+
+	// sort rows
+	if s := c.Query("s"); s != "" {
+		type sortKey struct {
+			column int
+			desc   bool
+		}
+		var keys []sortKey
+		for _, field := range strings.Split(s, ",") {
+			name, order := strings.TrimSpace(field), "asc"
+			if i := strings.LastIndex(name, ":"); i >= 0 {
+				name, order = name[:i], name[i+1:]
+			}
+			if order != "asc" && order != "desc" {
+				handleError(c, errors.New(errors.ErrorTypeIllegalArgumentException, "unsupported sort order ["+order+"] of column ["+name+"]"))
+				return
+			}
+			i := t.column(name)
+			if i < 0 {
+				handleError(c, errors.New(errors.ErrorTypeIllegalArgumentException, "Unable to sort by unknown sort key `"+name+"`"))
+				return
+			}
+			keys = append(keys, sortKey{column: i, desc: order == "desc"})
+		}
+		sort.SliceStable(t.rows, func(i, j int) bool {
+			for _, key := range keys {
+				cmp := compareCatValues(t.rows[i][key.column], t.rows[j][key.column])
+				if cmp != 0 {
+					return (cmp < 0) != key.desc
+				}
+			}
+			return false
+		})
+	}
+
+	unit := c.Query("bytes")
+	if _, ok := catByteUnits[unit]; unit != "" && !ok {
+		handleError(c, errors.New(errors.ErrorTypeIllegalArgumentException, "failed to parse setting [bytes] with value ["+unit+"]"))
+		return
+	}
+	cells := make([][]string, len(t.rows))
+	for i, row := range t.rows {
+		cells[i] = make([]string, len(columns))
+		for j, col := range columns {
+			cells[i][j] = t.format(col, row[col], unit)
+		}
+	}
+
+	switch format := c.DefaultQuery("format", "text"); format {
+	case "json":
+		items := make([]map[string]string, len(cells))
+		for i, row := range cells {
+			items[i] = make(map[string]string, len(columns))
+			for j, col := range columns {
+				items[i][t.columns[col].Name] = row[j]
+			}
+		}
+		c.JSON(http.StatusOK, items)
+	case "text", "txt":
+		v, ok := c.GetQuery("v")
+		verbose := ok && v != "false"
+		c.String(http.StatusOK, t.text(columns, cells, verbose))
+	default:
+		handleError(c, errors.New(errors.ErrorTypeIllegalArgumentException, "unsupported format ["+format+"]"))
+	}
+}
+
+// text aligns the cells in columns, numbers are aligned to the right
+func (t *catTable) text(columns []int, cells [][]string, verbose bool) string {
+	widths := make([]int, len(columns))
+	numeric := make([]bool, len(columns))
+	if len(t.rows) > 0 {
+		for j, col := range columns {
+			_, numeric[j] = t.rows[0][col].(int64)
+		}
+	}
+	if verbose {
+		for j, col := range columns {
+			widths[j] = len(t.columns[col].Name)
+		}
+	}
+	for _, row := range cells {
+		for j, cell := range row {
+			if len(cell) > widths[j] {
+				widths[j] = len(cell)
+			}
+		}
+	}
+
+	var b strings.Builder
+	writeLine := func(row []string, header bool) {
+		var line strings.Builder
+		for j, cell := range row {
+			if j > 0 {
+				line.WriteByte(' ')
+			}
+			padding := strings.Repeat(" ", widths[j]-len(cell))
+			if numeric[j] && !header {
+				line.WriteString(padding + cell)
+			} else {
+				line.WriteString(cell + padding)
+			}
+		}
+		b.WriteString(strings.TrimRight(line.String(), " "))
+		b.WriteByte('\n')
+	}
+	if verbose {
+		header := make([]string, len(columns))
+		for j, col := range columns {
+			header[j] = t.columns[col].Name
+		}
+		writeLine(header, true)
+	}
+	for _, row := range cells {
+		writeLine(row, false)
+	}
+	return b.String()
+}
+
+var catByteUnits = map[string]int64{
+	"b":  1,
+	"kb": 1 << 10,
+	"mb": 1 << 20,
+	"gb": 1 << 30,
+	"tb": 1 << 40,
+	"pb": 1 << 50,
+}
+
+// format formats the value of a column, sizes are in the unit or human readable if the unit is empty
+func (t *catTable) format(column int, value interface{}, unit string) string {
+	switch v := value.(type) {
+	case int64:
+		if !t.columns[column].Bytes {
+			return strconv.FormatInt(v, 10)
+		}
+		if unit != "" {
+			return strconv.FormatInt(v/catByteUnits[unit], 10)
+		}
+		for _, unit := range []string{"pb", "tb", "gb", "mb", "kb"} {
+			if v >= catByteUnits[unit] {
+				size := strconv.FormatFloat(float64(v)/float64(catByteUnits[unit]), 'f', 1, 64)
+				return strings.TrimSuffix(size, ".0") + unit
+			}
+		}
+		return strconv.FormatInt(v, 10) + "b"
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func compareCatValues(a, b interface{}) int {
+	if x, ok := a.(int64); ok {
+		if y, ok := b.(int64); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
@@ -0,0 +1,65 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// Count returns the number of documents matching the query, compatible with ES count API.
+// The query is the query DSL in the body or a query string in the q parameter.
+func Count(c *gin.Context) {
+	req := new(meta.CountRequest)
+	if c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// the body is optional
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := json.Unmarshal(body, req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+	if q, ok := c.GetQuery("q"); ok {
+		if req.Query != nil {
+			handleError(c, errors.New(errors.ErrorTypeIllegalArgumentException, "request [_count] contains both the q parameter and a request body"))
+			return
+		}
+		req.Query = map[string]interface{}{"query_string": map[string]interface{}{"query": q}}
+	}
+
+	resp, err := core.Count(strings.Split(c.Param("target"), ","), req.Query)
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,123 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// Explain returns the computation of the score of a document for the query and whether it matches,
+// compatible with ES explain API
+func Explain(c *gin.Context) {
+	q, err := bindQuery(c, "_explain")
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	if q == nil {
+		handleError(c, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: query is missing;"))
+		return
+	}
+
+	target := c.Param("target")
+	if _, err := core.ResolveIndex(target); err != nil {
+		handleError(c, err)
+		return
+	}
+	indexes, filters, err := core.MatchIndexFilters([]string{target})
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+	if len(indexes) == 0 {
+		handleIndexError(c, errors.New(errors.ErrorTypeIndexNotFoundException, "no such index ["+target+"]"))
+		return
+	}
+
+	resp, err := indexes[0].Explain(c.Param("id"), &meta.ZincQuery{Query: q}, filters[0])
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	if resp.Explanation == nil {
+		c.JSON(http.StatusNotFound, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ValidateQuery checks the query without executing it, compatible with ES validate API.
+// The explain or rewrite parameter returns the parsed query or the error of every index.
+func ValidateQuery(c *gin.Context) {
+	q, err := bindQuery(c, "_validate/query")
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	resp, err := core.ValidateQuery(strings.Split(c.Param("target"), ","), q)
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+	if !queryFlag(c, "explain") && !queryFlag(c, "rewrite") {
+		resp.Explanations = nil
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// bindQuery returns the query DSL of the optional body or the query string of the q parameter
+func bindQuery(c *gin.Context, api string) (map[string]interface{}, error) {
+	req := new(struct {
+		Query map[string]interface{} `json:"query"`
+	})
+	if c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(body)) > 0 {
+			if err := json.Unmarshal(body, req); err != nil {
+				return nil, errors.New(errors.ErrorTypeParsingException, "request body is malformed").Cause(err)
+			}
+		}
+	}
+	if q, ok := c.GetQuery("q"); ok {
+		if req.Query != nil {
+			return nil, errors.New(errors.ErrorTypeIllegalArgumentException, "request ["+api+"] contains both the q parameter and a request body")
+		}
+		req.Query = map[string]interface{}{"query_string": map[string]interface{}{"query": q}}
+	}
+	return req.Query, nil
+}
+
+// queryFlag reports whether the boolean parameter is set, a parameter without value is true
+func queryFlag(c *gin.Context, name string) bool {
+	v, ok := c.GetQuery(name)
+	return ok && v != "false"
+}
@@ -0,0 +1,95 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/zutils"
+)
+
+// OpenPointInTime opens a point in time of the indexes for consistent pagination with search_after
+func OpenPointInTime(c *gin.Context) {
+	keepAlive, ok := c.GetQuery("keep_alive")
+	if !ok {
+		handleError(c, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: [keep_alive] is not specified;"))
+		return
+	}
+	duration, err := parseKeepAlive(keepAlive)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	pit, err := core.OpenPointInTime(strings.Split(c.Param("target"), ","), duration)
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": pit.ID})
+}
+
+// ClosePointInTime closes a point in time, the id is in the body
+func ClosePointInTime(c *gin.Context) {
+	req := new(meta.PointInTime)
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ID == "" {
+		handleError(c, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: [id] is not specified;"))
+		return
+	}
+
+	if !core.ClosePointInTime(req.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"succeeded": true, "num_freed": 0})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"succeeded": true, "num_freed": 1})
+}
+
+func searchPointInTime(query *meta.ZincQuery) (*meta.SearchResponse, error) {
+	pit, ok := core.GetPointInTime(query.PIT.ID)
+	if !ok {
+		return nil, errors.New(errors.ErrorTypeResourceNotFound, "No search context found for id ["+query.PIT.ID+"]")
+	}
+
+	var keepAlive time.Duration
+	if query.PIT.KeepAlive != "" {
+		var err error
+		if keepAlive, err = parseKeepAlive(query.PIT.KeepAlive); err != nil {
+			return nil, err
+		}
+	}
+
+	return pit.Search(query, keepAlive)
+}
+
+func parseKeepAlive(v string) (time.Duration, error) {
+	duration, err := zutils.ParseDuration(v)
+	if err != nil {
+		return 0, errors.New(errors.ErrorTypeIllegalArgumentException, "failed to parse setting [keep_alive] with value ["+v+"] as a time value")
+	}
+	return duration, nil
+}
@@ -0,0 +1,104 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// Reindex copies documents from the source indexes into the dest index,
+// with wait_for_completion=false it runs in background and responds the task id
+func Reindex(c *gin.Context) {
+	req := new(meta.ReindexRequest)
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	waitForCompletion, err := strconv.ParseBool(c.DefaultQuery("wait_for_completion", "true"))
+	if err != nil {
+		handleError(c, errors.New(errors.ErrorTypeIllegalArgumentException, "[wait_for_completion] must be a boolean"))
+		return
+	}
+	requestsPerSecond, err := requestsPerSecondFromQuery(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	r, err := core.NewReindex(req, requestsPerSecond)
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	task := core.NewTask(core.ReindexAction, r.Description(), r)
+	if !waitForCompletion {
+		c.JSON(http.StatusOK, gin.H{"task": task.TaskID()})
+		return
+	}
+
+	resp, err := task.Result()
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RethrottleReindex changes the requests_per_second of a running reindex
+func RethrottleReindex(c *gin.Context) {
+	task, ok := core.GetTask(c.Param("task_id"))
+	if !ok || task.Action != core.ReindexAction {
+		taskNotFound(c)
+		return
+	}
+
+	if _, ok := c.GetQuery("requests_per_second"); !ok {
+		handleError(c, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: requests_per_second must be set;"))
+		return
+	}
+	requestsPerSecond, err := requestsPerSecondFromQuery(c)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	task.Runner.(*core.Reindex).Rethrottle(requestsPerSecond)
+	c.JSON(http.StatusOK, gin.H{"nodes": gin.H{
+		core.TaskNode: gin.H{"tasks": gin.H{task.TaskID(): task.Info().Task}},
+	}})
+}
+
+// requestsPerSecondFromQuery parses requests_per_second, -1 means unlimited
+func requestsPerSecondFromQuery(c *gin.Context) (float64, error) {
+	v := c.DefaultQuery("requests_per_second", "-1")
+	if v == "unlimited" {
+		return -1, nil
+	}
+	rps, err := strconv.ParseFloat(v, 64)
+	if err != nil || rps == 0 || (rps < 0 && rps != -1) {
+		return 0, errors.New(errors.ErrorTypeIllegalArgumentException, "[requests_per_second] must be a float greater than 0. Use -1 to disable throttling.")
+	}
+	return rps, nil
+}
@@ -0,0 +1,155 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// PutScript stores a mustache template of search requests, compatible with ES stored script API
+func PutScript(c *gin.Context) {
+	req := new(meta.StoredScriptRequest)
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := core.NewScript(c.Param("id"), req.Script); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"acknowledged": true})
+}
+
+// GetScript returns a stored script
+func GetScript(c *gin.Context) {
+	id := c.Param("id")
+	script, exists, err := core.LoadScript(id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"_id": id, "found": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"_id": id, "found": true, "script": script})
+}
+
+// DeleteScript deletes a stored script
+func DeleteScript(c *gin.Context) {
+	id := c.Param("id")
+	_, exists, err := core.LoadScript(id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	if !exists {
+		handleIndexError(c, errors.New(errors.ErrorTypeResourceNotFound, "stored script ["+id+"] does not exist"))
+		return
+	}
+
+	if err := core.DeleteScript(id); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"acknowledged": true})
+}
+
+// SearchTemplate searches the index with the search request rendered from a template
+func SearchTemplate(c *gin.Context) {
+	req := new(meta.SearchTemplateRequest)
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, err := renderSearchTemplate(req)
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	resp, err := searchIndex(strings.Split(c.Param("target"), ","), query)
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// MultiSearchTemplate like bulk searches with templates, the bodies are search template requests
+func MultiSearchTemplate(c *gin.Context) {
+	multipleSearch(c, func(line []byte) (*meta.ZincQuery, error) {
+		req := new(meta.SearchTemplateRequest)
+		if err := json.Unmarshal(line, req); err != nil {
+			return nil, err
+		}
+		return renderSearchTemplate(req)
+	})
+}
+
+// RenderTemplate returns the search request rendered from a template, the id of the path is the stored template
+func RenderTemplate(c *gin.Context) {
+	req := new(meta.SearchTemplateRequest)
+	if err := c.BindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if id := c.Param("id"); id != "" {
+		req.ID = id
+	}
+
+	rendered, err := core.RenderSearchTemplate(req)
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"template_output": json.RawMessage(rendered)})
+}
+
+// renderSearchTemplate renders the template of the request to a search request
+func renderSearchTemplate(req *meta.SearchTemplateRequest) (*meta.ZincQuery, error) {
+	rendered, err := core.RenderSearchTemplate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	query := new(meta.ZincQuery)
+	if err := json.Unmarshal(rendered, query); err != nil {
+		return nil, errors.New(errors.ErrorTypeXContentParseException, "the rendered template is not a search request").Cause(err)
+	}
+	if req.Explain {
+		query.Explain = true
+	}
+	if req.Profile {
+		query.Profile = true
+	}
+	return query, nil
+}
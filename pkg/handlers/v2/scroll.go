@@ -0,0 +1,121 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// Scroll returns the next page of a scroll opened by a search with the scroll parameter
+func Scroll(c *gin.Context) {
+	req := new(meta.ScrollRequest)
+	if c.Request.ContentLength != 0 && c.Request.Body != nil {
+		if err := c.BindJSON(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if v := c.Param("scroll_id"); v != "" {
+		req.ScrollID = v
+	}
+	if v, ok := c.GetQuery("scroll_id"); ok {
+		req.ScrollID = v
+	}
+	if v, ok := c.GetQuery("scroll"); ok {
+		req.Scroll = v
+	}
+	if req.ScrollID == "" {
+		handleError(c, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: scrollId is missing;"))
+		return
+	}
+
+	scroll, ok := core.GetScroll(req.ScrollID)
+	if !ok {
+		handleIndexError(c, errors.New(errors.ErrorTypeResourceNotFound, "No search context found for id ["+req.ScrollID+"]"))
+		return
+	}
+	var keepAlive time.Duration
+	if req.Scroll != "" {
+		var err error
+		if keepAlive, err = parseKeepAlive(req.Scroll); err != nil {
+			handleError(c, err)
+			return
+		}
+	}
+
+	resp, err := scroll.Next(keepAlive)
+	if err != nil {
+		handleIndexError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ClearScroll closes scrolls by id, _all closes all the scrolls
+func ClearScroll(c *gin.Context) {
+	var ids []string
+	if v := c.Param("scroll_id"); v != "" {
+		ids = append(ids, v)
+	} else if c.Request.ContentLength != 0 && c.Request.Body != nil {
+		req := new(meta.ClearScrollRequest)
+		if err := c.BindJSON(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		switch v := req.ScrollID.(type) {
+		case string:
+			ids = append(ids, v)
+		case []interface{}:
+			for _, v := range v {
+				if id, ok := v.(string); ok {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	if len(ids) == 0 {
+		handleError(c, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: no scroll ids specified;"))
+		return
+	}
+
+	var freed int
+	if len(ids) == 1 && ids[0] == "_all" {
+		freed = core.ClearScroll()
+	} else {
+		freed = core.ClearScroll(ids...)
+	}
+
+	status := http.StatusOK
+	if freed == 0 {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{"succeeded": true, "num_freed": freed})
+}
+
+func openScroll(indexNames []string, query *meta.ZincQuery, scroll string) (*meta.SearchResponse, error) {
+	keepAlive, err := parseKeepAlive(scroll)
+	if err != nil {
+		return nil, err
+	}
+	return core.OpenScroll(indexNames, query, keepAlive)
+}
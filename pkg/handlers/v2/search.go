@@ -16,8 +16,8 @@
 package v2
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 
@@ -28,6 +28,8 @@ import (
 	"github.com/zinclabs/zinc/pkg/core"
 	"github.com/zinclabs/zinc/pkg/errors"
 	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/startup"
+	"github.com/zinclabs/zinc/pkg/zutils/ndjson"
 )
 
 // SearchIndex searches the index for the given http request from end user
@@ -41,9 +43,15 @@ func SearchIndex(c *gin.Context) {
 		return
 	}
 
-	resp, err := searchIndex(strings.Split(indexName, ","), query)
+	var resp *meta.SearchResponse
+	var err error
+	if scroll, ok := c.GetQuery("scroll"); ok {
+		resp, err = openScroll(strings.Split(indexName, ","), query, scroll)
+	} else {
+		resp, err = searchIndex(strings.Split(indexName, ","), query)
+	}
 	if err != nil {
-		handleError(c, err)
+		handleIndexError(c, err)
 		return
 	}
 
@@ -60,6 +68,15 @@ func SearchIndex(c *gin.Context) {
 
 // MultipleSearch like bulk searches
 func MultipleSearch(c *gin.Context) {
+	multipleSearch(c, func(line []byte) (*meta.ZincQuery, error) {
+		var query *meta.ZincQuery
+		err := json.Unmarshal(line, &query)
+		return query, err
+	})
+}
+
+// multipleSearch searches the ndjson body of headers and search requests, parse decodes the search requests
+func multipleSearch(c *gin.Context, parse func(line []byte) (*meta.ZincQuery, error)) {
 	indexName := c.Param("target")
 	defaultIndexNames := make([]string, 0)
 	if indexName != "" {
@@ -68,25 +85,43 @@ func MultipleSearch(c *gin.Context) {
 
 	responses := make([]interface{}, 0)
 
-	// Prepare to read the entire raw text of the body
-	scanner := bufio.NewScanner(c.Request.Body)
+	// Prepare to stream the body line by line
+	maxDocumentSize := startup.LoadMaxDocumentSize()
+	reader := ndjson.NewReader(c.Request.Body, maxDocumentSize)
 	defer c.Request.Body.Close()
 
-	const maxCapacityPerLine = 1024 * 1024
-	buf := make([]byte, maxCapacityPerLine)
-	scanner.Buffer(buf, maxCapacityPerLine)
-
 	indexNames := make([]string, 0)
 	nextLineIsData := false
 
 	var doc map[string]interface{}
-	var err error
-	for scanner.Scan() { // Read each line
+	for { // Read each line
+		line, err := reader.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err == ndjson.ErrLineTooLarge {
+			if nextLineIsData {
+				nextLineIsData = false
+				responses = append(responses, &meta.SearchResponse{Error: fmt.Sprintf(
+					"document too large, line [%d] is larger than the max document size [%d] bytes", reader.Line(), maxDocumentSize,
+				)})
+			} else {
+				// the header is too large, search with the default indexes
+				nextLineIsData = true
+				indexNames = append(indexNames[:0], defaultIndexNames...)
+			}
+			continue
+		}
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+
 		if nextLineIsData {
 			nextLineIsData = false
-			var query *meta.ZincQuery
-			if err = json.Unmarshal(scanner.Bytes(), &query); err != nil {
-				log.Error().Msgf("handlers.v2.MultipleSearch: json.Unmarshal: err %s", err.Error())
+			query, err := parse(line)
+			if err != nil {
+				log.Error().Msgf("handlers.v2.MultipleSearch: parse: err %s", err.Error())
 				responses = append(responses, &meta.SearchResponse{Error: err.Error()})
 				continue
 			}
@@ -101,7 +136,7 @@ func MultipleSearch(c *gin.Context) {
 		} else {
 			nextLineIsData = true
 			indexNames = indexNames[:0]
-			if err = json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			if err = json.Unmarshal(line, &doc); err != nil {
 				log.Error().Msgf("handlers.v2.MultipleSearch: json.Unmarshal: err %s", err.Error())
 				continue
 			}
@@ -128,18 +163,18 @@ func searchIndex(indexNames []string, query *meta.ZincQuery) (*meta.SearchRespon
 	if len(indexNames) > 0 {
 		indexName = indexNames[0]
 	}
-	var err error
-	var resp *meta.SearchResponse
-	if indexName == "" || strings.HasSuffix(indexName, "*") || len(indexNames) > 1 {
-		resp, err = core.MultiSearchV2(indexNames, query)
-	} else {
-		index, exists := core.GetIndex(indexName)
-		if !exists {
-			return nil, fmt.Errorf("index %s does not exists", indexName)
+	if query.PIT != nil {
+		if indexName != "" {
+			return nil, errors.New(errors.ErrorTypeActionRequestValidation, "Validation Failed: 1: [indices] cannot be used with point in time;")
 		}
-		resp, err = index.SearchV2(query)
+		return searchPointInTime(query)
 	}
-	return resp, err
+	if len(indexNames) == 1 {
+		if index, exists := core.GetIndex(indexName); exists {
+			return index.SearchV2(query)
+		}
+	}
+	return core.MultiSearchV2(indexNames, query)
 }
 
 func handleError(c *gin.Context, err error) {
@@ -154,3 +189,13 @@ func handleError(c *gin.Context, err error) {
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "ok"})
 }
+
+// handleIndexError responds 404 if the index, the alias or the resource doesn't exist, other errors are handled by handleError
+func handleIndexError(c *gin.Context, err error) {
+	if v, ok := err.(*errors.Error); ok && (v.Type == errors.ErrorTypeIndexNotFoundException ||
+		v.Type == errors.ErrorTypeResourceNotFound || v.Type == errors.ErrorTypeAliasesNotFoundException) {
+		c.JSON(http.StatusNotFound, gin.H{"error": v, "status": http.StatusNotFound})
+		return
+	}
+	handleError(c, err)
+}
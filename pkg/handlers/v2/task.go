@@ -0,0 +1,91 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zinclabs/zinc/pkg/core"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// GetTask returns the status of a task, with wait_for_completion=true it waits for the task
+func GetTask(c *gin.Context) {
+	task, ok := core.GetTask(c.Param("task_id"))
+	if !ok {
+		taskNotFound(c)
+		return
+	}
+
+	if wait, _ := strconv.ParseBool(c.Query("wait_for_completion")); wait {
+		timeout := 30 * time.Second
+		if v := c.Query("timeout"); v != "" {
+			var err error
+			if timeout, err = time.ParseDuration(v); err != nil {
+				handleError(c, errors.New(errors.ErrorTypeIllegalArgumentException, "[timeout] "+err.Error()))
+				return
+			}
+		}
+		select {
+		case <-task.Done():
+		case <-time.After(timeout):
+		}
+	}
+
+	c.JSON(http.StatusOK, task.Info())
+}
+
+// ListTasks lists all the tasks, with actions it only lists the tasks of the action
+func ListTasks(c *gin.Context) {
+	action := c.Query("actions")
+	list := core.ListTasks()
+
+	infos := make(map[string]*meta.TaskInfo, len(list))
+	for _, task := range list {
+		if action != "" && action != "*" && action != task.Action {
+			continue
+		}
+		infos[task.TaskID()] = task.Info().Task
+	}
+
+	c.JSON(http.StatusOK, gin.H{"nodes": gin.H{core.TaskNode: gin.H{"tasks": infos}}})
+}
+
+// CancelTask cancels a running task
+func CancelTask(c *gin.Context) {
+	task, ok := core.GetTask(c.Param("task_id"))
+	if !ok {
+		taskNotFound(c)
+		return
+	}
+
+	task.Cancel()
+	c.JSON(http.StatusOK, gin.H{"nodes": gin.H{
+		core.TaskNode: gin.H{"tasks": gin.H{task.TaskID(): task.Info().Task}},
+	}})
+}
+
+func taskNotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error":  errors.New(errors.ErrorTypeResourceNotFound, "task ["+c.Param("task_id")+"] isn't running and hasn't stored its results"),
+		"status": http.StatusNotFound,
+	})
+}
@@ -0,0 +1,88 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// versionControlFromQuery parse concurrency control from url params, like:
+// ?if_seq_no=1&if_primary_term=1 or ?version=2&version_type=external
+func versionControlFromQuery(c *gin.Context) (*meta.VersionControl, error) {
+	params := make(map[string]interface{})
+	for _, k := range []string{"if_seq_no", "if_primary_term", "version", "version_type"} {
+		if v, ok := c.GetQuery(k); ok {
+			params[k] = v
+		}
+	}
+	return versionControl(params)
+}
+
+// versionControl parse concurrency control from a map, values can be numbers or strings,
+// it returns nil if no concurrency control is set
+func versionControl(params map[string]interface{}) (*meta.VersionControl, error) {
+	var vc *meta.VersionControl
+	for k, v := range params {
+		if v == nil {
+			continue
+		}
+		if vc == nil {
+			vc = new(meta.VersionControl)
+		}
+		if k == "version_type" {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, "[version_type] should be a string")
+			}
+			vc.VersionType = s
+			continue
+		}
+		n, err := versionNumber(k, v)
+		if err != nil {
+			return nil, err
+		}
+		switch k {
+		case "if_seq_no":
+			vc.IfSeqNo = &n
+		case "if_primary_term":
+			vc.IfPrimaryTerm = &n
+		case "version":
+			vc.Version = &n
+		default:
+		}
+	}
+	return vc, nil
+}
+
+func versionNumber(k string, v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case float64:
+		return int64(v), nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, errors.New(errors.ErrorTypeIllegalArgumentException, "["+k+"] should be a number, got ["+v+"]")
+		}
+		return n, nil
+	default:
+		return 0, errors.New(errors.ErrorTypeIllegalArgumentException, "["+k+"] should be a number")
+	}
+}
@@ -0,0 +1,39 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+// Alias is an alternate name of an index, a filtered alias applies the filter to every search through it
+type Alias struct {
+	Alias        string                 `json:"alias"`
+	Index        string                 `json:"index"`
+	Filter       map[string]interface{} `json:"filter,omitempty"`
+	IsWriteIndex *bool                  `json:"is_write_index,omitempty"` // writes to an alias of many indexes go to the write index
+}
+
+// AliasActions is the request of the _aliases API, the actions are applied in order
+type AliasActions struct {
+	Actions []map[string]*AliasAction `json:"actions"` // key is add or remove
+}
+
+// AliasAction adds or removes aliases, the index and alias names can be patterns with * wildcards
+type AliasAction struct {
+	Index        string                 `json:"index"`
+	Indices      []string               `json:"indices"`
+	Alias        string                 `json:"alias"`
+	Aliases      []string               `json:"aliases"`
+	Filter       map[string]interface{} `json:"filter"`
+	IsWriteIndex *bool                  `json:"is_write_index"`
+}
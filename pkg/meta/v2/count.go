@@ -0,0 +1,28 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+// CountRequest for _count, compatible with ES
+// {"query":{"match":{"field":"value"}}}
+type CountRequest struct {
+	Query map[string]interface{} `json:"query"`
+}
+
+// CountResponse for _count, compatible with ES
+type CountResponse struct {
+	Count  int64  `json:"count"`
+	Shards Shards `json:"_shards"`
+}
@@ -0,0 +1,110 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+import "time"
+
+// GetResponse for a single document, compatible with ES get API
+type GetResponse struct {
+	Index       string                 `json:"_index"`
+	Type        string                 `json:"_type"`
+	ID          string                 `json:"_id"`
+	Version     int64                  `json:"_version,omitempty"`
+	SeqNo       *int64                 `json:"_seq_no,omitempty"`
+	PrimaryTerm int64                  `json:"_primary_term,omitempty"`
+	Found       bool                   `json:"found"`
+	Timestamp   *time.Time             `json:"@timestamp,omitempty"`
+	Source      map[string]interface{} `json:"_source,omitempty"`
+	Error       interface{}            `json:"error,omitempty"` // only for mget
+}
+
+// VersionControl for optimistic concurrency control of writes
+// ?if_seq_no=1&if_primary_term=1 or ?version=2&version_type=external
+type VersionControl struct {
+	IfSeqNo       *int64
+	IfPrimaryTerm *int64
+	Version       *int64
+	VersionType   string // internal(default), external, external_gte
+}
+
+// MGetRequest
+// {"docs":[{"_index":"index1","_id":"1","_source":["field1"]}]}
+// {"ids":["1","2"]}
+type MGetRequest struct {
+	Docs []MGetDoc `json:"docs"`
+	Ids  []string  `json:"ids"`
+}
+
+type MGetDoc struct {
+	Index  string      `json:"_index"`
+	ID     string      `json:"_id"`
+	Source interface{} `json:"_source"` // true, false, ["field1", "field2.*"]
+}
+
+type MGetResponse struct {
+	Docs []*GetResponse `json:"docs"`
+}
+
+// UpdateRequest for partial update, compatible with ES update API
+// {"doc":{"field":"value"},"doc_as_upsert":true}
+type UpdateRequest struct {
+	Doc         map[string]interface{} `json:"doc"`
+	Upsert      map[string]interface{} `json:"upsert"`
+	DocAsUpsert bool                   `json:"doc_as_upsert"`
+	DetectNoop  *bool                  `json:"detect_noop"` // default is true
+	Script      interface{}            `json:"script"`      // not supported
+}
+
+type UpdateResponse struct {
+	Index       string      `json:"_index"`
+	Type        string      `json:"_type"`
+	ID          string      `json:"_id"`
+	Version     int64       `json:"_version"`
+	SeqNo       int64       `json:"_seq_no"`
+	PrimaryTerm int64       `json:"_primary_term"`
+	Result      string      `json:"result"` // created, updated, noop
+	Shards      Shards      `json:"_shards"`
+	Error       interface{} `json:"error,omitempty"`
+}
+
+// ByQueryRequest for delete_by_query and update_by_query
+// {"query":{"match":{"field":"value"}},"max_docs":1000}
+type ByQueryRequest struct {
+	Query     map[string]interface{} `json:"query"`
+	MaxDocs   int                    `json:"max_docs"`
+	Conflicts string                 `json:"conflicts"` // abort(default), proceed
+	Script    interface{}            `json:"script"`    // not supported
+}
+
+// ByQueryResponse for delete_by_query and update_by_query, compatible with ES
+type ByQueryResponse struct {
+	Took             int               `json:"took"`
+	TimedOut         bool              `json:"timed_out"`
+	Total            int64             `json:"total"`
+	Deleted          int64             `json:"deleted"`
+	Updated          int64             `json:"updated"`
+	Batches          int               `json:"batches"`
+	VersionConflicts int64             `json:"version_conflicts"`
+	Noops            int64             `json:"noops"`
+	Failures         []*ByQueryFailure `json:"failures"`
+}
+
+type ByQueryFailure struct {
+	Index  string      `json:"index"`
+	ID     string      `json:"id"`
+	Cause  interface{} `json:"cause"`
+	Status int         `json:"status"`
+}
@@ -0,0 +1,46 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+// Explanation is the computation of the score of a document
+type Explanation struct {
+	Value       float64        `json:"value"`
+	Description string         `json:"description"`
+	Details     []*Explanation `json:"details"`
+}
+
+// ExplainResponse is the response of the explain of a document, the explanation is nil when the document doesn't exist
+type ExplainResponse struct {
+	Index       string       `json:"_index"`
+	ID          string       `json:"_id"`
+	Matched     bool         `json:"matched"`
+	Explanation *Explanation `json:"explanation,omitempty"`
+}
+
+// ValidateResponse is the response of the validation of a query
+type ValidateResponse struct {
+	Shards       Shards                 `json:"_shards"`
+	Valid        bool                   `json:"valid"`
+	Explanations []*ValidateExplanation `json:"explanations,omitempty"` // returned with the explain parameter
+}
+
+// ValidateExplanation is the parsed query or the error of the query of an index
+type ValidateExplanation struct {
+	Index       string `json:"index"`
+	Valid       bool   `json:"valid"`
+	Explanation string `json:"explanation,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
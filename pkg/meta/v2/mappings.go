@@ -15,12 +15,24 @@
 
 package v2
 
+import "sort"
+
+// the fields of the hidden documents of nested objects
+const (
+	NestedPathField   = "_nested_path"   // the path of the nested field
+	NestedParentField = "_nested_parent" // the _id of the root document
+	NestedOffsetField = "_nested_offset" // the position of the object in the nested field
+)
+
+// FieldLengthField is the hidden field keeping the analyzed length of the text fields, see combined_fields
+const FieldLengthField = "_field_length"
+
 type Mappings struct {
 	Properties map[string]Property `json:"properties,omitempty"`
 }
 
 type Property struct {
-	Type           string `json:"type"` // text, keyword, date, numeric, boolean, geo_point
+	Type           string `json:"type"` // text, keyword, date, numeric, boolean, geo_point, nested, completion
 	Analyzer       string `json:"analyzer,omitempty"`
 	SearchAnalyzer string `json:"search_analyzer,omitempty"`
 	Format         string `json:"format,omitempty"` // date format yyyy-MM-dd HH:mm:ss || yyyy-MM-dd || epoch_millis
@@ -56,7 +68,24 @@ func NewProperty(typ string) Property {
 	case "numeric", "date":
 		p.Sortable = true
 		p.Aggregatable = true
+	case "geo_point":
+		p.Sortable = true
 	}
 
 	return p
 }
+
+// NestedPaths returns the nested fields of the mappings, their objects are indexed as hidden documents
+func (m *Mappings) NestedPaths() []string {
+	if m == nil {
+		return nil
+	}
+	var paths []string
+	for field, prop := range m.Properties {
+		if prop.Type == "nested" {
+			paths = append(paths, field)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -15,26 +15,82 @@
 
 package v2
 
-import "github.com/zinclabs/zinc/pkg/bluge/aggregation"
+import (
+	"github.com/blugelabs/bluge"
+
+	"github.com/zinclabs/zinc/pkg/bluge/aggregation"
+	"github.com/zinclabs/zinc/pkg/bluge/profile"
+)
 
 // ZincQuery is the query object for the zinc index. compatible ES Query DSL
 type ZincQuery struct {
-	Query          map[string]interface{}  `json:"query"`
-	Aggregations   map[string]Aggregations `json:"aggs"`
-	Highlight      *Highlight              `json:"highlight"`
-	Fields         interface{}             `json:"fields"`  // ["field1", "field2.*", {"field": "fieldName", "format": "epoch_millis"}]
-	Source         interface{}             `json:"_source"` // true, false, ["field1", "field2.*"]
-	Sort           interface{}             `json:"sort"`    // "_sorce", ["+Year","-Year", {"Year": "desc"}, "Date": {"order": "asc"", "format": "yyyy-MM-dd"}}"}]
-	Explain        bool                    `json:"explain"`
-	From           int                     `json:"from"`
-	Size           int                     `json:"size"`
-	Timeout        int                     `json:"timeout"`
-	TrackTotalHits bool                    `json:"track_total_hits"`
+	Query            map[string]interface{}  `json:"query"`
+	Aggregations     map[string]Aggregations `json:"aggs"`
+	Highlight        *Highlight              `json:"highlight"`
+	Fields           interface{}             `json:"fields"`  // ["field1", "field2.*", {"field": "fieldName", "format": "epoch_millis"}]
+	Source           interface{}             `json:"_source"` // true, false, ["field1", "field2.*"]
+	Sort             interface{}             `json:"sort"`    // "_sorce", ["+Year","-Year", {"Year": "desc"}, "Date": {"order": "asc"", "format": "yyyy-MM-dd"}}"}]
+	Explain          bool                    `json:"explain"`
+	From             int                     `json:"from"`
+	Size             int                     `json:"size"`
+	Timeout          int                     `json:"timeout"`
+	TrackTotalHits   bool                    `json:"track_total_hits"`
+	Version          bool                    `json:"version"`             // return _version of every hit
+	SeqNoPrimaryTerm bool                    `json:"seq_no_primary_term"` // return _seq_no and _primary_term of every hit
+	SearchAfter      []interface{}           `json:"search_after"`        // sort values of the last hit of the previous page
+	PIT              *PointInTime            `json:"pit"`                 // search a point in time instead of the index
+	Suggest          map[string]interface{}  `json:"suggest"`             // named suggesters and the global text
+	Suggesters       map[string]*Suggester   `json:"-"`                   // parsed from suggest
+	InnerHits        []*InnerHits            `json:"-"`                   // parsed from the inner_hits of the nested queries
+	IndexInnerHits   map[string][]*InnerHits `json:"-"`                   // inner_hits of each index when several indexes are searched
+	IndexHighlights  map[string]*Highlight   `json:"-"`                   // highlight of each index when several indexes are searched
+	Profile          bool                    `json:"profile"`             // return the timings of the query clauses, aggregations and fetch
+	Profiler         *profile.Profiler       `json:"-"`                   // collects the timings when profile is set
+	Rewritten        bluge.Query             `json:"-"`                   // the parsed query of the first index
+}
+
+// InnerHits returns the matching nested documents of every hit
+type InnerHits struct {
+	Name   string
+	Path   string
+	From   int
+	Size   int
+	Source *Source
+	Query  bluge.Query // matches the nested documents of the path
+}
+
+// PointInTime is the pit of a search request
+type PointInTime struct {
+	ID        string `json:"id"`
+	KeepAlive string `json:"keep_alive"` // extends the keep alive of the point in time, 1m, 1h
+}
+
+// SortField is a parsed field of sort, it is used to encode search_after and decode the sort values of hits
+type SortField struct {
+	Field        string           // _score for the score, _doc for the index order
+	Desc         bool             // the order, desc
+	Missing      interface{}      // _last by default, _first or the sort value of the documents without the field
+	Mode         string           // the value of multi-valued fields, min, max, sum, avg or median
+	UnmappedType string           // the type of the field if no index maps it, its documents are sorted as missing
+	Format       string           // the format of the sort values of date fields, a Go layout or epoch_millis
+	GeoDistance  *GeoDistanceSort // sort by _geo_distance of the field
+}
+
+// GeoDistanceSort sorts by the distance of a geo_point field to the points
+type GeoDistanceSort struct {
+	Points []GeoPoint
+	Unit   string // m(default), km, mi, ...
+	Mode   string // min, max, avg, median, sum
+}
+
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
 }
 
 type Query struct {
 	Bool              *BoolQuery                `json:"bool"`                // .
-	Boosting          *BoostingQuery            `json:"boosting"`            // TODO: not implemented
+	Boosting          *BoostingQuery            `json:"boosting"`            // .
 	Match             map[string]interface{}    `json:"match"`               // simple, MatchQuery
 	MatchBoolPrefix   map[string]interface{}    `json:"match_bool_prefix"`   // simple, MatchBoolPrefixQuery
 	MatchPhrase       map[string]interface{}    `json:"match_phrase"`        // simple, MatchPhraseQuery
@@ -42,7 +98,7 @@ type Query struct {
 	MultiMatch        *MultiMatchQuery          `json:"multi_match"`         // .
 	MatchAll          interface{}               `json:"match_all"`           // just set or null
 	MatchNone         interface{}               `json:"match_none"`          // just set or null
-	CombinedFields    *CombinedFieldsQuery      `json:"combined_fields"`     // TODO: not implemented
+	CombinedFields    *CombinedFieldsQuery      `json:"combined_fields"`     // .
 	QueryString       *QueryStringQuery         `json:"query_string"`        // .
 	SimpleQueryString *SimpleQueryStringQuery   `json:"simple_query_string"` // .
 	Exists            *ExistsQuery              `json:"exists"`              // .
@@ -53,11 +109,14 @@ type Query struct {
 	Wildcard          map[string]interface{}    `json:"wildcard"`            // simple, WildcardQuery
 	Term              map[string]interface{}    `json:"term"`                // simple, TermQuery
 	Terms             map[string]interface{}    `json:"terms"`               // .
-	TermsSet          map[string]*TermsSetQuery `json:"terms_set"`           // TODO: not implemented
-	GeoBoundingBox    interface{}               `json:"geo_bounding_box"`    // TODO: not implemented
-	GeoDistance       interface{}               `json:"geo_distance"`        // TODO: not implemented
-	GeoPolygon        interface{}               `json:"geo_polygon"`         // TODO: not implemented
+	TermsSet          map[string]*TermsSetQuery `json:"terms_set"`           // .
+	GeoBoundingBox    interface{}               `json:"geo_bounding_box"`    // .
+	GeoDistance       interface{}               `json:"geo_distance"`        // .
+	GeoPolygon        interface{}               `json:"geo_polygon"`         // .
 	GeoShape          interface{}               `json:"geo_shape"`           // TODO: not implemented
+	FunctionScore     *FunctionScoreQuery       `json:"function_score"`      // .
+	ScriptScore       *ScriptScoreQuery         `json:"script_score"`        // .
+	Nested            *NestedQuery              `json:"nested"`              // .
 }
 
 type BoolQuery struct {
@@ -72,6 +131,7 @@ type BoostingQuery struct {
 	Positive      interface{} `json:"positive"` // singe or multiple queries
 	Negative      interface{} `json:"negative"` // singe or multiple queries
 	NegativeBoost float64     `json:"negative_boost"`
+	Boost         float64     `json:"boost"`
 }
 
 type MatchQuery struct {
@@ -112,11 +172,13 @@ type MultiMatchQuery struct {
 }
 
 type CombinedFieldsQuery struct {
-	Query              string   `json:"query"`
-	Analyzer           string   `json:"analyzer"`
-	Fields             []string `json:"fields"`
-	Operator           string   `json:"operator"` // or(default), and
-	MinimumShouldMatch float64  `json:"minimum_should_match"`
+	Query              string      `json:"query"`
+	Analyzer           string      `json:"analyzer"`
+	Fields             []string    `json:"fields"`               // field, field^boost
+	Operator           string      `json:"operator"`             // or(default), and
+	MinimumShouldMatch interface{} `json:"minimum_should_match"` // 2, -1, "75%"
+	ZeroTermsQuery     string      `json:"zero_terms_query"`     // none(default), all
+	Boost              float64     `json:"boost"`
 }
 
 type QueryStringQuery struct {
@@ -208,8 +270,38 @@ type TermQuery struct {
 // {"terms": {"field": ["value1", "value2"], "boost": 1.0}}
 type TermsQuery map[string]interface{}
 
-// TermsSetQuery ...
-type TermsSetQuery struct{}
+type TermsSetQuery struct {
+	Terms                    []interface{} `json:"terms"`
+	MinimumShouldMatchField  string        `json:"minimum_should_match_field"`
+	MinimumShouldMatchScript interface{}   `json:"minimum_should_match_script"` // script, params.num_terms is the number of terms
+	Boost                    float64       `json:"boost"`
+}
+
+type FunctionScoreQuery struct {
+	Query     map[string]interface{}   `json:"query"`      // match_all by default
+	Functions []map[string]interface{} `json:"functions"`  // [{"filter": query, "weight": 1, "field_value_factor": {}}]
+	ScoreMode string                   `json:"score_mode"` // multiply(default), sum, avg, first, max, min
+	BoostMode string                   `json:"boost_mode"` // multiply(default), replace, sum, avg, max, min
+	MaxBoost  float64                  `json:"max_boost"`
+	MinScore  float64                  `json:"min_score"`
+	Boost     float64                  `json:"boost"`
+}
+
+type ScriptScoreQuery struct {
+	Query    map[string]interface{} `json:"query"`
+	Script   interface{}            `json:"script"` // script, _score is the score of the query
+	MinScore float64                `json:"min_score"`
+	Boost    float64                `json:"boost"`
+}
+
+type NestedQuery struct {
+	Path           string                 `json:"path"`
+	Query          map[string]interface{} `json:"query"`
+	ScoreMode      string                 `json:"score_mode"` // avg(default), max, min, sum, none
+	IgnoreUnmapped bool                   `json:"ignore_unmapped"`
+	InnerHits      map[string]interface{} `json:"inner_hits"` // {"name": "", "from": 0, "size": 3, "_source": true}
+	Boost          float64                `json:"boost"`
+}
 
 type Aggregations struct {
 	Avg               *AggregationMetric            `json:"avg"`
@@ -226,7 +318,17 @@ type Aggregations struct {
 	DateHistogram     *AggregationDateHistogram     `json:"date_histogram"`
 	AutoDateHistogram *AggregationAutoDateHistogram `json:"auto_date_histogram"`
 	IPRange           *AggregationIPRange           `json:"ip_range"` // TODO: not implemented
-	Aggregations      map[string]Aggregations       `json:"aggs"`     // nested aggregations
+	Nested            *AggregationNested            `json:"nested"`
+	ReverseNested     *AggregationReverseNested     `json:"reverse_nested"`
+	Aggregations      map[string]Aggregations       `json:"aggs"` // sub aggregations
+}
+
+type AggregationNested struct {
+	Path string `json:"path"`
+}
+
+type AggregationReverseNested struct {
+	Path string `json:"path"` // only the root documents are supported
 }
 
 type AggregationMetric struct {
@@ -313,11 +415,15 @@ type AggregationAutoDateHistogram struct {
 }
 
 type Highlight struct {
-	NumberOfFragments int                   `json:"number_of_fragments"`
-	FragmentSize      int                   `json:"fragment_size"`
-	PreTags           []string              `json:"pre_tags"`
-	PostTags          []string              `json:"post_tags"`
-	Fields            map[string]*Highlight `json:"fields"`
+	NumberOfFragments *int                   `json:"number_of_fragments"` // 0 highlights the whole field
+	FragmentSize      int                    `json:"fragment_size"`
+	PreTags           []string               `json:"pre_tags"`
+	PostTags          []string               `json:"post_tags"`
+	Encoder           string                 `json:"encoder"` // default, html
+	RequireFieldMatch *bool                  `json:"require_field_match"`
+	HighlightQuery    map[string]interface{} `json:"highlight_query"`
+	Fields            map[string]*Highlight  `json:"fields"` // field name or wildcard, such as: message.*
+	Query             bluge.Query            `json:"-"`      // highlight_query or the search query
 }
 
 type Field struct {
@@ -326,6 +432,7 @@ type Field struct {
 }
 
 type Source struct {
-	Enable bool     // enable _source returns, default is true
-	Fields []string // what fields can returns
+	Enable   bool     // enable _source returns, default is true
+	Fields   []string // what fields can returns
+	Excludes []string // what fields are removed from the returned fields
 }
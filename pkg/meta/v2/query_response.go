@@ -15,7 +15,11 @@
 
 package v2
 
-import "time"
+import (
+	"time"
+
+	"github.com/goccy/go-json"
+)
 
 // SearchResponse for a query
 type SearchResponse struct {
@@ -24,7 +28,11 @@ type SearchResponse struct {
 	Shards       Shards                         `json:"_shards"`
 	Hits         Hits                           `json:"hits"`
 	Aggregations map[string]AggregationResponse `json:"aggregations,omitempty"`
+	Suggest      map[string][]SuggestEntry      `json:"suggest,omitempty"`
 	Error        string                         `json:"error"`
+	PitID        string                         `json:"pit_id,omitempty"`
+	ScrollID     string                         `json:"_scroll_id,omitempty"`
+	Profile      *Profile                       `json:"profile,omitempty"`
 }
 
 type Shards struct {
@@ -41,14 +49,30 @@ type Hits struct {
 }
 
 type Hit struct {
-	Index     string                 `json:"_index"`
-	Type      string                 `json:"_type"`
-	ID        string                 `json:"_id"`
-	Score     float64                `json:"_score"`
-	Timestamp time.Time              `json:"@timestamp"`
-	Source    map[string]interface{} `json:"_source,omitempty"`
-	Fields    map[string]interface{} `json:"fields,omitempty"`
-	Highlight map[string]interface{} `json:"highlight,omitempty"`
+	Index       string                 `json:"_index"`
+	Type        string                 `json:"_type"`
+	ID          string                 `json:"_id"`
+	Score       float64                `json:"_score"`
+	Version     int64                  `json:"_version,omitempty"`
+	SeqNo       *int64                 `json:"_seq_no,omitempty"`
+	PrimaryTerm int64                  `json:"_primary_term,omitempty"`
+	Timestamp   time.Time              `json:"@timestamp"`
+	Source      map[string]interface{} `json:"_source,omitempty"`
+	Fields      map[string]interface{} `json:"fields,omitempty"`
+	Highlight   map[string]interface{} `json:"highlight,omitempty"`
+	Sort        []interface{}          `json:"sort,omitempty"`
+	Nested      *NestedIdentity        `json:"_nested,omitempty"`    // the nested object of an inner hit
+	InnerHits   map[string]InnerHit    `json:"inner_hits,omitempty"` // the matching nested objects of the hit
+	Explanation *Explanation           `json:"_explanation,omitempty"`
+}
+
+type NestedIdentity struct {
+	Field  string `json:"field"`
+	Offset int    `json:"offset"`
+}
+
+type InnerHit struct {
+	Hits Hits `json:"hits"`
 }
 
 type Total struct {
@@ -56,7 +80,100 @@ type Total struct {
 }
 
 type AggregationResponse struct {
-	Value    interface{} `json:"value,omitempty"`
-	Buckets  interface{} `json:"buckets,omitempty"`  // slice or map
-	Interval string      `json:"interval,omitempty"` // support for auto_date_histogram_aggregation
+	Value        interface{}                    `json:"value,omitempty"`
+	Buckets      interface{}                    `json:"buckets,omitempty"`   // slice or map
+	Interval     string                         `json:"interval,omitempty"`  // support for auto_date_histogram_aggregation
+	DocCount     *int64                         `json:"doc_count,omitempty"` // support for single bucket aggregations, nested and reverse_nested
+	Aggregations map[string]AggregationResponse `json:"-"`                   // the sub aggregations of a single bucket aggregation, they are inlined
+}
+
+func (r AggregationResponse) MarshalJSON() ([]byte, error) {
+	data := make(map[string]interface{}, len(r.Aggregations)+3)
+	for name, agg := range r.Aggregations {
+		data[name] = agg
+	}
+	if r.Value != nil {
+		data["value"] = r.Value
+	}
+	if r.Buckets != nil {
+		data["buckets"] = r.Buckets
+	}
+	if r.Interval != "" {
+		data["interval"] = r.Interval
+	}
+	if r.DocCount != nil {
+		data["doc_count"] = *r.DocCount
+	}
+	return json.Marshal(data)
+}
+
+func (r *AggregationResponse) UnmarshalJSON(b []byte) error {
+	data := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	*r = AggregationResponse{}
+	for k, v := range data {
+		var err error
+		switch k {
+		case "value":
+			err = json.Unmarshal(v, &r.Value)
+		case "buckets":
+			err = json.Unmarshal(v, &r.Buckets)
+		case "interval":
+			err = json.Unmarshal(v, &r.Interval)
+		case "doc_count":
+			r.DocCount = new(int64)
+			err = json.Unmarshal(v, r.DocCount)
+		default:
+			if len(v) == 0 || v[0] != '{' {
+				continue // not a sub aggregation
+			}
+			if r.Aggregations == nil {
+				r.Aggregations = make(map[string]AggregationResponse)
+			}
+			agg := AggregationResponse{}
+			err = json.Unmarshal(v, &agg)
+			r.Aggregations[k] = agg
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Profile is the profile of a search request, every index is reported as a shard
+type Profile struct {
+	Shards []ProfileShard `json:"shards"`
+}
+
+type ProfileShard struct {
+	ID           string          `json:"id"` // [node][index][shard]
+	Searches     []ProfileSearch `json:"searches"`
+	Aggregations []ProfileResult `json:"aggregations"`
+	Fetch        *ProfileResult  `json:"fetch,omitempty"`
+}
+
+type ProfileSearch struct {
+	Query       []ProfileResult    `json:"query"`
+	RewriteTime int64              `json:"rewrite_time"`
+	Collector   []ProfileCollector `json:"collector"`
+}
+
+// ProfileResult is the profile of a query clause, an aggregation or the fetch phase, the times are in nanoseconds
+type ProfileResult struct {
+	Type        string                 `json:"type"`
+	Description string                 `json:"description"`
+	TimeInNanos int64                  `json:"time_in_nanos"`
+	Breakdown   map[string]int64       `json:"breakdown"`
+	Debug       map[string]interface{} `json:"debug,omitempty"`
+	Children    []ProfileResult        `json:"children,omitempty"`
+}
+
+type ProfileCollector struct {
+	Name        string             `json:"name"`
+	Reason      string             `json:"reason"`
+	TimeInNanos int64              `json:"time_in_nanos"`
+	Children    []ProfileCollector `json:"children,omitempty"`
 }
@@ -0,0 +1,65 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+// ReindexRequest copies documents from the source indexes to the dest index, compatible with ES reindex API
+type ReindexRequest struct {
+	Source    ReindexSource `json:"source"`
+	Dest      ReindexDest   `json:"dest"`
+	MaxDocs   int           `json:"max_docs"`
+	Conflicts string        `json:"conflicts"` // abort(default), proceed
+	Script    interface{}   `json:"script"`    // not supported
+}
+
+type ReindexSource struct {
+	Index  interface{}            `json:"index"`   // "index1" or ["index1", "index2"]
+	Query  map[string]interface{} `json:"query"`   // default match_all
+	Source interface{}            `json:"_source"` // true, false, ["field1", "field2.*"]
+	Size   int                    `json:"size"`    // batch size
+	Remote *ReindexRemote         `json:"remote"`
+}
+
+// ReindexRemote is a remote Zinc server, documents are read from its /es search API
+type ReindexRemote struct {
+	Host           string `json:"host"` // http://otherhost:4080
+	Username       string `json:"username"`
+	Password       string `json:"password"`
+	SocketTimeout  string `json:"socket_timeout"`  // default 30s
+	ConnectTimeout string `json:"connect_timeout"` // default 30s
+}
+
+type ReindexDest struct {
+	Index  string `json:"index"`
+	OpType string `json:"op_type"` // index(default), create
+}
+
+// ReindexResponse is the result of a reindex, it is also the status of a running reindex task
+type ReindexResponse struct {
+	Took                 int64             `json:"took,omitempty"`
+	TimedOut             bool              `json:"timed_out"`
+	Total                int64             `json:"total"`
+	Created              int64             `json:"created"`
+	Updated              int64             `json:"updated"`
+	Deleted              int64             `json:"deleted"`
+	Batches              int               `json:"batches"`
+	VersionConflicts     int64             `json:"version_conflicts"`
+	Noops                int64             `json:"noops"`
+	ThrottledMillis      int64             `json:"throttled_millis"`
+	RequestsPerSecond    float64           `json:"requests_per_second"` // -1 means unlimited
+	ThrottledUntilMillis int64             `json:"throttled_until_millis"`
+	Canceled             string            `json:"canceled,omitempty"`
+	Failures             []*ByQueryFailure `json:"failures,omitempty"`
+}
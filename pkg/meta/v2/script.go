@@ -0,0 +1,36 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+// StoredScript is a stored script, only mustache templates of search requests are supported
+type StoredScript struct {
+	Lang   string      `json:"lang"`
+	Source interface{} `json:"source"` // the template as a string or a JSON object, it is stored as a string
+}
+
+// StoredScriptRequest is the body of the request to store a script
+type StoredScriptRequest struct {
+	Script *StoredScript `json:"script"`
+}
+
+// SearchTemplateRequest is a search request rendered from a stored template or an inline template
+type SearchTemplateRequest struct {
+	ID      string                 `json:"id"`     // the id of the stored template
+	Source  interface{}            `json:"source"` // the inline template as a string or a JSON object
+	Params  map[string]interface{} `json:"params"`
+	Explain bool                   `json:"explain"`
+	Profile bool                   `json:"profile"`
+}
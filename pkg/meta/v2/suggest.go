@@ -0,0 +1,83 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+// Suggester is a named suggester of the suggest section, exactly one of term, phrase or completion is set
+type Suggester struct {
+	Text       string               `json:"text"`   // the text to suggest for, defaults to the global text of the suggest section
+	Prefix     string               `json:"prefix"` // the prefix of the completion suggester, defaults to text
+	Term       *TermSuggester       `json:"term"`
+	Phrase     *PhraseSuggester     `json:"phrase"`
+	Completion *CompletionSuggester `json:"completion"`
+}
+
+// TermSuggester suggests terms of the field dictionary within the edit distance of every token of the text
+type TermSuggester struct {
+	Field         string `json:"field"`
+	Analyzer      string `json:"analyzer"`        // defaults to the search analyzer of the field
+	Size          int    `json:"size"`            // max options of every token, default 5
+	SuggestMode   string `json:"suggest_mode"`    // missing(default), popular, always
+	Sort          string `json:"sort"`            // score(default), frequency
+	MaxEdits      int    `json:"max_edits"`       // 1 or 2(default)
+	PrefixLength  *int   `json:"prefix_length"`   // leading characters which must match, default 1
+	MinWordLength *int   `json:"min_word_length"` // shorter tokens get no options, default 4
+}
+
+// PhraseSuggester suggests corrections of the whole text, the candidates of every token are
+// generated like the term suggester and the phrases are scored with a unigram language model
+type PhraseSuggester struct {
+	Field                   string           `json:"field"`
+	Analyzer                string           `json:"analyzer"`                   // defaults to the search analyzer of the field
+	Size                    int              `json:"size"`                       // max options, default 5
+	MaxErrors               float64          `json:"max_errors"`                 // max corrected tokens, a ratio of the tokens if less than 1, default 1
+	Confidence              *float64         `json:"confidence"`                 // options must score higher than the text times the confidence, default 1
+	RealWordErrorLikelihood float64          `json:"real_word_error_likelihood"` // likelihood that an indexed token is still misspelled, default 0.95
+	Highlight               *PhraseHighlight `json:"highlight"`
+	DirectGenerator         []*TermSuggester `json:"direct_generator"` // candidate generators, defaults to one on the field
+}
+
+// PhraseHighlight marks the corrected tokens of the phrase options
+type PhraseHighlight struct {
+	PreTag  string `json:"pre_tag"`
+	PostTag string `json:"post_tag"`
+}
+
+// CompletionSuggester suggests the inputs of a completion field starting with the prefix, heaviest first
+type CompletionSuggester struct {
+	Field          string `json:"field"`
+	Size           int    `json:"size"` // max options, default 5
+	SkipDuplicates bool   `json:"skip_duplicates"`
+}
+
+// SuggestEntry is the options of a token of the text, or of the whole text for phrase and completion suggesters
+type SuggestEntry struct {
+	Text    string          `json:"text"`
+	Offset  int             `json:"offset"`
+	Length  int             `json:"length"`
+	Options []SuggestOption `json:"options"`
+}
+
+type SuggestOption struct {
+	Text        string                 `json:"text"`
+	Highlighted string                 `json:"highlighted,omitempty"` // phrase
+	Score       float64                `json:"score,omitempty"`       // term, phrase
+	Freq        int64                  `json:"freq,omitempty"`        // term
+	Index       string                 `json:"_index,omitempty"`      // completion
+	Type        string                 `json:"_type,omitempty"`       // completion
+	ID          string                 `json:"_id,omitempty"`         // completion
+	DocScore    float64                `json:"_score,omitempty"`      // completion, the weight of the input
+	Source      map[string]interface{} `json:"_source,omitempty"`     // completion
+}
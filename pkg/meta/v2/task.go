@@ -0,0 +1,37 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package v2
+
+// TaskResponse for the task API, compatible with ES tasks API
+type TaskResponse struct {
+	Completed bool        `json:"completed"`
+	Task      *TaskInfo   `json:"task"`
+	Response  interface{} `json:"response,omitempty"`
+	Error     interface{} `json:"error,omitempty"`
+}
+
+type TaskInfo struct {
+	Node               string      `json:"node"`
+	ID                 int64       `json:"id"`
+	Type               string      `json:"type"`
+	Action             string      `json:"action"` // indices:data/write/reindex
+	Status             interface{} `json:"status,omitempty"`
+	Description        string      `json:"description"`
+	StartTimeInMillis  int64       `json:"start_time_in_millis"`
+	RunningTimeInNanos int64       `json:"running_time_in_nanos"`
+	Cancellable        bool        `json:"cancellable"`
+	Cancelled          bool        `json:"cancelled"`
+}
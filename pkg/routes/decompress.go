@@ -0,0 +1,131 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/zinclabs/zinc/pkg/startup"
+)
+
+// Decompress decodes the request body according to the Content-Encoding header,
+// supports gzip, deflate and zstd. The decoded body of a route that reads the whole body
+// is limited by startup.LoadMaxDecompressedSize(), a larger body is rejected with 413.
+// _bulk streams the body line by line, every line is limited by startup.LoadMaxDocumentSize().
+func Decompress(r *gin.Engine) {
+	r.Use(func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		body, err := decompressReader(encoding, c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid request body with Content-Encoding [" + encoding + "]: " + err.Error()})
+			return
+		}
+		if body == nil {
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported Content-Encoding [" + encoding + "]"})
+			return
+		}
+
+		if !strings.HasSuffix(c.FullPath(), "/_bulk") {
+			limit := startup.LoadMaxDecompressedSize()
+			buf := new(bytes.Buffer)
+			_, err := io.Copy(buf, io.LimitReader(body, int64(limit)+1))
+			body.Close()
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid request body with Content-Encoding [" + encoding + "]: " + err.Error()})
+				return
+			}
+			if buf.Len() > limit {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error": "decompressed request body is larger than the limit [" + strconv.Itoa(limit) + "] bytes, the limit can be set by changing the [ZINC_MAX_DECOMPRESSED_SIZE] environment variable",
+				})
+				return
+			}
+			body = io.NopCloser(buf)
+		}
+
+		c.Request.Body = body
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+
+		c.Next()
+	})
+}
+
+// decompressReader returns nil without error if the encoding is not supported
+func decompressReader(encoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return &decompressBody{Reader: r, decoder: r, body: body}, nil
+	case "deflate":
+		// deflate should be zlib format, but some clients send raw deflate
+		br := bufio.NewReader(body)
+		header, err := br.Peek(2)
+		if err != nil {
+			return nil, err
+		}
+		if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			r, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, err
+			}
+			return &decompressBody{Reader: r, decoder: r, body: body}, nil
+		}
+		r := flate.NewReader(br)
+		return &decompressBody{Reader: r, decoder: r, body: body}, nil
+	case "zstd":
+		r, err := zstd.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		decoder := r.IOReadCloser()
+		return &decompressBody{Reader: decoder, decoder: decoder, body: body}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// decompressBody closes both the decoder and the original body
+type decompressBody struct {
+	io.Reader
+	decoder io.Closer
+	body    io.Closer
+}
+
+func (b *decompressBody) Close() error {
+	b.decoder.Close()
+	return b.body.Close()
+}
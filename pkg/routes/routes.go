@@ -49,6 +49,9 @@ func SetRoutes(r *gin.Engine) {
 		AccessLog(r)
 	}
 
+	// decode compressed request body
+	Decompress(r)
+
 	r.GET("/", v1.GUI)
 	r.GET("/version", v1.GetVersion)
 	r.GET("/healthz", v1.GetHealthz)
@@ -94,8 +97,18 @@ func SetRoutes(r *gin.Engine) {
 	r.PUT("/api/:target/document", auth.ZincAuthMiddleware, handlers.UpdateDocument)
 	r.POST("/api/:target/_doc", auth.ZincAuthMiddleware, handlers.UpdateDocument)
 	r.PUT("/api/:target/_doc/:id", auth.ZincAuthMiddleware, handlers.UpdateDocument)
+	r.GET("/api/:target/_doc/:id", auth.ZincAuthMiddleware, handlers.GetDocument)
+	r.HEAD("/api/:target/_doc/:id", auth.ZincAuthMiddleware, handlers.GetDocument)
 	r.POST("/api/:target/_search", auth.ZincAuthMiddleware, handlers.SearchIndex)
+	r.GET("/api/:target/_count", auth.ZincAuthMiddleware, handlersV2.Count)
+	r.POST("/api/:target/_count", auth.ZincAuthMiddleware, handlersV2.Count)
+	r.POST("/api/:target/_delete_by_query", auth.ZincAuthMiddleware, handlersV2.DeleteByQuery)
+	r.POST("/api/:target/_update_by_query", auth.ZincAuthMiddleware, handlersV2.UpdateByQuery)
+	r.POST("/api/_reindex", auth.ZincAuthMiddleware, handlersV2.Reindex)
+	r.POST("/api/_reindex/:task_id/_rethrottle", auth.ZincAuthMiddleware, handlersV2.RethrottleReindex)
 	r.DELETE("/api/:target/_doc/:id", auth.ZincAuthMiddleware, handlers.DeleteDocument)
+	r.POST("/api/_mget", auth.ZincAuthMiddleware, handlers.MultiGetDocuments)
+	r.POST("/api/:target/_mget", auth.ZincAuthMiddleware, handlers.MultiGetDocuments)
 
 	r.GET("/api/:target/_mapping", auth.ZincAuthMiddleware, handlersV2.GetIndexMapping)
 	r.PUT("/api/:target/_mapping", auth.ZincAuthMiddleware, handlersV2.UpdateIndexMapping)
@@ -103,6 +116,10 @@ func SetRoutes(r *gin.Engine) {
 	r.GET("/api/:target/_settings", auth.ZincAuthMiddleware, handlersV2.GetIndexSettings)
 	r.PUT("/api/:target/_settings", auth.ZincAuthMiddleware, handlersV2.UpdateIndexSettings)
 
+	r.GET("/api/_tasks", auth.ZincAuthMiddleware, handlersV2.ListTasks)
+	r.GET("/api/_tasks/:task_id", auth.ZincAuthMiddleware, handlersV2.GetTask)
+	r.POST("/api/_tasks/:task_id/_cancel", auth.ZincAuthMiddleware, handlersV2.CancelTask)
+
 	r.POST("/api/_analyze", auth.ZincAuthMiddleware, handlersV2.Analyze)
 	r.POST("/api/:target/_analyze", auth.ZincAuthMiddleware, handlersV2.Analyze)
 
@@ -120,10 +137,53 @@ func SetRoutes(r *gin.Engine) {
 		c.JSON(http.StatusOK, v1.NewESXPack(c))
 	})
 
+	r.GET("/es/_cat", auth.ZincAuthMiddleware, handlersV2.Cat)
+	r.GET("/es/_cat/indices", auth.ZincAuthMiddleware, handlersV2.CatIndices)
+	r.GET("/es/_cat/indices/:target", auth.ZincAuthMiddleware, handlersV2.CatIndices)
+	r.GET("/es/_cat/count", auth.ZincAuthMiddleware, handlersV2.CatCount)
+	r.GET("/es/_cat/count/:target", auth.ZincAuthMiddleware, handlersV2.CatCount)
+	r.GET("/es/_cat/templates", auth.ZincAuthMiddleware, handlersV2.CatTemplates)
+	r.GET("/es/_cat/templates/:name", auth.ZincAuthMiddleware, handlersV2.CatTemplates)
+	r.GET("/es/_cat/health", auth.ZincAuthMiddleware, handlersV2.CatHealth)
+	r.GET("/es/_cat/aliases", auth.ZincAuthMiddleware, handlersV2.CatAliases)
+	r.GET("/es/_cat/aliases/:name", auth.ZincAuthMiddleware, handlersV2.CatAliases)
+
 	r.POST("/es/_search", auth.ZincAuthMiddleware, handlersV2.SearchIndex)
+	r.GET("/es/_search/scroll", auth.ZincAuthMiddleware, handlersV2.Scroll)
+	r.POST("/es/_search/scroll", auth.ZincAuthMiddleware, handlersV2.Scroll)
+	r.GET("/es/_search/scroll/:scroll_id", auth.ZincAuthMiddleware, handlersV2.Scroll)
+	r.POST("/es/_search/scroll/:scroll_id", auth.ZincAuthMiddleware, handlersV2.Scroll)
+	r.DELETE("/es/_search/scroll", auth.ZincAuthMiddleware, handlersV2.ClearScroll)
+	r.DELETE("/es/_search/scroll/:scroll_id", auth.ZincAuthMiddleware, handlersV2.ClearScroll)
 	r.POST("/es/_msearch", auth.ZincAuthMiddleware, handlersV2.MultipleSearch)
+	r.GET("/es/_search/template", auth.ZincAuthMiddleware, handlersV2.SearchTemplate)
+	r.POST("/es/_search/template", auth.ZincAuthMiddleware, handlersV2.SearchTemplate)
+	r.POST("/es/_msearch/template", auth.ZincAuthMiddleware, handlersV2.MultiSearchTemplate)
 	r.POST("/es/:target/_search", auth.ZincAuthMiddleware, handlersV2.SearchIndex)
 	r.POST("/es/:target/_msearch", auth.ZincAuthMiddleware, handlersV2.MultipleSearch)
+	r.GET("/es/:target/_search/template", auth.ZincAuthMiddleware, handlersV2.SearchTemplate)
+	r.POST("/es/:target/_search/template", auth.ZincAuthMiddleware, handlersV2.SearchTemplate)
+	r.POST("/es/:target/_msearch/template", auth.ZincAuthMiddleware, handlersV2.MultiSearchTemplate)
+	r.POST("/es/:target/_pit", auth.ZincAuthMiddleware, handlersV2.OpenPointInTime)
+	r.DELETE("/es/_pit", auth.ZincAuthMiddleware, handlersV2.ClosePointInTime)
+	r.GET("/es/_count", auth.ZincAuthMiddleware, handlersV2.Count)
+	r.POST("/es/_count", auth.ZincAuthMiddleware, handlersV2.Count)
+	r.GET("/es/:target/_count", auth.ZincAuthMiddleware, handlersV2.Count)
+	r.POST("/es/:target/_count", auth.ZincAuthMiddleware, handlersV2.Count)
+	r.GET("/es/:target/_explain/:id", auth.ZincAuthMiddleware, handlersV2.Explain)
+	r.POST("/es/:target/_explain/:id", auth.ZincAuthMiddleware, handlersV2.Explain)
+	r.GET("/es/_validate/query", auth.ZincAuthMiddleware, handlersV2.ValidateQuery)
+	r.POST("/es/_validate/query", auth.ZincAuthMiddleware, handlersV2.ValidateQuery)
+	r.GET("/es/:target/_validate/query", auth.ZincAuthMiddleware, handlersV2.ValidateQuery)
+	r.POST("/es/:target/_validate/query", auth.ZincAuthMiddleware, handlersV2.ValidateQuery)
+	r.POST("/es/:target/_delete_by_query", auth.ZincAuthMiddleware, handlersV2.DeleteByQuery)
+	r.POST("/es/:target/_update_by_query", auth.ZincAuthMiddleware, handlersV2.UpdateByQuery)
+	r.POST("/es/_reindex", auth.ZincAuthMiddleware, handlersV2.Reindex)
+	r.POST("/es/_reindex/:task_id/_rethrottle", auth.ZincAuthMiddleware, handlersV2.RethrottleReindex)
+
+	r.GET("/es/_tasks", auth.ZincAuthMiddleware, handlersV2.ListTasks)
+	r.GET("/es/_tasks/:task_id", auth.ZincAuthMiddleware, handlersV2.GetTask)
+	r.POST("/es/_tasks/:task_id/_cancel", auth.ZincAuthMiddleware, handlersV2.CancelTask)
 
 	r.GET("/es/_index_template", auth.ZincAuthMiddleware, handlersV2.ListIndexTemplate)
 	r.PUT("/es/_index_template/:target", auth.ZincAuthMiddleware, handlersV2.UpdateIndexTemplate)
@@ -131,6 +191,29 @@ func SetRoutes(r *gin.Engine) {
 	r.HEAD("/es/_index_template/:target", auth.ZincAuthMiddleware, handlersV2.GetIndexTemplate)
 	r.DELETE("/es/_index_template/:target", auth.ZincAuthMiddleware, handlersV2.DeleteIndexTemplate)
 
+	r.PUT("/es/_scripts/:id", auth.ZincAuthMiddleware, handlersV2.PutScript)
+	r.POST("/es/_scripts/:id", auth.ZincAuthMiddleware, handlersV2.PutScript)
+	r.GET("/es/_scripts/:id", auth.ZincAuthMiddleware, handlersV2.GetScript)
+	r.DELETE("/es/_scripts/:id", auth.ZincAuthMiddleware, handlersV2.DeleteScript)
+	r.GET("/es/_render/template", auth.ZincAuthMiddleware, handlersV2.RenderTemplate)
+	r.POST("/es/_render/template", auth.ZincAuthMiddleware, handlersV2.RenderTemplate)
+	r.GET("/es/_render/template/:id", auth.ZincAuthMiddleware, handlersV2.RenderTemplate)
+	r.POST("/es/_render/template/:id", auth.ZincAuthMiddleware, handlersV2.RenderTemplate)
+
+	r.POST("/es/_aliases", auth.ZincAuthMiddleware, handlersV2.UpdateAliases)
+	r.GET("/es/_alias", auth.ZincAuthMiddleware, handlersV2.GetAlias)
+	r.GET("/es/_alias/:name", auth.ZincAuthMiddleware, handlersV2.GetAlias)
+	r.HEAD("/es/_alias/:name", auth.ZincAuthMiddleware, handlersV2.GetAlias)
+	r.GET("/es/:target/_alias", auth.ZincAuthMiddleware, handlersV2.GetAlias)
+	r.GET("/es/:target/_alias/:name", auth.ZincAuthMiddleware, handlersV2.GetAlias)
+	r.HEAD("/es/:target/_alias/:name", auth.ZincAuthMiddleware, handlersV2.GetAlias)
+	r.PUT("/es/:target/_alias/:name", auth.ZincAuthMiddleware, handlersV2.PutAlias)
+	r.POST("/es/:target/_alias/:name", auth.ZincAuthMiddleware, handlersV2.PutAlias)
+	r.PUT("/es/:target/_aliases/:name", auth.ZincAuthMiddleware, handlersV2.PutAlias)
+	r.POST("/es/:target/_aliases/:name", auth.ZincAuthMiddleware, handlersV2.PutAlias)
+	r.DELETE("/es/:target/_alias/:name", auth.ZincAuthMiddleware, handlersV2.DeleteAlias)
+	r.DELETE("/es/:target/_aliases/:name", auth.ZincAuthMiddleware, handlersV2.DeleteAlias)
+
 	r.GET("/es/:target/_mapping", auth.ZincAuthMiddleware, handlersV2.GetIndexMapping)
 	r.PUT("/es/:target/_mapping", auth.ZincAuthMiddleware, handlersV2.UpdateIndexMapping)
 
@@ -142,10 +225,18 @@ func SetRoutes(r *gin.Engine) {
 
 	r.POST("/es/:target/_doc", auth.ZincAuthMiddleware, handlers.UpdateDocument)
 	r.PUT("/es/:target/_doc/:id", auth.ZincAuthMiddleware, handlers.UpdateDocument)
-	r.PUT("/es/:target/_create/:id", auth.ZincAuthMiddleware, handlers.UpdateDocument)
-	r.POST("/es/:target/_create/:id", auth.ZincAuthMiddleware, handlers.UpdateDocument)
-	r.POST("/es/:target/_update/:id", auth.ZincAuthMiddleware, handlers.UpdateDocument)
+	r.GET("/es/:target/_doc/:id", auth.ZincAuthMiddleware, handlers.GetDocument)
+	r.HEAD("/es/:target/_doc/:id", auth.ZincAuthMiddleware, handlers.GetDocument)
+	r.GET("/es/:target/_source/:id", auth.ZincAuthMiddleware, handlers.GetDocumentSource)
+	r.HEAD("/es/:target/_source/:id", auth.ZincAuthMiddleware, handlers.GetDocumentSource)
+	r.PUT("/es/:target/_create/:id", auth.ZincAuthMiddleware, handlers.CreateDocument)
+	r.POST("/es/:target/_create/:id", auth.ZincAuthMiddleware, handlers.CreateDocument)
+	r.POST("/es/:target/_update/:id", auth.ZincAuthMiddleware, handlers.ESUpdateDocument)
 	r.DELETE("/es/:target/_doc/:id", auth.ZincAuthMiddleware, handlers.DeleteDocument)
+	r.GET("/es/_mget", auth.ZincAuthMiddleware, handlers.MultiGetDocuments)
+	r.POST("/es/_mget", auth.ZincAuthMiddleware, handlers.MultiGetDocuments)
+	r.GET("/es/:target/_mget", auth.ZincAuthMiddleware, handlers.MultiGetDocuments)
+	r.POST("/es/:target/_mget", auth.ZincAuthMiddleware, handlers.MultiGetDocuments)
 
 	// Bulk update/insert
 	r.POST("/es/_bulk", auth.ZincAuthMiddleware, handlers.ESBulkHandler)
@@ -0,0 +1,494 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package script
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+type scope struct {
+	env    Env
+	params map[string]interface{}
+	steps  int
+}
+
+// eval evaluates the node within the budget of maxSteps
+func (ctx *scope) eval(n node) (interface{}, error) {
+	ctx.steps++
+	if ctx.steps > maxSteps {
+		return nil, fmt.Errorf("script exceeded the limit of %d evaluation steps", maxSteps)
+	}
+	return n.eval(ctx)
+}
+
+type node interface {
+	eval(ctx *scope) (interface{}, error)
+}
+
+// docObject is the doc variable of the script
+type docObject struct{}
+
+// docValues are the values of a field of the document
+type docValues []interface{}
+
+// mathObject is the Math variable of the script
+type mathObject struct{}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(ctx *scope) (interface{}, error) {
+	return n.value, nil
+}
+
+type variableNode struct {
+	name string
+}
+
+func (n *variableNode) eval(ctx *scope) (interface{}, error) {
+	switch n.name {
+	case "params":
+		return ctx.params, nil
+	case "doc":
+		return docObject{}, nil
+	case "_score":
+		if ctx.env == nil {
+			return 0.0, nil
+		}
+		return ctx.env.Score(), nil
+	case "Math":
+		return mathObject{}, nil
+	}
+	return nil, fmt.Errorf("cannot resolve symbol [%s]", n.name)
+}
+
+type conditionalNode struct {
+	cond      node
+	then      node
+	otherwise node
+}
+
+func (n *conditionalNode) eval(ctx *scope) (interface{}, error) {
+	cond, err := ctx.eval(n.cond)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := toBool(cond)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return ctx.eval(n.then)
+	}
+	return ctx.eval(n.otherwise)
+}
+
+type unaryNode struct {
+	op    string
+	value node
+}
+
+func (n *unaryNode) eval(ctx *scope) (interface{}, error) {
+	v, err := ctx.eval(n.value)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		b, err := toBool(v)
+		return !b, err
+	}
+	f, err := toNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "-" {
+		return -f, nil
+	}
+	return f, nil
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *binaryNode) eval(ctx *scope) (interface{}, error) {
+	left, err := ctx.eval(n.left)
+	if err != nil {
+		return nil, err
+	}
+
+	// short circuit for the logical operators
+	if n.op == "&&" || n.op == "||" {
+		l, err := toBool(left)
+		if err != nil {
+			return nil, err
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := ctx.eval(n.right)
+		if err != nil {
+			return nil, err
+		}
+		return toBool(right)
+	}
+
+	right, err := ctx.eval(n.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equals(left, right), nil
+	case "!=":
+		return !equals(left, right), nil
+	}
+
+	l, err := toNumber(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := toNumber(right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		return l / r, nil
+	case "%":
+		return math.Mod(l, r), nil
+	default:
+		return compare(n.op, l, r), nil
+	}
+}
+
+func compare(op string, l, r float64) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	default:
+		return l >= r
+	}
+}
+
+type memberNode struct {
+	target node
+	key    node
+}
+
+func (n *memberNode) eval(ctx *scope) (interface{}, error) {
+	target, err := ctx.eval(n.target)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ctx.eval(n.key)
+	if err != nil {
+		return nil, err
+	}
+
+	switch target := target.(type) {
+	case docObject:
+		field, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("doc field name must be a string, got %T", key)
+		}
+		if ctx.env == nil {
+			return docValues{}, nil
+		}
+		values, err := ctx.env.Doc(field)
+		if err != nil {
+			return nil, err
+		}
+		return docValues(values), nil
+	case docValues:
+		switch key {
+		case "value":
+			return docValue(target)
+		case "empty":
+			return len(target) == 0, nil
+		case "length":
+			return float64(len(target)), nil
+		}
+		return index([]interface{}(target), key)
+	case mathObject:
+		switch key {
+		case "PI":
+			return math.Pi, nil
+		case "E":
+			return math.E, nil
+		}
+	case map[string]interface{}:
+		if k, ok := key.(string); ok {
+			return target[k], nil
+		}
+	case []interface{}:
+		if key == "length" {
+			return float64(len(target)), nil
+		}
+		return index(target, key)
+	}
+	return nil, fmt.Errorf("cannot access [%v] of %s", key, typeName(target))
+}
+
+func docValue(values docValues) (interface{}, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("a document doesn't have a value for a field! Use doc[<field>].size()==0 to check if a document is missing a field!")
+	}
+	return values[0], nil
+}
+
+func index(values []interface{}, key interface{}) (interface{}, error) {
+	f, err := toNumber(key)
+	if err != nil {
+		return nil, err
+	}
+	i := int(f)
+	if i < 0 || i >= len(values) {
+		return nil, fmt.Errorf("index %d out of bounds for length %d", i, len(values))
+	}
+	return values[i], nil
+}
+
+type callNode struct {
+	target node // nil for the global functions
+	name   string
+	args   []node
+}
+
+func (n *callNode) eval(ctx *scope) (interface{}, error) {
+	var target interface{}
+	if n.target != nil {
+		var err error
+		if target, err = ctx.eval(n.target); err != nil {
+			return nil, err
+		}
+	}
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := ctx.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	if n.target == nil {
+		fn, ok := functions[n.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown function [%s]", n.name)
+		}
+		return fn(args)
+	}
+
+	switch target := target.(type) {
+	case mathObject:
+		if fn, ok := mathFunctions[n.name]; ok {
+			return fn(args)
+		}
+	case docValues:
+		switch n.name {
+		case "size":
+			return float64(len(target)), nil
+		case "isEmpty":
+			return len(target) == 0, nil
+		case "getValue":
+			return docValue(target)
+		case "get":
+			if len(args) != 1 {
+				return nil, fmt.Errorf("method [get] expects 1 argument")
+			}
+			return index([]interface{}(target), args[0])
+		}
+	case time.Time:
+		switch n.name {
+		case "getMillis", "toEpochMilli":
+			return float64(target.UnixMilli()), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown method [%s] of %s", n.name, typeName(target))
+}
+
+// functions are the global functions of the script
+var functions = map[string]func(args []interface{}) (interface{}, error){}
+
+var mathFunctions = map[string]func(args []interface{}) (interface{}, error){
+	"abs":    mathFunction1(math.Abs),
+	"ceil":   mathFunction1(math.Ceil),
+	"floor":  mathFunction1(math.Floor),
+	"round":  mathFunction1(func(x float64) float64 { return math.Floor(x + 0.5) }),
+	"signum": mathFunction1(signum),
+	"sqrt":   mathFunction1(math.Sqrt),
+	"cbrt":   mathFunction1(math.Cbrt),
+	"exp":    mathFunction1(math.Exp),
+	"log":    mathFunction1(math.Log),
+	"log10":  mathFunction1(math.Log10),
+	"log1p":  mathFunction1(math.Log1p),
+	"sin":    mathFunction1(math.Sin),
+	"cos":    mathFunction1(math.Cos),
+	"tan":    mathFunction1(math.Tan),
+	"min":    mathFunction2(math.Min),
+	"max":    mathFunction2(math.Max),
+	"pow":    mathFunction2(math.Pow),
+	"atan2":  mathFunction2(math.Atan2),
+}
+
+func signum(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	default:
+		return x
+	}
+}
+
+func mathFunction1(fn func(float64) float64) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		values, err := numberArgs(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return fn(values[0]), nil
+	}
+}
+
+func mathFunction2(fn func(float64, float64) float64) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		values, err := numberArgs(args, 2)
+		if err != nil {
+			return nil, err
+		}
+		return fn(values[0], values[1]), nil
+	}
+}
+
+func numberArgs(args []interface{}, n int) ([]float64, error) {
+	if len(args) != n {
+		return nil, fmt.Errorf("expects %d arguments but got %d", n, len(args))
+	}
+	values := make([]float64, n)
+	for i, arg := range args {
+		v, err := toNumber(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func toNumber(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case time.Time:
+		return float64(v.UnixMilli()), nil
+	}
+	return 0, fmt.Errorf("cannot cast %s to a number", typeName(v))
+}
+
+func toBool(v interface{}) (bool, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	return false, fmt.Errorf("cannot cast %s to boolean", typeName(v))
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return fmt.Sprintf("%d", int64(v))
+		}
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func equals(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if x, err := toNumber(a); err == nil {
+		if y, err := toNumber(b); err == nil {
+			return x == y
+		}
+		return false
+	}
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		return ok && a == b
+	case bool:
+		b, ok := b.(bool)
+		return ok && a == b
+	}
+	return false
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case float64, float32, int, int64:
+		return "number"
+	case string:
+		return "String"
+	case bool:
+		return "boolean"
+	case time.Time:
+		return "ZonedDateTime"
+	case docObject:
+		return "doc"
+	case docValues:
+		return "ScriptDocValues"
+	case mathObject:
+		return "Math"
+	case map[string]interface{}:
+		return "Map"
+	case []interface{}:
+		return "List"
+	}
+	return fmt.Sprintf("%T", v)
+}
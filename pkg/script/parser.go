@@ -0,0 +1,401 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value float64
+	pos   int
+}
+
+type lexer struct {
+	source string
+	pos    int
+}
+
+func newLexer(source string) *lexer {
+	return &lexer{source: source}
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%", "<", ">", "!", "?", ":", "(", ")", "[", "]", ",", ".", ";"}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.source) && strings.ContainsRune(" \t\r\n", rune(l.source[l.pos])) {
+		l.pos++
+	}
+	if l.pos >= len(l.source) {
+		return token{kind: tokenEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.source[l.pos]
+	switch {
+	case c >= '0' && c <= '9':
+		for l.pos < len(l.source) && (isDigit(l.source[l.pos]) || l.source[l.pos] == '.' ||
+			l.source[l.pos] == 'e' || l.source[l.pos] == 'E' ||
+			((l.source[l.pos] == '-' || l.source[l.pos] == '+') && (l.source[l.pos-1] == 'e' || l.source[l.pos-1] == 'E'))) {
+			l.pos++
+		}
+		text := l.source[start:l.pos]
+		// painless number suffixes: 1L, 1.0f, 1.0d
+		if l.pos < len(l.source) && strings.ContainsRune("lLfFdD", rune(l.source[l.pos])) {
+			l.pos++
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid number [%s] at position %d", text, start)
+		}
+		return token{kind: tokenNumber, text: text, value: v, pos: start}, nil
+	case c == '\'' || c == '"':
+		l.pos++
+		var sb strings.Builder
+		for l.pos < len(l.source) && l.source[l.pos] != c {
+			if l.source[l.pos] == '\\' && l.pos+1 < len(l.source) {
+				l.pos++
+			}
+			sb.WriteByte(l.source[l.pos])
+			l.pos++
+		}
+		if l.pos >= len(l.source) {
+			return token{}, fmt.Errorf("unterminated string at position %d", start)
+		}
+		l.pos++
+		return token{kind: tokenString, text: sb.String(), pos: start}, nil
+	case isLetter(c):
+		for l.pos < len(l.source) && (isLetter(l.source[l.pos]) || isDigit(l.source[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.source[start:l.pos], pos: start}, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.source[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokenOperator, text: op, pos: start}, nil
+		}
+	}
+	return token{}, fmt.Errorf("unexpected character [%c] at position %d", c, start)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+type parser struct {
+	lexer  *lexer
+	tok    token
+	fields map[string]struct{}
+	depth  int
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) is(op string) bool {
+	return p.tok.kind == tokenOperator && p.tok.text == op
+}
+
+func (p *parser) expect(op string) error {
+	if !p.is(op) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of script")
+	}
+	return fmt.Errorf("unexpected token [%s] at position %d", p.tok.text, p.tok.pos)
+}
+
+// parse parses the script, it is one expression with an optional return and ;
+func (p *parser) parse() (node, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenIdent && p.tok.text == "return" {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.tok.kind == tokenEOF {
+		return nil, fmt.Errorf("empty script")
+	}
+	n, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if p.is(";") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+	return n, nil
+}
+
+// enter counts the nesting of the expressions and the unary operators, the caller must defer leave
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return fmt.Errorf("script is nested more than %d levels", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) expression() (node, error) {
+	defer p.leave()
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.is("?") {
+		return cond, nil
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	then, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return &conditionalNode{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+// precedences of the binary operators, from the lowest
+var precedences = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) binary(level int) (node, error) {
+	if level == len(precedences) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range precedences[level] {
+			if p.is(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if p.is("-") || p.is("!") || p.is("+") {
+		defer p.leave()
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		op := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		value, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, value: value}, nil
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (node, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.is("."):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind != tokenIdent {
+				return nil, p.unexpected()
+			}
+			name := p.tok.text
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.is("(") {
+				args, err := p.arguments()
+				if err != nil {
+					return nil, err
+				}
+				n = &callNode{target: n, name: name, args: args}
+			} else {
+				if v, ok := n.(*variableNode); ok && v.name == "doc" {
+					p.fields[name] = struct{}{}
+				}
+				n = &memberNode{target: n, key: &literalNode{value: name}}
+			}
+		case p.is("["):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			key, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			if v, ok := n.(*variableNode); ok && v.name == "doc" {
+				if l, ok := key.(*literalNode); ok {
+					if field, ok := l.value.(string); ok {
+						p.fields[field] = struct{}{}
+					}
+				}
+			}
+			n = &memberNode{target: n, key: key}
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) arguments() ([]node, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []node
+	for !p.is(")") {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.is(",") {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func (p *parser) primary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenNumber:
+		return &literalNode{value: tok.value}, p.advance()
+	case tokenString:
+		return &literalNode{value: tok.text}, p.advance()
+	case tokenIdent:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if p.is("(") {
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			return &callNode{name: tok.text, args: args}, nil
+		}
+		return &variableNode{name: tok.text}, nil
+	case tokenOperator:
+		if tok.text == "(" {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			n, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		}
+	}
+	return nil, p.unexpected()
+}
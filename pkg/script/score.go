@@ -0,0 +1,176 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package script
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/blugelabs/bluge/numeric/geo"
+
+	"github.com/zinclabs/zinc/pkg/zutils"
+	zincgeo "github.com/zinclabs/zinc/pkg/zutils/geo"
+)
+
+// DecayFunction scores a value by its distance to the origin, the score is 1 within the offset
+// and it equals decay at the distance of scale beyond the offset
+type DecayFunction func(distance, offset, scale, decay float64) float64
+
+// Decays are the decay functions by name
+var Decays = map[string]DecayFunction{
+	"gauss":  DecayGauss,
+	"exp":    DecayExp,
+	"linear": DecayLinear,
+}
+
+func DecayGauss(distance, offset, scale, decay float64) float64 {
+	d := math.Max(0, math.Abs(distance)-offset)
+	sigmaSquared := -scale * scale / (2 * math.Log(decay))
+	return math.Exp(-d * d / (2 * sigmaSquared))
+}
+
+func DecayExp(distance, offset, scale, decay float64) float64 {
+	d := math.Max(0, math.Abs(distance)-offset)
+	return math.Exp(math.Log(decay) / scale * d)
+}
+
+func DecayLinear(distance, offset, scale, decay float64) float64 {
+	d := math.Max(0, math.Abs(distance)-offset)
+	s := scale / (1 - decay)
+	return math.Max(0, (s-d)/s)
+}
+
+func init() {
+	for name, fn := range scoreFunctions {
+		functions[name] = fn
+	}
+}
+
+// scoreFunctions are the functions of script_score
+var scoreFunctions = map[string]func(args []interface{}) (interface{}, error){
+	"saturation": func(args []interface{}) (interface{}, error) {
+		values, err := numberArgs(args, 2)
+		if err != nil {
+			return nil, err
+		}
+		return values[0] / (values[1] + values[0]), nil
+	},
+	"sigmoid": func(args []interface{}) (interface{}, error) {
+		values, err := numberArgs(args, 3)
+		if err != nil {
+			return nil, err
+		}
+		v := math.Pow(values[0], values[2])
+		return v / (math.Pow(values[1], values[2]) + v), nil
+	},
+	"decayNumericGauss":  decayNumeric(DecayGauss),
+	"decayNumericExp":    decayNumeric(DecayExp),
+	"decayNumericLinear": decayNumeric(DecayLinear),
+	"decayDateGauss":     decayDate(DecayGauss),
+	"decayDateExp":       decayDate(DecayExp),
+	"decayDateLinear":    decayDate(DecayLinear),
+	"decayGeoGauss":      decayGeo(DecayGauss),
+	"decayGeoExp":        decayGeo(DecayExp),
+	"decayGeoLinear":     decayGeo(DecayLinear),
+}
+
+// decayNumeric returns the function decayNumericXxx(origin, scale, offset, decay, docValue)
+func decayNumeric(fn DecayFunction) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		values, err := numberArgs(args, 5)
+		if err != nil {
+			return nil, err
+		}
+		return fn(values[4]-values[0], values[2], values[1], values[3]), nil
+	}
+}
+
+// decayDate returns the function decayDateXxx(origin, scale, offset, decay, docValue),
+// origin is a date string, scale and offset are durations like 10d
+func decayDate(fn DecayFunction) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if len(args) != 5 {
+			return nil, fmt.Errorf("expects 5 arguments but got %d", len(args))
+		}
+		origin, err := ParseDate(toString(args[0]))
+		if err != nil {
+			return nil, err
+		}
+		scale, err := zutils.ParseDuration(toString(args[1]))
+		if err != nil {
+			return nil, err
+		}
+		offset, err := zutils.ParseDuration(toString(args[2]))
+		if err != nil {
+			return nil, err
+		}
+		decay, err := toNumber(args[3])
+		if err != nil {
+			return nil, err
+		}
+		value, ok := args[4].(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("cannot cast %s to date", typeName(args[4]))
+		}
+		return fn(float64(value.Sub(origin).Milliseconds()), float64(offset.Milliseconds()), float64(scale.Milliseconds()), decay), nil
+	}
+}
+
+// decayGeo returns the function decayGeoXxx(origin, scale, offset, decay, docValue),
+// origin is a point like "lat,lon", scale and offset are distances like 2km
+func decayGeo(fn DecayFunction) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if len(args) != 5 {
+			return nil, fmt.Errorf("expects 5 arguments but got %d", len(args))
+		}
+		origin, err := zincgeo.Parse(args[0])
+		if err != nil {
+			return nil, err
+		}
+		scale, err := geo.ParseDistance(toString(args[1]))
+		if err != nil {
+			return nil, err
+		}
+		offset, err := geo.ParseDistance(toString(args[2]))
+		if err != nil {
+			return nil, err
+		}
+		decay, err := toNumber(args[3])
+		if err != nil {
+			return nil, err
+		}
+		value, err := zincgeo.Parse(args[4])
+		if err != nil {
+			return nil, err
+		}
+		return fn(GeoDistance(origin, value), offset, scale, decay), nil
+	}
+}
+
+// GeoDistance returns the distance of the points in meters
+func GeoDistance(a, b geo.Point) float64 {
+	return geo.Haversin(a.Lon, a.Lat, b.Lon, b.Lat) * 1000
+}
+
+// ParseDate parses the date of a script or a decay origin, it can be RFC3339 or yyyy-MM-dd
+func ParseDate(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
@@ -0,0 +1,128 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package script
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+)
+
+// Env gives the script access to the document being scored
+type Env interface {
+	// Doc returns the doc values of the field, numbers are float64, dates are time.Time
+	Doc(field string) ([]interface{}, error)
+	// Score returns the score of the document from the query
+	Score() float64
+}
+
+// the limits of a script, the expressions can't be nested deeper than maxDepth
+// and an execution can't evaluate more than maxSteps nodes
+const (
+	maxDepth = 64
+	maxSteps = 10000
+)
+
+// Script is a compiled script, it supports the subset of painless used by terms_set and script_score:
+// one expression of params, doc values, _score, Math and score functions with arithmetic, comparison,
+// logical and ternary operators
+type Script struct {
+	Source string
+	Params map[string]interface{}
+	fields []string
+	root   node
+}
+
+// New compiles a script from the request, it can be the source string or an object with source, lang and params
+func New(v interface{}) (*Script, error) {
+	switch v := v.(type) {
+	case string:
+		return Compile(v, nil)
+	case map[string]interface{}:
+		source := ""
+		var params map[string]interface{}
+		for k, v := range v {
+			k := strings.ToLower(k)
+			switch k {
+			case "source", "inline":
+				s, ok := v.(string)
+				if !ok {
+					return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[script] source doesn't support values of type: %T", v))
+				}
+				source = s
+			case "lang":
+				lang, _ := v.(string)
+				if lang != "painless" && lang != "expression" {
+					return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("script_lang not supported [%v]", v))
+				}
+			case "params":
+				p, ok := v.(map[string]interface{})
+				if !ok {
+					return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[script] params doesn't support values of type: %T", v))
+				}
+				params = p
+			case "id":
+				return nil, errors.New(errors.ErrorTypeNotImplemented, "[script] stored scripts doesn't support")
+			default:
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[script] unknown field [%s]", k))
+			}
+		}
+		if source == "" {
+			return nil, errors.New(errors.ErrorTypeParsingException, "[script] must specify either [source] for an inline script or [id] for a stored script")
+		}
+		return Compile(source, params)
+	default:
+		return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[script] doesn't support values of type: %T", v))
+	}
+}
+
+// Compile parses the source of the script
+func Compile(source string, params map[string]interface{}) (*Script, error) {
+	p := &parser{lexer: newLexer(source), fields: make(map[string]struct{})}
+	root, err := p.parse()
+	if err != nil {
+		return nil, errors.New(errors.ErrorTypeScriptException, "compile error").Cause(err)
+	}
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	s := &Script{Source: source, Params: params, root: root}
+	for field := range p.fields {
+		s.fields = append(s.fields, field)
+	}
+	return s, nil
+}
+
+// Fields returns the fields accessed with doc['field'] in the script
+func (s *Script) Fields() []string {
+	return s.fields
+}
+
+// Execute runs the script and returns the value of the expression
+func (s *Script) Execute(env Env) (interface{}, error) {
+	ctx := &scope{env: env, params: s.Params}
+	return ctx.eval(s.root)
+}
+
+// Number runs the script and converts the result to a number
+func (s *Script) Number(env Env) (float64, error) {
+	value, err := s.Execute(env)
+	if err != nil {
+		return 0, err
+	}
+	return toNumber(value)
+}
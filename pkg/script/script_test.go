@@ -0,0 +1,155 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package script
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+)
+
+type testEnv struct {
+	docs  map[string][]interface{}
+	score float64
+}
+
+func (e *testEnv) Doc(field string) ([]interface{}, error) {
+	return e.docs[field], nil
+}
+
+func (e *testEnv) Score() float64 {
+	return e.score
+}
+
+func TestScript(t *testing.T) {
+	Convey("script:execute", t, func() {
+		env := &testEnv{
+			docs: map[string][]interface{}{
+				"likes": {10.0},
+				"tags":  {"a", "b"},
+			},
+			score: 2,
+		}
+		params := map[string]interface{}{"num_terms": 3.0, "factor": 1.5}
+
+		cases := map[string]float64{
+			"1 + 2 * 3":   7,
+			"(1 + 2) * 3": 9,
+			"-2 + 10 % 4": 0,
+			"Math.min(params.num_terms, doc['likes'].value)":        3,
+			"_score * params['factor']":                             3,
+			"doc['tags'].size() > 1 ? 1 : 0":                        1,
+			"doc['missing'].size() == 0 ? 5 : doc['missing'].value": 5,
+			"return Math.log10(100) * 2;":                           4,
+			"Math.pow(2, 10)":                                       1024,
+			"doc.likes.value / 4.0":                                 2.5,
+		}
+		for source, want := range cases {
+			s, err := Compile(source, params)
+			So(err, ShouldBeNil)
+			v, err := s.Number(env)
+			So(err, ShouldBeNil)
+			So(v, ShouldAlmostEqual, want)
+		}
+
+		s, err := Compile("doc['likes'].value + doc['tags'].size()", nil)
+		So(err, ShouldBeNil)
+		So(s.Fields(), ShouldHaveLength, 2)
+
+		s, err = Compile("doc['missing'].value", nil)
+		So(err, ShouldBeNil)
+		_, err = s.Number(env)
+		So(err, ShouldNotBeNil)
+
+		_, err = Compile("1 +", nil)
+		So(err, ShouldNotBeNil)
+
+		for _, source := range []string{"def x = 1; return x;", "1; 2", "for (int i = 0; i < 10; i++) {}", "while (true) {}"} {
+			_, err = Compile(source, nil)
+			So(err, ShouldNotBeNil)
+		}
+
+		s, err = New(map[string]interface{}{"source": "params.a + 1", "params": map[string]interface{}{"a": 1.0}})
+		So(err, ShouldBeNil)
+		v, err := s.Number(env)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 2)
+	})
+}
+
+func TestScriptLimits(t *testing.T) {
+	Convey("script:limits", t, func() {
+		source := strings.Repeat("(", maxDepth) + "1" + strings.Repeat(")", maxDepth)
+		_, err := Compile(source, nil)
+		So(err, ShouldNotBeNil)
+		So(err.(*errors.Error).CausedBy.Error(), ShouldContainSubstring, "nested")
+
+		source = strings.Repeat("-", maxDepth) + "1"
+		_, err = Compile(source, nil)
+		So(err, ShouldNotBeNil)
+
+		source = "1" + strings.Repeat(" + 1", maxSteps/2)
+		s, err := Compile(source, nil)
+		So(err, ShouldBeNil)
+		_, err = s.Number(nil)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "evaluation steps")
+
+		source = "1" + strings.Repeat(" + 1", maxSteps/4)
+		s, err = Compile(source, nil)
+		So(err, ShouldBeNil)
+		v, err := s.Number(nil)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, maxSteps/4+1)
+	})
+}
+
+func TestScoreFunctions(t *testing.T) {
+	Convey("script:score functions", t, func() {
+		So(DecayGauss(10, 0, 10, 0.5), ShouldAlmostEqual, 0.5)
+		So(DecayExp(10, 0, 10, 0.5), ShouldAlmostEqual, 0.5)
+		So(DecayLinear(10, 0, 10, 0.5), ShouldAlmostEqual, 0.5)
+		So(DecayLinear(-25, 5, 10, 0.5), ShouldEqual, 0)
+		So(DecayGauss(3, 5, 10, 0.5), ShouldEqual, 1)
+
+		env := &testEnv{docs: map[string][]interface{}{}}
+		cases := map[string]float64{
+			"saturation(4, 4)":                                       0.5,
+			"sigmoid(2, 2, 3)":                                       0.5,
+			"decayNumericLinear(10, 10, 0, 0.5, 20)":                 0.5,
+			"decayDateExp('2022-01-01', '10d', '0d', 0.5, params.d)": 0.5,
+			"decayGeoGauss('0,0', '1km', '0km', 0.5, '0,0')":         1,
+		}
+		for source, want := range cases {
+			s, err := Compile(source, map[string]interface{}{"d": mustParseDate("2022-01-11")})
+			So(err, ShouldBeNil)
+			v, err := s.Number(env)
+			So(err, ShouldBeNil)
+			So(v, ShouldAlmostEqual, want)
+		}
+	})
+}
+
+func mustParseDate(s string) interface{} {
+	t, err := ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
@@ -18,6 +18,7 @@ package startup
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
@@ -27,11 +28,18 @@ const (
 	DEFAULT_BATCH_SIZE             = 1024
 	DEFAULT_MAX_RESULTS            = 10000
 	DEFAULT_AGGREGATION_TERMS_SIZE = 1000
+	DEFAULT_MAX_DOCUMENT_SIZE      = 100 * 1024 * 1024 // bytes
+	DEFAULT_MAX_SCROLL_CONTEXTS    = 500
+	DEFAULT_MAX_DECOMPRESSED_SIZE  = 100 * 1024 * 1024 // bytes
 )
 
 var batchSize = DEFAULT_BATCH_SIZE
 var maxResults = DEFAULT_MAX_RESULTS
 var aggregationTermsSize = DEFAULT_AGGREGATION_TERMS_SIZE
+var maxDocumentSize = DEFAULT_MAX_DOCUMENT_SIZE
+var maxScrollContexts = DEFAULT_MAX_SCROLL_CONTEXTS
+var maxDecompressedSize = DEFAULT_MAX_DECOMPRESSED_SIZE
+var reindexRemoteWhitelist []string
 
 func init() {
 	err := godotenv.Load()
@@ -62,6 +70,34 @@ func init() {
 		}
 	}
 
+	vs = os.Getenv("ZINC_MAX_DOCUMENT_SIZE")
+	if vs != "" {
+		if vi, err = strconv.Atoi(vs); err == nil {
+			maxDocumentSize = vi
+		}
+	}
+
+	vs = os.Getenv("ZINC_MAX_SCROLL_CONTEXTS")
+	if vs != "" {
+		if vi, err = strconv.Atoi(vs); err == nil {
+			maxScrollContexts = vi
+		}
+	}
+
+	vs = os.Getenv("ZINC_MAX_DECOMPRESSED_SIZE")
+	if vs != "" {
+		if vi, err = strconv.Atoi(vs); err == nil {
+			maxDecompressedSize = vi
+		}
+	}
+
+	vs = os.Getenv("ZINC_REINDEX_REMOTE_WHITELIST")
+	for _, v := range strings.Split(vs, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			reindexRemoteWhitelist = append(reindexRemoteWhitelist, v)
+		}
+	}
+
 }
 
 func LoadBatchSize() int {
@@ -75,3 +111,21 @@ func LoadMaxResults() int {
 func LoadAggregationTermsSize() int {
 	return aggregationTermsSize
 }
+
+func LoadMaxDocumentSize() int {
+	return maxDocumentSize
+}
+
+func LoadMaxScrollContexts() int {
+	return maxScrollContexts
+}
+
+func LoadMaxDecompressedSize() int {
+	return maxDecompressedSize
+}
+
+// LoadReindexRemoteWhitelist returns the host:port patterns of the remote servers reindex can read from,
+// * matches any characters, such as: otherhost:4080, *.example.com:*
+func LoadReindexRemoteWhitelist() []string {
+	return reindexRemoteWhitelist
+}
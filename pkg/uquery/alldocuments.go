@@ -18,14 +18,15 @@ package uquery
 import (
 	"github.com/blugelabs/bluge"
 	v1 "github.com/zinclabs/zinc/pkg/meta/v1"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 )
 
-func AllDocuments(iQuery *v1.ZincQuery) (bluge.SearchRequest, error) {
+func AllDocuments(iQuery *v1.ZincQuery, mappings *meta.Mappings) (bluge.SearchRequest, error) {
 	dateQuery := bluge.NewDateRangeQuery(iQuery.Query.StartTime, iQuery.Query.EndTime).SetField("@timestamp")
 	allquery := bluge.NewMatchAllQuery()
 	query := bluge.NewBooleanQuery().AddMust(dateQuery).AddMust(allquery)
 
-	searchRequest := buildRequest(iQuery, query)
+	searchRequest := buildRequest(iQuery, query, mappings)
 
 	return searchRequest, nil
 
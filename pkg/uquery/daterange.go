@@ -18,13 +18,14 @@ package uquery
 import (
 	"github.com/blugelabs/bluge"
 	v1 "github.com/zinclabs/zinc/pkg/meta/v1"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 )
 
-func DateRangeQuery(iQuery *v1.ZincQuery) (bluge.SearchRequest, error) {
+func DateRangeQuery(iQuery *v1.ZincQuery, mappings *meta.Mappings) (bluge.SearchRequest, error) {
 	dateQuery := bluge.NewDateRangeQuery(iQuery.Query.StartTime, iQuery.Query.EndTime).SetField("@timestamp")
 	query := bluge.NewBooleanQuery().AddMust(dateQuery)
 
-	searchRequest := buildRequest(iQuery, query)
+	searchRequest := buildRequest(iQuery, query, mappings)
 
 	return searchRequest, nil
 }
@@ -19,9 +19,10 @@ import (
 	"github.com/blugelabs/bluge"
 	"github.com/blugelabs/bluge/analysis/analyzer"
 	v1 "github.com/zinclabs/zinc/pkg/meta/v1"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 )
 
-func MatchQuery(iQuery *v1.ZincQuery) (bluge.SearchRequest, error) {
+func MatchQuery(iQuery *v1.ZincQuery, mappings *meta.Mappings) (bluge.SearchRequest, error) {
 	dateQuery := bluge.NewDateRangeQuery(iQuery.Query.StartTime, iQuery.Query.EndTime).SetField("@timestamp")
 
 	var field string
@@ -34,7 +35,7 @@ func MatchQuery(iQuery *v1.ZincQuery) (bluge.SearchRequest, error) {
 	matchQuery := bluge.NewMatchQuery(iQuery.Query.Term).SetField(field).SetAnalyzer(analyzer.NewStandardAnalyzer())
 	query := bluge.NewBooleanQuery().AddMust(dateQuery).AddMust(matchQuery)
 
-	searchRequest := buildRequest(iQuery, query)
+	searchRequest := buildRequest(iQuery, query, mappings)
 
 	return searchRequest, nil
 }
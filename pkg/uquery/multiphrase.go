@@ -18,9 +18,10 @@ package uquery
 import (
 	"github.com/blugelabs/bluge"
 	v1 "github.com/zinclabs/zinc/pkg/meta/v1"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 )
 
-func MultiPhraseQuery(iQuery *v1.ZincQuery) (bluge.SearchRequest, error) {
+func MultiPhraseQuery(iQuery *v1.ZincQuery, mappings *meta.Mappings) (bluge.SearchRequest, error) {
 	dateQuery := bluge.NewDateRangeQuery(iQuery.Query.StartTime, iQuery.Query.EndTime).SetField("@timestamp")
 
 	var field string
@@ -33,7 +34,7 @@ func MultiPhraseQuery(iQuery *v1.ZincQuery) (bluge.SearchRequest, error) {
 	multiPhraseQuery := bluge.NewMultiPhraseQuery(iQuery.Query.Terms).SetField(field)
 	query := bluge.NewBooleanQuery().AddMust(dateQuery).AddMust(multiPhraseQuery)
 
-	searchRequest := buildRequest(iQuery, query)
+	searchRequest := buildRequest(iQuery, query, mappings)
 
 	return searchRequest, nil
 }
@@ -22,9 +22,10 @@ import (
 	"github.com/blugelabs/bluge/analysis/analyzer"
 	querystr "github.com/blugelabs/query_string"
 	v1 "github.com/zinclabs/zinc/pkg/meta/v1"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 )
 
-func QueryStringQuery(iQuery *v1.ZincQuery) (bluge.SearchRequest, error) {
+func QueryStringQuery(iQuery *v1.ZincQuery, mappings *meta.Mappings) (bluge.SearchRequest, error) {
 	options := querystr.DefaultOptions()
 	options.WithDefaultAnalyzer(analyzer.NewStandardAnalyzer())
 	userQuery, err := querystr.ParseQueryString(iQuery.Query.Term, options)
@@ -37,7 +38,7 @@ func QueryStringQuery(iQuery *v1.ZincQuery) (bluge.SearchRequest, error) {
 
 	// sortFields := []string{"-@timestamp"} // adding a - (minus) before the field name will sort the field in descending order
 
-	searchRequest := buildRequest(iQuery, finalQuery)
+	searchRequest := buildRequest(iQuery, finalQuery, mappings)
 
 	return searchRequest, nil
 
@@ -18,11 +18,14 @@ package uquery
 import (
 	"github.com/blugelabs/bluge"
 	v1 "github.com/zinclabs/zinc/pkg/meta/v1"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/query"
 )
 
-// buildRequest combines the ZincQuery with the bluge Query to create a SearchRequest
-func buildRequest(iQuery *v1.ZincQuery, query bluge.Query) bluge.SearchRequest {
-	return bluge.NewTopNSearch(iQuery.MaxResults, query).
+// buildRequest combines the ZincQuery with the bluge Query to create a SearchRequest,
+// the hidden documents of nested objects are not matched
+func buildRequest(iQuery *v1.ZincQuery, q bluge.Query, mappings *meta.Mappings) bluge.SearchRequest {
+	return bluge.NewTopNSearch(iQuery.MaxResults, query.RootQuery(q, mappings)).
 		SetFrom(iQuery.From).
 		SortBy(iQuery.SortFields).
 		WithStandardAggregations()
@@ -311,6 +311,28 @@ func Request(req zincaggregation.SearchAggregation, aggs map[string]meta.Aggrega
 				}
 			}
 			req.AddAggregation(name, subreq)
+		case agg.Nested != nil:
+			if prop, ok := mappings.Properties[agg.Nested.Path]; !ok || prop.Type != "nested" {
+				return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[nested] nested path [%s] is not nested", agg.Nested.Path))
+			}
+			subreq := zincaggregation.NewNestedAggregation(agg.Nested.Path, meta.NestedPathField, meta.NestedParentField)
+			if len(agg.Aggregations) > 0 {
+				if err := Request(subreq, agg.Aggregations, mappings); err != nil {
+					return err
+				}
+			}
+			req.AddAggregation(name, subreq)
+		case agg.ReverseNested != nil:
+			if agg.ReverseNested.Path != "" {
+				return errors.New(errors.ErrorTypeNotImplemented, "[reverse_nested] aggregation only support the root path")
+			}
+			subreq := zincaggregation.NewReverseNestedAggregation(meta.NestedParentField)
+			if len(agg.Aggregations) > 0 {
+				if err := Request(subreq, agg.Aggregations, mappings); err != nil {
+					return err
+				}
+			}
+			req.AddAggregation(name, subreq)
 		case agg.IPRange != nil:
 			return errors.New(errors.ErrorTypeNotImplemented, "[ip_range] aggregation doesn't support")
 		default:
@@ -326,6 +348,18 @@ func Response(bucket *search.Bucket) (map[string]meta.AggregationResponse, error
 	aggs := bucket.Aggregations()
 	for name, v := range aggs {
 		switch v := v.(type) {
+		case *zincaggregation.NestedCalculator:
+			docCount := v.DocCount()
+			aggResp := meta.AggregationResponse{DocCount: &docCount}
+			if v.Bucket() != nil {
+				subResp, err := Response(v.Bucket())
+				if err != nil {
+					return nil, err
+				}
+				delete(subResp, "count")
+				aggResp.Aggregations = subResp
+			}
+			resp[name] = aggResp
 		case search.MetricCalculator:
 			f := v.Value()
 			if math.IsNaN(f) {
@@ -16,25 +16,311 @@
 package highlight
 
 import (
+	"fmt"
+	"html"
+	"path"
+	"strings"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+	"github.com/blugelabs/bluge/search"
+	"github.com/blugelabs/bluge/search/highlight"
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/errors"
 	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	zincanalysis "github.com/zinclabs/zinc/pkg/uquery/v2/analysis"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/query"
+	"github.com/zinclabs/zinc/pkg/zutils/flatten"
+)
+
+const (
+	defaultNumberOfFragments = 3
+	defaultFragmentSize      = 100
+	defaultPreTag            = "<em>"
+	defaultPostTag           = "</em>"
 )
 
-func Request(highlight *meta.Highlight) error {
+// Request checks the highlight options and fills the options of the fields from the top level ones,
+// q is the search query, it is used unless a highlight_query is set
+func Request(highlight *meta.Highlight, q bluge.Query, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) error {
 	if len(highlight.Fields) == 0 {
 		return nil
 	}
 
-	if highlight.NumberOfFragments == 0 {
-		highlight.NumberOfFragments = 3
+	if highlight.NumberOfFragments == nil {
+		n := defaultNumberOfFragments
+		highlight.NumberOfFragments = &n
 	}
-	for _, field := range highlight.Fields {
-		if field.FragmentSize == 0 && highlight.FragmentSize > 0 {
-			field.FragmentSize = highlight.FragmentSize
+	if highlight.FragmentSize == 0 {
+		highlight.FragmentSize = defaultFragmentSize
+	}
+	if len(highlight.PreTags) == 0 {
+		highlight.PreTags = []string{defaultPreTag}
+	}
+	if len(highlight.PostTags) == 0 {
+		highlight.PostTags = []string{defaultPostTag}
+	}
+	if highlight.RequireFieldMatch == nil {
+		requireFieldMatch := true
+		highlight.RequireFieldMatch = &requireFieldMatch
+	}
+	highlight.Query = q
+	if err := requestOptions(highlight, mappings, analyzers); err != nil {
+		return err
+	}
+
+	for name, field := range highlight.Fields {
+		if field == nil {
+			field = new(meta.Highlight)
+			highlight.Fields[name] = field
 		}
-		if field.NumberOfFragments == 0 && highlight.NumberOfFragments > 0 {
+		if field.NumberOfFragments == nil {
 			field.NumberOfFragments = highlight.NumberOfFragments
 		}
+		if field.FragmentSize == 0 {
+			field.FragmentSize = highlight.FragmentSize
+		}
+		if len(field.PreTags) == 0 {
+			field.PreTags = highlight.PreTags
+		}
+		if len(field.PostTags) == 0 {
+			field.PostTags = highlight.PostTags
+		}
+		if field.Encoder == "" {
+			field.Encoder = highlight.Encoder
+		}
+		if field.RequireFieldMatch == nil {
+			field.RequireFieldMatch = highlight.RequireFieldMatch
+		}
+		field.Query = highlight.Query
+		if err := requestOptions(field, mappings, analyzers); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// Clone copies the highlight and the options of its fields, Request fills them in place
+func Clone(highlight *meta.Highlight) *meta.Highlight {
+	clone := *highlight
+	if highlight.Fields != nil {
+		clone.Fields = make(map[string]*meta.Highlight, len(highlight.Fields))
+		for name, field := range highlight.Fields {
+			if field != nil {
+				field = Clone(field)
+			}
+			clone.Fields[name] = field
+		}
+	}
+	return &clone
+}
+
+func requestOptions(highlight *meta.Highlight, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) error {
+	if *highlight.NumberOfFragments < 0 {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, "[highlight] number_of_fragments must be a positive number")
+	}
+	if highlight.FragmentSize < 0 {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, "[highlight] fragment_size must be a positive number")
+	}
+	if len(highlight.PreTags) != len(highlight.PostTags) {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, "[highlight] pre_tags and post_tags must have the same number of tags")
+	}
+	switch highlight.Encoder {
+	case "", "default", "html":
+	default:
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[highlight] unknown encoder [%s]", highlight.Encoder))
+	}
+	if highlight.HighlightQuery != nil {
+		q, err := query.Query(highlight.HighlightQuery, mappings, analyzers)
+		if err != nil {
+			return errors.New(errors.ErrorTypeXContentParseException, "[highlight_query] failed to parse field").Cause(err)
+		}
+		highlight.Query = q
+	}
+	return nil
+}
+
+// Response highlights the fields of the source, the fields are analyzed again to find the terms of the query
+func Response(highlight *meta.Highlight, source []byte, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) map[string]interface{} {
+	if len(highlight.Fields) == 0 || len(source) == 0 {
+		return nil
+	}
+
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal(source, &doc); err != nil {
+		return nil
+	}
+	flatDoc, err := flatten.Flatten(doc, "")
+	if err != nil {
+		return nil
+	}
+
+	rets := make(map[string]interface{})
+	for name, options := range highlight.Fields {
+		terms := queryTerms(options.Query)
+		if len(terms) == 0 {
+			continue
+		}
+		for _, field := range matchFields(name, mappings) {
+			if fragments := highlightField(options, field, flatDoc[field], terms, mappings, analyzers); len(fragments) > 0 {
+				rets[field] = fragments
+			}
+		}
+	}
+
+	return rets
+}
+
+// matchFields returns the text and keyword fields matching the name, the name can be a wildcard
+func matchFields(name string, mappings *meta.Mappings) []string {
+	if !strings.ContainsAny(name, "*?") {
+		if prop, ok := mappings.Properties[name]; ok && (prop.Type == "text" || prop.Type == "keyword") {
+			return []string{name}
+		}
+		return nil
+	}
+
+	var fields []string
+	for field, prop := range mappings.Properties {
+		if prop.Type != "text" && prop.Type != "keyword" {
+			continue
+		}
+		if ok, _ := path.Match(name, field); ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func highlightField(options *meta.Highlight, field string, value interface{}, terms []*queryTerm, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) []string {
+	var values []string
+	switch v := value.(type) {
+	case string:
+		values = append(values, v)
+	case []interface{}:
+		for _, v := range v {
+			if v, ok := v.(string); ok {
+				values = append(values, v)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	if *options.RequireFieldMatch {
+		fieldTerms := make([]*queryTerm, 0, len(terms))
+		for _, term := range terms {
+			if term.matchField(field) {
+				fieldTerms = append(fieldTerms, term)
+			}
+		}
+		terms = fieldTerms
+		if len(terms) == 0 {
+			return nil
+		}
+	}
+
+	zer := fieldAnalyzer(field, mappings, analyzers)
+	formatter := &fragmentFormatter{
+		preTags:  options.PreTags,
+		postTags: options.PostTags,
+		encode:   options.Encoder == "html",
+		tags:     make(map[string]int),
+	}
+	var fragmenter highlight.Fragmenter = highlight.NewSimpleFragmenterSized(options.FragmentSize)
+	num := *options.NumberOfFragments
+	if num == 0 {
+		fragmenter = wholeFragmenter{}
+	}
+	highlighter := highlight.NewSimpleHighlighter(fragmenter, formatter, "")
+
+	var fragments []string
+	for _, value := range values {
+		tlm := make(search.TermLocationMap)
+		pos := 0
+		for _, token := range zer.Analyze([]byte(value)) {
+			pos += token.PositionIncr
+			term := string(token.Term)
+			for i, t := range terms {
+				if t.match(term) {
+					tlm.AddLocation(term, &search.Location{Pos: pos, Start: token.Start, End: token.End})
+					if _, ok := formatter.tags[term]; !ok {
+						formatter.tags[term] = i
+					}
+					break
+				}
+			}
+		}
+		if len(tlm) == 0 {
+			continue
+		}
+		if num == 0 {
+			fragments = append(fragments, highlighter.BestFragments(tlm, []byte(value), 1)...)
+			continue
+		}
+		fragments = append(fragments, highlighter.BestFragments(tlm, []byte(value), num-len(fragments))...)
+		if len(fragments) >= num {
+			break
+		}
+	}
+	return fragments
+}
+
+// fieldAnalyzer returns the index analyzer of the field, keyword fields are not analyzed
+func fieldAnalyzer(field string, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) *analysis.Analyzer {
+	if mappings.Properties[field].Type == "keyword" {
+		zer, _ := zincanalysis.QueryAnalyzer(nil, "keyword")
+		return zer
+	}
+	zer, _ := zincanalysis.QueryAnalyzerForField(analyzers, mappings, field)
+	if zer == nil {
+		zer, _ = zincanalysis.QueryAnalyzer(nil, "standard")
+	}
+	return zer
+}
+
+// fragmentFormatter marks the terms with the tags of their query terms, the text is escaped by the html encoder
+type fragmentFormatter struct {
+	preTags  []string
+	postTags []string
+	encode   bool
+	tags     map[string]int
+}
+
+func (f *fragmentFormatter) Format(fragment *highlight.Fragment, orderedTermLocations highlight.TermLocations) string {
+	var sb strings.Builder
+	curr := fragment.Start
+	for _, termLocation := range orderedTermLocations {
+		if termLocation == nil || termLocation.Start < curr {
+			continue
+		}
+		if termLocation.End > fragment.End {
+			break
+		}
+		tag := f.tags[termLocation.Term] % len(f.preTags)
+		sb.WriteString(f.encodeText(fragment.Orig[curr:termLocation.Start]))
+		sb.WriteString(f.preTags[tag])
+		sb.WriteString(f.encodeText(fragment.Orig[termLocation.Start:termLocation.End]))
+		sb.WriteString(f.postTags[tag])
+		curr = termLocation.End
+	}
+	sb.WriteString(f.encodeText(fragment.Orig[curr:fragment.End]))
+	return sb.String()
+}
+
+func (f *fragmentFormatter) encodeText(text []byte) string {
+	if f.encode {
+		return html.EscapeString(string(text))
+	}
+	return string(text)
+}
+
+// wholeFragmenter returns the whole value as one fragment, it is used when number_of_fragments is 0
+type wholeFragmenter struct{}
+
+func (wholeFragmenter) Fragment(orig []byte, ot highlight.TermLocations) []*highlight.Fragment {
+	return []*highlight.Fragment{{Orig: orig, Start: 0, End: len(orig)}}
+}
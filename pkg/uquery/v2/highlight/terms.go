@@ -0,0 +1,156 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package highlight
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+
+	zincquery "github.com/zinclabs/zinc/pkg/bluge/query"
+	zincanalysis "github.com/zinclabs/zinc/pkg/uquery/v2/analysis"
+)
+
+// queryTerm is a term of the query, it matches the analyzed terms of a field
+type queryTerm struct {
+	field     string // empty matches any field
+	term      string
+	prefix    bool
+	fuzziness int
+	pattern   *regexp.Regexp
+}
+
+func (t *queryTerm) matchField(field string) bool {
+	return t.field == "" || t.field == "_all" || t.field == field
+}
+
+func (t *queryTerm) match(term string) bool {
+	switch {
+	case t.pattern != nil:
+		return t.pattern.MatchString(term)
+	case t.prefix:
+		return strings.HasPrefix(term, t.term)
+	case t.fuzziness > 0:
+		return levenshtein(t.term, term) <= t.fuzziness
+	default:
+		return t.term == term
+	}
+}
+
+// queryTerms returns the terms of the positive clauses of the query
+func queryTerms(q bluge.Query) []*queryTerm {
+	var terms []*queryTerm
+	switch q := q.(type) {
+	case *bluge.BooleanQuery:
+		for _, subq := range q.Musts() {
+			terms = append(terms, queryTerms(subq)...)
+		}
+		for _, subq := range q.Shoulds() {
+			terms = append(terms, queryTerms(subq)...)
+		}
+	case *bluge.TermQuery:
+		terms = append(terms, &queryTerm{field: q.Field(), term: q.Term()})
+	case *bluge.MatchQuery:
+		for _, term := range analyze(q.Analyzer(), q.Match()) {
+			terms = append(terms, &queryTerm{field: q.Field(), term: term, fuzziness: q.Fuzziness()})
+		}
+	case *bluge.MatchPhraseQuery:
+		for _, term := range analyze(q.Analyzer(), q.Phrase()) {
+			terms = append(terms, &queryTerm{field: q.Field(), term: term})
+		}
+	case *bluge.MultiPhraseQuery:
+		for _, position := range q.Terms() {
+			for _, term := range position {
+				terms = append(terms, &queryTerm{field: q.Field(), term: term})
+			}
+		}
+	case *bluge.PrefixQuery:
+		terms = append(terms, &queryTerm{field: q.Field(), term: q.Prefix(), prefix: true})
+	case *bluge.FuzzyQuery:
+		terms = append(terms, &queryTerm{field: q.Field(), term: q.Term(), fuzziness: q.Fuzziness()})
+	case *bluge.WildcardQuery:
+		pattern := regexp.QuoteMeta(q.Wildcard())
+		pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+		pattern = strings.ReplaceAll(pattern, `\?`, ".")
+		if re, err := regexp.Compile("^" + pattern + "$"); err == nil {
+			terms = append(terms, &queryTerm{field: q.Field(), pattern: re})
+		}
+	case *bluge.RegexpQuery:
+		if re, err := regexp.Compile("^(?:" + q.Regexp() + ")$"); err == nil {
+			terms = append(terms, &queryTerm{field: q.Field(), pattern: re})
+		}
+	case *zincquery.CombinedFieldsQuery:
+		for _, field := range q.Fields() {
+			for _, term := range q.Terms() {
+				terms = append(terms, &queryTerm{field: field, term: term})
+			}
+		}
+	case *zincquery.TermsSetQuery:
+		for _, term := range q.Terms() {
+			terms = append(terms, &queryTerm{field: q.Field(), term: term})
+		}
+	case *zincquery.BoostingQuery:
+		terms = append(terms, queryTerms(q.Positive())...)
+	case *zincquery.FunctionScoreQuery:
+		terms = append(terms, queryTerms(q.Query())...)
+	}
+	return terms
+}
+
+func analyze(zer *analysis.Analyzer, text string) []string {
+	if zer == nil {
+		zer, _ = zincanalysis.QueryAnalyzer(nil, "standard")
+	}
+	var terms []string
+	for _, token := range zer.Analyze([]byte(text)) {
+		terms = append(terms, string(token.Term))
+	}
+	return terms
+}
+
+// levenshtein returns the edit distance of the terms
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
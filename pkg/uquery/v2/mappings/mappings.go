@@ -51,8 +51,16 @@ func Request(analyzers map[string]*analysis.Analyzer, data map[string]interface{
 			if _, ok := v.(map[string]interface{}); !ok {
 				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[mappings] properties [%s] should be an object", field))
 			}
+			nested := false
+			if typ, ok := prop["type"].(string); ok && strings.ToLower(typ) == "nested" {
+				nested = true
+				mappings.Properties[field] = meta.NewProperty("nested")
+			}
 			if subMappings, err := Request(analyzers, prop); err == nil {
 				for k, v := range subMappings.Properties {
+					if nested && v.Type == "nested" {
+						return nil, errors.New(errors.ErrorTypeNotImplemented, fmt.Sprintf("[mappings] nested field [%s.%s] inside nested field [%s] doesn't support", field, k, field))
+					}
 					mappings.Properties[field+"."+k] = v
 				}
 			} else {
@@ -72,7 +80,7 @@ func Request(analyzers map[string]*analysis.Analyzer, data map[string]interface{
 		var newProp meta.Property
 		propTypeStr = strings.ToLower(propTypeStr)
 		switch propTypeStr {
-		case "text", "keyword", "numeric", "bool", "date":
+		case "text", "keyword", "numeric", "bool", "date", "geo_point", "nested", "completion":
 			newProp = meta.NewProperty(propTypeStr)
 		case "constant_keyword":
 			newProp = meta.NewProperty("keyword")
@@ -84,7 +92,7 @@ func Request(analyzers map[string]*analysis.Analyzer, data map[string]interface{
 			newProp = meta.NewProperty("bool")
 		case "time", "datetime":
 			newProp = meta.NewProperty("date")
-		case "flattened", "object", "nested", "wildcard", "byte", "alias", "geo_point", "ip", "ip_range", "scaled_float":
+		case "flattened", "object", "wildcard", "byte", "alias", "ip", "ip_range", "scaled_float":
 			// ignore
 		default:
 			return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[mappings] properties [%s] doesn't support type [%s]", field, propTypeStr))
@@ -16,10 +16,97 @@
 package query
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+
+	zincquery "github.com/zinclabs/zinc/pkg/bluge/query"
 	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 )
 
-func BoostingQuery(query map[string]interface{}) (bluge.Query, error) {
-	return nil, errors.New(errors.ErrorTypeNotImplemented, "[boosting] query doesn't support")
+func BoostingQuery(query map[string]interface{}, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) (bluge.Query, error) {
+	value := new(meta.BoostingQuery)
+	value.NegativeBoost = -1.0
+	value.Boost = -1.0
+	var positive, negative bluge.Query
+	var err error
+	for k, v := range query {
+		k := strings.ToLower(k)
+		switch k {
+		case "positive":
+			if positive, err = boostingClause(k, v, mappings, analyzers); err != nil {
+				return nil, err
+			}
+		case "negative":
+			if negative, err = boostingClause(k, v, mappings, analyzers); err != nil {
+				return nil, err
+			}
+		case "negative_boost":
+			vv, ok := v.(float64)
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[boosting] negative_boost doesn't support values of type: %T", v))
+			}
+			value.NegativeBoost = vv
+		case "boost":
+			vv, ok := v.(float64)
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[boosting] boost doesn't support values of type: %T", v))
+			}
+			value.Boost = vv
+		default:
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[boosting] unknown field [%s]", k))
+		}
+	}
+
+	if positive == nil {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[boosting] query requires 'positive' query to be set")
+	}
+	if negative == nil {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[boosting] query requires 'negative' query to be set")
+	}
+	if value.NegativeBoost < 0 {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[boosting] query requires 'negative_boost' to be set to be a positive value")
+	}
+
+	subq := zincquery.NewBoostingQuery(positive, negative, value.NegativeBoost)
+	if value.Boost >= 0 {
+		subq.SetBoost(value.Boost)
+	}
+	return subq, nil
+}
+
+// boostingClause parses the positive or negative clause, multiple queries are combined,
+// all of them must match for the positive clause and any of them for the negative clause
+func boostingClause(name string, v interface{}, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) (bluge.Query, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		subq, err := Query(v, mappings, analyzers)
+		if err != nil {
+			return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[%s] failed to parse field", name)).Cause(err)
+		}
+		return subq, nil
+	case []interface{}:
+		boolQuery := bluge.NewBooleanQuery()
+		for _, vv := range v {
+			vv, ok := vv.(map[string]interface{})
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[boosting] %s doesn't support values of type: %T", name, vv))
+			}
+			subq, err := Query(vv, mappings, analyzers)
+			if err != nil {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[%s] failed to parse field", name)).Cause(err)
+			}
+			if name == "positive" {
+				boolQuery.AddMust(subq)
+			} else {
+				boolQuery.AddShould(subq)
+			}
+		}
+		return boolQuery, nil
+	default:
+		return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[boosting] %s doesn't support values of type: %T", name, v))
+	}
 }
@@ -16,10 +16,207 @@
 package query
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
 	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+	"github.com/blugelabs/bluge/analysis/analyzer"
+
+	zincquery "github.com/zinclabs/zinc/pkg/bluge/query"
 	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	zincanalysis "github.com/zinclabs/zinc/pkg/uquery/v2/analysis"
 )
 
-func CombinedFieldsQuery(query map[string]interface{}) (bluge.Query, error) {
-	return nil, errors.New(errors.ErrorTypeNotImplemented, "[combined_fields] query doesn't support")
+func CombinedFieldsQuery(query map[string]interface{}, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) (bluge.Query, error) {
+	value := new(meta.CombinedFieldsQuery)
+	value.Boost = -1.0
+	for k, v := range query {
+		k := strings.ToLower(k)
+		switch k {
+		case "query":
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[combined_fields] query doesn't support values of type: %T", v))
+			}
+			value.Query = s
+		case "analyzer":
+			value.Analyzer, _ = v.(string)
+		case "fields":
+			vv, ok := v.([]interface{})
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[combined_fields] fields doesn't support values of type: %T", v))
+			}
+			for _, vvv := range vv {
+				field, ok := vvv.(string)
+				if !ok {
+					return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[combined_fields] fields doesn't support values of type: %T", vvv))
+				}
+				value.Fields = append(value.Fields, field)
+			}
+		case "operator":
+			value.Operator, _ = v.(string)
+		case "minimum_should_match":
+			value.MinimumShouldMatch = v
+		case "zero_terms_query":
+			value.ZeroTermsQuery, _ = v.(string)
+		case "auto_generate_synonyms_phrase_query":
+			// noop
+		case "boost":
+			value.Boost = v.(float64)
+		default:
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[combined_fields] unknown field [%s]", k))
+		}
+	}
+
+	if value.Query == "" {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[combined_fields] requires 'query' field")
+	}
+	if len(value.Fields) == 0 {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[combined_fields] requires 'fields' field")
+	}
+
+	var operator bluge.MatchQueryOperator = bluge.MatchQueryOperatorOr
+	switch strings.ToUpper(value.Operator) {
+	case "", "OR":
+	case "AND":
+		operator = bluge.MatchQueryOperatorAnd
+	default:
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[combined_fields] unknown operator %s", value.Operator))
+	}
+
+	fields := make([]string, 0, len(value.Fields))
+	weights := make([]float64, 0, len(value.Fields))
+	searchAnalyzer := ""
+	for i, field := range value.Fields {
+		weight := 1.0
+		if n := strings.LastIndex(field, "^"); n > 0 {
+			w, err := strconv.ParseFloat(field[n+1:], 64)
+			if err != nil {
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[combined_fields] invalid field weight [%s]", field))
+			}
+			if w < 1 {
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[combined_fields] requires field weights to be at least 1.0, but got [%s]", field))
+			}
+			field, weight = field[:n], w
+		}
+
+		// all the fields must be text and use the same search analyzer
+		fieldAnalyzer := ""
+		var prop meta.Property
+		var ok bool
+		if mappings != nil {
+			prop, ok = mappings.Properties[field]
+		}
+		if ok {
+			if prop.Type != "text" {
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("Field [%s] of type [%s] does not support [combined_fields] queries", field, prop.Type))
+			}
+			fieldAnalyzer = prop.Analyzer
+			if prop.SearchAnalyzer != "" {
+				fieldAnalyzer = prop.SearchAnalyzer
+			}
+		}
+		if i == 0 {
+			searchAnalyzer = fieldAnalyzer
+		} else if value.Analyzer == "" && fieldAnalyzer != searchAnalyzer {
+			return nil, errors.New(errors.ErrorTypeIllegalArgumentException, "All fields in [combined_fields] query must have the same search analyzer")
+		}
+		fields = append(fields, field)
+		weights = append(weights, weight)
+	}
+
+	if value.Analyzer != "" {
+		searchAnalyzer = value.Analyzer
+	}
+	var zer *analysis.Analyzer
+	if searchAnalyzer != "" {
+		var err error
+		if zer, err = zincanalysis.QueryAnalyzer(analyzers, searchAnalyzer); err != nil {
+			return nil, err
+		}
+	} else {
+		_, zer = zincanalysis.QueryAnalyzerForField(analyzers, mappings, fields[0])
+	}
+	if zer == nil {
+		zer = analyzer.NewStandardAnalyzer()
+	}
+
+	var terms []string
+	for _, token := range zer.Analyze([]byte(value.Query)) {
+		terms = append(terms, string(token.Term))
+	}
+	if len(terms) == 0 {
+		switch strings.ToLower(value.ZeroTermsQuery) {
+		case "", "none":
+			return bluge.NewMatchNoneQuery(), nil
+		case "all":
+			return bluge.NewMatchAllQuery(), nil
+		default:
+			return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[combined_fields] unknown zero_terms_query %s", value.ZeroTermsQuery))
+		}
+	}
+	subq := zincquery.NewCombinedFieldsQuery(terms).SetOperator(operator).SetLengthField(meta.FieldLengthField)
+	for i, field := range fields {
+		subq.AddField(field, weights[i])
+	}
+
+	if value.MinimumShouldMatch != nil {
+		minShould, err := minimumShouldMatch(value.MinimumShouldMatch, len(terms))
+		if err != nil {
+			return nil, err
+		}
+		subq.SetMinShould(minShould)
+	}
+	if value.Boost >= 0 {
+		subq.SetBoost(value.Boost)
+	}
+
+	return subq, nil
+}
+
+// minimumShouldMatch resolves the minimum_should_match value for the number of optional clauses,
+// it supports an integer, a negative integer, a percentage and a negative percentage
+func minimumShouldMatch(v interface{}, count int) (int, error) {
+	var s string
+	switch v := v.(type) {
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		s = strings.TrimSpace(v)
+	default:
+		return 0, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[minimum_should_match] doesn't support values of type: %T", v))
+	}
+
+	var n int
+	if strings.HasSuffix(s, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return 0, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[minimum_should_match] invalid value [%s]", s))
+		}
+		n = int(math.Trunc(float64(count) * percent / 100))
+		if percent < 0 {
+			n = count + n
+		}
+	} else {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[minimum_should_match] invalid value [%s]", s))
+		}
+		n = int(f)
+		if n < 0 {
+			n = count + n
+		}
+	}
+
+	if n < 0 {
+		n = 0
+	}
+	if n > count {
+		n = count
+	}
+	return n, nil
 }
@@ -0,0 +1,693 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+	"github.com/blugelabs/bluge/numeric/geo"
+
+	zincquery "github.com/zinclabs/zinc/pkg/bluge/query"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/script"
+	"github.com/zinclabs/zinc/pkg/zutils"
+	zincgeo "github.com/zinclabs/zinc/pkg/zutils/geo"
+)
+
+func FunctionScoreQuery(query map[string]interface{}, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) (bluge.Query, error) {
+	value := new(meta.FunctionScoreQuery)
+	value.MaxBoost = -1.0
+	value.MinScore = -1.0
+	value.Boost = -1.0
+	var subq bluge.Query
+	var clauses []*zincquery.FunctionClause
+	single := &zincquery.FunctionClause{Weight: 1.0}
+	hasSingle := false
+	var err error
+	for k, v := range query {
+		k := strings.ToLower(k)
+		switch k {
+		case "query":
+			vv, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[function_score] query doesn't support values of type: %T", v))
+			}
+			if subq, err = Query(vv, mappings, analyzers); err != nil {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, "[query] failed to parse field").Cause(err)
+			}
+		case "functions":
+			vv, ok := v.([]interface{})
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[function_score] functions doesn't support values of type: %T", v))
+			}
+			for _, vvv := range vv {
+				function, ok := vvv.(map[string]interface{})
+				if !ok {
+					return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[function_score] functions doesn't support values of type: %T", vvv))
+				}
+				clause, err := functionClause(function, mappings, analyzers)
+				if err != nil {
+					return nil, err
+				}
+				clauses = append(clauses, clause)
+			}
+		case "score_mode":
+			value.ScoreMode, _ = v.(string)
+			switch value.ScoreMode {
+			case "multiply", "sum", "avg", "first", "max", "min":
+			default:
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[function_score] illegal score_mode [%v]", v))
+			}
+		case "boost_mode":
+			value.BoostMode, _ = v.(string)
+			switch value.BoostMode {
+			case "multiply", "replace", "sum", "avg", "max", "min":
+			default:
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[function_score] illegal boost_mode [%v]", v))
+			}
+		case "max_boost", "min_score", "boost", "weight":
+			vv, ok := v.(float64)
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[function_score] %s doesn't support values of type: %T", k, v))
+			}
+			switch k {
+			case "max_boost":
+				value.MaxBoost = vv
+			case "min_score":
+				value.MinScore = vv
+			case "boost":
+				value.Boost = vv
+			default:
+				single.Weight = vv
+				hasSingle = true
+			}
+		default:
+			function, ok, err := scoreFunction(k, v, mappings)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[function_score] unknown field [%s]", k))
+			}
+			if single.Function != nil {
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[function_score] failed to parse function, only one function is allowed but found [%s]", k))
+			}
+			single.Function = function
+			hasSingle = true
+		}
+	}
+
+	if hasSingle {
+		if len(clauses) > 0 {
+			return nil, errors.New(errors.ErrorTypeParsingException, "[function_score] already found [functions] array, now encountering a single function")
+		}
+		clauses = append(clauses, single)
+	}
+	if subq == nil {
+		subq = bluge.NewMatchAllQuery()
+	}
+
+	q := zincquery.NewFunctionScoreQuery(subq)
+	for _, clause := range clauses {
+		q.AddFunction(clause)
+	}
+	if value.ScoreMode != "" {
+		q.SetScoreMode(value.ScoreMode)
+	}
+	if value.BoostMode != "" {
+		q.SetBoostMode(value.BoostMode)
+	}
+	if value.MaxBoost >= 0 {
+		q.SetMaxBoost(value.MaxBoost)
+	}
+	if value.MinScore >= 0 {
+		q.SetMinScore(value.MinScore)
+	}
+	if value.Boost >= 0 {
+		q.SetBoost(value.Boost)
+	}
+	return q, nil
+}
+
+func ScriptScoreQuery(query map[string]interface{}, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) (bluge.Query, error) {
+	value := new(meta.ScriptScoreQuery)
+	value.MinScore = -1.0
+	value.Boost = -1.0
+	var subq bluge.Query
+	var err error
+	for k, v := range query {
+		k := strings.ToLower(k)
+		switch k {
+		case "query":
+			vv, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[script_score] query doesn't support values of type: %T", v))
+			}
+			if subq, err = Query(vv, mappings, analyzers); err != nil {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, "[query] failed to parse field").Cause(err)
+			}
+		case "script":
+			value.Script = v
+		case "min_score", "boost":
+			vv, ok := v.(float64)
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[script_score] %s doesn't support values of type: %T", k, v))
+			}
+			if k == "min_score" {
+				value.MinScore = vv
+			} else {
+				value.Boost = vv
+			}
+		default:
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[script_score] unknown field [%s]", k))
+		}
+	}
+
+	if subq == nil {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[script_score] must specify a query")
+	}
+	if value.Script == nil {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[script_score] must specify a script")
+	}
+	s, err := script.New(value.Script)
+	if err != nil {
+		return nil, err
+	}
+
+	q := zincquery.NewFunctionScoreQuery(subq).SetBoostMode("replace")
+	q.AddFunction(&zincquery.FunctionClause{Function: &scriptScoreFunction{Script: s, mappings: mappings}, Weight: 1.0})
+	if value.MinScore >= 0 {
+		q.SetMinScore(value.MinScore)
+	}
+	if value.Boost >= 0 {
+		q.SetBoost(value.Boost)
+	}
+	return q, nil
+}
+
+// functionClause parses a function of the functions array, it has an optional filter and weight
+func functionClause(function map[string]interface{}, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) (*zincquery.FunctionClause, error) {
+	clause := &zincquery.FunctionClause{Weight: 1.0}
+	for k, v := range function {
+		k := strings.ToLower(k)
+		switch k {
+		case "filter":
+			vv, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[function_score] filter doesn't support values of type: %T", v))
+			}
+			filter, err := Query(vv, mappings, analyzers)
+			if err != nil {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, "[filter] failed to parse field").Cause(err)
+			}
+			clause.Filter = filter
+		case "weight":
+			vv, ok := v.(float64)
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[function_score] weight doesn't support values of type: %T", v))
+			}
+			clause.Weight = vv
+		default:
+			fn, ok, err := scoreFunction(k, v, mappings)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[function_score] unknown field [%s]", k))
+			}
+			if clause.Function != nil {
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[function_score] failed to parse function, only one function is allowed but found [%s]", k))
+			}
+			clause.Function = fn
+		}
+	}
+	return clause, nil
+}
+
+// scoreFunction parses the score function by name, it reports false if the name isn't a score function
+func scoreFunction(name string, v interface{}, mappings *meta.Mappings) (zincquery.ScoreFunction, bool, error) {
+	var fn zincquery.ScoreFunction
+	var err error
+	switch name {
+	case "field_value_factor":
+		fn, err = fieldValueFactor(v, mappings)
+	case "gauss", "linear", "exp":
+		fn, err = decayFunction(name, v, mappings)
+	case "random_score":
+		fn, err = randomScore(v)
+	case "script_score":
+		fn, err = scriptScore(v, mappings)
+	default:
+		return nil, false, nil
+	}
+	return fn, true, err
+}
+
+// fieldValueFactorFunction scores by the value of a numeric field: modifier(factor * value)
+type fieldValueFactorFunction struct {
+	field    string
+	factor   float64
+	modifier string
+	missing  *float64
+	mappings *meta.Mappings
+}
+
+func fieldValueFactor(v interface{}, mappings *meta.Mappings) (*fieldValueFactorFunction, error) {
+	options, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[field_value_factor] doesn't support values of type: %T", v))
+	}
+	fn := &fieldValueFactorFunction{factor: 1.0, modifier: "none", mappings: mappings}
+	for k, v := range options {
+		k := strings.ToLower(k)
+		switch k {
+		case "field":
+			fn.field, _ = v.(string)
+		case "factor":
+			vv, ok := v.(float64)
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[field_value_factor] factor doesn't support values of type: %T", v))
+			}
+			fn.factor = vv
+		case "modifier":
+			fn.modifier, _ = v.(string)
+			switch fn.modifier {
+			case "none", "log", "log1p", "log2p", "ln", "ln1p", "ln2p", "square", "sqrt", "reciprocal":
+			default:
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[field_value_factor] illegal modifier [%v]", v))
+			}
+		case "missing":
+			vv, ok := v.(float64)
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[field_value_factor] missing doesn't support values of type: %T", v))
+			}
+			fn.missing = &vv
+		default:
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[field_value_factor] unknown field [%s]", k))
+		}
+	}
+
+	if fn.field == "" {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[field_value_factor] required field 'field' missing")
+	}
+	prop, ok := mappings.Properties[fn.field]
+	if !ok && fn.missing == nil {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[field_value_factor] unable to find a field mapper for field [%s]. No 'missing' value defined.", fn.field))
+	}
+	if ok && prop.Type != "numeric" {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[field_value_factor] field [%s] of type [%s] is not supported, it must be numeric", fn.field, prop.Type))
+	}
+	return fn, nil
+}
+
+func (f *fieldValueFactorFunction) Fields() []string {
+	return []string{f.field}
+}
+
+func (f *fieldValueFactorFunction) Score(score float64, values map[string][][]byte) (float64, error) {
+	var value float64
+	var docValues []interface{}
+	if _, ok := f.mappings.Properties[f.field]; ok {
+		var err error
+		if docValues, err = (&scriptEnv{values: values, mappings: f.mappings}).Doc(f.field); err != nil {
+			return 0, err
+		}
+	}
+	switch {
+	case len(docValues) > 0:
+		value = docValues[0].(float64)
+	case f.missing != nil:
+		value = *f.missing
+	default:
+		return 0, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("missing value for field [%s]", f.field))
+	}
+
+	v := f.factor * value
+	switch f.modifier {
+	case "log":
+		v = math.Log10(v)
+	case "log1p":
+		v = math.Log10(v + 1)
+	case "log2p":
+		v = math.Log10(v + 2)
+	case "ln":
+		v = math.Log(v)
+	case "ln1p":
+		v = math.Log1p(v)
+	case "ln2p":
+		v = math.Log(v + 2)
+	case "square":
+		v = v * v
+	case "sqrt":
+		v = math.Sqrt(v)
+	case "reciprocal":
+		v = 1 / v
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+		return 0, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("field value function must not produce negative scores, but got: [%v] for field value: [%v]", v, value))
+	}
+	return v, nil
+}
+
+// decayDistanceFunction scores by the distance of the field values to the origin
+type decayDistanceFunction struct {
+	field    string
+	fieldTyp string
+	decay    script.DecayFunction
+	origin   float64
+	point    geo.Point // the origin of geo_point fields
+	scale    float64   // milliseconds for dates and meters for geo_point fields
+	offset   float64
+	decayVal float64
+	mode     string
+	mappings *meta.Mappings
+}
+
+func decayFunction(name string, v interface{}, mappings *meta.Mappings) (*decayDistanceFunction, error) {
+	options, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[%s] doesn't support values of type: %T", name, v))
+	}
+	fn := &decayDistanceFunction{decay: script.Decays[name], decayVal: 0.5, mode: "min", mappings: mappings}
+	var params map[string]interface{}
+	for k, v := range options {
+		if strings.ToLower(k) == "multi_value_mode" {
+			fn.mode, _ = v.(string)
+			switch fn.mode {
+			case "min", "max", "avg", "sum":
+			default:
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[%s] illegal multi_value_mode [%v]", name, v))
+			}
+			continue
+		}
+		if fn.field != "" {
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] query doesn't support multiple fields, found [%s] and [%s]", name, fn.field, k))
+		}
+		vv, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[%s] %s doesn't support values of type: %T", name, k, v))
+		}
+		fn.field = k
+		params = vv
+	}
+	if fn.field == "" {
+		return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] requires a field", name))
+	}
+	prop, ok := mappings.Properties[fn.field]
+	if !ok {
+		return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] unknown field [%s]", name, fn.field))
+	}
+	fn.fieldTyp = prop.Type
+
+	var origin, scale, offset interface{}
+	for k, v := range params {
+		k := strings.ToLower(k)
+		switch k {
+		case "origin":
+			origin = v
+		case "scale":
+			scale = v
+		case "offset":
+			offset = v
+		case "decay":
+			vv, ok := v.(float64)
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[%s] decay doesn't support values of type: %T", name, v))
+			}
+			fn.decayVal = vv
+		default:
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] unknown field [%s]", name, k))
+		}
+	}
+	if fn.decayVal <= 0 || fn.decayVal >= 1 {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[%s] decay must be in the range (0..1), got [%v]", name, fn.decayVal))
+	}
+	if scale == nil {
+		return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] must have a scale for field [%s]", name, fn.field))
+	}
+
+	var err error
+	switch fn.fieldTyp {
+	case "numeric":
+		if origin == nil {
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] must have an origin for field [%s]", name, fn.field))
+		}
+		var ok1, ok2, ok3 bool
+		fn.origin, ok1 = origin.(float64)
+		fn.scale, ok2 = scale.(float64)
+		ok3 = true
+		if offset != nil {
+			fn.offset, ok3 = offset.(float64)
+		}
+		if !ok1 || !ok2 || !ok3 {
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] origin, scale and offset must be numbers for field [%s]", name, fn.field))
+		}
+	case "date", "time":
+		t := time.Now()
+		if origin != nil {
+			if t, err = decayDate(origin, prop.Format); err != nil {
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] failed to parse origin [%v] for field [%s]", name, origin, fn.field)).Cause(err)
+			}
+		}
+		fn.origin = float64(t.UnixMilli())
+		if fn.scale, err = decayDuration(scale); err != nil {
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] failed to parse scale [%v] for field [%s]", name, scale, fn.field)).Cause(err)
+		}
+		if offset != nil {
+			if fn.offset, err = decayDuration(offset); err != nil {
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] failed to parse offset [%v] for field [%s]", name, offset, fn.field)).Cause(err)
+			}
+		}
+	case "geo_point":
+		if origin == nil {
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] must have an origin for field [%s]", name, fn.field))
+		}
+		if fn.point, err = zincgeo.Parse(origin); err != nil {
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] failed to parse origin [%v] for field [%s]", name, origin, fn.field)).Cause(err)
+		}
+		if fn.scale, err = decayDistance(scale); err != nil {
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] failed to parse scale [%v] for field [%s]", name, scale, fn.field)).Cause(err)
+		}
+		if offset != nil {
+			if fn.offset, err = decayDistance(offset); err != nil {
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] failed to parse offset [%v] for field [%s]", name, offset, fn.field)).Cause(err)
+			}
+		}
+	default:
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[%s] field [%s] of type [%s] is not supported, it must be numeric, date or geo_point", name, fn.field, fn.fieldTyp))
+	}
+	if fn.scale <= 0 {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[%s] scale must be greater than 0 for field [%s]", name, fn.field))
+	}
+	if fn.offset < 0 {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[%s] offset must not be negative for field [%s]", name, fn.field))
+	}
+	return fn, nil
+}
+
+// decayDate parses the origin of a date field, it is epoch_millis or a date in the format of the field
+func decayDate(v interface{}, format string) (time.Time, error) {
+	switch v := v.(type) {
+	case float64:
+		return time.UnixMilli(int64(v)), nil
+	case string:
+		if v == "now" {
+			return time.Now(), nil
+		}
+		if format != "" && format != "epoch_millis" {
+			if t, err := time.Parse(format, v); err == nil {
+				return t, nil
+			}
+		}
+		return script.ParseDate(v)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// decayDuration parses the scale or offset of a date field in milliseconds
+func decayDuration(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		d, err := zutils.ParseDuration(v)
+		if err != nil {
+			return 0, err
+		}
+		return float64(d.Milliseconds()), nil
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// decayDistance parses the scale or offset of a geo_point field in meters
+func decayDistance(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return geo.ParseDistance(v)
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func (f *decayDistanceFunction) Fields() []string {
+	return []string{f.field}
+}
+
+func (f *decayDistanceFunction) Score(score float64, values map[string][][]byte) (float64, error) {
+	docValues, err := (&scriptEnv{values: values, mappings: f.mappings}).Doc(f.field)
+	if err != nil {
+		return 0, err
+	}
+	if len(docValues) == 0 {
+		return 1.0, nil
+	}
+
+	distance := 0.0
+	for i, v := range docValues {
+		var d float64
+		switch v := v.(type) {
+		case float64:
+			d = math.Abs(v - f.origin)
+		case time.Time:
+			d = math.Abs(float64(v.UnixMilli()) - f.origin)
+		case map[string]interface{}:
+			d = script.GeoDistance(f.point, geo.Point{Lat: v["lat"].(float64), Lon: v["lon"].(float64)})
+		}
+		switch {
+		case i == 0:
+			distance = d
+		case f.mode == "min":
+			distance = math.Min(distance, d)
+		case f.mode == "max":
+			distance = math.Max(distance, d)
+		default:
+			distance += d
+		}
+	}
+	if f.mode == "avg" {
+		distance /= float64(len(docValues))
+	}
+	return f.decay(distance, f.offset, f.scale, f.decayVal), nil
+}
+
+// randomScoreFunction scores randomly in [0, 1), the scores are reproducible by the seed and the field value
+type randomScoreFunction struct {
+	seed  string
+	field string
+}
+
+func randomScore(v interface{}) (*randomScoreFunction, error) {
+	options, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[random_score] doesn't support values of type: %T", v))
+	}
+	fn := new(randomScoreFunction)
+	for k, v := range options {
+		k := strings.ToLower(k)
+		switch k {
+		case "seed":
+			switch v := v.(type) {
+			case float64:
+				fn.seed = fmt.Sprintf("%d", int64(v))
+			case string:
+				fn.seed = v
+			default:
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[random_score] seed doesn't support values of type: %T", v))
+			}
+		case "field":
+			fn.field, _ = v.(string)
+		default:
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[random_score] unknown field [%s]", k))
+		}
+	}
+	if fn.seed != "" && fn.field == "" {
+		fn.field = "_id"
+	}
+	return fn, nil
+}
+
+func (f *randomScoreFunction) Fields() []string {
+	if f.seed == "" {
+		return nil
+	}
+	return []string{f.field}
+}
+
+func (f *randomScoreFunction) Score(score float64, values map[string][][]byte) (float64, error) {
+	if f.seed == "" {
+		return rand.Float64(), nil
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(f.seed))
+	for _, term := range values[f.field] {
+		_, _ = h.Write(term)
+	}
+	return float64(h.Sum64()>>11) / (1 << 53), nil
+}
+
+// scriptScoreFunction scores by a script, _score is the score of the query
+type scriptScoreFunction struct {
+	*script.Script
+	mappings *meta.Mappings
+}
+
+func scriptScore(v interface{}, mappings *meta.Mappings) (*scriptScoreFunction, error) {
+	options, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[script_score] doesn't support values of type: %T", v))
+	}
+	var source interface{}
+	for k, v := range options {
+		k := strings.ToLower(k)
+		switch k {
+		case "script":
+			source = v
+		default:
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[script_score] unknown field [%s]", k))
+		}
+	}
+	if source == nil {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[script_score] must specify a script")
+	}
+	s, err := script.New(source)
+	if err != nil {
+		return nil, err
+	}
+	return &scriptScoreFunction{Script: s, mappings: mappings}, nil
+}
+
+func (f *scriptScoreFunction) Score(score float64, values map[string][][]byte) (float64, error) {
+	v, err := f.Number(&scriptEnv{values: values, mappings: f.mappings, score: score})
+	if err != nil {
+		return 0, errors.New(errors.ErrorTypeScriptException, "runtime error").Cause(err)
+	}
+	if v < 0 || math.IsNaN(v) {
+		return 0, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("script score function must not produce negative scores, but got: [%v]", v))
+	}
+	return v, nil
+}
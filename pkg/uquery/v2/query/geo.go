@@ -16,22 +16,294 @@
 package query
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/numeric/geo"
+
 	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	zincgeo "github.com/zinclabs/zinc/pkg/zutils/geo"
 )
 
-func GeoBoundingBoxQuery(query map[string]interface{}) (bluge.Query, error) {
-	return nil, errors.New(errors.ErrorTypeNotImplemented, "[geo_bounding_box] query doesn't support")
+func GeoBoundingBoxQuery(query map[string]interface{}, mappings *meta.Mappings) (bluge.Query, error) {
+	field := ""
+	var box map[string]interface{}
+	boost := -1.0
+	validate := true
+	ignoreUnmapped := false
+	for k, v := range query {
+		switch strings.ToLower(k) {
+		case "boost":
+			boost = v.(float64)
+		case "validation_method":
+			validate = geoValidate(v)
+		case "ignore_unmapped":
+			ignoreUnmapped, _ = v.(bool)
+		case "type", "_name":
+			// noop
+		default:
+			if field != "" {
+				return nil, errors.New(errors.ErrorTypeParsingException, "[geo_bounding_box] query doesn't support multiple fields")
+			}
+			vv, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[geo_bounding_box] doesn't support values of type: %T", v))
+			}
+			field, box = k, vv
+		}
+	}
+	if field == "" {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[geo_bounding_box] requires a field")
+	}
+	if q, err := geoField("geo_bounding_box", field, mappings, ignoreUnmapped); q != nil || err != nil {
+		return q, err
+	}
+
+	var top, left, bottom, right *float64
+	setPoint := func(v interface{}, lat, lon **float64) error {
+		point, err := zincgeo.Parse(v)
+		if err != nil {
+			return err
+		}
+		*lat, *lon = &point.Lat, &point.Lon
+		return nil
+	}
+	setValue := func(v interface{}, value **float64) error {
+		f, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("[geo_bounding_box] doesn't support values of type: %T", v)
+		}
+		*value = &f
+		return nil
+	}
+	for k, v := range box {
+		var err error
+		switch strings.ToLower(k) {
+		case "top_left":
+			err = setPoint(v, &top, &left)
+		case "bottom_right":
+			err = setPoint(v, &bottom, &right)
+		case "top_right":
+			err = setPoint(v, &top, &right)
+		case "bottom_left":
+			err = setPoint(v, &bottom, &left)
+		case "top":
+			err = setValue(v, &top)
+		case "left":
+			err = setValue(v, &left)
+		case "bottom":
+			err = setValue(v, &bottom)
+		case "right":
+			err = setValue(v, &right)
+		case "wkt":
+			s, _ := v.(string)
+			top, left, bottom, right, err = parseBBox(s)
+		default:
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[geo_bounding_box] unknown field [%s]", k))
+		}
+		if err != nil {
+			return nil, errors.New(errors.ErrorTypeParsingException, "[geo_bounding_box] failed to parse bounding box").Cause(err)
+		}
+	}
+	if top == nil || left == nil || bottom == nil || right == nil {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[geo_bounding_box] requires top, left, bottom and right of the bounding box")
+	}
+	if validate {
+		for _, point := range []geo.Point{{Lon: *left, Lat: *top}, {Lon: *right, Lat: *bottom}} {
+			if err := zincgeo.Validate(point); err != nil {
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[geo_bounding_box] %s", err.Error()))
+			}
+		}
+		if *top < *bottom {
+			return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[geo_bounding_box] top is below bottom corner: %v vs. %v", *top, *bottom))
+		}
+	}
+
+	subq := bluge.NewGeoBoundingBoxQuery(*left, *top, *right, *bottom).SetField(field)
+	if boost >= 0 {
+		subq.SetBoost(boost)
+	}
+	return subq, nil
 }
 
-func GeoDistanceQuery(query map[string]interface{}) (bluge.Query, error) {
-	return nil, errors.New(errors.ErrorTypeNotImplemented, "[geo_distance] query doesn't support")
+func GeoDistanceQuery(query map[string]interface{}, mappings *meta.Mappings) (bluge.Query, error) {
+	field := ""
+	var point geo.Point
+	distance := ""
+	boost := -1.0
+	validate := true
+	ignoreUnmapped := false
+	for k, v := range query {
+		switch strings.ToLower(k) {
+		case "distance":
+			switch v := v.(type) {
+			case string:
+				distance = v
+			case float64:
+				distance = strconv.FormatFloat(v, 'f', -1, 64) + "m"
+			default:
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[geo_distance] distance doesn't support values of type: %T", v))
+			}
+		case "boost":
+			boost = v.(float64)
+		case "validation_method":
+			validate = geoValidate(v)
+		case "ignore_unmapped":
+			ignoreUnmapped, _ = v.(bool)
+		case "distance_type", "_name":
+			// noop
+		default:
+			if field != "" {
+				return nil, errors.New(errors.ErrorTypeParsingException, "[geo_distance] query doesn't support multiple fields")
+			}
+			var err error
+			if point, err = zincgeo.Parse(v); err != nil {
+				return nil, errors.New(errors.ErrorTypeParsingException, "[geo_distance] failed to parse point").Cause(err)
+			}
+			field = k
+		}
+	}
+	if field == "" {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[geo_distance] requires a field")
+	}
+	if distance == "" {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[geo_distance] requires 'distance' to be specified")
+	}
+	if _, err := geo.ParseDistance(distance); err != nil {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[geo_distance] failed to parse distance [%s]", distance))
+	}
+	if q, err := geoField("geo_distance", field, mappings, ignoreUnmapped); q != nil || err != nil {
+		return q, err
+	}
+	if validate {
+		if err := zincgeo.Validate(point); err != nil {
+			return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[geo_distance] %s", err.Error()))
+		}
+	}
+
+	subq := bluge.NewGeoDistanceQuery(point.Lon, point.Lat, distance).SetField(field)
+	if boost >= 0 {
+		subq.SetBoost(boost)
+	}
+	return subq, nil
 }
 
-func GeoPolygonQuery(query map[string]interface{}) (bluge.Query, error) {
-	return nil, errors.New(errors.ErrorTypeNotImplemented, "[geo_polygon] query doesn't support")
+func GeoPolygonQuery(query map[string]interface{}, mappings *meta.Mappings) (bluge.Query, error) {
+	field := ""
+	var points []geo.Point
+	boost := -1.0
+	validate := true
+	ignoreUnmapped := false
+	for k, v := range query {
+		switch strings.ToLower(k) {
+		case "boost":
+			boost = v.(float64)
+		case "validation_method":
+			validate = geoValidate(v)
+		case "ignore_unmapped":
+			ignoreUnmapped, _ = v.(bool)
+		case "_name":
+			// noop
+		default:
+			if field != "" {
+				return nil, errors.New(errors.ErrorTypeParsingException, "[geo_polygon] query doesn't support multiple fields")
+			}
+			field = k
+			vv, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[geo_polygon] doesn't support values of type: %T", v))
+			}
+			for kk, vv := range vv {
+				if strings.ToLower(kk) != "points" {
+					return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[geo_polygon] unknown field [%s]", kk))
+				}
+				values, ok := vv.([]interface{})
+				if !ok {
+					return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[geo_polygon] points doesn't support values of type: %T", vv))
+				}
+				for _, value := range values {
+					point, err := zincgeo.Parse(value)
+					if err != nil {
+						return nil, errors.New(errors.ErrorTypeParsingException, "[geo_polygon] failed to parse point").Cause(err)
+					}
+					points = append(points, point)
+				}
+			}
+		}
+	}
+	if field == "" {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[geo_polygon] requires a field")
+	}
+	if len(points) < 3 {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[geo_polygon] too few points defined for geo_polygon query")
+	}
+	if q, err := geoField("geo_polygon", field, mappings, ignoreUnmapped); q != nil || err != nil {
+		return q, err
+	}
+	if validate {
+		for _, point := range points {
+			if err := zincgeo.Validate(point); err != nil {
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[geo_polygon] %s", err.Error()))
+			}
+		}
+	}
+
+	subq := bluge.NewGeoBoundingPolygonQuery(points).SetField(field)
+	if boost >= 0 {
+		subq.SetBoost(boost)
+	}
+	return subq, nil
 }
 
 func GeoShapeQuery(query map[string]interface{}) (bluge.Query, error) {
 	return nil, errors.New(errors.ErrorTypeNotImplemented, "[geo_shape] query doesn't support")
 }
+
+// geoField checks the field is a geo_point, an unmapped field matches nothing if ignore_unmapped is set
+func geoField(name, field string, mappings *meta.Mappings, ignoreUnmapped bool) (bluge.Query, error) {
+	var prop meta.Property
+	var ok bool
+	if mappings != nil {
+		prop, ok = mappings.Properties[field]
+	}
+	if !ok {
+		if ignoreUnmapped {
+			return bluge.NewMatchNoneQuery(), nil
+		}
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[%s] failed to find geo field [%s]", name, field))
+	}
+	if prop.Type != "geo_point" {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[%s] field [%s] is of type [%s], but only geo_point fields are supported", name, field, prop.Type))
+	}
+	return nil, nil
+}
+
+// geoValidate reports if the points must be validated, only strict (default) validates
+func geoValidate(v interface{}) bool {
+	method, _ := v.(string)
+	method = strings.ToLower(method)
+	return method != "ignore_malformed" && method != "coerce"
+}
+
+// parseBBox parses a WKT "BBOX (minLon, maxLon, maxLat, minLat)"
+func parseBBox(s string) (top, left, bottom, right *float64, err error) {
+	start := strings.Index(s, "(")
+	end := strings.LastIndex(s, ")")
+	if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(s)), "BBOX") || start < 0 || end < start {
+		return nil, nil, nil, nil, fmt.Errorf("failed to parse WKT bounding box [%s]", s)
+	}
+	parts := strings.Split(s[start+1:end], ",")
+	if len(parts) != 4 {
+		return nil, nil, nil, nil, fmt.Errorf("failed to parse WKT bounding box [%s]", s)
+	}
+	values := make([]float64, 4)
+	for i, part := range parts {
+		if values[i], err = strconv.ParseFloat(strings.TrimSpace(part), 64); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("failed to parse WKT bounding box [%s]", s)
+		}
+	}
+	return &values[2], &values[0], &values[3], &values[1], nil
+}
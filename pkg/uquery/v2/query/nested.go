@@ -0,0 +1,187 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+
+	zincquery "github.com/zinclabs/zinc/pkg/bluge/query"
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/source"
+)
+
+func NestedQuery(query map[string]interface{}, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) (bluge.Query, error) {
+	value := new(meta.NestedQuery)
+	value.Boost = -1.0
+	for k, v := range query {
+		k := strings.ToLower(k)
+		switch k {
+		case "path":
+			value.Path, _ = v.(string)
+		case "query":
+			vv, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[nested] query doesn't support values of type: %T", v))
+			}
+			value.Query = vv
+		case "score_mode":
+			value.ScoreMode, _ = v.(string)
+			switch value.ScoreMode {
+			case "avg", "max", "min", "sum", "none":
+			default:
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[nested] illegal score_mode [%v]", v))
+			}
+		case "ignore_unmapped":
+			value.IgnoreUnmapped, _ = v.(bool)
+		case "inner_hits":
+			vv, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[nested] inner_hits doesn't support values of type: %T", v))
+			}
+			value.InnerHits = vv
+		case "boost":
+			vv, ok := v.(float64)
+			if !ok {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[nested] boost doesn't support values of type: %T", v))
+			}
+			value.Boost = vv
+		case "_name":
+			// noop
+		default:
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[nested] unknown field [%s]", k))
+		}
+	}
+
+	if value.Path == "" {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[nested] requires 'path' field")
+	}
+	if value.Query == nil {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[nested] requires 'query' field")
+	}
+	prop, ok := mappings.Properties[value.Path]
+	if !ok {
+		if value.IgnoreUnmapped {
+			return bluge.NewMatchNoneQuery(), nil
+		}
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[nested] failed to find nested object under path [%s]", value.Path))
+	}
+	if prop.Type != "nested" {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[nested] nested object under path [%s] is not of nested type", value.Path))
+	}
+
+	subq, err := Query(value.Query, mappings, analyzers)
+	if err != nil {
+		return nil, errors.New(errors.ErrorTypeXContentParseException, "[query] failed to parse field").Cause(err)
+	}
+	q := zincquery.NewNestedQuery(value.Path, meta.NestedPathField, meta.NestedParentField, subq)
+	if value.ScoreMode != "" {
+		q.SetScoreMode(value.ScoreMode)
+	}
+	if value.Boost >= 0 {
+		q.SetBoost(value.Boost)
+	}
+	if value.InnerHits == nil {
+		return q, nil
+	}
+
+	innerHits, err := innerHitsRequest(value.InnerHits)
+	if err != nil {
+		return nil, err
+	}
+	innerHits.Path = value.Path
+	if innerHits.Name == "" {
+		innerHits.Name = value.Path
+	}
+	innerHits.Query = q.ChildQuery()
+	return &nestedQuery{NestedQuery: q, innerHits: innerHits}, nil
+}
+
+// nestedQuery is a nested query with inner_hits, the inner hits are searched for the hits of the query
+type nestedQuery struct {
+	*zincquery.NestedQuery
+	innerHits *meta.InnerHits
+}
+
+func innerHitsRequest(options map[string]interface{}) (*meta.InnerHits, error) {
+	innerHits := &meta.InnerHits{Size: 3}
+	var err error
+	for k, v := range options {
+		k := strings.ToLower(k)
+		switch k {
+		case "name":
+			innerHits.Name, _ = v.(string)
+		case "from", "size":
+			vv, ok := v.(float64)
+			if !ok || vv < 0 {
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[inner_hits] %s must be a positive number", k))
+			}
+			if k == "from" {
+				innerHits.From = int(vv)
+			} else {
+				innerHits.Size = int(vv)
+			}
+		case "_source":
+			if innerHits.Source, err = source.Request(v); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[inner_hits] unknown field [%s]", k))
+		}
+	}
+	if innerHits.Source == nil {
+		innerHits.Source, _ = source.Request(nil)
+	}
+	return innerHits, nil
+}
+
+// InnerHits returns the inner_hits of the nested queries in the query
+func InnerHits(q bluge.Query) []*meta.InnerHits {
+	var innerHits []*meta.InnerHits
+	switch q := q.(type) {
+	case *nestedQuery:
+		innerHits = append(innerHits, q.innerHits)
+	case *bluge.BooleanQuery:
+		for _, subq := range q.Musts() {
+			innerHits = append(innerHits, InnerHits(subq)...)
+		}
+		for _, subq := range q.Shoulds() {
+			innerHits = append(innerHits, InnerHits(subq)...)
+		}
+	case *zincquery.BoostingQuery:
+		innerHits = append(innerHits, InnerHits(q.Positive())...)
+	case *zincquery.FunctionScoreQuery:
+		innerHits = append(innerHits, InnerHits(q.Query())...)
+	}
+	return innerHits
+}
+
+// RootQuery restricts the query to the documents of the index, the hidden documents of nested objects don't match
+func RootQuery(q bluge.Query, mappings *meta.Mappings) bluge.Query {
+	paths := mappings.NestedPaths()
+	if len(paths) == 0 {
+		return q
+	}
+	rootQuery := bluge.NewBooleanQuery().AddMust(q)
+	for _, path := range paths {
+		rootQuery.AddMustNot(bluge.NewTermQuery(path).SetField(meta.NestedPathField))
+	}
+	return rootQuery
+}
@@ -50,7 +50,7 @@ func Query(query map[string]interface{}, mappings *meta.Mappings, analyzers map[
 				return nil, errors.New(errors.ErrorTypeXContentParseException, "[bool] failed to parse field").Cause(err)
 			}
 		case "boosting":
-			if subq, err = BoostingQuery(v); err != nil {
+			if subq, err = BoostingQuery(v, mappings, analyzers); err != nil {
 				return nil, errors.New(errors.ErrorTypeXContentParseException, "[boosting] failed to parse field").Cause(err)
 			}
 		case "match":
@@ -82,7 +82,7 @@ func Query(query map[string]interface{}, mappings *meta.Mappings, analyzers map[
 				return nil, errors.New(errors.ErrorTypeXContentParseException, "[match_none] failed to parse field").Cause(err)
 			}
 		case "combined_fields":
-			if subq, err = CombinedFieldsQuery(v); err != nil {
+			if subq, err = CombinedFieldsQuery(v, mappings, analyzers); err != nil {
 				return nil, errors.New(errors.ErrorTypeXContentParseException, "[combined_fields] failed to parse field").Cause(err)
 			}
 		case "query_string":
@@ -130,25 +130,37 @@ func Query(query map[string]interface{}, mappings *meta.Mappings, analyzers map[
 				return nil, errors.New(errors.ErrorTypeXContentParseException, "[terms] failed to parse field").Cause(err)
 			}
 		case "terms_set":
-			if subq, err = TermsSetQuery(v); err != nil {
+			if subq, err = TermsSetQuery(v, mappings); err != nil {
 				return nil, errors.New(errors.ErrorTypeXContentParseException, "[terms_set] failed to parse field").Cause(err)
 			}
 		case "geo_bounding_box":
-			if subq, err = GeoBoundingBoxQuery(v); err != nil {
+			if subq, err = GeoBoundingBoxQuery(v, mappings); err != nil {
 				return nil, errors.New(errors.ErrorTypeXContentParseException, "[geo_bounding_box] failed to parse field").Cause(err)
 			}
 		case "geo_distance":
-			if subq, err = GeoDistanceQuery(v); err != nil {
+			if subq, err = GeoDistanceQuery(v, mappings); err != nil {
 				return nil, errors.New(errors.ErrorTypeXContentParseException, "[geo_distance] failed to parse field").Cause(err)
 			}
 		case "geo_polygon":
-			if subq, err = GeoPolygonQuery(v); err != nil {
+			if subq, err = GeoPolygonQuery(v, mappings); err != nil {
 				return nil, errors.New(errors.ErrorTypeXContentParseException, "[geo_polygon] failed to parse field").Cause(err)
 			}
 		case "geo_shape":
 			if subq, err = GeoShapeQuery(v); err != nil {
 				return nil, errors.New(errors.ErrorTypeXContentParseException, "[geo_shape] failed to parse field").Cause(err)
 			}
+		case "nested":
+			if subq, err = NestedQuery(v, mappings, analyzers); err != nil {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, "[nested] failed to parse field").Cause(err)
+			}
+		case "function_score":
+			if subq, err = FunctionScoreQuery(v, mappings, analyzers); err != nil {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, "[function_score] failed to parse field").Cause(err)
+			}
+		case "script_score":
+			if subq, err = ScriptScoreQuery(v, mappings, analyzers); err != nil {
+				return nil, errors.New(errors.ErrorTypeXContentParseException, "[script_score] failed to parse field").Cause(err)
+			}
 		default:
 			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[%s] query doesn't support", k))
 		}
@@ -0,0 +1,95 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/numeric"
+
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/script"
+)
+
+// scriptEnv exposes the doc values of a document to a script, the values are decoded by the field mappings
+type scriptEnv struct {
+	values   map[string][][]byte
+	mappings *meta.Mappings
+	score    float64
+}
+
+func (e *scriptEnv) Doc(field string) ([]interface{}, error) {
+	var prop meta.Property
+	var ok bool
+	if e.mappings != nil {
+		prop, ok = e.mappings.Properties[field]
+	}
+	if !ok {
+		return nil, fmt.Errorf("no field found for [%s] in mapping", field)
+	}
+
+	values := make([]interface{}, 0, len(e.values[field]))
+	for _, term := range e.values[field] {
+		switch prop.Type {
+		case "numeric", "date", "time":
+			// numeric doc values are indexed with several shifts, the exact value has shift 0
+			prefixCoded := numeric.PrefixCoded(term)
+			if shift, err := prefixCoded.Shift(); err != nil || shift != 0 {
+				continue
+			}
+			v, err := prefixCoded.Int64()
+			if err != nil {
+				return nil, err
+			}
+			if prop.Type == "numeric" {
+				values = append(values, numeric.Int64ToFloat64(v))
+			} else {
+				values = append(values, time.Unix(0, v).UTC())
+			}
+		case "geo_point":
+			prefixCoded := numeric.PrefixCoded(term)
+			if shift, err := prefixCoded.Shift(); err != nil || shift != 0 {
+				continue
+			}
+			lon, lat, err := bluge.DecodeGeoLonLat(term)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, map[string]interface{}{"lat": lat, "lon": lon})
+		case "bool":
+			values = append(values, string(term) == "true")
+		default:
+			values = append(values, string(term))
+		}
+	}
+	return values, nil
+}
+
+func (e *scriptEnv) Score() float64 {
+	return e.score
+}
+
+// minimumShouldMatchScript runs the minimum_should_match_script of the terms_set query
+type minimumShouldMatchScript struct {
+	*script.Script
+	mappings *meta.Mappings
+}
+
+func (s *minimumShouldMatchScript) Execute(values map[string][][]byte) (float64, error) {
+	return s.Number(&scriptEnv{values: values, mappings: s.mappings})
+}
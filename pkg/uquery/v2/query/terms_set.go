@@ -16,10 +16,109 @@
 package query
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/numeric"
+
+	zincquery "github.com/zinclabs/zinc/pkg/bluge/query"
 	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	"github.com/zinclabs/zinc/pkg/script"
 )
 
-func TermsSetQuery(query map[string]interface{}) (bluge.Query, error) {
-	return nil, errors.New(errors.ErrorTypeNotImplemented, "[terms_set] query doesn't support")
+func TermsSetQuery(query map[string]interface{}, mappings *meta.Mappings) (bluge.Query, error) {
+	if len(query) > 1 {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[terms_set] query doesn't support multiple fields")
+	}
+
+	field := ""
+	value := new(meta.TermsSetQuery)
+	value.Boost = -1.0
+	for k, v := range query {
+		field = k
+		vv, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[terms_set] doesn't support values of type: %T", v))
+		}
+		for k, v := range vv {
+			k := strings.ToLower(k)
+			switch k {
+			case "terms":
+				terms, ok := v.([]interface{})
+				if !ok {
+					return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[terms_set] terms doesn't support values of type: %T", v))
+				}
+				value.Terms = terms
+			case "minimum_should_match_field":
+				value.MinimumShouldMatchField, _ = v.(string)
+			case "minimum_should_match_script":
+				value.MinimumShouldMatchScript = v
+			case "boost":
+				value.Boost = v.(float64)
+			default:
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[terms_set] unknown field [%s]", k))
+			}
+		}
+	}
+
+	if value.MinimumShouldMatchField == "" && value.MinimumShouldMatchScript == nil {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[terms_set] requires 'minimum_should_match_field' or 'minimum_should_match_script'")
+	}
+	if value.MinimumShouldMatchField != "" && value.MinimumShouldMatchScript != nil {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[terms_set] specify either 'minimum_should_match_field' or 'minimum_should_match_script', not both")
+	}
+
+	var prop meta.Property
+	if mappings != nil {
+		prop = mappings.Properties[field]
+	}
+	terms := make([]string, 0, len(value.Terms))
+	for _, term := range value.Terms {
+		switch term := term.(type) {
+		case string:
+			terms = append(terms, term)
+		case float64:
+			if prop.Type == "numeric" {
+				terms = append(terms, string(numeric.MustNewPrefixCodedInt64(numeric.Float64ToInt64(term), 0)))
+			} else {
+				terms = append(terms, strconv.FormatFloat(term, 'f', -1, 64))
+			}
+		case bool:
+			terms = append(terms, strconv.FormatBool(term))
+		default:
+			return nil, errors.New(errors.ErrorTypeXContentParseException, fmt.Sprintf("[terms_set] doesn't support values of type: %T", term))
+		}
+	}
+
+	subq := zincquery.NewTermsSetQuery(field, terms)
+	if value.MinimumShouldMatchField != "" {
+		var msmProp meta.Property
+		if mappings != nil {
+			msmProp = mappings.Properties[value.MinimumShouldMatchField]
+		}
+		if msmProp.Type != "numeric" {
+			return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[terms_set] minimum_should_match_field [%s] must be a numeric field", value.MinimumShouldMatchField))
+		}
+		subq.SetMinimumShouldMatchField(value.MinimumShouldMatchField)
+	} else {
+		s, err := script.New(value.MinimumShouldMatchScript)
+		if err != nil {
+			return nil, errors.New(errors.ErrorTypeXContentParseException, "[terms_set] failed to parse minimum_should_match_script").Cause(err)
+		}
+		params := make(map[string]interface{}, len(s.Params)+1)
+		for k, v := range s.Params {
+			params[k] = v
+		}
+		params["num_terms"] = float64(len(terms))
+		s.Params = params
+		subq.SetMinimumShouldMatchScript(&minimumShouldMatchScript{Script: s, mappings: mappings})
+	}
+	if value.Boost >= 0 {
+		subq.SetBoost(value.Boost)
+	}
+
+	return subq, nil
 }
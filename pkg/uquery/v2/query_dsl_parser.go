@@ -17,11 +17,13 @@ package parser
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/blugelabs/bluge"
 	"github.com/blugelabs/bluge/analysis"
-	"github.com/blugelabs/bluge/search"
 
+	"github.com/zinclabs/zinc/pkg/bluge/profile"
+	zincquery "github.com/zinclabs/zinc/pkg/bluge/query"
 	"github.com/zinclabs/zinc/pkg/errors"
 	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 	"github.com/zinclabs/zinc/pkg/startup"
@@ -31,10 +33,27 @@ import (
 	"github.com/zinclabs/zinc/pkg/uquery/v2/query"
 	"github.com/zinclabs/zinc/pkg/uquery/v2/sort"
 	"github.com/zinclabs/zinc/pkg/uquery/v2/source"
+	"github.com/zinclabs/zinc/pkg/uquery/v2/suggest"
 )
 
+// IndexMappings are the mappings and analyzers of an index of a multi-index search
+type IndexMappings struct {
+	Name      string // index name, it selects the highlight and inner hits of the hits of the index
+	Mappings  *meta.Mappings
+	Analyzers map[string]*analysis.Analyzer
+	Filter    map[string]interface{} // filter query DSL of the aliases the index is searched through
+}
+
 // ParseQueryDSL parse query DSL and return searchRequest
 func ParseQueryDSL(q *meta.ZincQuery, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) (bluge.SearchRequest, error) {
+	return ParseMultiQueryDSL(q, mappings, []IndexMappings{{Mappings: mappings, Analyzers: analyzers}})
+}
+
+// ParseMultiQueryDSL parse query DSL for the readers of the indexes and return searchRequest,
+// the query of each reader is parsed with the mappings and analyzers of its index,
+// the merged mappings of the indexes are used by the other parts of the request
+func ParseMultiQueryDSL(q *meta.ZincQuery, mappings *meta.Mappings, indexes []IndexMappings) (bluge.SearchRequest, error) {
+	analyzers := indexes[0].Analyzers
 	// parse size
 	if q.Size == 0 {
 		q.Size = 10
@@ -43,23 +62,77 @@ func ParseQueryDSL(q *meta.ZincQuery, mappings *meta.Mappings, analyzers map[str
 		q.Size = startup.LoadMaxResults()
 	}
 
-	// parse query
-	query, err := query.Query(q.Query, mappings, analyzers)
-	if err != nil {
-		return nil, err
+	// parse query, the parsing is reported as the rewrite time of the profile
+	start := time.Now()
+	queries := make([]bluge.Query, len(indexes))
+	for i, index := range indexes {
+		subq, err := query.Query(q.Query, index.Mappings, index.Analyzers)
+		if err != nil {
+			return nil, err
+		}
+		if subq == nil {
+			return nil, errors.New(errors.ErrorTypeNotImplemented, fmt.Sprintf("[%s] query doesn't support", q.Query))
+		}
+		queries[i] = subq
 	}
-	if query == nil {
-		return nil, errors.New(errors.ErrorTypeNotImplemented, fmt.Sprintf("[%s] query doesn't support", q.Query))
+	subq := queries[0]
+	q.Rewritten = subq
+	q.InnerHits = query.InnerHits(subq)
+
+	// parse highlight, the terms and inner hits of every index come from the query parsed with its analyzers
+	if q.Highlight != nil && len(indexes) > 1 {
+		q.IndexHighlights = make(map[string]*meta.Highlight, len(indexes))
+		for i, index := range indexes {
+			options := highlight.Clone(q.Highlight)
+			if err := highlight.Request(options, queries[i], index.Mappings, index.Analyzers); err != nil {
+				return nil, err
+			}
+			q.IndexHighlights[index.Name] = options
+		}
+	}
+	if q.Highlight != nil {
+		if err := highlight.Request(q.Highlight, subq, mappings, analyzers); err != nil {
+			return nil, err
+		}
+	}
+	if len(indexes) > 1 && len(q.InnerHits) > 0 {
+		q.IndexInnerHits = make(map[string][]*meta.InnerHits, len(indexes))
+		for i, index := range indexes {
+			q.IndexInnerHits[index.Name] = query.InnerHits(queries[i])
+		}
 	}
 
-	// create search request
-	request := bluge.NewTopNSearch(q.Size, query).WithStandardAggregations()
+	// parse suggest
+	if q.Suggest != nil {
+		var err error
+		if q.Suggesters, err = suggest.Request(q.Suggest, mappings, analyzers); err != nil {
+			return nil, err
+		}
+	}
 
-	// parse highlight
-	if q.Highlight != nil {
-		highlight.Request(q.Highlight)
-		request.IncludeLocations()
+	// create search request, the filters of the aliases don't score, highlight or match inner hits
+	for i, index := range indexes {
+		if index.Filter != nil {
+			filterq, err := query.Query(index.Filter, index.Mappings, index.Analyzers)
+			if err != nil {
+				return nil, err
+			}
+			queries[i] = bluge.NewBooleanQuery().AddMust(queries[i]).AddMust(bluge.NewBooleanQuery().SetBoost(0).AddMust(filterq))
+		}
+		queries[i] = query.RootQuery(queries[i], index.Mappings)
 	}
+	if q.Profile {
+		q.Profiler = profile.NewProfiler(len(queries), time.Since(start))
+		for i := range queries {
+			queries[i], q.Profiler.Queries[i] = profile.NewQuery(queries[i])
+		}
+	}
+	if len(queries) > 1 {
+		subq = zincquery.NewMultiReaderQuery(queries)
+	} else {
+		subq = queries[0]
+	}
+	request := bluge.NewTopNSearch(q.Size, subq).WithStandardAggregations()
 
 	// parse from
 	if q.From > 0 {
@@ -76,9 +149,20 @@ func ParseQueryDSL(q *meta.ZincQuery, mappings *meta.Mappings, analyzers map[str
 		if err := aggregation.Request(request, q.Aggregations, mappings); err != nil {
 			return nil, err
 		}
+		if q.Profiler != nil {
+			aggs := request.Aggregations()
+			for name := range q.Aggregations {
+				if agg, ok := aggs[name]; ok {
+					var node *profile.Node
+					aggs[name], node = profile.NewAggregation(name, agg)
+					q.Profiler.Aggregations = append(q.Profiler.Aggregations, node)
+				}
+			}
+		}
 	}
 
 	// parse fields
+	var err error
 	if q.Fields != nil {
 		if v, ok := q.Fields.([]interface{}); ok {
 			if q.Fields, err = fields.Request(v); err != nil {
@@ -94,16 +178,36 @@ func ParseQueryDSL(q *meta.ZincQuery, mappings *meta.Mappings, analyzers map[str
 
 	// parse sort
 	if q.Sort != nil {
-		if q.Sort, err = sort.Request(q.Sort); err != nil {
+		if q.Sort, err = sort.Request(q.Sort, mappings); err != nil {
 			return nil, err
 		}
-		if q.Sort != nil {
-			request.SortByCustom(q.Sort.(search.SortOrder))
-		}
 	}
 
-	// pagenation
-	// TODO: search after PIT support
+	// parse search after and point in time
+	if q.SearchAfter != nil || q.PIT != nil {
+		if q.From > 0 {
+			return nil, errors.New(errors.ErrorTypeIllegalArgumentException, "[from] parameter must be set to 0 when [search_after] is used")
+		}
+		sortFields, _ := q.Sort.([]*meta.SortField)
+		if len(sortFields) == 0 {
+			sortFields = []*meta.SortField{{Field: "_score", Desc: true}}
+		}
+		// hits with the same sort values would be skipped by the next page, the point in time breaks the ties by _id
+		if q.PIT != nil && sortFields[len(sortFields)-1].Field != "_id" {
+			sortFields = append(sortFields, &meta.SortField{Field: "_id"})
+		}
+		q.Sort = sortFields
+		if q.SearchAfter != nil {
+			after, err := sort.SearchAfter(sortFields, q.SearchAfter, mappings)
+			if err != nil {
+				return nil, err
+			}
+			request.After(after)
+		}
+	}
+	if v, ok := q.Sort.([]*meta.SortField); ok && len(v) > 0 {
+		request.SortByCustom(sort.Order(v, mappings))
+	}
 
 	return request, nil
 }
@@ -0,0 +1,114 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package searchtemplate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cbroglie/mustache"
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+)
+
+// Parse checks the syntax of the mustache template
+func Parse(source string) error {
+	if _, err := mustache.ParseStringRaw(source, true); err != nil {
+		return errors.New(errors.ErrorTypeScriptException, "compile error").Cause(err)
+	}
+	return nil
+}
+
+// Render renders the mustache template of a search request with the params. The variables are escaped
+// for JSON strings, {{#toJson}}name{{/toJson}} renders a param as JSON and {{#join}}name{{/join}}
+// joins the values of an array param with commas. A default value is given by an inverted section,
+// like {{size}}{{^size}}10{{/size}}.
+func Render(source string, params map[string]interface{}) (string, error) {
+	tmpl, err := mustache.ParseStringRaw(source, true)
+	if err != nil {
+		return "", errors.New(errors.ErrorTypeScriptException, "compile error").Cause(err)
+	}
+
+	escaped := escape(params)
+	lambdas := map[string]interface{}{
+		"toJson": mustache.LambdaFunc(func(text string, render mustache.RenderFunc) (string, error) {
+			v, ok := lookup(params, text)
+			if !ok {
+				return "", nil
+			}
+			data, err := json.Marshal(v)
+			return string(data), err
+		}),
+		"join": mustache.LambdaFunc(func(text string, render mustache.RenderFunc) (string, error) {
+			v, _ := lookup(escaped, text)
+			values, ok := v.([]interface{})
+			if !ok {
+				if v == nil {
+					return "", nil
+				}
+				return fmt.Sprint(v), nil
+			}
+			items := make([]string, len(values))
+			for i, value := range values {
+				items[i] = fmt.Sprint(value)
+			}
+			return strings.Join(items, ","), nil
+		}),
+	}
+
+	rendered, err := tmpl.Render(lambdas, escaped)
+	if err != nil {
+		return "", errors.New(errors.ErrorTypeScriptException, "runtime error").Cause(err)
+	}
+	return rendered, nil
+}
+
+// escape escapes the strings of the params for JSON strings
+func escape(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		data, _ := json.Marshal(v)
+		return string(data[1 : len(data)-1])
+	case map[string]interface{}:
+		rv := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			rv[k] = escape(value)
+		}
+		return rv
+	case []interface{}:
+		rv := make([]interface{}, len(v))
+		for i, value := range v {
+			rv[i] = escape(value)
+		}
+		return rv
+	}
+	return v
+}
+
+// lookup returns the param of the dotted path
+func lookup(v interface{}, path string) (interface{}, bool) {
+	for _, name := range strings.Split(strings.TrimSpace(path), ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[name]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
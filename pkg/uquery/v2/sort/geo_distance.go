@@ -0,0 +1,165 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package sort
+
+import (
+	"fmt"
+	gosort "sort"
+	"strings"
+
+	"github.com/blugelabs/bluge/numeric"
+	"github.com/blugelabs/bluge/numeric/geo"
+	"github.com/blugelabs/bluge/search"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	zincgeo "github.com/zinclabs/zinc/pkg/zutils/geo"
+)
+
+// geoDistanceRequest parses the _geo_distance sort, the field is the key which is not an option
+func geoDistanceRequest(v interface{}) (*meta.SortField, error) {
+	options, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[_geo_distance] sort should be an object")
+	}
+
+	sort := &meta.SortField{GeoDistance: &meta.GeoDistanceSort{Unit: "m"}}
+	for k, v := range options {
+		switch strings.ToLower(k) {
+		case "order":
+			if v, ok := v.(string); ok {
+				sort.Desc = strings.ToLower(v) == "desc"
+			}
+		case "unit":
+			unit, _ := v.(string)
+			if _, err := geo.ParseDistanceUnit(unit); err != nil {
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[_geo_distance] no distance unit match [%v]", v))
+			}
+			sort.GeoDistance.Unit = unit
+		case "mode":
+			mode, _ := v.(string)
+			mode = strings.ToLower(mode)
+			switch mode {
+			case "min", "max", "avg", "median", "sum":
+			default:
+				return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[_geo_distance] unknown mode [%v]", v))
+			}
+			sort.GeoDistance.Mode = mode
+		case "distance_type", "ignore_unmapped", "validation_method", "nested":
+			// noop
+		default:
+			if sort.Field != "" {
+				return nil, errors.New(errors.ErrorTypeParsingException, "[_geo_distance] sort doesn't support multiple fields")
+			}
+			points, err := zincgeo.ParsePoints(v)
+			if err != nil {
+				return nil, errors.New(errors.ErrorTypeParsingException, "[_geo_distance] failed to parse points").Cause(err)
+			}
+			sort.Field = k
+			for _, point := range points {
+				sort.GeoDistance.Points = append(sort.GeoDistance.Points, meta.GeoPoint{Lat: point.Lat, Lon: point.Lon})
+			}
+		}
+	}
+	if sort.Field == "" || len(sort.GeoDistance.Points) == 0 {
+		return nil, errors.New(errors.ErrorTypeParsingException, "[_geo_distance] requires a field and points")
+	}
+	if sort.GeoDistance.Mode == "" {
+		// the shortest distance for ascending order and the longest distance for descending order
+		sort.GeoDistance.Mode = "min"
+		if sort.Desc {
+			sort.GeoDistance.Mode = "max"
+		}
+	}
+
+	return sort, nil
+}
+
+// geoDistanceSource is the distance of the geo points of a document to the points of the sort
+type geoDistanceSource struct {
+	field  search.FieldSource
+	points []meta.GeoPoint
+	unit   float64 // meters of the unit
+	mode   string
+}
+
+func newGeoDistanceSource(sort *meta.SortField) *geoDistanceSource {
+	unit, _ := geo.ParseDistanceUnit(sort.GeoDistance.Unit)
+	if unit == 0 {
+		unit = 1
+	}
+	return &geoDistanceSource{
+		field:  search.Field(sort.Field),
+		points: sort.GeoDistance.Points,
+		unit:   unit,
+		mode:   sort.GeoDistance.Mode,
+	}
+}
+
+func (s *geoDistanceSource) Fields() []string {
+	return s.field.Fields()
+}
+
+func (s *geoDistanceSource) Value(match *search.DocumentMatch) []byte {
+	docPoints := s.field.GeoPoints(match)
+	if len(docPoints) == 0 {
+		return nil
+	}
+
+	distances := make([]float64, 0, len(docPoints)*len(s.points))
+	for _, docPoint := range docPoints {
+		for _, point := range s.points {
+			// Haversin returns kilometers
+			distance := geo.Haversin(docPoint.Lon, docPoint.Lat, point.Lon, point.Lat) * 1000 / s.unit
+			distances = append(distances, distance)
+		}
+	}
+
+	var distance float64
+	switch s.mode {
+	case "max":
+		distance = distances[0]
+		for _, d := range distances {
+			if d > distance {
+				distance = d
+			}
+		}
+	case "avg", "sum":
+		for _, d := range distances {
+			distance += d
+		}
+		if s.mode == "avg" {
+			distance /= float64(len(distances))
+		}
+	case "median":
+		gosort.Float64s(distances)
+		n := len(distances)
+		if n%2 == 1 {
+			distance = distances[n/2]
+		} else {
+			distance = (distances[n/2-1] + distances[n/2]) / 2
+		}
+	default:
+		distance = distances[0]
+		for _, d := range distances {
+			if d < distance {
+				distance = d
+			}
+		}
+	}
+
+	return numeric.MustNewPrefixCodedInt64(numeric.Float64ToInt64(distance), 0)
+}
@@ -16,51 +16,57 @@
 package sort
 
 import (
+	"bytes"
+	"fmt"
+	gosort "sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/blugelabs/bluge/numeric"
 	"github.com/blugelabs/bluge/search"
+
 	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// sort values of a missing field, see search.SortBy
+var (
+	missingHigh = bytes.Repeat([]byte{0xff}, 10)
+	missingLow  = []byte{0x00}
 )
 
-func Request(v interface{}) (search.SortOrder, error) {
+// Request parses the sort, the fields are checked against the mappings
+func Request(v interface{}, mappings *meta.Mappings) ([]*meta.SortField, error) {
 	if v == nil {
 		return nil, nil
 	}
 
-	sorts := make(search.SortOrder, 0, 1)
+	sorts := make([]*meta.SortField, 0, 1)
 	switch v := v.(type) {
 	case string:
-		sorts = append(sorts, search.ParseSearchSortString(v))
-		return sorts, nil
+		sorts = append(sorts, parseSortString(v))
 	case []interface{}:
 		for _, v := range v {
 			switch v := v.(type) {
 			case string:
-				sorts = append(sorts, search.ParseSearchSortString(v))
+				sorts = append(sorts, parseSortString(v))
 			case map[string]interface{}:
 				if len(v) > 1 {
 					return nil, errors.New(errors.ErrorTypeParsingException, "[sort] field doesn't support multiple values")
 				}
 				for field, v := range v {
-					sort := search.SortBy(search.Field(field))
-					switch v := v.(type) {
-					case string:
-						if strings.ToLower(v) == "desc" {
-							sort.Desc()
+					if field == "_geo_distance" {
+						sort, err := geoDistanceRequest(v)
+						if err != nil {
+							return nil, err
 						}
-					case map[string]interface{}:
-						for kk, vv := range v {
-							kk = strings.ToLower(kk)
-							switch kk {
-							case "order":
-								if strings.ToLower(vv.(string)) == "desc" {
-									sort.Desc()
-								}
-							case "format":
-							default:
-							}
-						}
-					default:
+						sorts = append(sorts, sort)
+						continue
+					}
+					sort, err := fieldSortRequest(field, v)
+					if err != nil {
+						return nil, err
 					}
 					sorts = append(sorts, sort)
 				}
@@ -70,5 +76,467 @@ func Request(v interface{}) (search.SortOrder, error) {
 		return nil, errors.New(errors.ErrorTypeXContentParseException, "[sort] value should be string or array")
 	}
 
+	for _, sort := range sorts {
+		if err := validate(sort, mappings); err != nil {
+			return nil, err
+		}
+	}
 	return sorts, nil
 }
+
+// parseSortString parses a field with an optional - or + prefix, _score is sorted desc by default
+func parseSortString(v string) *meta.SortField {
+	sort := new(meta.SortField)
+	if strings.HasPrefix(v, "-") {
+		sort.Desc = true
+		v = v[1:]
+	}
+	sort.Field = strings.TrimPrefix(v, "+")
+	if sort.Field == "_score" {
+		sort.Desc = true
+	}
+	return sort
+}
+
+// fieldSortRequest parses the order or the options of a field, _score is sorted desc by default
+func fieldSortRequest(field string, v interface{}) (*meta.SortField, error) {
+	sort := &meta.SortField{Field: field, Desc: field == "_score"}
+	switch v := v.(type) {
+	case string:
+		order, err := parseOrder(field, v)
+		if err != nil {
+			return nil, err
+		}
+		sort.Desc = order
+	case map[string]interface{}:
+		for k, v := range v {
+			switch strings.ToLower(k) {
+			case "order":
+				order, _ := v.(string)
+				desc, err := parseOrder(field, order)
+				if err != nil {
+					return nil, err
+				}
+				sort.Desc = desc
+			case "missing":
+				sort.Missing = v
+			case "mode":
+				mode, _ := v.(string)
+				mode = strings.ToLower(mode)
+				switch mode {
+				case "min", "max", "avg", "sum", "median":
+				default:
+					return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[sort] unknown mode [%v] of field [%s]", v, field))
+				}
+				sort.Mode = mode
+			case "unmapped_type":
+				sort.UnmappedType, _ = v.(string)
+			case "format":
+				sort.Format, _ = v.(string)
+			case "numeric_type":
+				// noop
+			default:
+				return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[field_sort] unknown field [%s]", k))
+			}
+		}
+	default:
+		return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[sort] value of field [%s] should be an order or an object", field))
+	}
+	return sort, nil
+}
+
+func parseOrder(field, order string) (bool, error) {
+	switch strings.ToLower(order) {
+	case "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	}
+	return false, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[sort] unknown order [%s] of field [%s], it should be [asc] or [desc]", order, field))
+}
+
+// validate checks the field is mapped and sortable, the fields which are not mapped by any index
+// require an unmapped_type, their documents are sorted as missing
+func validate(sort *meta.SortField, mappings *meta.Mappings) error {
+	switch sort.Field {
+	case "_score", "_doc":
+		if sort.Missing != nil || sort.Mode != "" {
+			return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[sort] [%s] doesn't support [missing] or [mode]", sort.Field))
+		}
+		return nil
+	case "_id", "_seq_no", "@timestamp":
+		return validateMissing(sort, mappings)
+	}
+
+	prop, ok := meta.Property{}, false
+	if mappings != nil {
+		prop, ok = mappings.Properties[sort.Field]
+	}
+	if !ok {
+		if sort.UnmappedType == "" {
+			return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("No mapping found for [%s] in order to sort on", sort.Field))
+		}
+		return validateMissing(sort, mappings)
+	}
+	if !prop.Sortable {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf(
+			"field [%s] of type [%s] is not sortable, set [sortable] to true in the mappings of the field to sort on it", sort.Field, prop.Type,
+		))
+	}
+	if sort.GeoDistance != nil {
+		if prop.Type != "geo_point" {
+			return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("field [%s] of type [%s] doesn't support [_geo_distance] sort", sort.Field, prop.Type))
+		}
+		return nil
+	}
+	switch sort.Mode {
+	case "avg", "sum", "median":
+		if t := fieldType(sort, mappings); t != "numeric" && t != "date" {
+			return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[sort] mode [%s] of field [%s] is only supported for numeric and date fields", sort.Mode, sort.Field))
+		}
+	}
+	return validateMissing(sort, mappings)
+}
+
+// validateMissing checks the missing value can be encoded in the type of the field
+func validateMissing(sort *meta.SortField, mappings *meta.Mappings) error {
+	_, err := missingValue(sort, mappings)
+	return err
+}
+
+// missingValue encodes the custom missing value, it returns nil for _first and _last
+func missingValue(sort *meta.SortField, mappings *meta.Mappings) ([]byte, error) {
+	switch sort.Missing {
+	case nil, "_last", "_first":
+		return nil, nil
+	}
+	value, err := encodeValue(sort, sort.Missing, mappings)
+	if err != nil {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[sort] invalid missing value of field [%s]", sort.Field)).Cause(err)
+	}
+	return value, nil
+}
+
+// Order returns the bluge sort order of the fields
+func Order(fields []*meta.SortField, mappings *meta.Mappings) search.SortOrder {
+	order := make(search.SortOrder, 0, len(fields))
+	for _, field := range fields {
+		var sort *search.Sort
+		switch {
+		case field.Field == "_score":
+			sort = search.SortBy(search.DocumentScore())
+		case field.Field == "_doc":
+			sort = search.SortBy(docSource{})
+		case field.GeoDistance != nil:
+			sort = search.SortBy(newGeoDistanceSource(field))
+		default:
+			var source search.TextValueSource = newFieldSource(field, mappings)
+			if missing, _ := missingValue(field, mappings); missing != nil {
+				source = search.MissingTextValue(source, search.ConstantTextValueSource(missing))
+			}
+			sort = search.SortBy(source)
+		}
+		if field.Desc {
+			sort.Desc()
+		}
+		if field.Missing == "_first" {
+			sort.MissingFirst()
+		}
+		order = append(order, sort)
+	}
+	return order
+}
+
+// Values decodes the sort values of a hit, it returns numbers for numeric fields and the score,
+// dates in the format of the sort or epoch milliseconds or RFC3339 with nanoseconds, strings for other fields
+// and nil for missing fields
+func Values(fields []*meta.SortField, values [][]byte, mappings *meta.Mappings) []interface{} {
+	resp := make([]interface{}, 0, len(values))
+	for i, value := range values {
+		if i >= len(fields) {
+			break
+		}
+		if bytes.Equal(value, missingHigh) || bytes.Equal(value, missingLow) {
+			resp = append(resp, nil)
+			continue
+		}
+		switch fieldType(fields[i], mappings) {
+		case "numeric":
+			v, err := numeric.PrefixCoded(value).Int64()
+			if err != nil {
+				resp = append(resp, nil)
+				continue
+			}
+			resp = append(resp, numeric.Int64ToFloat64(v))
+		case "date":
+			v, err := numeric.PrefixCoded(value).Int64()
+			if err != nil {
+				resp = append(resp, nil)
+				continue
+			}
+			t := time.Unix(0, v).UTC()
+			switch format := fields[i].Format; {
+			case format == "epoch_millis":
+				resp = append(resp, t.UnixMilli())
+			case format != "":
+				resp = append(resp, t.Format(format))
+			case t.Nanosecond()%int(time.Millisecond) == 0:
+				// dates are sorted in nanoseconds, epoch milliseconds would skip or repeat hits of the same millisecond
+				resp = append(resp, t.UnixMilli())
+			default:
+				resp = append(resp, t.Format(time.RFC3339Nano))
+			}
+		default:
+			resp = append(resp, string(value))
+		}
+	}
+	return resp
+}
+
+// SearchAfter encodes the sort values of search_after in the sort order of bluge
+func SearchAfter(fields []*meta.SortField, values []interface{}, mappings *meta.Mappings) ([][]byte, error) {
+	if len(values) != len(fields) {
+		return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf(
+			"search_after has %d value(s) but sort has %d.", len(values), len(fields),
+		))
+	}
+
+	after := make([][]byte, 0, len(values))
+	for i, value := range values {
+		field := fields[i]
+		if value == nil {
+			// missing values are sorted last unless missing is _first, see search.SortBy
+			if field.Desc == (field.Missing == "_first") {
+				after = append(after, missingHigh)
+			} else {
+				after = append(after, missingLow)
+			}
+			continue
+		}
+
+		v, err := encodeValue(field, value, mappings)
+		if err != nil {
+			return nil, err
+		}
+		after = append(after, v)
+	}
+
+	return after, nil
+}
+
+// encodeValue encodes a sort value of the field in the sort order of bluge
+func encodeValue(field *meta.SortField, value interface{}, mappings *meta.Mappings) ([]byte, error) {
+	switch fieldType(field, mappings) {
+	case "numeric":
+		v, err := searchAfterNumber(field.Field, value)
+		if err != nil {
+			return nil, err
+		}
+		return numeric.MustNewPrefixCodedInt64(numeric.Float64ToInt64(v), 0), nil
+	case "date":
+		var t time.Time
+		switch v := value.(type) {
+		case string:
+			var err error
+			if field.Format != "" && field.Format != "epoch_millis" {
+				t, err = time.Parse(field.Format, v)
+			} else {
+				t, err = time.Parse(time.RFC3339Nano, v)
+			}
+			if err != nil {
+				n, err := strconv.ParseInt(v, 10, 64)
+				if err != nil {
+					return nil, errors.New(errors.ErrorTypeIllegalArgumentException, "search_after value ["+v+"] of date field ["+field.Field+"] must be epoch_millis or RFC3339")
+				}
+				t = time.UnixMilli(n)
+			}
+		default:
+			n, err := searchAfterNumber(field.Field, value)
+			if err != nil {
+				return nil, err
+			}
+			t = time.UnixMilli(int64(n))
+		}
+		return numeric.MustNewPrefixCodedInt64(t.UnixNano(), 0), nil
+	default:
+		switch v := value.(type) {
+		case string:
+			return []byte(v), nil
+		case bool:
+			return []byte(strconv.FormatBool(v)), nil
+		default:
+			return nil, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf(
+				"search_after value of field [%s] must be a string but got a %T value", field.Field, value,
+			))
+		}
+	}
+}
+
+func searchAfterNumber(field string, value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			return n, nil
+		}
+	}
+	return 0, errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf(
+		"search_after value of field [%s] must be a number but got [%v]", field, value,
+	))
+}
+
+// fieldType returns numeric for the score, the document order, geo distances and numeric fields,
+// date for date fields, keyword for others. Unmapped fields have the unmapped_type of the sort.
+func fieldType(sort *meta.SortField, mappings *meta.Mappings) string {
+	if sort.GeoDistance != nil {
+		return "numeric"
+	}
+	field := sort.Field
+	switch field {
+	case "_score", "_seq_no", "_doc":
+		return "numeric"
+	case "@timestamp":
+		return "date"
+	case "_id":
+		return "keyword"
+	}
+	typ := sort.UnmappedType
+	if mappings != nil {
+		if prop, ok := mappings.Properties[field]; ok {
+			typ = prop.Type
+		}
+	}
+	switch typ {
+	case "numeric", "long", "integer", "short", "byte", "double", "float":
+		return "numeric"
+	case "date", "time":
+		return "date"
+	}
+	return "keyword"
+}
+
+// fieldSource is the sort value of a field, mode selects the value of multi-valued fields,
+// the lowest value for ascending order and the highest value for descending order by default
+type fieldSource struct {
+	field string
+	mode  string
+	typ   string
+}
+
+func newFieldSource(sort *meta.SortField, mappings *meta.Mappings) *fieldSource {
+	mode := sort.Mode
+	if mode == "" {
+		mode = "min"
+		if sort.Desc {
+			mode = "max"
+		}
+	}
+	return &fieldSource{field: sort.Field, mode: mode, typ: fieldType(sort, mappings)}
+}
+
+func (s *fieldSource) Fields() []string {
+	return []string{s.field}
+}
+
+func (s *fieldSource) Value(match *search.DocumentMatch) []byte {
+	values := match.DocValues(s.field)
+	if s.typ == "numeric" || s.typ == "date" {
+		values = fullPrecisionTerms(values)
+	}
+	switch len(values) {
+	case 0:
+		return nil
+	case 1:
+		return values[0]
+	}
+
+	switch s.mode {
+	case "avg", "sum", "median":
+		return s.aggregate(values)
+	case "max":
+		max := values[0]
+		for _, v := range values[1:] {
+			if bytes.Compare(v, max) > 0 {
+				max = v
+			}
+		}
+		return max
+	default:
+		min := values[0]
+		for _, v := range values[1:] {
+			if bytes.Compare(v, min) < 0 {
+				min = v
+			}
+		}
+		return min
+	}
+}
+
+// fullPrecisionTerms removes the terms of lower precision which are indexed for numeric range queries,
+// search.RemoveNumericPaddedTerms keeps all terms of multi-valued fields
+func fullPrecisionTerms(values [][]byte) [][]byte {
+	terms := make([][]byte, 0, len(values))
+	for _, v := range values {
+		if shift, err := numeric.PrefixCoded(v).Shift(); err == nil && shift == 0 {
+			terms = append(terms, v)
+		}
+	}
+	return terms
+}
+
+// aggregate computes the sum, average or median of the values of numeric and date fields
+func (s *fieldSource) aggregate(values [][]byte) []byte {
+	numbers := make([]float64, 0, len(values))
+	for _, value := range values {
+		v, err := numeric.PrefixCoded(value).Int64()
+		if err != nil {
+			continue
+		}
+		if s.typ == "date" {
+			numbers = append(numbers, float64(v))
+		} else {
+			numbers = append(numbers, numeric.Int64ToFloat64(v))
+		}
+	}
+	if len(numbers) == 0 {
+		return nil
+	}
+
+	var n float64
+	switch s.mode {
+	case "median":
+		gosort.Float64s(numbers)
+		if l := len(numbers); l%2 == 1 {
+			n = numbers[l/2]
+		} else {
+			n = (numbers[l/2-1] + numbers[l/2]) / 2
+		}
+	default:
+		for _, v := range numbers {
+			n += v
+		}
+		if s.mode == "avg" {
+			n /= float64(len(numbers))
+		}
+	}
+
+	if s.typ == "date" {
+		return numeric.MustNewPrefixCodedInt64(int64(n), 0)
+	}
+	return numeric.MustNewPrefixCodedInt64(numeric.Float64ToInt64(n), 0)
+}
+
+// docSource is the order of the documents in the index
+type docSource struct{}
+
+func (docSource) Fields() []string {
+	return nil
+}
+
+func (docSource) Value(match *search.DocumentMatch) []byte {
+	return numeric.MustNewPrefixCodedInt64(numeric.Float64ToInt64(float64(match.Number)), 0)
+}
@@ -63,19 +63,14 @@ func Response(source *meta.Source, data []byte) map[string]interface{} {
 
 	// return all fields
 	if len(source.Fields) == 0 {
-		return ret
+		return exclude(source.Excludes, ret)
 	}
 
-	wildcard := false
 	rets := make(map[string]interface{})
 	for _, field := range source.Fields {
-		wildcard = false
-		if strings.HasSuffix(field, "*") {
-			wildcard = true
-		}
 		if _, ok := ret[field]; ok {
 			rets[field] = ret[field]
-		} else if wildcard {
+		} else if strings.HasSuffix(field, "*") {
 			for k, v := range ret {
 				if strings.HasPrefix(k, field[:len(field)-1]) {
 					rets[k] = v
@@ -84,5 +79,21 @@ func Response(source *meta.Source, data []byte) map[string]interface{} {
 		}
 	}
 
-	return rets
+	return exclude(source.Excludes, rets)
+}
+
+// exclude removes the excluded fields, the fields ending with * remove the fields with the prefix
+func exclude(excludes []string, data map[string]interface{}) map[string]interface{} {
+	for _, field := range excludes {
+		if !strings.HasSuffix(field, "*") {
+			delete(data, field)
+			continue
+		}
+		for k := range data {
+			if strings.HasPrefix(k, field[:len(field)-1]) {
+				delete(data, k)
+			}
+		}
+	}
+	return data
 }
@@ -0,0 +1,261 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package suggest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+	"github.com/blugelabs/bluge/analysis/analyzer"
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	zincanalysis "github.com/zinclabs/zinc/pkg/uquery/v2/analysis"
+)
+
+// completionSeparator separates the normalized input, the input and the weight in the terms of completion fields
+const completionSeparator = "\x00"
+
+// CompletionAnalyzer returns the analyzer normalizing the inputs of the completion field, simple by default
+func CompletionAnalyzer(analyzers map[string]*analysis.Analyzer, prop meta.Property) *analysis.Analyzer {
+	name := prop.Analyzer
+	if name == "" {
+		name = "simple"
+	}
+	ana, err := zincanalysis.QueryAnalyzer(analyzers, name)
+	if err != nil {
+		return analyzer.NewSimpleAnalyzer()
+	}
+	return ana
+}
+
+// CompletionTerms returns the terms of the value of a completion field. A value is an input, a list of inputs,
+// an object with input and weight or a list of objects. A term is the normalized input followed by the input
+// and its weight, so a prefix lookup in the FST of the field dictionary finds the suggestions and their
+// weights without reading the documents.
+func CompletionTerms(value interface{}, ana *analysis.Analyzer) ([]string, error) {
+	var terms []string
+	add := func(input interface{}, weight int64) error {
+		v, ok := input.(string)
+		if !ok {
+			return fmt.Errorf("input should be a string but got a %T value", input)
+		}
+		v = strings.ReplaceAll(v, completionSeparator, "")
+		if normalized := normalize(ana, v); normalized != "" {
+			terms = append(terms, normalized+completionSeparator+v+completionSeparator+strconv.FormatInt(weight, 10))
+		}
+		return nil
+	}
+
+	var parse func(value interface{}, list bool) error
+	parse = func(value interface{}, list bool) error {
+		switch v := value.(type) {
+		case string:
+			return add(v, 1)
+		case []interface{}:
+			if list {
+				return fmt.Errorf("nested lists are not supported")
+			}
+			for _, v := range v {
+				if err := parse(v, true); err != nil {
+					return err
+				}
+			}
+			return nil
+		case map[string]interface{}:
+			weight, err := completionWeight(v["weight"])
+			if err != nil {
+				return err
+			}
+			switch input := v["input"].(type) {
+			case []interface{}:
+				for _, input := range input {
+					if err := add(input, weight); err != nil {
+						return err
+					}
+				}
+				return nil
+			default:
+				return add(input, weight)
+			}
+		default:
+			return fmt.Errorf("expected a string, a list or an object but got a %T value", value)
+		}
+	}
+
+	if err := parse(value, false); err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+// completionWeight parses the weight of the inputs, a positive integer, 1 by default
+func completionWeight(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 1, nil
+	case float64:
+		if v < 0 || v != math.Trunc(v) || v > math.MaxInt32 {
+			return 0, fmt.Errorf("weight must be a positive integer but got [%v]", v)
+		}
+		return int64(v), nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("weight must be a positive integer but got [%s]", v)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("weight must be a number but got a %T value", value)
+	}
+}
+
+// normalize joins the tokens of the text with a space
+func normalize(ana *analysis.Analyzer, text string) string {
+	tokens := ana.Analyze([]byte(text))
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = string(token.Term)
+	}
+	return strings.Join(terms, " ")
+}
+
+func requestCompletion(name, prefix string, completion *meta.CompletionSuggester, mappings *meta.Mappings) error {
+	if completion.Field == "" {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] requires a field", name))
+	}
+	if prop, ok := mappings.Properties[completion.Field]; !ok || prop.Type != "completion" {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] field [%s] is not a completion suggest field", completion.Field))
+	}
+	if prefix == "" {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] requires a prefix or a text", name))
+	}
+	if completion.Size == 0 {
+		completion.Size = defaultSize
+	}
+	return nil
+}
+
+// completion is an input of a completion field starting with the prefix
+type completion struct {
+	term   string
+	input  string
+	weight int64
+	reader *bluge.Reader
+}
+
+func completionResponse(prefix string, completion *meta.CompletionSuggester, readers []*bluge.Reader, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) ([]meta.SuggestEntry, error) {
+	entry := meta.SuggestEntry{Text: prefix, Length: len(prefix), Options: []meta.SuggestOption{}}
+	normalized := []byte(normalize(CompletionAnalyzer(analyzers, mappings.Properties[completion.Field]), prefix))
+	if len(normalized) == 0 {
+		return []meta.SuggestEntry{entry}, nil
+	}
+
+	completions, err := lookupCompletions(readers, completion.Field, normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	// a document is suggested once, by its heaviest input
+	docs := make(map[string]struct{})
+	inputs := make(map[string]struct{})
+	for _, comp := range completions {
+		if len(entry.Options) >= completion.Size {
+			break
+		}
+		if _, ok := inputs[comp.input]; ok && completion.SkipDuplicates {
+			continue
+		}
+		req := bluge.NewTopNSearch(completion.Size+len(docs), bluge.NewTermQuery(comp.term).SetField(completion.Field))
+		dmi, err := comp.reader.Search(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+		next, err := dmi.Next()
+		for err == nil && next != nil && len(entry.Options) < completion.Size {
+			option := meta.SuggestOption{Text: comp.input, Type: "_doc", DocScore: float64(comp.weight)}
+			err = next.VisitStoredFields(func(field string, value []byte) bool {
+				switch field {
+				case "_id":
+					option.ID = string(value)
+				case "_index":
+					option.Index = string(value)
+				case "_source":
+					_ = json.Unmarshal(value, &option.Source)
+				}
+				return true
+			})
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := docs[option.Index+"/"+option.ID]; !ok {
+				docs[option.Index+"/"+option.ID] = struct{}{}
+				inputs[comp.input] = struct{}{}
+				entry.Options = append(entry.Options, option)
+				if completion.SkipDuplicates {
+					break
+				}
+			}
+			next, err = dmi.Next()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return []meta.SuggestEntry{entry}, nil
+}
+
+// lookupCompletions returns the inputs of the field starting with the normalized prefix, heaviest first
+func lookupCompletions(readers []*bluge.Reader, field string, normalized []byte) ([]completion, error) {
+	var completions []completion
+	for _, reader := range readers {
+		dict, err := reader.DictionaryIterator(field, nil, normalized, incrementBytes(normalized))
+		if err != nil {
+			return nil, err
+		}
+		entry, err := dict.Next()
+		for err == nil && entry != nil {
+			term := entry.Term()
+			i := strings.Index(term, completionSeparator)
+			j := strings.LastIndex(term, completionSeparator)
+			if i >= 0 && j > i {
+				weight, _ := strconv.ParseInt(term[j+1:], 10, 64)
+				completions = append(completions, completion{term: term, input: term[i+1 : j], weight: weight, reader: reader})
+			}
+			entry, err = dict.Next()
+		}
+		dict.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(completions, func(i, j int) bool {
+		if completions[i].weight != completions[j].weight {
+			return completions[i].weight > completions[j].weight
+		}
+		return completions[i].input < completions[j].input
+	})
+	return completions, nil
+}
@@ -0,0 +1,203 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package suggest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// phraseBeamSize is the number of partial phrases kept while the candidates of the tokens are combined
+const phraseBeamSize = 100
+
+func requestPhrase(name, text string, phrase *meta.PhraseSuggester, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) error {
+	if text == "" {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] requires a text", name))
+	}
+	if phrase.Field == "" {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] requires a field", name))
+	}
+	if _, err := fieldAnalyzer(phrase.Analyzer, phrase.Field, mappings, analyzers); err != nil {
+		return err
+	}
+	if phrase.Size == 0 {
+		phrase.Size = defaultSize
+	}
+	if phrase.MaxErrors == 0 {
+		phrase.MaxErrors = defaultMaxErrors
+	}
+	if phrase.MaxErrors < 0 {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] max_errors must be positive", name))
+	}
+	if phrase.Confidence == nil {
+		confidence := defaultConfidence
+		phrase.Confidence = &confidence
+	}
+	if phrase.RealWordErrorLikelihood == 0 {
+		phrase.RealWordErrorLikelihood = defaultRealWordErrorLikelihood
+	}
+	if phrase.RealWordErrorLikelihood < 0 || phrase.RealWordErrorLikelihood > 1 {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] real_word_error_likelihood must be between 0 and 1", name))
+	}
+	if phrase.Highlight != nil && (phrase.Highlight.PreTag == "") != (phrase.Highlight.PostTag == "") {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] highlight requires both pre_tag and post_tag", name))
+	}
+	if len(phrase.DirectGenerator) == 0 {
+		phrase.DirectGenerator = []*meta.TermSuggester{{Field: phrase.Field, SuggestMode: "always"}}
+	}
+	for _, gen := range phrase.DirectGenerator {
+		if gen == nil {
+			return errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[suggest] suggester [%s] direct_generator should be an object", name))
+		}
+		if err := requestGenerator(name, gen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// phraseCandidate is a candidate of a token of the phrase, its score is the likelihood of the error
+// times the smoothed frequency of the term
+type phraseCandidate struct {
+	term     string
+	score    float64
+	original bool
+}
+
+// phraseOption is a combination of candidates, one for each token
+type phraseOption struct {
+	candidates []*phraseCandidate
+	score      float64
+	errors     int
+}
+
+func phraseResponse(text string, phrase *meta.PhraseSuggester, readers []*bluge.Reader, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) ([]meta.SuggestEntry, error) {
+	ana, err := fieldAnalyzer(phrase.Analyzer, phrase.Field, mappings, analyzers)
+	if err != nil {
+		return nil, err
+	}
+	entry := meta.SuggestEntry{Text: text, Length: len(text), Options: []meta.SuggestOption{}}
+	tokens := ana.Analyze([]byte(text))
+	if len(tokens) == 0 {
+		return []meta.SuggestEntry{entry}, nil
+	}
+
+	var dictSize float64
+	for _, reader := range readers {
+		n, err := reader.Count()
+		if err != nil {
+			return nil, err
+		}
+		dictSize += float64(n)
+	}
+	score := func(freq int64, likelihood float64) float64 {
+		return likelihood * (float64(freq) + 1) / (dictSize + 1)
+	}
+
+	// the original term is the first candidate of every token
+	positions := make([][]*phraseCandidate, len(tokens))
+	for i, token := range tokens {
+		term := string(token.Term)
+		freq, err := docFreq(readers, phrase.Field, term)
+		if err != nil {
+			return nil, err
+		}
+		positions[i] = []*phraseCandidate{{term: term, score: score(freq, phrase.RealWordErrorLikelihood), original: true}}
+		seen := map[string]struct{}{term: {}}
+		for _, gen := range phrase.DirectGenerator {
+			cands, err := candidates(readers, gen, term)
+			if err != nil {
+				return nil, err
+			}
+			for _, cand := range cands {
+				if _, ok := seen[cand.term]; ok {
+					continue
+				}
+				seen[cand.term] = struct{}{}
+				positions[i] = append(positions[i], &phraseCandidate{term: cand.term, score: score(cand.freq, cand.score)})
+			}
+		}
+	}
+
+	maxErrors := int(phrase.MaxErrors)
+	if phrase.MaxErrors < 1 {
+		maxErrors = int(math.Max(1, math.Round(phrase.MaxErrors*float64(len(tokens)))))
+	}
+
+	// combine the candidates token by token, keeping the best partial phrases
+	beam := []*phraseOption{{score: 1}}
+	for _, cands := range positions {
+		next := make([]*phraseOption, 0, len(beam)*len(cands))
+		for _, option := range beam {
+			for _, cand := range cands {
+				errs := option.errors
+				if !cand.original {
+					errs++
+				}
+				if errs > maxErrors {
+					continue
+				}
+				next = append(next, &phraseOption{
+					candidates: append(append([]*phraseCandidate{}, option.candidates...), cand),
+					score:      option.score * cand.score,
+					errors:     errs,
+				})
+			}
+		}
+		sort.SliceStable(next, func(i, j int) bool { return next[i].score > next[j].score })
+		if len(next) > phraseBeamSize {
+			next = next[:phraseBeamSize]
+		}
+		beam = next
+	}
+
+	// options must score better than the text itself times the confidence
+	cutoff := *phrase.Confidence
+	for _, cands := range positions {
+		cutoff *= cands[0].score
+	}
+	for _, option := range beam {
+		if len(entry.Options) >= phrase.Size {
+			break
+		}
+		if option.errors == 0 || option.score <= cutoff {
+			continue
+		}
+		terms := make([]string, len(option.candidates))
+		highlighted := make([]string, len(option.candidates))
+		for i, cand := range option.candidates {
+			terms[i], highlighted[i] = cand.term, cand.term
+			if !cand.original && phrase.Highlight != nil {
+				highlighted[i] = phrase.Highlight.PreTag + cand.term + phrase.Highlight.PostTag
+			}
+		}
+		suggestion := meta.SuggestOption{Text: strings.Join(terms, " "), Score: option.score}
+		if phrase.Highlight != nil {
+			suggestion.Highlighted = strings.Join(highlighted, " ")
+		}
+		entry.Options = append(entry.Options, suggestion)
+	}
+
+	return []meta.SuggestEntry{entry}, nil
+}
@@ -0,0 +1,164 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package suggest
+
+import (
+	"fmt"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+	"github.com/blugelabs/bluge/analysis/analyzer"
+	"github.com/goccy/go-json"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+	zincanalysis "github.com/zinclabs/zinc/pkg/uquery/v2/analysis"
+)
+
+const (
+	defaultSize                    = 5
+	defaultMaxEdits                = 2
+	defaultPrefixLength            = 1
+	defaultMinWordLength           = 4
+	defaultMaxErrors               = 1.0
+	defaultConfidence              = 1.0
+	defaultRealWordErrorLikelihood = 0.95
+)
+
+// Request parses the suggest section of the search request into named suggesters and fills their defaults,
+// the text key of the section is the default text of the suggesters
+func Request(suggest map[string]interface{}, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) (map[string]*meta.Suggester, error) {
+	var globalText string
+	if v, ok := suggest["text"]; ok {
+		if globalText, ok = v.(string); !ok {
+			return nil, errors.New(errors.ErrorTypeParsingException, "[suggest] text should be a string")
+		}
+	}
+
+	suggesters := make(map[string]*meta.Suggester, len(suggest))
+	for name, v := range suggest {
+		if name == "text" {
+			continue
+		}
+		if _, ok := v.(map[string]interface{}); !ok {
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[suggest] suggester [%s] should be an object", name))
+		}
+		data, _ := json.Marshal(v)
+		suggester := new(meta.Suggester)
+		if err := json.Unmarshal(data, suggester); err != nil {
+			return nil, errors.New(errors.ErrorTypeParsingException, fmt.Sprintf("[suggest] failed to parse suggester [%s]", name)).Cause(err)
+		}
+		if suggester.Text == "" {
+			suggester.Text = globalText
+		}
+
+		var err error
+		switch {
+		case suggester.Term != nil && suggester.Phrase == nil && suggester.Completion == nil:
+			err = requestTerm(name, suggester.Text, suggester.Term, mappings, analyzers)
+		case suggester.Phrase != nil && suggester.Term == nil && suggester.Completion == nil:
+			err = requestPhrase(name, suggester.Text, suggester.Phrase, mappings, analyzers)
+		case suggester.Completion != nil && suggester.Term == nil && suggester.Phrase == nil:
+			if suggester.Prefix == "" {
+				suggester.Prefix = suggester.Text
+			}
+			err = requestCompletion(name, suggester.Prefix, suggester.Completion, mappings)
+		default:
+			err = errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] must have exactly one of [term, phrase, completion]", name))
+		}
+		if err != nil {
+			return nil, err
+		}
+		suggesters[name] = suggester
+	}
+
+	return suggesters, nil
+}
+
+// Response runs the suggesters on the readers of the indexes, the text is analyzed with the analyzers of the first index
+func Response(suggesters map[string]*meta.Suggester, readers []*bluge.Reader, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) (map[string][]meta.SuggestEntry, error) {
+	resp := make(map[string][]meta.SuggestEntry, len(suggesters))
+	for name, suggester := range suggesters {
+		var entries []meta.SuggestEntry
+		var err error
+		switch {
+		case suggester.Term != nil:
+			entries, err = termResponse(suggester.Text, suggester.Term, readers, mappings, analyzers)
+		case suggester.Phrase != nil:
+			entries, err = phraseResponse(suggester.Text, suggester.Phrase, readers, mappings, analyzers)
+		case suggester.Completion != nil:
+			entries, err = completionResponse(suggester.Prefix, suggester.Completion, readers, mappings, analyzers)
+		}
+		if err != nil {
+			return nil, err
+		}
+		resp[name] = entries
+	}
+	return resp, nil
+}
+
+// fieldAnalyzer returns the named analyzer, or the search analyzer of the field, text fields use the standard
+// analyzer by default and the other fields are not analyzed
+func fieldAnalyzer(name, field string, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) (*analysis.Analyzer, error) {
+	if name != "" {
+		return zincanalysis.QueryAnalyzer(analyzers, name)
+	}
+	indexAnalyzer, searchAnalyzer := zincanalysis.QueryAnalyzerForField(analyzers, mappings, field)
+	if searchAnalyzer != nil {
+		return searchAnalyzer, nil
+	}
+	if indexAnalyzer != nil {
+		return indexAnalyzer, nil
+	}
+	if prop, ok := mappings.Properties[field]; ok && prop.Type == "text" {
+		return zincanalysis.QueryAnalyzer(analyzers, "standard")
+	}
+	return analyzer.NewKeywordAnalyzer(), nil
+}
+
+// docFreq returns the number of documents of the readers with the term in the field
+func docFreq(readers []*bluge.Reader, field, term string) (int64, error) {
+	var freq int64
+	for _, reader := range readers {
+		start := []byte(term)
+		dict, err := reader.DictionaryIterator(field, nil, start, append(start, 0))
+		if err != nil {
+			return 0, err
+		}
+		entry, err := dict.Next()
+		if err == nil && entry != nil && entry.Term() == term {
+			freq += int64(entry.Count())
+		}
+		dict.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return freq, nil
+}
+
+// incrementBytes returns the first key after all the keys with the prefix, nil if there is none
+func incrementBytes(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil
+}
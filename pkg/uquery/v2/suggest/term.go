@@ -0,0 +1,233 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package suggest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/blevesearch/vellum"
+	"github.com/blevesearch/vellum/levenshtein"
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+	segment "github.com/blugelabs/bluge_segment_api"
+
+	"github.com/zinclabs/zinc/pkg/errors"
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
+)
+
+// levBuilders build the levenshtein automatons of the max edits, with transpositions, they are expensive to create
+var levBuilders struct {
+	once     sync.Once
+	builders map[int]*levenshtein.LevenshteinAutomatonBuilder
+	err      error
+}
+
+func levAutomaton(term string, edits int) (segment.Automaton, error) {
+	levBuilders.once.Do(func() {
+		levBuilders.builders = make(map[int]*levenshtein.LevenshteinAutomatonBuilder)
+		for edits := 1; edits <= defaultMaxEdits; edits++ {
+			builder, err := levenshtein.NewLevenshteinAutomatonBuilder(uint8(edits), true)
+			if err != nil {
+				levBuilders.err = err
+				return
+			}
+			levBuilders.builders[edits] = builder
+		}
+	})
+	if levBuilders.err != nil {
+		return nil, levBuilders.err
+	}
+	return levBuilders.builders[edits].BuildDfa(term, uint8(edits))
+}
+
+func requestTerm(name, text string, term *meta.TermSuggester, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) error {
+	if text == "" {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] requires a text", name))
+	}
+	if err := requestGenerator(name, term); err != nil {
+		return err
+	}
+	if _, err := fieldAnalyzer(term.Analyzer, term.Field, mappings, analyzers); err != nil {
+		return err
+	}
+	return nil
+}
+
+// requestGenerator checks the options of the candidate generation and fills their defaults
+func requestGenerator(name string, term *meta.TermSuggester) error {
+	if term.Field == "" {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] requires a field", name))
+	}
+	if term.Size == 0 {
+		term.Size = defaultSize
+	}
+	switch term.SuggestMode {
+	case "":
+		term.SuggestMode = "missing"
+	case "missing", "popular", "always":
+	default:
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] unknown suggest_mode [%s]", name, term.SuggestMode))
+	}
+	switch term.Sort {
+	case "":
+		term.Sort = "score"
+	case "score", "frequency":
+	default:
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] unknown sort [%s]", name, term.Sort))
+	}
+	if term.MaxEdits == 0 {
+		term.MaxEdits = defaultMaxEdits
+	}
+	if term.MaxEdits < 1 || term.MaxEdits > defaultMaxEdits {
+		return errors.New(errors.ErrorTypeIllegalArgumentException, fmt.Sprintf("[suggest] suggester [%s] max_edits must be 1 or 2", name))
+	}
+	if term.PrefixLength == nil {
+		n := defaultPrefixLength
+		term.PrefixLength = &n
+	}
+	if term.MinWordLength == nil {
+		n := defaultMinWordLength
+		term.MinWordLength = &n
+	}
+	return nil
+}
+
+func termResponse(text string, term *meta.TermSuggester, readers []*bluge.Reader, mappings *meta.Mappings, analyzers map[string]*analysis.Analyzer) ([]meta.SuggestEntry, error) {
+	ana, err := fieldAnalyzer(term.Analyzer, term.Field, mappings, analyzers)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := ana.Analyze([]byte(text))
+	entries := make([]meta.SuggestEntry, 0, len(tokens))
+	for _, token := range tokens {
+		cands, err := candidates(readers, term, string(token.Term))
+		if err != nil {
+			return nil, err
+		}
+		entry := meta.SuggestEntry{
+			Text:    string(token.Term),
+			Offset:  token.Start,
+			Length:  token.End - token.Start,
+			Options: make([]meta.SuggestOption, 0, len(cands)),
+		}
+		for _, cand := range cands {
+			entry.Options = append(entry.Options, meta.SuggestOption{Text: cand.term, Score: cand.score, Freq: cand.freq})
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// candidate is a term of the field dictionary close to a token
+type candidate struct {
+	term  string
+	freq  int64   // documents with the term
+	score float64 // 1 - edits / length of the shorter term
+}
+
+// candidates returns the terms of the field dictionary within the max edits of the token,
+// the token itself is not a candidate
+func candidates(readers []*bluge.Reader, gen *meta.TermSuggester, token string) ([]candidate, error) {
+	tokenLen := utf8.RuneCountInString(token)
+	if tokenLen < *gen.MinWordLength {
+		return nil, nil
+	}
+	tokenFreq, err := docFreq(readers, gen.Field, token)
+	if err != nil {
+		return nil, err
+	}
+	if gen.SuggestMode == "missing" && tokenFreq > 0 {
+		return nil, nil
+	}
+
+	// automatons[0] accepts the max edits, the others one edit less each to find the distance of a term
+	automatons := make([]segment.Automaton, 0, gen.MaxEdits)
+	for edits := gen.MaxEdits; edits > 0; edits-- {
+		automaton, err := levAutomaton(token, edits)
+		if err != nil {
+			return nil, err
+		}
+		automatons = append(automatons, automaton)
+	}
+	var prefixBeg, prefixEnd []byte
+	if *gen.PrefixLength > 0 {
+		prefix := []rune(token)
+		if len(prefix) > *gen.PrefixLength {
+			prefix = prefix[:*gen.PrefixLength]
+		}
+		prefixBeg = []byte(string(prefix))
+		prefixEnd = incrementBytes(prefixBeg)
+	}
+
+	freqs := make(map[string]int64)
+	for _, reader := range readers {
+		dict, err := reader.DictionaryIterator(gen.Field, automatons[0], prefixBeg, prefixEnd)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := dict.Next()
+		for err == nil && entry != nil {
+			if entry.Term() != token {
+				freqs[entry.Term()] += int64(entry.Count())
+			}
+			entry, err = dict.Next()
+		}
+		dict.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cands := make([]candidate, 0, len(freqs))
+	for term, freq := range freqs {
+		if gen.SuggestMode == "popular" && freq <= tokenFreq {
+			continue
+		}
+		edits := gen.MaxEdits
+		for i := 1; i < len(automatons); i++ {
+			if vellum.AutomatonContains(automatons[i], []byte(term)) {
+				edits--
+			}
+		}
+		minLen := tokenLen
+		if n := utf8.RuneCountInString(term); n < minLen {
+			minLen = n
+		}
+		cands = append(cands, candidate{term: term, freq: freq, score: 1 - float64(edits)/float64(minLen)})
+	}
+
+	sort.Slice(cands, func(i, j int) bool {
+		a, b := cands[i], cands[j]
+		if gen.Sort == "frequency" && a.freq != b.freq {
+			return a.freq > b.freq
+		}
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.freq != b.freq {
+			return a.freq > b.freq
+		}
+		return a.term < b.term
+	})
+	if len(cands) > gen.Size {
+		cands = cands[:gen.Size]
+	}
+	return cands, nil
+}
@@ -65,3 +65,33 @@ func TestUnflatten(t *testing.T) {
 		So(undata["foo"].(map[string]interface{})["bar"].(map[string]interface{})["coo"], ShouldEqual, "abc")
 	})
 }
+
+func TestMerge(t *testing.T) {
+	Convey("zutils:merge", t, func() {
+		data := map[string]interface{}{
+			"foo": map[string]interface{}{
+				"bar": "abc",
+				"arr": []interface{}{"a", "b"},
+			},
+			"baz.qux": 1.0,
+		}
+		changed := Merge(data, map[string]interface{}{
+			"foo": map[string]interface{}{"bar": "abc"},
+		})
+		So(changed, ShouldBeFalse)
+
+		changed = Merge(data, map[string]interface{}{
+			"foo.bar": "cba",
+			"foo":     map[string]interface{}{"arr": []interface{}{"c"}},
+			"baz":     map[string]interface{}{"qux": 2.0},
+			"new":     true,
+		})
+		So(changed, ShouldBeTrue)
+		So(data["foo"].(map[string]interface{})["bar"], ShouldEqual, "cba")
+		So(data["foo"].(map[string]interface{})["arr"], ShouldResemble, []interface{}{"c"})
+		So(data["baz.qux"], ShouldEqual, 2.0)
+		So(data["new"], ShouldEqual, true)
+		_, ok := data["baz"]
+		So(ok, ShouldBeFalse)
+	})
+}
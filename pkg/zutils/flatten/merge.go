@@ -0,0 +1,85 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package flatten
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Merge deep merges src into dst and reports whether dst was changed.
+// Objects are merged recursively, any other value (including arrays) replaces the old one.
+// Dotted keys like "foo.bar" are resolved against nested objects in dst, so that both
+// {"foo.bar": 1} and {"foo": {"bar": 1}} update the same field produced by Flatten.
+func Merge(dst, src map[string]interface{}) bool {
+	changed := false
+	for key, value := range src {
+		if mergeKey(dst, key, value) {
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+func mergeKey(dst map[string]interface{}, key string, value interface{}) bool {
+	if old, ok := dst[key]; ok {
+		oldMap, oldIsMap := old.(map[string]interface{})
+		newMap, newIsMap := value.(map[string]interface{})
+		if oldIsMap && newIsMap {
+			return Merge(oldMap, newMap)
+		}
+		if reflect.DeepEqual(old, value) {
+			return false
+		}
+		dst[key] = value
+		return true
+	}
+
+	// dotted key, try to find the nested object it points to
+	if strings.Contains(key, ".") {
+		parts := strings.Split(key, ".")
+		for i := len(parts) - 1; i > 0; i-- {
+			prefix := strings.Join(parts[:i], ".")
+			if sub, ok := dst[prefix].(map[string]interface{}); ok {
+				return mergeKey(sub, strings.Join(parts[i:], "."), value)
+			}
+		}
+	}
+
+	// nested object, but dst stores it with dotted keys
+	if newMap, ok := value.(map[string]interface{}); ok && hasPrefixKey(dst, key+".") {
+		changed := false
+		for k, v := range newMap {
+			if mergeKey(dst, key+"."+k, v) {
+				changed = true
+			}
+		}
+		return changed
+	}
+
+	dst[key] = value
+	return true
+}
+
+func hasPrefixKey(m map[string]interface{}, prefix string) bool {
+	for k := range m {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
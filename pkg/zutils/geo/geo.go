@@ -0,0 +1,121 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package geo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blugelabs/bluge/numeric/geo"
+)
+
+const geohashChars = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Parse parses a geo point in the formats of elasticsearch: an object with lat and lon,
+// a "lat,lon" string, a geohash, a [lon, lat] array or a WKT "POINT (lon lat)"
+func Parse(v interface{}) (geo.Point, error) {
+	if s, ok := v.(string); ok {
+		s = strings.TrimSpace(s)
+		if upper := strings.ToUpper(s); strings.HasPrefix(upper, "POINT") {
+			return parseWKT(s)
+		}
+		if !strings.Contains(s, ",") {
+			if s == "" || len(s) > 12 || strings.Trim(strings.ToLower(s), geohashChars) != "" {
+				return geo.Point{}, fmt.Errorf("unsupported symbol in geohash [%s]", s)
+			}
+			v = strings.ToLower(s)
+		}
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		for k := range m {
+			if k != "lat" && k != "lon" {
+				return geo.Point{}, fmt.Errorf("field must be either [lat], [lon]")
+			}
+		}
+	}
+
+	lon, lat, ok := geo.ExtractGeoPoint(v)
+	if !ok {
+		return geo.Point{}, fmt.Errorf("failed to parse geo_point from [%v]", v)
+	}
+	return geo.Point{Lon: lon, Lat: lat}, nil
+}
+
+// ParsePoints parses a geo point or an array of geo points
+func ParsePoints(v interface{}) ([]geo.Point, error) {
+	if values, ok := v.([]interface{}); ok && !isLonLat(values) {
+		points := make([]geo.Point, 0, len(values))
+		for _, value := range values {
+			point, err := Parse(value)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, point)
+		}
+		return points, nil
+	}
+	point, err := Parse(v)
+	if err != nil {
+		return nil, err
+	}
+	return []geo.Point{point}, nil
+}
+
+// Validate checks the latitude and the longitude are in range
+func Validate(point geo.Point) error {
+	if point.Lat < -90 || point.Lat > 90 {
+		return fmt.Errorf("illegal latitude value [%v]", point.Lat)
+	}
+	if point.Lon < -180 || point.Lon > 180 {
+		return fmt.Errorf("illegal longitude value [%v]", point.Lon)
+	}
+	return nil
+}
+
+// isLonLat reports if the array is a single point in [lon, lat] format
+func isLonLat(values []interface{}) bool {
+	if len(values) != 2 {
+		return false
+	}
+	for _, v := range values {
+		if _, ok := v.(float64); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func parseWKT(s string) (geo.Point, error) {
+	start := strings.Index(s, "(")
+	end := strings.LastIndex(s, ")")
+	if start < 0 || end < start {
+		return geo.Point{}, fmt.Errorf("failed to parse WKT point [%s]", s)
+	}
+	coordinates := strings.Fields(s[start+1 : end])
+	if len(coordinates) < 2 {
+		return geo.Point{}, fmt.Errorf("failed to parse WKT point [%s]", s)
+	}
+	lon, err := strconv.ParseFloat(coordinates[0], 64)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("failed to parse WKT point [%s]", s)
+	}
+	lat, err := strconv.ParseFloat(coordinates[1], 64)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("failed to parse WKT point [%s]", s)
+	}
+	return geo.Point{Lon: lon, Lat: lat}, nil
+}
@@ -110,3 +110,23 @@ func GetAnyFromMap(m interface{}, key string) (interface{}, error) {
 
 	return v, nil
 }
+
+// GetPath returns the value of a dotted path like a.b.c in the nested objects, a key containing dots is also matched
+func GetPath(m map[string]interface{}, path string) (interface{}, bool) {
+	if v, ok := m[path]; ok {
+		return v, true
+	}
+	for i := 0; i < len(path); i++ {
+		if path[i] != '.' {
+			continue
+		}
+		sub, ok := m[path[:i]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if v, ok := GetPath(sub, path[i+1:]); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
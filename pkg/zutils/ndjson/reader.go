@@ -0,0 +1,100 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package ndjson
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrLineTooLarge is returned for a line longer than the max size, the line is skipped
+// and the reader can continue with the next line
+var ErrLineTooLarge = errors.New("ndjson: line too large")
+
+// Reader reads newline delimited json line by line, there is no limit of the line size
+// other than the max size, and lines are streamed from the underlying reader
+type Reader struct {
+	r       *bufio.Reader
+	maxSize int
+	buf     []byte
+	line    int
+}
+
+// NewReader returns a Reader, lines longer than maxSize bytes are rejected with ErrLineTooLarge
+func NewReader(r io.Reader, maxSize int) *Reader {
+	return &Reader{
+		r:       bufio.NewReaderSize(r, 64*1024),
+		maxSize: maxSize,
+	}
+}
+
+// ReadLine returns the next non empty line without the line break, io.EOF at the end.
+// The returned slice is only valid until the next call.
+func (r *Reader) ReadLine() ([]byte, error) {
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line, nil
+		}
+	}
+}
+
+// Line returns the number of the last line read, starts from 1
+func (r *Reader) Line() int {
+	return r.line
+}
+
+func (r *Reader) readLine() ([]byte, error) {
+	r.buf = r.buf[:0]
+	size := 0
+	for {
+		chunk, err := r.r.ReadSlice('\n')
+		size += len(chunk)
+		// keep reading the rest of a too large line, but don't buffer it
+		if size <= r.maxSize+2 { // the line break can be \r\n
+			r.buf = append(r.buf, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF {
+			if size == 0 {
+				return nil, io.EOF
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	r.line++
+
+	if size > r.maxSize+2 {
+		return nil, ErrLineTooLarge
+	}
+	line := bytes.TrimSuffix(r.buf, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(line) > r.maxSize {
+		return nil, ErrLineTooLarge
+	}
+	return line, nil
+}
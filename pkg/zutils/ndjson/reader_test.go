@@ -0,0 +1,50 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package ndjson
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader_ReadLine(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	input := "{\"a\":1}\r\n\n" + long + "\n{\"b\":2}\n" + long[:10] + "\n{\"c\":3}"
+
+	r := NewReader(strings.NewReader(input), 20)
+	want := []struct {
+		line string
+		err  error
+	}{
+		{line: `{"a":1}`},
+		{err: ErrLineTooLarge},
+		{line: `{"b":2}`},
+		{line: long[:10]},
+		{line: `{"c":3}`},
+		{err: io.EOF},
+	}
+	for _, w := range want {
+		line, err := r.ReadLine()
+		assert.Equal(t, w.err, err)
+		if w.err == nil {
+			assert.Equal(t, w.line, string(line))
+		}
+	}
+	assert.Equal(t, 6, r.Line())
+}
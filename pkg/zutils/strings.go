@@ -17,6 +17,7 @@ package zutils
 
 import (
 	"strconv"
+	"strings"
 	"unicode"
 )
 
@@ -33,3 +34,24 @@ func IsNumeric(s string) bool {
 	}
 	return true
 }
+
+// MatchWildcard reports whether s matches the pattern, * in the pattern matches any characters
+func MatchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
@@ -0,0 +1,35 @@
+/* Copyright 2022 Zinc Labs Inc. and Contributors
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package zutils
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMatchWildcard(t *testing.T) {
+	Convey("zutils:MatchWildcard", t, func() {
+		So(MatchWildcard("logs", "logs"), ShouldBeTrue)
+		So(MatchWildcard("logs", "logs-1"), ShouldBeFalse)
+		So(MatchWildcard("logs*", "logs-1"), ShouldBeTrue)
+		So(MatchWildcard("*-1", "logs-1"), ShouldBeTrue)
+		So(MatchWildcard("l*s-*", "logs-1"), ShouldBeTrue)
+		So(MatchWildcard("l*x*", "logs-1"), ShouldBeFalse)
+		So(MatchWildcard("*", ""), ShouldBeTrue)
+		So(MatchWildcard("a*a", "a"), ShouldBeFalse)
+	})
+}
@@ -283,11 +283,11 @@ func TestApiStandard(t *testing.T) {
 				resp := request("PUT", "/api/"+indexName+"/document", body)
 				So(resp.Code, ShouldEqual, http.StatusOK)
 
-				data := make(map[string]string)
+				data := make(map[string]interface{})
 				err := json.Unmarshal(resp.Body.Bytes(), &data)
 				So(err, ShouldBeNil)
 				So(data["id"], ShouldNotEqual, "")
-				_id = data["id"]
+				_id = data["id"].(string)
 			})
 			Convey("update document with exist indexName and exist id", func() {
 				body := bytes.NewBuffer(nil)
@@ -324,11 +324,11 @@ func TestApiStandard(t *testing.T) {
 				resp := request("POST", "/api/"+indexName+"/_doc", body)
 				So(resp.Code, ShouldEqual, http.StatusOK)
 
-				data := make(map[string]string)
+				data := make(map[string]interface{})
 				err := json.Unmarshal(resp.Body.Bytes(), &data)
 				So(err, ShouldBeNil)
 				So(data["id"], ShouldNotEqual, "")
-				_id = data["id"]
+				_id = data["id"].(string)
 			})
 			Convey("update document with exist indexName and exist id", func() {
 				body := bytes.NewBuffer(nil)
@@ -378,6 +378,39 @@ func TestApiStandard(t *testing.T) {
 			})
 		})
 
+		Convey("GET /api/:target/_doc/:id", func() {
+			Convey("get document with not exist indexName", func() {
+				resp := request("GET", "/api/notExistIndexGet/_doc/1111", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("get document with exist indexName not exist id", func() {
+				resp := request("GET", "/api/"+indexName+"/_doc/notexistGet", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["found"], ShouldEqual, false)
+			})
+			Convey("get document with exist indexName and exist id", func() {
+				resp := request("GET", "/api/"+indexName+"/_doc/1111", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["found"], ShouldEqual, true)
+				So(data["_id"], ShouldEqual, "1111")
+				So(data["_source"].(map[string]interface{})["City"], ShouldEqual, "Turin")
+			})
+			Convey("check document exists with HEAD", func() {
+				resp := request("HEAD", "/api/"+indexName+"/_doc/1111", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				resp = request("HEAD", "/api/"+indexName+"/_doc/notexistGet", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+
 		Convey("DELETE /api/:target/_doc/:id", func() {
 			Convey("delete document with not exist indexName", func() {
 				resp := request("DELETE", "/api/notExistIndexDelete/_doc/1111", nil)
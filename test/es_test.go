@@ -17,12 +17,23 @@ package test
 
 import (
 	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"encoding/json"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
 	. "github.com/smartystreets/goconvey/convey"
+
+	meta "github.com/zinclabs/zinc/pkg/meta/v2"
 )
 
 func TestApiES(t *testing.T) {
@@ -46,6 +57,131 @@ func TestApiES(t *testing.T) {
 				resp := request("POST", "/es/_bulk", body)
 				So(resp.Code, ShouldEqual, http.StatusOK)
 			})
+			Convey("bulk with per item errors", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "bulkerrors", "_id": "1"}}
+{"num": 1}
+{"index": {"_index": "bulkerrors", "_id": "2"}}
+{"num": "abc"}
+{"index": {"_index": "bulkerrors", "_id": "3"}}
+{"num":
+{"delete": {"_index": "notExistIndexBulk", "_id": "1"}}
+{"unknown": {"_index": "bulkerrors"}}
+{"index": {"_index": "bulkerrors", "_id": "4"}}
+{"num": 4}`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(struct {
+					Errors bool                                `json:"errors"`
+					Items  []map[string]map[string]interface{} `json:"items"`
+				})
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(data.Errors, ShouldBeTrue)
+				So(len(data.Items), ShouldEqual, 6)
+				So(data.Items[0]["index"]["status"], ShouldEqual, http.StatusOK)
+				So(data.Items[1]["index"]["status"], ShouldEqual, http.StatusBadRequest)
+				So(data.Items[1]["index"]["error"].(map[string]interface{})["type"], ShouldEqual, "mapper_parsing_exception")
+				So(data.Items[2]["index"]["status"], ShouldEqual, http.StatusBadRequest)
+				So(data.Items[3]["delete"]["status"], ShouldEqual, http.StatusNotFound)
+				So(data.Items[4]["index"]["status"], ShouldEqual, http.StatusBadRequest)
+				So(data.Items[5]["index"]["status"], ShouldEqual, http.StatusOK)
+
+				resp = request("GET", "/es/bulkerrors/_doc/4", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("bulk documents with gzip body", func() {
+				body := bytes.NewBuffer(nil)
+				gz := gzip.NewWriter(body)
+				_, err := gz.Write([]byte(`{"index": {"_index": "bulkgzip", "_id": "1"}}
+{"name": "compressed"}
+`))
+				So(err, ShouldBeNil)
+				So(gz.Close(), ShouldBeNil)
+
+				req, _ := http.NewRequest("POST", "/es/_bulk", body)
+				req.SetBasicAuth(username, password)
+				req.Header.Set("Content-Encoding", "gzip")
+				resp := httptest.NewRecorder()
+				server().ServeHTTP(resp, req)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				resp = request("GET", "/es/bulkgzip/_doc/1", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			compressed := func(api, encoding string, write func(w io.Writer) error) *httptest.ResponseRecorder {
+				body := bytes.NewBuffer(nil)
+				var w io.WriteCloser
+				switch encoding {
+				case "gzip":
+					w = gzip.NewWriter(body)
+				case "zlib":
+					w = zlib.NewWriter(body)
+					encoding = "deflate"
+				case "deflate":
+					w, _ = flate.NewWriter(body, flate.DefaultCompression)
+				case "zstd":
+					w, _ = zstd.NewWriter(body)
+				}
+				So(write(w), ShouldBeNil)
+				So(w.Close(), ShouldBeNil)
+
+				req, _ := http.NewRequest("POST", api, body)
+				req.SetBasicAuth(username, password)
+				req.Header.Set("Content-Encoding", encoding)
+				resp := httptest.NewRecorder()
+				server().ServeHTTP(resp, req)
+				return resp
+			}
+			Convey("bulk documents with deflate and zstd body", func() {
+				for i, encoding := range []string{"zlib", "deflate", "zstd"} {
+					id := strconv.Itoa(i + 2)
+					resp := compressed("/es/_bulk", encoding, func(w io.Writer) error {
+						_, err := w.Write([]byte(`{"index": {"_index": "bulkgzip", "_id": "` + id + `"}}
+{"name": "` + encoding + `"}
+`))
+						return err
+					})
+					So(resp.Code, ShouldEqual, http.StatusOK)
+					So(resp.Body.String(), ShouldNotContainSubstring, `"errors":true`)
+
+					resp = request("GET", "/es/bulkgzip/_doc/"+id, nil)
+					So(resp.Code, ShouldEqual, http.StatusOK)
+					So(resp.Body.String(), ShouldContainSubstring, encoding)
+				}
+			})
+			Convey("msearch with gzip body", func() {
+				resp := compressed("/es/_msearch", "gzip", func(w io.Writer) error {
+					_, err := w.Write([]byte(`{"index": "bulkgzip"}
+{"query": {"match_all": {}}}
+`))
+					return err
+				})
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, `"responses"`)
+				So(resp.Body.String(), ShouldContainSubstring, "compressed")
+			})
+			Convey("request with unsupported Content-Encoding", func() {
+				req, _ := http.NewRequest("POST", "/es/_bulk", strings.NewReader(`{}`))
+				req.SetBasicAuth(username, password)
+				req.Header.Set("Content-Encoding", "br")
+				resp := httptest.NewRecorder()
+				server().ServeHTTP(resp, req)
+				So(resp.Code, ShouldEqual, http.StatusUnsupportedMediaType)
+			})
+			Convey("request with too large decompressed body", func() {
+				resp := compressed("/es/bulkgzip/_search", "gzip", func(w io.Writer) error {
+					chunk := bytes.Repeat([]byte(" "), 1024*1024)
+					for i := 0; i <= 100; i++ {
+						if _, err := w.Write(chunk); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				So(resp.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+			})
 		})
 
 		Convey("POST /es/:target/_bulk", func() {
@@ -103,11 +239,11 @@ func TestApiES(t *testing.T) {
 				resp := request("POST", "/es/"+indexName+"/_doc", body)
 				So(resp.Code, ShouldEqual, http.StatusOK)
 
-				data := make(map[string]string)
+				data := make(map[string]interface{})
 				err := json.Unmarshal(resp.Body.Bytes(), &data)
 				So(err, ShouldBeNil)
 				So(data["id"], ShouldNotEqual, "")
-				_id = data["id"]
+				_id = data["id"].(string)
 			})
 			Convey("update document with exist indexName and exist id", func() {
 				body := bytes.NewBuffer(nil)
@@ -157,6 +293,115 @@ func TestApiES(t *testing.T) {
 			})
 		})
 
+		Convey("GET /es/:target/_doc/:id", func() {
+			Convey("get document with not exist indexName", func() {
+				resp := request("GET", "/es/notExistIndexGet/_doc/1111", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["error"].(map[string]interface{})["type"], ShouldEqual, "index_not_found_exception")
+			})
+			Convey("get document with source filtering", func() {
+				resp := request("GET", "/es/"+indexName+"/_doc/1111?_source=City,Year", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["found"], ShouldEqual, true)
+				So(data["_source"], ShouldResemble, map[string]interface{}{"City": "Turin", "Year": 2006.0})
+			})
+			Convey("get document with source excludes", func() {
+				resp := request("GET", "/es/"+indexName+"/_doc/1111?_source_excludes=City,Ye*", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				source := data["_source"].(map[string]interface{})
+				So(source, ShouldNotBeEmpty)
+				So(source, ShouldNotContainKey, "City")
+				So(source, ShouldNotContainKey, "Year")
+			})
+		})
+
+		Convey("GET /es/:target/_source/:id", func() {
+			Convey("get source with exist id", func() {
+				resp := request("GET", "/es/"+indexName+"/_source/1111", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["City"], ShouldEqual, "Turin")
+			})
+			Convey("get source with not exist id", func() {
+				resp := request("GET", "/es/"+indexName+"/_source/notexistGet", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("get source with source includes and excludes", func() {
+				resp := request("GET", "/es/"+indexName+"/_source/1111?_source_includes=City,Year&_source_excludes=Year", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data, ShouldResemble, map[string]interface{}{"City": "Turin"})
+			})
+		})
+
+		Convey("POST /es/_mget", func() {
+			Convey("mget documents with docs", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"docs": [
+					{"_index": "` + indexName + `", "_id": "1111"},
+					{"_index": "` + indexName + `", "_id": "notexistGet"},
+					{"_index": "notExistIndexGet", "_id": "1111"}
+				]}`)
+				resp := request("POST", "/es/_mget", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string][]map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(len(data["docs"]), ShouldEqual, 3)
+				So(data["docs"][0]["found"], ShouldEqual, true)
+				So(data["docs"][1]["found"], ShouldEqual, false)
+				So(data["docs"][2]["error"], ShouldNotBeNil)
+			})
+			Convey("mget documents with ids", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"ids": ["1111", "notexistGet"]}`)
+				resp := request("POST", "/es/"+indexName+"/_mget", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string][]map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(len(data["docs"]), ShouldEqual, 2)
+				So(data["docs"][0]["_id"], ShouldEqual, "1111")
+			})
+			Convey("mget documents with error input", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"ids": ["1111"]}`)
+				resp := request("POST", "/es/_mget", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("mget documents with invalid _source", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"docs": [{"_index": "` + indexName + `", "_id": "1111", "_source": 1}]}`)
+				resp := request("POST", "/es/_mget", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["error"].(map[string]interface{})["type"], ShouldEqual, "x_content_parse_exception")
+			})
+		})
+
 		Convey("DELETE /es/:target/_doc/:id", func() {
 			Convey("delete document with not exist indexName", func() {
 				resp := request("DELETE", "/es/notExistIndexDelete/_doc/1111", nil)
@@ -173,31 +418,41 @@ func TestApiES(t *testing.T) {
 		})
 
 		Convey("PUT /es/:target/_create/:id", func() {
-			Convey("update document with not exist indexName", func() {
+			Convey("create document with not exist indexName", func() {
 				body := bytes.NewBuffer(nil)
 				body.WriteString(indexData)
 				resp := request("PUT", "/es/notExistIndexCreate/_create/1111", body)
 				So(resp.Code, ShouldEqual, http.StatusOK)
 			})
-			Convey("update document with exist indexName", func() {
+			Convey("create document with exist indexName", func() {
 				body := bytes.NewBuffer(nil)
 				body.WriteString(indexData)
 				resp := request("PUT", "/es/"+indexName+"/_create/1111", body)
 				So(resp.Code, ShouldEqual, http.StatusOK)
 			})
-			Convey("update document with exist indexName not exist id", func() {
+			Convey("create document with exist indexName not exist id", func() {
 				body := bytes.NewBuffer(nil)
 				body.WriteString(indexData)
 				resp := request("PUT", "/es/"+indexName+"/_create/notexistCreate", body)
 				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["result"], ShouldEqual, "created")
 			})
-			Convey("update document with exist indexName and exist id", func() {
+			Convey("create document with exist indexName and exist id", func() {
 				body := bytes.NewBuffer(nil)
 				body.WriteString(indexData)
 				resp := request("PUT", "/es/"+indexName+"/_create/1111", body)
-				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Code, ShouldEqual, http.StatusConflict)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["error"].(map[string]interface{})["type"], ShouldEqual, "version_conflict_engine_exception")
 			})
-			Convey("update document with error input", func() {
+			Convey("create document with error input", func() {
 				body := bytes.NewBuffer(nil)
 				body.WriteString(`xxx`)
 				resp := request("PUT", "/es/"+indexName+"/_create/1111", body)
@@ -206,62 +461,142 @@ func TestApiES(t *testing.T) {
 		})
 
 		Convey("POST /es/:target/_create/:id", func() {
-			Convey("update document with not exist indexName", func() {
+			Convey("create document with exist indexName not exist id", func() {
 				body := bytes.NewBuffer(nil)
 				body.WriteString(indexData)
-				resp := request("POST", "/es/notExistIndexCreate/_create/1111", body)
+				resp := request("POST", "/es/"+indexName+"/_create/notexistCreatePost", body)
 				So(resp.Code, ShouldEqual, http.StatusOK)
 			})
-			Convey("update document with exist indexName", func() {
+			Convey("create document with exist indexName and exist id", func() {
 				body := bytes.NewBuffer(nil)
 				body.WriteString(indexData)
 				resp := request("POST", "/es/"+indexName+"/_create/1111", body)
-				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Code, ShouldEqual, http.StatusConflict)
 			})
-			Convey("update document with exist indexName not exist id", func() {
+			Convey("create document with error input", func() {
 				body := bytes.NewBuffer(nil)
-				body.WriteString(indexData)
-				resp := request("POST", "/es/"+indexName+"/_create/notexistCreate", body)
-				So(resp.Code, ShouldEqual, http.StatusOK)
+				body.WriteString(`xxx`)
+				resp := request("POST", "/es/"+indexName+"/_create/1111", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
 			})
-			Convey("update document with exist indexName and exist id", func() {
+		})
+
+		Convey("PUT /es/:target/_doc/:id?op_type=create", func() {
+			Convey("create document with exist id", func() {
 				body := bytes.NewBuffer(nil)
 				body.WriteString(indexData)
-				resp := request("POST", "/es/"+indexName+"/_create/1111", body)
-				So(resp.Code, ShouldEqual, http.StatusOK)
+				resp := request("PUT", "/es/"+indexName+"/_doc/1111?op_type=create", body)
+				So(resp.Code, ShouldEqual, http.StatusConflict)
 			})
-			Convey("update document with error input", func() {
+			Convey("create document with unknown op_type", func() {
 				body := bytes.NewBuffer(nil)
-				body.WriteString(`xxx`)
-				resp := request("POST", "/es/"+indexName+"/_create/1111", body)
+				body.WriteString(indexData)
+				resp := request("PUT", "/es/"+indexName+"/_doc/1111?op_type=xxx", body)
 				So(resp.Code, ShouldEqual, http.StatusBadRequest)
 			})
 		})
 
+		Convey("POST /es/_bulk with create", func() {
+			Convey("bulk create existing and new documents", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"create": {"_index": "` + indexName + `", "_id": "1111"}}
+{"name": "exists"}
+{"create": {"_index": "` + indexName + `", "_id": "bulkCreate"}}
+{"name": "new"}
+{"index": {"_index": "` + indexName + `", "_id": "bulkCreate"}}
+{"name": "again"}`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(struct {
+					Errors bool                                `json:"errors"`
+					Items  []map[string]map[string]interface{} `json:"items"`
+				})
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(data.Errors, ShouldBeTrue)
+				So(data.Items[0]["create"]["status"], ShouldEqual, http.StatusConflict)
+				So(data.Items[1]["create"]["result"], ShouldEqual, "created")
+				So(data.Items[2]["index"]["result"], ShouldEqual, "updated")
+			})
+		})
+
 		Convey("POST /es/:target/_update/:id", func() {
 			Convey("update document with not exist indexName", func() {
 				body := bytes.NewBuffer(nil)
-				body.WriteString(indexData)
-				resp := request("POST", "/es/notExistIndexCreate/_update/1111", body)
+				body.WriteString(`{"doc": ` + indexData + `}`)
+				resp := request("POST", "/es/notExistIndexUpdate/_update/1111", body)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("upsert document with not exist indexName", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"doc": ` + indexData + `, "doc_as_upsert": true}`)
+				resp := request("POST", "/es/notExistIndexUpsert/_update/1111", body)
 				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["result"], ShouldEqual, "created")
 			})
-			Convey("update document with exist indexName", func() {
+			Convey("update document with exist indexName and exist id", func() {
 				body := bytes.NewBuffer(nil)
-				body.WriteString(indexData)
+				body.WriteString(`{"doc": {"City": "Paris", "Extra": {"Note": "moved"}}}`)
+				resp := request("POST", "/es/"+indexName+"/_update/1111", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["result"], ShouldEqual, "updated")
+
+				resp = request("GET", "/es/"+indexName+"/_source/1111", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				source := make(map[string]interface{})
+				err = json.Unmarshal(resp.Body.Bytes(), &source)
+				So(err, ShouldBeNil)
+				So(source["City"], ShouldEqual, "Paris")
+				So(source["Athlete"], ShouldEqual, "DEMTSCHENKO, Albert")
+				So(source["Extra"], ShouldResemble, map[string]interface{}{"Note": "moved"})
+			})
+			Convey("update document with dotted field and no changes", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"doc": {"City": "Paris", "Extra.Note": "moved"}}`)
 				resp := request("POST", "/es/"+indexName+"/_update/1111", body)
 				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["result"], ShouldEqual, "noop")
 			})
 			Convey("update document with exist indexName not exist id", func() {
 				body := bytes.NewBuffer(nil)
-				body.WriteString(indexData)
-				resp := request("POST", "/es/"+indexName+"/_update/notexistCreate", body)
+				body.WriteString(`{"doc": ` + indexData + `}`)
+				resp := request("POST", "/es/"+indexName+"/_update/notexistUpdate", body)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["error"].(map[string]interface{})["type"], ShouldEqual, "document_missing_exception")
+			})
+			Convey("upsert document with exist indexName not exist id", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"doc": {"City": "Paris"}, "upsert": ` + indexData + `}`)
+				resp := request("POST", "/es/"+indexName+"/_update/notexistUpsert", body)
 				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["result"], ShouldEqual, "created")
 			})
-			Convey("update document with exist indexName and exist id", func() {
+			Convey("update document without doc", func() {
 				body := bytes.NewBuffer(nil)
 				body.WriteString(indexData)
 				resp := request("POST", "/es/"+indexName+"/_update/1111", body)
-				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
 			})
 			Convey("update document with error input", func() {
 				body := bytes.NewBuffer(nil)
@@ -271,5 +606,1833 @@ func TestApiES(t *testing.T) {
 			})
 		})
 
+		Convey("POST /es/_bulk with update", func() {
+			Convey("bulk partial update documents", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "` + indexName + `", "_id": "bulkUpdate"}}
+{"field1": "value1", "field2": "value2"}
+{"update": {"_index": "` + indexName + `", "_id": "bulkUpdate"}}
+{"doc": {"field2": "changed"}}
+{"update": {"_index": "` + indexName + `", "_id": "bulkUpdate"}}
+{"doc": {"field2": "changed"}}
+{"update": {"_index": "` + indexName + `", "_id": "bulkUpdateMissing"}}
+{"doc": {"field2": "changed"}}`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(struct {
+					Errors bool                                `json:"errors"`
+					Items  []map[string]map[string]interface{} `json:"items"`
+				})
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(data.Errors, ShouldBeTrue)
+				So(len(data.Items), ShouldEqual, 4)
+				So(data.Items[1]["update"]["result"], ShouldEqual, "updated")
+				So(data.Items[2]["update"]["result"], ShouldEqual, "noop")
+				So(data.Items[3]["update"]["status"], ShouldEqual, 404)
+
+				resp = request("GET", "/es/"+indexName+"/_source/bulkUpdate", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				source := make(map[string]interface{})
+				err = json.Unmarshal(resp.Body.Bytes(), &source)
+				So(err, ShouldBeNil)
+				So(source, ShouldResemble, map[string]interface{}{"field1": "value1", "field2": "changed"})
+			})
+			Convey("bulk update documents of a missing index", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"update": {"_index": "bulkupdatemissing", "_id": "1"}}
+{"doc": {"field1": "value1"}}
+{"update": {"_index": "bulkupdateupsert", "_id": "1"}}
+{"doc": {"field1": "value1"}, "doc_as_upsert": true}
+`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(struct {
+					Items []map[string]map[string]interface{} `json:"items"`
+				})
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(data.Items[0]["update"]["status"], ShouldEqual, 404)
+				So(data.Items[1]["update"]["result"], ShouldEqual, "created")
+
+				resp = request("GET", "/es/bulkupdatemissing/_doc/1", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+				So(resp.Body.String(), ShouldContainSubstring, "index_not_found_exception")
+				resp = request("GET", "/es/bulkupdateupsert/_doc/1", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("PUT /es/:target/_doc/:id with version control", func() {
+			request("DELETE", "/es/"+indexName+"/_doc/versioned", nil)
+			data := make(map[string]interface{})
+			body := bytes.NewBuffer(nil)
+			body.WriteString(`{"name": "v1"}`)
+			resp := request("PUT", "/es/"+indexName+"/_doc/versioned", body)
+			So(resp.Code, ShouldEqual, http.StatusOK)
+			err := json.Unmarshal(resp.Body.Bytes(), &data)
+			So(err, ShouldBeNil)
+			So(data["_version"], ShouldEqual, 1)
+			So(data["result"], ShouldEqual, "created")
+			seqNo := strconv.Itoa(int(data["_seq_no"].(float64)))
+
+			Convey("update document with current if_seq_no", func() {
+				body.Reset()
+				body.WriteString(`{"name": "v2"}`)
+				resp := request("PUT", "/es/"+indexName+"/_doc/versioned?if_seq_no="+seqNo+"&if_primary_term=1", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["_version"], ShouldEqual, 2)
+				So(data["result"], ShouldEqual, "updated")
+
+				resp = request("GET", "/es/"+indexName+"/_doc/versioned", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				err = json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["_version"], ShouldEqual, 2)
+
+				// the old sequence number is stale now
+				body.Reset()
+				body.WriteString(`{"doc": {"name": "v3"}}`)
+				resp = request("POST", "/es/"+indexName+"/_update/versioned?if_seq_no="+seqNo+"&if_primary_term=1", body)
+				So(resp.Code, ShouldEqual, http.StatusConflict)
+				err = json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["error"].(map[string]interface{})["type"], ShouldEqual, "version_conflict_engine_exception")
+			})
+			Convey("update document with external version", func() {
+				body.Reset()
+				body.WriteString(`{"name": "v10"}`)
+				resp := request("PUT", "/es/"+indexName+"/_doc/versioned?version=10&version_type=external", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["_version"], ShouldEqual, 10)
+
+				body.Reset()
+				body.WriteString(`{"name": "v5"}`)
+				resp = request("PUT", "/es/"+indexName+"/_doc/versioned?version=5&version_type=external", body)
+				So(resp.Code, ShouldEqual, http.StatusConflict)
+			})
+			Convey("delete a missing document doesn't take a sequence number", func() {
+				resp := request("DELETE", "/es/"+indexName+"/_doc/versionedMissing", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["result"], ShouldEqual, "not_found")
+				So(strconv.Itoa(int(data["_seq_no"].(float64))), ShouldEqual, seqNo)
+
+				// the current if_seq_no still matches
+				body.Reset()
+				body.WriteString(`{"name": "v2"}`)
+				resp = request("PUT", "/es/"+indexName+"/_doc/versioned?if_seq_no="+seqNo+"&if_primary_term=1", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("bulk with stale if_seq_no", func() {
+				body.Reset()
+				body.WriteString(`{"index": {"_index": "` + indexName + `", "_id": "versioned", "if_seq_no": 0, "if_primary_term": 1}}
+{"name": "stale"}
+{"delete": {"_index": "` + indexName + `", "_id": "versioned", "if_seq_no": ` + seqNo + `, "if_primary_term": 1}}`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				ret := new(struct {
+					Errors bool                                `json:"errors"`
+					Items  []map[string]map[string]interface{} `json:"items"`
+				})
+				err := json.Unmarshal(resp.Body.Bytes(), ret)
+				So(err, ShouldBeNil)
+				So(ret.Errors, ShouldBeTrue)
+				So(ret.Items[0]["index"]["status"], ShouldEqual, http.StatusConflict)
+				So(ret.Items[1]["delete"]["result"], ShouldEqual, "deleted")
+			})
+			Convey("concurrent bulk with the same if_seq_no", func() {
+				var wg sync.WaitGroup
+				results := make([]string, 8)
+				for i := range results {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						body := bytes.NewBuffer(nil)
+						body.WriteString(`{"index": {"_index": "` + indexName + `", "_id": "versioned", "if_seq_no": ` + seqNo + `, "if_primary_term": 1}}
+{"name": "racer` + strconv.Itoa(i) + `"}
+`)
+						// more actions after the check keep the batch open longer
+						for j := 0; j < 200; j++ {
+							body.WriteString(`{"index": {"_index": "` + indexName + `"}}
+{"name": "filler"}
+`)
+						}
+						results[i] = request("POST", "/es/_bulk", body).Body.String()
+					}(i)
+				}
+				wg.Wait()
+
+				succeeded := 0
+				for _, result := range results {
+					if !strings.Contains(result, `"status":409`) {
+						succeeded++
+					}
+				}
+				So(succeeded, ShouldEqual, 1)
+			})
+		})
+
+		Convey("POST /es/:target/_update_by_query", func() {
+			Convey("init data for by query", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "byquery", "_id": "1"}}
+{"level": "error", "message": "disk full"}
+{"index": {"_index": "byquery", "_id": "2"}}
+{"level": "error", "message": "out of memory"}
+{"index": {"_index": "byquery", "_id": "3"}}
+{"level": "info", "message": "started"}`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("update by query with not exist indexName", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"match_all": {}}}`)
+				resp := request("POST", "/es/notExistIndexByQuery/_update_by_query", body)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("update by query with exist indexName", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"match": {"level": "error"}}}`)
+				resp := request("POST", "/es/byquery/_update_by_query", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["total"], ShouldEqual, 2)
+				So(data["updated"], ShouldEqual, 2)
+				So(data["version_conflicts"], ShouldEqual, 0)
+			})
+			Convey("update by query with invalid conflicts", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"match_all": {}}}`)
+				resp := request("POST", "/es/byquery/_update_by_query?conflicts=ignore", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+
+		Convey("POST /es/:target/_delete_by_query", func() {
+			Convey("delete by query with error query", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"unknown": {}}}`)
+				resp := request("POST", "/es/byquery/_delete_by_query", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("delete by query with exist indexName", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"match": {"level": "error"}}}`)
+				resp := request("POST", "/es/byquery/_delete_by_query", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["total"], ShouldEqual, 2)
+				So(data["deleted"], ShouldEqual, 2)
+				So(data["failures"], ShouldBeEmpty)
+
+				resp = request("GET", "/es/byquery/_doc/1", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+				resp = request("GET", "/es/byquery/_doc/3", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("POST /es/:target/_count", func() {
+			Convey("init data for count", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "countdocs", "_id": "1"}}
+{"level": "error", "message": "disk full"}
+{"index": {"_index": "countdocs", "_id": "1"}}
+{"level": "error", "message": "disk full again"}
+{"index": {"_index": "countdocs", "_id": "2"}}
+{"level": "info", "message": "started"}
+{"delete": {"_index": "countdocs", "_id": "notExist"}}`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("count with not exist indexName", func() {
+				resp := request("POST", "/es/notExistIndexCount/_count", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("count with query", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"match": {"level": "error"}}}`)
+				resp := request("POST", "/es/countdocs/_count", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["count"], ShouldEqual, 1)
+			})
+			Convey("count without query", func() {
+				resp := request("GET", "/es/countdocs/_count", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["count"], ShouldEqual, 2)
+			})
+			Convey("docs count of the index", func() {
+				resp := request("GET", "/api/index", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make([]map[string]interface{}, 0)
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				for _, index := range data {
+					if index["name"] == "countdocs" {
+						So(index["docs_count"], ShouldEqual, 2)
+					}
+				}
+			})
+			Convey("docs count after single document writes", func() {
+				docsCount := func() interface{} {
+					resp := request("GET", "/api/index", nil)
+					So(resp.Code, ShouldEqual, http.StatusOK)
+					data := make([]map[string]interface{}, 0)
+					err := json.Unmarshal(resp.Body.Bytes(), &data)
+					So(err, ShouldBeNil)
+					for _, index := range data {
+						if index["name"] == "countdocs" {
+							return index["docs_count"]
+						}
+					}
+					return nil
+				}
+
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"level": "debug", "message": "created"}`)
+				resp := request("PUT", "/es/countdocs/_doc/3", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(docsCount(), ShouldEqual, 3)
+
+				body.Reset()
+				body.WriteString(`{"level": "debug", "message": "updated"}`)
+				resp = request("PUT", "/es/countdocs/_doc/3", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(docsCount(), ShouldEqual, 3)
+
+				resp = request("DELETE", "/es/countdocs/_doc/notExist", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(docsCount(), ShouldEqual, 3)
+
+				resp = request("DELETE", "/es/countdocs/_doc/3", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(docsCount(), ShouldEqual, 2)
+			})
+		})
+
+		Convey("GET /es/_cat", func() {
+			Convey("cat indices", func() {
+				resp := request("GET", "/es/_cat/indices/countdocs?v&h=index,dc", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldEqual, "index     docs.count\ncountdocs          2\n")
+			})
+			Convey("cat indices with the store size in bytes", func() {
+				resp := request("GET", "/es/_cat/indices/countdocs?h=ss&bytes=b", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				size, err := strconv.Atoi(strings.TrimSpace(resp.Body.String()))
+				So(err, ShouldBeNil)
+				So(size, ShouldBeGreaterThan, 1024)
+			})
+			Convey("cat indices with json format", func() {
+				resp := request("GET", "/es/_cat/indices?format=json&s=index:desc&bytes=b", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make([]map[string]string, 0)
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(len(data), ShouldBeGreaterThanOrEqualTo, 2)
+				So(data[0]["index"], ShouldBeGreaterThan, data[1]["index"])
+				So(data[0]["health"], ShouldEqual, "green")
+				_, err = strconv.Atoi(data[0]["store.size"])
+				So(err, ShouldBeNil)
+			})
+			Convey("cat indices with not exist indexName", func() {
+				resp := request("GET", "/es/_cat/indices/notExistIndexCat", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("cat indices with unknown column", func() {
+				resp := request("GET", "/es/_cat/indices?h=unknown", nil)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("cat count", func() {
+				resp := request("GET", "/es/_cat/count/countdocs?h=count", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldEqual, "2\n")
+			})
+			Convey("cat templates", func() {
+				resp := request("GET", "/es/_cat/templates?format=json", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("cat health", func() {
+				resp := request("GET", "/es/_cat/health?h=cluster,status", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldEqual, "zinc green\n")
+			})
+		})
+
+		Convey("POST /es/_reindex", func() {
+			Convey("init data for reindex", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "reindexsrc", "_id": "1"}}
+{"level": "error", "message": "disk full"}
+{"index": {"_index": "reindexsrc", "_id": "2"}}
+{"level": "error", "message": "out of memory"}
+{"index": {"_index": "reindexsrc", "_id": "3"}}
+{"level": "info", "message": "started"}`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("reindex with not exist source", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"source": {"index": "notExistIndexReindex"}, "dest": {"index": "reindexdest"}}`)
+				resp := request("POST", "/es/_reindex", body)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("reindex with query and _source", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"source": {"index": "reindexsrc", "query": {"match": {"level": "error"}}, "_source": ["message"]}, "dest": {"index": "reindexdest"}}`)
+				resp := request("POST", "/es/_reindex", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["total"], ShouldEqual, 2)
+				So(data["failures"], ShouldBeEmpty)
+
+				resp = request("GET", "/es/reindexdest/_doc/1", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				err = json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["_source"].(map[string]interface{})["message"], ShouldEqual, "disk full")
+				So(data["_source"].(map[string]interface{})["level"], ShouldBeNil)
+				resp = request("GET", "/es/reindexdest/_doc/3", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("reindex with op_type create", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"source": {"index": "reindexsrc"}, "dest": {"index": "reindexdest", "op_type": "create"}, "conflicts": "proceed"}`)
+				resp := request("POST", "/es/_reindex", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["created"], ShouldEqual, 1)
+				So(data["version_conflicts"], ShouldEqual, 2)
+				So(data["failures"], ShouldBeEmpty)
+			})
+			Convey("reindex in background", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"source": {"index": "reindexsrc"}, "dest": {"index": "reindexbackground"}}`)
+				resp := request("POST", "/es/_reindex?wait_for_completion=false", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["task"], ShouldNotBeEmpty)
+
+				resp = request("GET", "/es/_tasks/"+data["task"].(string)+"?wait_for_completion=true", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				err = json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["completed"], ShouldBeTrue)
+				So(data["response"].(map[string]interface{})["created"], ShouldEqual, 3)
+
+				resp = request("GET", "/es/_tasks/zinc:0", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("reindex from remote", func() {
+				remote := httptest.NewServer(server())
+				defer remote.Close()
+
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"source": {"index": "reindexsrc", "size": 2, "remote": {"host": "` + remote.URL + `", "username": "` + username + `", "password": "` + password + `"}}, "dest": {"index": "reindexremote"}}`)
+				resp := request("POST", "/es/_reindex", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["total"], ShouldEqual, 3)
+				So(data["batches"], ShouldEqual, 2)
+
+				resp = request("GET", "/es/reindexremote/_doc/2", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("reindex from remote with a failed request", func() {
+				remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusForbidden)
+					w.Write([]byte(`<html>secret page</html>`))
+				}))
+				defer remote.Close()
+
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"source": {"index": "reindexsrc", "remote": {"host": "` + remote.URL + `"}}, "dest": {"index": "reindexremotefailed"}}`)
+				resp := request("POST", "/es/_reindex", body)
+				So(resp.Body.String(), ShouldContainSubstring, "responded [403]")
+				So(resp.Body.String(), ShouldNotContainSubstring, "secret page")
+			})
+			Convey("reindex from a remote not in the whitelist", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"source": {"index": "reindexsrc", "remote": {"host": "http://localhost:4080"}}, "dest": {"index": "reindexremote"}}`)
+				resp := request("POST", "/es/_reindex", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(resp.Body.String(), ShouldContainSubstring, "illegal_argument_exception")
+				So(resp.Body.String(), ShouldContainSubstring, "[localhost:4080] not whitelisted")
+			})
+		})
+
+		Convey("POST /es/:target/_search with search_after", func() {
+			Convey("init data for search_after", func() {
+				body := bytes.NewBuffer(nil)
+				for i := 1; i <= 5; i++ {
+					body.WriteString(`{"index": {"_index": "searchafter", "_id": "` + strconv.Itoa(i) + `"}}
+{"n": ` + strconv.Itoa(i) + `, "name": "doc` + strconv.Itoa(i) + `"}
+`)
+				}
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("search with search_after", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"match_all": {}}, "sort": [{"n": "asc"}], "size": 2, "search_after": [2]}`)
+				resp := request("POST", "/es/searchafter/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(len(data.Hits.Hits), ShouldEqual, 2)
+				So(data.Hits.Hits[0].ID, ShouldEqual, "3")
+				So(data.Hits.Hits[1].ID, ShouldEqual, "4")
+				So(data.Hits.Hits[1].Sort, ShouldResemble, []interface{}{float64(4)})
+			})
+			Convey("search with search_after and from", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"sort": [{"n": "asc"}], "from": 2, "search_after": [2]}`)
+				resp := request("POST", "/es/searchafter/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("search with point in time", func() {
+				resp := request("POST", "/es/searchafter/_pit?keep_alive=1m", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				pit := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &pit)
+				So(err, ShouldBeNil)
+				So(pit["id"], ShouldNotBeEmpty)
+				pitID := pit["id"].(string)
+
+				// the point in time doesn't see new documents
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"n": 0}`)
+				resp = request("PUT", "/es/searchafter/_doc/0", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"pit": {"id": "` + pitID + `", "keep_alive": "1m"}, "sort": [{"n": "asc"}], "size": 3}`)
+				resp = request("POST", "/es/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				data := new(meta.SearchResponse)
+				err = json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(data.PitID, ShouldEqual, pitID)
+				So(len(data.Hits.Hits), ShouldEqual, 3)
+				So(data.Hits.Hits[0].ID, ShouldEqual, "1")
+				last := data.Hits.Hits[2].Sort
+				So(last, ShouldResemble, []interface{}{float64(3), "3"})
+
+				after, _ := json.Marshal(last)
+				body.Reset()
+				body.WriteString(`{"pit": {"id": "` + pitID + `"}, "sort": [{"n": "asc"}], "size": 3, "search_after": ` + string(after) + `}`)
+				resp = request("POST", "/es/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				data = new(meta.SearchResponse)
+				err = json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(len(data.Hits.Hits), ShouldEqual, 2)
+				So(data.Hits.Hits[0].ID, ShouldEqual, "4")
+
+				body.Reset()
+				body.WriteString(`{"id": "` + pitID + `"}`)
+				resp = request("DELETE", "/es/_pit", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"pit": {"id": "` + pitID + `"}}`)
+				resp = request("POST", "/es/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("search with scroll", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"match_all": {}}, "size": 2}`)
+				resp := request("POST", "/es/searchafter/_search?scroll=1m", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(data.ScrollID, ShouldNotBeEmpty)
+				scrollID := data.ScrollID
+
+				ids := make(map[string]struct{})
+				for len(data.Hits.Hits) > 0 {
+					So(len(data.Hits.Hits), ShouldBeLessThanOrEqualTo, 2)
+					for _, hit := range data.Hits.Hits {
+						So(hit.Sort, ShouldBeEmpty)
+						ids[hit.ID] = struct{}{}
+					}
+					body.Reset()
+					body.WriteString(`{"scroll": "1m", "scroll_id": "` + scrollID + `"}`)
+					resp = request("POST", "/es/_search/scroll", body)
+					So(resp.Code, ShouldEqual, http.StatusOK)
+					data = new(meta.SearchResponse)
+					err = json.Unmarshal(resp.Body.Bytes(), data)
+					So(err, ShouldBeNil)
+				}
+				So(len(ids), ShouldEqual, data.Hits.Total.Value)
+
+				body.Reset()
+				body.WriteString(`{"scroll_id": ["` + scrollID + `"]}`)
+				resp = request("DELETE", "/es/_search/scroll", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				resp = request("DELETE", "/es/_search/scroll/"+scrollID, nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+				resp = request("GET", "/es/_search/scroll?scroll_id="+scrollID, nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+		Convey("POST /es/:target/_search with boosting", func() {
+			Convey("init data for boosting", func() {
+				body := bytes.NewBuffer(nil)
+				for i, name := range []string{"apple juice", "apple pie", "apple tart"} {
+					body.WriteString(`{"index": {"_index": "boosting", "_id": "` + strconv.Itoa(i+1) + `"}}
+{"name": "` + name + `"}
+`)
+				}
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("search with boosting", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"boosting": {"positive": {"match": {"name": "apple"}}, "negative": {"match": {"name": "juice"}}, "negative_boost": 0.1}}}`)
+				resp := request("POST", "/es/boosting/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(len(data.Hits.Hits), ShouldEqual, 3)
+				So(data.Hits.Hits[2].ID, ShouldEqual, "1")
+				So(data.Hits.Hits[2].Score, ShouldBeLessThan, data.Hits.Hits[1].Score)
+			})
+			Convey("search with boosting without negative_boost", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"boosting": {"positive": {"match": {"name": "apple"}}, "negative": {"match": {"name": "juice"}}}}}`)
+				resp := request("POST", "/es/boosting/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+		Convey("POST /es/:target/_search with combined_fields and terms_set", func() {
+			Convey("init data for combined_fields and terms_set", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "combinedfields", "_id": "1"}}
+{"title": "quick brown fox", "body": "lazy dog", "tags": ["red", "green", "blue"], "required": 2}
+{"index": {"_index": "combinedfields", "_id": "2"}}
+{"title": "lazy cat", "body": "quick fox jumps", "tags": ["red"], "required": 2}
+{"index": {"_index": "combinedfields", "_id": "3"}}
+{"title": "slow turtle", "body": "nothing here", "tags": ["green", "blue"], "required": 1}
+`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			search := func(query string) []string {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": ` + query + `}`)
+				resp := request("POST", "/es/combinedfields/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				ids := make([]string, 0, len(data.Hits.Hits))
+				for _, hit := range data.Hits.Hits {
+					ids = append(ids, hit.ID)
+				}
+				return ids
+			}
+			Convey("search with combined_fields", func() {
+				ids := search(`{"combined_fields": {"query": "quick fox", "fields": ["title", "body"], "operator": "and"}}`)
+				So(ids, ShouldHaveLength, 2)
+				So(ids, ShouldContain, "1")
+				So(ids, ShouldContain, "2")
+
+				ids = search(`{"combined_fields": {"query": "quick fox turtle", "fields": ["title", "body"], "minimum_should_match": "2"}}`)
+				So(ids, ShouldHaveLength, 2)
+				So(ids, ShouldNotContain, "3")
+
+				ids = search(`{"combined_fields": {"query": "quick fox", "fields": ["title^2", "body"]}}`)
+				So(ids, ShouldResemble, []string{"1", "2"})
+			})
+			Convey("search with combined_fields counts the length of all the fields", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "combinedlength", "_id": "1"}}
+{"title": "fox", "body": "a long body without the term of the query"}
+{"index": {"_index": "combinedlength", "_id": "2"}}
+{"title": "fox", "body": "short"}
+`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"query": {"combined_fields": {"query": "fox", "fields": ["title", "body"]}}}`)
+				resp = request("POST", "/es/combinedlength/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(data.Hits.Hits, ShouldHaveLength, 2)
+				So(data.Hits.Hits[0].ID, ShouldEqual, "2")
+				So(data.Hits.Hits[0].Score, ShouldBeGreaterThan, data.Hits.Hits[1].Score)
+			})
+			Convey("search with combined_fields on not text field", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"combined_fields": {"query": "2", "fields": ["title", "required"]}}}`)
+				resp := request("POST", "/es/combinedfields/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("search with terms_set", func() {
+				ids := search(`{"terms_set": {"tags": {"terms": ["red", "green", "blue"], "minimum_should_match_field": "required"}}}`)
+				So(ids, ShouldHaveLength, 2)
+				So(ids, ShouldContain, "1")
+				So(ids, ShouldContain, "3")
+
+				ids = search(`{"terms_set": {"tags": {"terms": ["red", "green", "blue"], "minimum_should_match_script": {"source": "Math.min(params.num_terms, doc['required'].value)"}}}}`)
+				So(ids, ShouldHaveLength, 2)
+				So(ids, ShouldNotContain, "2")
+
+				ids = search(`{"terms_set": {"tags": {"terms": ["red", "green", "blue"], "minimum_should_match_script": {"source": "params.num_terms"}}}}`)
+				So(ids, ShouldResemble, []string{"1"})
+			})
+		})
+		Convey("POST /es/:target/_search with geo_point", func() {
+			Convey("init data for geo_point", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"mappings": {"properties": {"name": {"type": "keyword"}, "location": {"type": "geo_point"}}}}`)
+				resp := request("PUT", "/es/geostores/_mapping", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"index": {"_index": "geostores", "_id": "1"}}
+{"name": "new york", "location": {"lat": 40.7128, "lon": -74.0060}}
+{"index": {"_index": "geostores", "_id": "2"}}
+{"name": "brooklyn", "location": "40.6782,-73.9442"}
+{"index": {"_index": "geostores", "_id": "3"}}
+{"name": "san francisco", "location": "9q8yyk8yt"}
+{"index": {"_index": "geostores", "_id": "4"}}
+{"name": "london", "location": [-0.1276, 51.5072]}
+`)
+				resp = request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, `"errors":false`)
+			})
+			search := func(query string) *meta.SearchResponse {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(query)
+				resp := request("POST", "/es/geostores/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				return data
+			}
+			ids := func(data *meta.SearchResponse) []string {
+				ids := make([]string, 0, len(data.Hits.Hits))
+				for _, hit := range data.Hits.Hits {
+					ids = append(ids, hit.ID)
+				}
+				return ids
+			}
+			Convey("index document with invalid geo_point", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"name": "nowhere", "location": {"lat": 100, "lon": 0}}`)
+				resp := request("PUT", "/es/geostores/_doc/5", body)
+				So(resp.Code, ShouldNotEqual, http.StatusOK)
+			})
+			Convey("search with geo_distance", func() {
+				data := search(`{"query": {"geo_distance": {"distance": "20km", "location": {"lat": 40.7128, "lon": -74.0060}}}}`)
+				So(ids(data), ShouldHaveLength, 2)
+				So(ids(data), ShouldContain, "1")
+				So(ids(data), ShouldContain, "2")
+			})
+			Convey("search with geo_bounding_box", func() {
+				data := search(`{"query": {"geo_bounding_box": {"location": {"top_left": {"lat": 42, "lon": -75}, "bottom_right": "40,-73"}}}}`)
+				So(ids(data), ShouldHaveLength, 2)
+				So(ids(data), ShouldNotContain, "3")
+
+				data = search(`{"query": {"geo_bounding_box": {"location": {"wkt": "BBOX (-123, -122, 38, 37)"}}}}`)
+				So(ids(data), ShouldResemble, []string{"3"})
+			})
+			Convey("search with geo_polygon", func() {
+				data := search(`{"query": {"geo_polygon": {"location": {"points": [[-1, 51], [1, 51], [0, 52]]}}}}`)
+				So(ids(data), ShouldResemble, []string{"4"})
+			})
+			Convey("search with geo query on not geo_point field", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"geo_distance": {"distance": "20km", "name": [0, 0]}}}`)
+				resp := request("POST", "/es/geostores/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("search with _geo_distance sort", func() {
+				data := search(`{"sort": [{"_geo_distance": {"location": [-0.1276, 51.5072], "order": "asc", "unit": "km"}}]}`)
+				So(ids(data), ShouldHaveLength, 4)
+				So(data.Hits.Hits[0].ID, ShouldEqual, "4")
+				So(data.Hits.Hits[0].Sort[0], ShouldBeLessThan, 1)
+				So(data.Hits.Hits[3].ID, ShouldEqual, "3")
+				So(data.Hits.Hits[3].Sort[0], ShouldBeGreaterThan, 8000)
+			})
+		})
+		Convey("POST /es/:target/_search with function_score", func() {
+			Convey("init data for function_score", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"mappings": {"properties": {"name": {"type": "text"}, "likes": {"type": "numeric"}, "published": {"type": "date"}}}}`)
+				resp := request("PUT", "/es/functionscore/_mapping", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"index": {"_index": "functionscore", "_id": "1"}}
+{"name": "red phone", "likes": 10, "published": "2022-01-01T00:00:00Z"}
+{"index": {"_index": "functionscore", "_id": "2"}}
+{"name": "blue phone", "likes": 100, "published": "2021-01-01T00:00:00Z"}
+{"index": {"_index": "functionscore", "_id": "3"}}
+{"name": "green phone", "likes": 1, "published": "2022-01-10T00:00:00Z"}
+`)
+				resp = request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			search := func(query string) *meta.SearchResponse {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": ` + query + `}`)
+				resp := request("POST", "/es/functionscore/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				return data
+			}
+			ids := func(data *meta.SearchResponse) []string {
+				ids := make([]string, 0, len(data.Hits.Hits))
+				for _, hit := range data.Hits.Hits {
+					ids = append(ids, hit.ID)
+				}
+				return ids
+			}
+			Convey("search with field_value_factor", func() {
+				data := search(`{"function_score": {"query": {"match": {"name": "phone"}}, "field_value_factor": {"field": "likes"}, "boost_mode": "replace"}}`)
+				So(ids(data), ShouldResemble, []string{"2", "1", "3"})
+				So(data.Hits.Hits[0].Score, ShouldEqual, 100)
+
+				data = search(`{"function_score": {"field_value_factor": {"field": "likes", "modifier": "log1p"}, "boost_mode": "replace", "min_score": 1.5}}`)
+				So(ids(data), ShouldResemble, []string{"2"})
+			})
+			Convey("search with decay functions", func() {
+				for _, decay := range []string{"gauss", "linear", "exp"} {
+					data := search(`{"function_score": {"` + decay + `": {"published": {"origin": "2022-01-10T00:00:00Z", "scale": "10d"}}, "boost_mode": "replace"}}`)
+					So(ids(data), ShouldResemble, []string{"3", "1", "2"})
+					So(data.Hits.Hits[0].Score, ShouldEqual, 1)
+				}
+				data := search(`{"function_score": {"gauss": {"likes": {"origin": 0, "scale": 10, "offset": 1}}, "boost_mode": "replace"}}`)
+				So(ids(data), ShouldResemble, []string{"3", "1", "2"})
+				So(data.Hits.Hits[1].Score, ShouldAlmostEqual, 0.5, 0.1)
+			})
+			Convey("search with weight, filter and score_mode", func() {
+				data := search(`{"function_score": {"query": {"match": {"name": "phone"}}, "functions": [{"filter": {"match": {"name": "blue"}}, "weight": 5}, {"filter": {"match": {"name": "green"}}, "weight": 3}], "score_mode": "sum", "boost_mode": "replace"}}`)
+				So(ids(data), ShouldResemble, []string{"2", "3", "1"})
+				So(data.Hits.Hits[0].Score, ShouldEqual, 5)
+				So(data.Hits.Hits[2].Score, ShouldEqual, 1)
+			})
+			Convey("search with random_score", func() {
+				query := `{"function_score": {"random_score": {"seed": 10, "field": "_id"}}}`
+				So(ids(search(query)), ShouldResemble, ids(search(query)))
+			})
+			Convey("search with script_score", func() {
+				data := search(`{"script_score": {"query": {"match": {"name": "phone"}}, "script": {"source": "doc['likes'].value * params.factor", "params": {"factor": 2}}}}`)
+				So(ids(data), ShouldResemble, []string{"2", "1", "3"})
+				So(data.Hits.Hits[0].Score, ShouldEqual, 200)
+
+				data = search(`{"function_score": {"script_score": {"script": "saturation(doc['likes'].value, 10)"}, "boost_mode": "replace"}}`)
+				So(ids(data), ShouldResemble, []string{"2", "1", "3"})
+				So(data.Hits.Hits[1].Score, ShouldEqual, 0.5)
+			})
+			Convey("search with function_score on a text field", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"function_score": {"field_value_factor": {"field": "name"}}}}`)
+				resp := request("POST", "/es/functionscore/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+		Convey("POST /es/:target/_search with nested", func() {
+			Convey("init data for nested", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"mappings": {"properties": {"customer": {"type": "keyword"}, "items": {"type": "nested", "properties": {"sku": {"type": "keyword"}, "qty": {"type": "numeric"}}}}}}`)
+				resp := request("PUT", "/es/nestedorders/_mapping", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"index": {"_index": "nestedorders", "_id": "1"}}
+{"customer": "alice", "items": [{"sku": "a", "qty": 1}, {"sku": "b", "qty": 5}]}
+{"index": {"_index": "nestedorders", "_id": "2"}}
+{"customer": "bob", "items": [{"sku": "a", "qty": 5}]}
+{"index": {"_index": "nestedorders", "_id": "3"}}
+{"customer": "carol", "items": [{"sku": "c", "qty": 2}]}
+`)
+				resp = request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			search := func(query string) *meta.SearchResponse {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(query)
+				resp := request("POST", "/es/nestedorders/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				return data
+			}
+			Convey("search without nested query", func() {
+				data := search(`{"query": {"match_all": {}}}`)
+				So(data.Hits.Total.Value, ShouldEqual, 3)
+
+				resp := request("POST", "/es/nestedorders/_count", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, `"count":3`)
+			})
+			Convey("count documents of the index", func() {
+				resp := request("GET", "/es/_cat/indices/nestedorders?h=dc", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(strings.TrimSpace(resp.Body.String()), ShouldEqual, "3")
+			})
+			Convey("search with the v1 api", func() {
+				for _, query := range []string{
+					`{"search_type": "alldocuments", "max_results": 10}`,
+					`{"search_type": "matchall", "max_results": 10}`,
+					`{"search_type": "querystring", "query": {"term": "customer:alice"}, "max_results": 10}`,
+				} {
+					body := bytes.NewBuffer(nil)
+					body.WriteString(query)
+					resp := request("POST", "/api/nestedorders/_search", body)
+					So(resp.Code, ShouldEqual, http.StatusOK)
+
+					data := new(meta.SearchResponse)
+					err := json.Unmarshal(resp.Body.Bytes(), data)
+					So(err, ShouldBeNil)
+					for _, hit := range data.Hits.Hits {
+						So(hit.ID, ShouldNotContainSubstring, "\x00")
+						So(hit.Source, ShouldContainKey, "customer")
+					}
+					if strings.Contains(query, "alice") {
+						So(data.Hits.Total.Value, ShouldEqual, 1)
+					} else {
+						So(data.Hits.Total.Value, ShouldEqual, 3)
+					}
+				}
+			})
+			Convey("search with nested query", func() {
+				data := search(`{"query": {"nested": {"path": "items", "query": {"bool": {"must": [{"term": {"items.sku": "a"}}, {"range": {"items.qty": {"gte": 5, "lte": 10}}}]}}}}}`)
+				So(data.Hits.Total.Value, ShouldEqual, 1)
+				So(data.Hits.Hits[0].ID, ShouldEqual, "2")
+
+				data = search(`{"query": {"nested": {"path": "items", "query": {"term": {"items.sku": "a"}}, "score_mode": "none"}}}`)
+				So(data.Hits.Total.Value, ShouldEqual, 2)
+				So(data.Hits.Hits[0].Score, ShouldEqual, 0)
+			})
+			Convey("search with inner_hits", func() {
+				data := search(`{"query": {"nested": {"path": "items", "query": {"term": {"items.sku": "b"}}, "inner_hits": {}}}}`)
+				So(data.Hits.Total.Value, ShouldEqual, 1)
+				innerHits := data.Hits.Hits[0].InnerHits["items"].Hits
+				So(innerHits.Total.Value, ShouldEqual, 1)
+				So(innerHits.Hits[0].Nested.Field, ShouldEqual, "items")
+				So(innerHits.Hits[0].Nested.Offset, ShouldEqual, 1)
+				So(innerHits.Hits[0].Source["sku"], ShouldEqual, "b")
+			})
+			Convey("search with inner_hits on indexes with the same document id", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"mappings": {"properties": {"customer": {"type": "keyword"}, "items": {"type": "nested", "properties": {"sku": {"type": "keyword"}, "qty": {"type": "numeric"}}}}}}`)
+				resp := request("PUT", "/es/nestedorders2/_mapping", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"index": {"_index": "nestedorders2", "_id": "1"}}
+{"customer": "dave", "items": [{"sku": "b", "qty": 1}, {"sku": "b", "qty": 2}]}
+`)
+				resp = request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"query": {"nested": {"path": "items", "query": {"term": {"items.sku": "b"}}, "inner_hits": {}}}}`)
+				resp = request("POST", "/es/nestedorders,nestedorders2/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(data.Hits.Total.Value, ShouldEqual, 2)
+				for _, hit := range data.Hits.Hits {
+					innerHits := hit.InnerHits["items"].Hits
+					if hit.Index == "nestedorders" {
+						So(innerHits.Total.Value, ShouldEqual, 1)
+					} else {
+						So(innerHits.Total.Value, ShouldEqual, 2)
+					}
+					for _, innerHit := range innerHits.Hits {
+						So(innerHit.Index, ShouldEqual, hit.Index)
+					}
+				}
+			})
+			Convey("search with nested and reverse_nested aggregations", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"size": 0, "aggs": {"items": {"nested": {"path": "items"}, "aggs": {"skus": {"terms": {"field": "items.sku"}, "aggs": {"orders": {"reverse_nested": {}}}}}}}}`)
+				resp := request("POST", "/es/nestedorders/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				items := data["aggregations"].(map[string]interface{})["items"].(map[string]interface{})
+				So(items["doc_count"], ShouldEqual, 4)
+				buckets := items["skus"].(map[string]interface{})["buckets"].([]interface{})
+				So(buckets[0].(map[string]interface{})["key"], ShouldEqual, "a")
+				So(buckets[0].(map[string]interface{})["doc_count"], ShouldEqual, 2)
+				So(buckets[0].(map[string]interface{})["orders"].(map[string]interface{})["doc_count"], ShouldEqual, 2)
+			})
+			Convey("search with nested query on a non nested field", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"nested": {"path": "customer", "query": {"match_all": {}}}}}`)
+				resp := request("POST", "/es/nestedorders/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("update and delete documents with nested objects", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"customer": "alice", "items": [{"sku": "d", "qty": 1}]}`)
+				resp := request("PUT", "/es/nestedorders/_doc/1", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := search(`{"query": {"nested": {"path": "items", "query": {"term": {"items.sku": "b"}}}}}`)
+				So(data.Hits.Total.Value, ShouldEqual, 0)
+
+				resp = request("DELETE", "/es/nestedorders/_doc/3", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"size": 0, "aggs": {"items": {"nested": {"path": "items"}}}}`)
+				resp = request("POST", "/es/nestedorders/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, `"items":{"doc_count":2}`)
+			})
+		})
+		Convey("POST /es/:target/_search with highlight", func() {
+			Convey("init data for highlight", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"mappings": {"properties": {"title": {"type": "text"}, "message.body": {"type": "text"}, "message.subject": {"type": "text"}, "tag": {"type": "keyword"}}}}`)
+				resp := request("PUT", "/es/highlights/_mapping", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"index": {"_index": "highlights", "_id": "1"}}
+{"title": "the quick brown fox", "message": {"subject": "fox <news>", "body": "a quick fox jumps over the lazy dog, then the fox sleeps and the dog barks at the fox"}, "tag": "fox"}
+`)
+				resp = request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			search := func(query string) map[string]interface{} {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(query)
+				resp := request("POST", "/es/highlights/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(len(data.Hits.Hits), ShouldEqual, 1)
+				return data.Hits.Hits[0].Highlight
+			}
+			Convey("highlight from _source", func() {
+				highlight := search(`{"query": {"match": {"title": "fox"}}, "highlight": {"fields": {"title": {}}}}`)
+				So(highlight["title"], ShouldResemble, []interface{}{"the quick brown <em>fox</em>"})
+			})
+			Convey("highlight with fragment_size and number_of_fragments", func() {
+				highlight := search(`{"query": {"match": {"message.body": "fox"}}, "highlight": {"fields": {"message.body": {"fragment_size": 20, "number_of_fragments": 2}}}}`)
+				fragments := highlight["message.body"].([]interface{})
+				So(len(fragments), ShouldEqual, 2)
+				for _, fragment := range fragments {
+					So(fragment, ShouldContainSubstring, "<em>fox</em>")
+					So(len([]rune(fragment.(string))), ShouldBeLessThan, 40)
+				}
+
+				highlight = search(`{"query": {"match": {"message.body": "fox"}}, "highlight": {"number_of_fragments": 0, "fields": {"message.body": {}}}}`)
+				So(highlight["message.body"], ShouldResemble, []interface{}{"a quick <em>fox</em> jumps over the lazy dog, then the <em>fox</em> sleeps and the dog barks at the <em>fox</em>"})
+			})
+			Convey("highlight with multiple tags", func() {
+				highlight := search(`{"query": {"match": {"title": "quick fox"}}, "highlight": {"pre_tags": ["<b>", "<i>"], "post_tags": ["</b>", "</i>"], "fields": {"title": {}}}}`)
+				So(highlight["title"], ShouldResemble, []interface{}{"the <b>quick</b> brown <i>fox</i>"})
+			})
+			Convey("highlight with wildcard fields and require_field_match", func() {
+				highlight := search(`{"query": {"match": {"title": "fox"}}, "highlight": {"fields": {"message.*": {}}}}`)
+				So(highlight, ShouldBeNil)
+
+				highlight = search(`{"query": {"match": {"title": "fox"}}, "highlight": {"require_field_match": false, "fields": {"message.*": {}, "tag": {}}}}`)
+				So(highlight["message.subject"], ShouldResemble, []interface{}{"<em>fox</em> <news>"})
+				So(highlight["message.body"], ShouldNotBeNil)
+				So(highlight["tag"], ShouldResemble, []interface{}{"<em>fox</em>"})
+			})
+			Convey("highlight with encoder html", func() {
+				highlight := search(`{"query": {"match": {"message.subject": "fox"}}, "highlight": {"encoder": "html", "fields": {"message.subject": {}}}}`)
+				So(highlight["message.subject"], ShouldResemble, []interface{}{"<em>fox</em> &lt;news&gt;"})
+			})
+			Convey("highlight with highlight_query", func() {
+				highlight := search(`{"query": {"match": {"title": "fox"}}, "highlight": {"fields": {"title": {"highlight_query": {"match": {"title": "brown"}}}}}}`)
+				So(highlight["title"], ShouldResemble, []interface{}{"the quick <em>brown</em> fox"})
+			})
+			Convey("highlight with a wrong encoder", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"match": {"title": "fox"}}, "highlight": {"encoder": "xml", "fields": {"title": {}}}}`)
+				resp := request("POST", "/es/highlights/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+		Convey("POST /es/:target/_search with multiple indexes", func() {
+			Convey("init data for multiple indexes", func() {
+				for _, index := range []struct{ name, mappings string }{
+					{"multia", `{"title": {"type": "text", "analyzer": "keyword", "sortable": true}, "count": {"type": "numeric"}}`},
+					{"multiab", `{"title": {"type": "text"}, "count": {"type": "numeric"}}`},
+					{"multib", `{"title": {"type": "text", "sortable": true}, "count": {"type": "keyword"}}`},
+				} {
+					body := bytes.NewBuffer(nil)
+					body.WriteString(`{"mappings": {"properties": ` + index.mappings + `}}`)
+					resp := request("PUT", "/es/"+index.name+"/_mapping", body)
+					So(resp.Code, ShouldEqual, http.StatusOK)
+				}
+
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "multia", "_id": "1"}}
+{"title": "Hello World", "count": 1}
+{"index": {"_index": "multiab", "_id": "2"}}
+{"title": "Hello World", "count": 2}
+{"index": {"_index": "multib", "_id": "3"}}
+{"title": "Hello World", "count": "3"}
+`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			search := func(target, query string) []string {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(query)
+				resp := request("POST", "/es/"+target+"/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				indexes := make([]string, 0, len(data.Hits.Hits))
+				for _, hit := range data.Hits.Hits {
+					indexes = append(indexes, hit.Index)
+				}
+				sort.Strings(indexes)
+				return indexes
+			}
+			Convey("search with exact names, wildcards and exclusions", func() {
+				So(search("multia,multib", `{}`), ShouldResemble, []string{"multia", "multib"})
+				So(search("multi*,-multiab", `{}`), ShouldResemble, []string{"multia", "multib"})
+				So(search("mul*b", `{}`), ShouldResemble, []string{"multiab", "multib"})
+
+				resp := request("POST", "/es/multia,multix/_search", bytes.NewBufferString(`{}`))
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("search with the analyzers of each index", func() {
+				So(search("multia,multib", `{"query": {"match": {"title": "Hello World"}}}`), ShouldResemble, []string{"multia", "multib"})
+			})
+			Convey("highlight with the analyzers of each index", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"match": {"title": "Hello World"}}, "highlight": {"fields": {"title": {}}}}`)
+				resp := request("POST", "/es/multia,multib/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				highlights := make(map[string]interface{})
+				for _, hit := range data.Hits.Hits {
+					highlights[hit.Index] = hit.Highlight["title"]
+				}
+				So(highlights["multia"], ShouldResemble, []interface{}{"<em>Hello World</em>"})
+				So(highlights["multib"], ShouldResemble, []interface{}{"<em>Hello</em> <em>World</em>"})
+			})
+			Convey("search with conflicting mappings", func() {
+				So(search("multia,multib", `{"query": {"match_all": {}}, "sort": ["title"]}`), ShouldHaveLength, 2)
+
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"size": 0, "aggs": {"counts": {"terms": {"field": "count"}}}}`)
+				resp := request("POST", "/es/multia,multiab/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"size": 0, "aggs": {"counts": {"terms": {"field": "count"}}}}`)
+				resp = request("POST", "/es/multia,multib/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(resp.Body.String(), ShouldContainSubstring, "conflicting mappings")
+			})
+		})
+		Convey("index aliases", func() {
+			Convey("init data for aliases", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "aliasidx1", "_id": "1"}}
+{"level": "info", "message": "started"}
+{"index": {"_index": "aliasidx1", "_id": "2"}}
+{"level": "error", "message": "failed"}
+{"index": {"_index": "aliasidx2", "_id": "3"}}
+{"level": "error", "message": "failed again"}
+`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"actions": [
+					{"add": {"index": "aliasidx1", "alias": "aliaslogs", "is_write_index": false}},
+					{"add": {"index": "aliasidx2", "alias": "aliaslogs", "is_write_index": true}},
+					{"add": {"index": "aliasidx*", "alias": "aliaserrors", "filter": {"match": {"level": "error"}}}}
+				]}`)
+				resp = request("POST", "/es/_aliases", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				resp = request("PUT", "/es/aliasidx1/_alias/aliasone", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+			})
+			total := func(target string) int {
+				resp := request("POST", "/es/"+target+"/_search", bytes.NewBufferString(`{"query": {"match_all": {}}}`))
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				return data.Hits.Total.Value
+			}
+			Convey("search through aliases", func() {
+				So(total("aliaslogs"), ShouldEqual, 3)
+				So(total("aliaserrors"), ShouldEqual, 2)
+				So(total("aliaserrors,aliasidx1"), ShouldEqual, 3)
+
+				resp := request("POST", "/es/aliaserrors/_count", bytes.NewBufferString(`{}`))
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, `"count":2`)
+			})
+			Convey("get and write documents through aliases", func() {
+				resp := request("GET", "/es/aliasone/_doc/1", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, `"_index":"aliasidx1"`)
+
+				resp = request("GET", "/es/aliaslogs/_doc/1", nil)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+
+				resp = request("PUT", "/es/aliaslogs/_doc/4", bytes.NewBufferString(`{"level": "info", "message": "written"}`))
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, `"_index":"aliasidx2"`)
+
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "aliaslogs", "_id": "5"}}
+{"level": "info", "message": "bulk"}
+`)
+				resp = request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, `"_index":"aliasidx2"`)
+
+				resp = request("PUT", "/es/aliaserrors/_doc/6", bytes.NewBufferString(`{"level": "error"}`))
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(resp.Body.String(), ShouldContainSubstring, "no write index is defined for alias [aliaserrors]")
+			})
+			Convey("get aliases", func() {
+				resp := request("GET", "/es/_alias/aliaslogs", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				data := make(map[string]map[string]map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data, ShouldContainKey, "aliasidx1")
+				So(data["aliasidx2"]["aliases"]["aliaslogs"], ShouldResemble, map[string]interface{}{"is_write_index": true})
+
+				resp = request("GET", "/es/aliasidx1/_alias", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, "aliasone")
+
+				resp = request("GET", "/es/_alias/aliasmissing", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+
+				resp = request("GET", "/es/_cat/aliases/alias*?format=json&s=alias,index", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				rows := make([]map[string]string, 0)
+				err = json.Unmarshal(resp.Body.Bytes(), &rows)
+				So(err, ShouldBeNil)
+				So(rows, ShouldHaveLength, 5)
+				So(rows[0]["alias"], ShouldEqual, "aliaserrors")
+				So(rows[0]["filter"], ShouldEqual, "*")
+			})
+			Convey("invalid aliases", func() {
+				resp := request("PUT", "/es/aliasidx2/_alias/aliasidx1", nil)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+
+				resp = request("PUT", "/es/aliasidx1/_alias/aliaslogs", bytes.NewBufferString(`{"is_write_index": true}`))
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(resp.Body.String(), ShouldContainSubstring, "more than one write index")
+
+				resp = request("PUT", "/es/aliasidx1/_alias/aliasbad", bytes.NewBufferString(`{"filter": {"unknown": {}}}`))
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("delete aliases", func() {
+				resp := request("DELETE", "/es/aliasidx1/_alias/aliasone", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				resp = request("DELETE", "/es/aliasidx1/_alias/aliasone", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+				resp = request("GET", "/es/aliasone/_doc/1", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("reindex through aliases", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"source": {"index": "aliaserrors"}, "dest": {"index": "aliasreindexed"}}`)
+				resp := request("POST", "/es/_reindex", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, `"created":2`)
+				So(total("aliasreindexed"), ShouldEqual, 2)
+
+				resp = request("PUT", "/es/aliasreindexed/_alias/aliasdest", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				body.Reset()
+				body.WriteString(`{"source": {"index": "aliasidx1"}, "dest": {"index": "aliasdest"}}`)
+				resp = request("POST", "/es/_reindex", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(total("aliasreindexed"), ShouldEqual, 3)
+
+				body.Reset()
+				body.WriteString(`{"source": {"index": "aliasidx1"}, "dest": {"index": "aliaserrors"}}`)
+				resp = request("POST", "/es/_reindex", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(resp.Body.String(), ShouldContainSubstring, "no write index is defined for alias [aliaserrors]")
+			})
+			Convey("delete by query through a filtered alias", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"match_all": {}}}`)
+				resp := request("POST", "/es/aliaserrors/_delete_by_query", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, `"deleted":2`)
+				So(total("aliaslogs"), ShouldEqual, 3)
+				So(total("aliaserrors"), ShouldEqual, 0)
+
+				body.Reset()
+				body.WriteString(`{"query": {"match_all": {}}}`)
+				resp = request("POST", "/es/aliasmissing/_delete_by_query", body)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+		Convey("POST /es/:target/_search with suggest", func() {
+			Convey("init data for suggesters", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"mappings": {"properties": {"title": {"type": "text"}, "suggest": {"type": "completion"}}}}`)
+				resp := request("PUT", "/es/suggests/_mapping", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"index": {"_index": "suggests", "_id": "1"}}
+{"title": "the quick brown fox", "suggest": {"input": ["Nirvana", "Nevermind"], "weight": 34}}
+{"index": {"_index": "suggests", "_id": "2"}}
+{"title": "quick brown dogs", "suggest": {"input": "Nirvana Live", "weight": 10}}
+{"index": {"_index": "suggests", "_id": "3"}}
+{"title": "a quick fox jumps", "suggest": ["Nickelback"]}
+`)
+				resp = request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldNotContainSubstring, `"errors":true`)
+			})
+			suggest := func(query string) map[string][]meta.SuggestEntry {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(query)
+				resp := request("POST", "/es/suggests/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				return data.Suggest
+			}
+			Convey("term suggester", func() {
+				data := suggest(`{"size": 0, "suggest": {"text": "quikc brwn fox", "spelling": {"term": {"field": "title"}}}}`)
+				So(data["spelling"], ShouldHaveLength, 3)
+				So(data["spelling"][0].Text, ShouldEqual, "quikc")
+				So(data["spelling"][0].Options, ShouldNotBeEmpty)
+				So(data["spelling"][0].Options[0].Text, ShouldEqual, "quick")
+				So(data["spelling"][0].Options[0].Freq, ShouldEqual, 3)
+				So(data["spelling"][1].Offset, ShouldEqual, 6)
+				So(data["spelling"][1].Options[0].Text, ShouldEqual, "brown")
+				So(data["spelling"][2].Options, ShouldBeEmpty)
+			})
+			Convey("phrase suggester", func() {
+				data := suggest(`{"size": 0, "suggest": {"didyoumean": {"text": "quikc brown fox", "phrase": {"field": "title", "highlight": {"pre_tag": "<em>", "post_tag": "</em>"}}}}}`)
+				So(data["didyoumean"], ShouldHaveLength, 1)
+				So(data["didyoumean"][0].Options, ShouldNotBeEmpty)
+				So(data["didyoumean"][0].Options[0].Text, ShouldEqual, "quick brown fox")
+				So(data["didyoumean"][0].Options[0].Highlighted, ShouldEqual, "<em>quick</em> brown fox")
+			})
+			Convey("completion suggester", func() {
+				data := suggest(`{"suggest": {"songs": {"prefix": "nir", "completion": {"field": "suggest"}}}}`)
+				So(data["songs"], ShouldHaveLength, 1)
+				options := data["songs"][0].Options
+				So(options, ShouldHaveLength, 2)
+				So(options[0].Text, ShouldEqual, "Nirvana")
+				So(options[0].ID, ShouldEqual, "1")
+				So(options[0].DocScore, ShouldEqual, 34)
+				So(options[1].Text, ShouldEqual, "Nirvana Live")
+
+				data = suggest(`{"suggest": {"songs": {"prefix": "N", "completion": {"field": "suggest", "size": 5}}}}`)
+				ids := make([]string, 0)
+				for _, option := range data["songs"][0].Options {
+					ids = append(ids, option.ID)
+				}
+				So(ids, ShouldResemble, []string{"1", "2", "3"})
+			})
+			Convey("completion suggester on a text field", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"suggest": {"songs": {"prefix": "nir", "completion": {"field": "title"}}}}`)
+				resp := request("POST", "/es/suggests/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+		Convey("POST /es/:target/_search with profile", func() {
+			Convey("init data for profile", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "profiles", "_id": "1"}}
+{"level": "error", "message": "disk is full"}
+{"index": {"_index": "profiles", "_id": "2"}}
+{"level": "info", "message": "disk is fine"}
+{"index": {"_index": "profiles", "_id": "3"}}
+{"level": "error", "message": "network is down"}
+`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldNotContainSubstring, `"errors":true`)
+			})
+			search := func(target, query string) *meta.SearchResponse {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(query)
+				resp := request("POST", "/es/"+target+"/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				return data
+			}
+			var findQuery func(results []meta.ProfileResult, description string) *meta.ProfileResult
+			findQuery = func(results []meta.ProfileResult, description string) *meta.ProfileResult {
+				for i := range results {
+					if results[i].Description == description {
+						return &results[i]
+					}
+					if v := findQuery(results[i].Children, description); v != nil {
+						return v
+					}
+				}
+				return nil
+			}
+			Convey("profile the query, aggregations and fetch", func() {
+				data := search("profiles", `{"profile": true, "query": {"bool": {"must": [{"term": {"level": "error"}}]}}, "aggs": {"levels": {"terms": {"field": "level"}}}, "highlight": {"fields": {"message": {}}}}`)
+				So(data.Hits.Total.Value, ShouldEqual, 2)
+				So(data.Aggregations["levels"].Buckets, ShouldHaveLength, 1)
+				So(data.Profile, ShouldNotBeNil)
+				So(data.Profile.Shards, ShouldHaveLength, 1)
+				shard := data.Profile.Shards[0]
+				So(shard.ID, ShouldEqual, "[zinc][profiles][0]")
+				So(shard.Searches, ShouldHaveLength, 1)
+				So(shard.Searches[0].Query, ShouldHaveLength, 1)
+				So(shard.Searches[0].Query[0].Type, ShouldEqual, "BooleanQuery")
+				So(shard.Searches[0].Query[0].Breakdown["build_scorer_count"], ShouldBeGreaterThan, 0)
+				So(shard.Searches[0].Collector, ShouldHaveLength, 1)
+				So(shard.Searches[0].Collector[0].TimeInNanos, ShouldBeGreaterThan, 0)
+				term := findQuery(shard.Searches[0].Query, "level:error")
+				So(term, ShouldNotBeNil)
+				So(term.Type, ShouldEqual, "TermQuery")
+				So(term.Breakdown["next_doc_count"]+term.Breakdown["advance_count"], ShouldBeGreaterThanOrEqualTo, 2)
+				So(term.TimeInNanos, ShouldBeGreaterThan, 0)
+				So(shard.Aggregations, ShouldHaveLength, 1)
+				So(shard.Aggregations[0].Description, ShouldEqual, "levels")
+				So(shard.Aggregations[0].Breakdown["collect_count"], ShouldEqual, 2)
+				So(shard.Fetch, ShouldNotBeNil)
+				So(shard.Fetch.Breakdown["load_stored_fields_count"], ShouldEqual, 2)
+				phases := make(map[string]int64)
+				for _, child := range shard.Fetch.Children {
+					phases[child.Type] = child.Breakdown["process_count"]
+				}
+				So(phases["FetchSourcePhase"], ShouldEqual, 2)
+				So(phases["HighlightPhase"], ShouldEqual, 2)
+			})
+			Convey("profile every index as a shard", func() {
+				data := search("profiles,suggests", `{"profile": true, "query": {"match_all": {}}}`)
+				So(data.Profile, ShouldNotBeNil)
+				So(data.Profile.Shards, ShouldHaveLength, 2)
+				So(data.Profile.Shards[1].ID, ShouldEqual, "[zinc][suggests][0]")
+				So(data.Profile.Shards[1].Searches[0].Query, ShouldHaveLength, 1)
+			})
+			Convey("no profile by default", func() {
+				data := search("profiles", `{"query": {"match_all": {}}}`)
+				So(data.Profile, ShouldBeNil)
+			})
+		})
+		Convey("explain and validate query", func() {
+			Convey("init data for explain", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "explains", "_id": "1"}}
+{"level": "error", "message": "disk is full"}
+{"index": {"_index": "explains", "_id": "2"}}
+{"level": "info", "message": "disk is fine"}
+`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldNotContainSubstring, `"errors":true`)
+			})
+			explain := func(id, query string, code int) *meta.ExplainResponse {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(query)
+				resp := request("POST", "/es/explains/_explain/"+id, body)
+				So(resp.Code, ShouldEqual, code)
+
+				data := new(meta.ExplainResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				return data
+			}
+			Convey("POST /es/:target/_explain/:id", func() {
+				data := explain("1", `{"query": {"match": {"message": "full"}}}`, http.StatusOK)
+				So(data.Index, ShouldEqual, "explains")
+				So(data.ID, ShouldEqual, "1")
+				So(data.Matched, ShouldBeTrue)
+				So(data.Explanation, ShouldNotBeNil)
+				So(data.Explanation.Value, ShouldBeGreaterThan, 0)
+				So(data.Explanation.Description, ShouldNotBeEmpty)
+
+				data = explain("2", `{"query": {"match": {"message": "full"}}}`, http.StatusOK)
+				So(data.Matched, ShouldBeFalse)
+				So(data.Explanation, ShouldNotBeNil)
+				So(data.Explanation.Value, ShouldEqual, 0)
+
+				data = explain("3", `{"query": {"match": {"message": "full"}}}`, http.StatusNotFound)
+				So(data.Matched, ShouldBeFalse)
+				So(data.Explanation, ShouldBeNil)
+			})
+			Convey("POST /es/:target/_explain/:id with the q parameter", func() {
+				resp := request("GET", "/es/explains/_explain/2?q=level:info", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldContainSubstring, `"matched":true`)
+			})
+			Convey("POST /es/:target/_explain/:id without query", func() {
+				resp := request("POST", "/es/explains/_explain/1", nil)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+			Convey("POST /es/:target/_search with explain", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"explain": true, "query": {"match": {"message": "disk"}}}`)
+				resp := request("POST", "/es/explains/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(data.Hits.Hits, ShouldHaveLength, 2)
+				for _, hit := range data.Hits.Hits {
+					So(hit.Explanation, ShouldNotBeNil)
+					So(hit.Explanation.Value, ShouldAlmostEqual, hit.Score)
+				}
+
+				body.Reset()
+				body.WriteString(`{"query": {"match": {"message": "disk"}}}`)
+				resp = request("POST", "/es/explains/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldNotContainSubstring, `"_explanation"`)
+			})
+			validate := func(api, query string) *meta.ValidateResponse {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(query)
+				resp := request("POST", api, body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.ValidateResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				return data
+			}
+			Convey("POST /es/:target/_validate/query", func() {
+				data := validate("/es/explains/_validate/query", `{"query": {"term": {"level": "error"}}}`)
+				So(data.Valid, ShouldBeTrue)
+				So(data.Explanations, ShouldBeEmpty)
+
+				data = validate("/es/explains/_validate/query?explain", `{"query": {"bool": {"must": [{"term": {"level": "error"}}], "must_not": [{"term": {"level": "info"}}]}}}`)
+				So(data.Valid, ShouldBeTrue)
+				So(data.Explanations, ShouldHaveLength, 1)
+				So(data.Explanations[0].Index, ShouldEqual, "explains")
+				So(data.Explanations[0].Valid, ShouldBeTrue)
+				So(data.Explanations[0].Explanation, ShouldEqual, "+level:error -level:info")
+
+				data = validate("/es/explains/_validate/query", `{"query": {"unknown": {"level": "error"}}}`)
+				So(data.Valid, ShouldBeFalse)
+				So(data.Explanations, ShouldBeEmpty)
+
+				data = validate("/es/explains/_validate/query?explain=true", `{"query": {"unknown": {"level": "error"}}}`)
+				So(data.Valid, ShouldBeFalse)
+				So(data.Explanations, ShouldHaveLength, 1)
+				So(data.Explanations[0].Valid, ShouldBeFalse)
+				So(data.Explanations[0].Error, ShouldContainSubstring, "unknown")
+			})
+			Convey("POST /es/:target/_validate/query with a missing index", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"query": {"match_all": {}}}`)
+				resp := request("POST", "/es/explainsnotexist/_validate/query", body)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+		Convey("search templates", func() {
+			Convey("init data for search templates", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"index": {"_index": "templated", "_id": "1"}}
+{"level": "error", "message": "disk is \"full\"", "tag": "a"}
+{"index": {"_index": "templated", "_id": "2"}}
+{"level": "info", "message": "disk is fine", "tag": "b"}
+{"index": {"_index": "templated", "_id": "3"}}
+{"level": "error", "message": "network is down", "tag": "c"}
+`)
+				resp := request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldNotContainSubstring, `"errors":true`)
+			})
+			Convey("PUT /es/_scripts/:id", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"script": {"lang": "mustache", "source": "{\"query\": {\"term\": {\"level\": \"{{level}}\"}}, \"size\": {{size}}{{^size}}10{{/size}}}"}}`)
+				resp := request("PUT", "/es/_scripts/bylevel", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldEqual, `{"acknowledged":true}`)
+
+				body.Reset()
+				body.WriteString(`{"script": {"lang": "painless", "source": "ctx._source"}}`)
+				resp = request("PUT", "/es/_scripts/painless", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+
+				body.Reset()
+				body.WriteString(`{"script": {"lang": "mustache", "source": "{{#level}}"}}`)
+				resp = request("PUT", "/es/_scripts/broken", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(resp.Body.String(), ShouldContainSubstring, "script_exception")
+			})
+			Convey("GET /es/_scripts/:id", func() {
+				resp := request("GET", "/es/_scripts/bylevel", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				data := make(map[string]interface{})
+				err := json.Unmarshal(resp.Body.Bytes(), &data)
+				So(err, ShouldBeNil)
+				So(data["found"], ShouldBeTrue)
+				script := data["script"].(map[string]interface{})
+				So(script["lang"], ShouldEqual, "mustache")
+				So(script["source"], ShouldContainSubstring, "{{level}}")
+
+				resp = request("GET", "/es/_scripts/notexist", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			search := func(api, query string) *meta.SearchResponse {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(query)
+				resp := request("POST", api, body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				return data
+			}
+			Convey("POST /es/:target/_search/template with a stored template", func() {
+				data := search("/es/templated/_search/template", `{"id": "bylevel", "params": {"level": "error"}}`)
+				So(data.Hits.Total.Value, ShouldEqual, 2)
+				So(data.Hits.Hits, ShouldHaveLength, 2)
+
+				data = search("/es/templated/_search/template", `{"id": "bylevel", "params": {"level": "error", "size": 1}, "explain": true}`)
+				So(data.Hits.Total.Value, ShouldEqual, 2)
+				So(data.Hits.Hits, ShouldHaveLength, 1)
+				So(data.Hits.Hits[0].Explanation, ShouldNotBeNil)
+
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"id": "notexist", "params": {}}`)
+				resp := request("POST", "/es/templated/_search/template", body)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+			Convey("POST /es/_search/template with an inline template", func() {
+				data := search("/es/_search/template", `{"source": "{\"query\": {\"terms\": {\"tag\": {{#toJson}}tags{{/toJson}}}}}", "params": {"tags": ["a", "c"]}}`)
+				So(data.Hits.Total.Value, ShouldEqual, 2)
+
+				data = search("/es/templated/_search/template", `{"source": "{\"query\": {\"match_phrase\": {\"message\": \"{{text}}\"}}}", "params": {"text": "is \"full\""}}`)
+				So(data.Hits.Total.Value, ShouldEqual, 1)
+				So(data.Hits.Hits[0].ID, ShouldEqual, "1")
+
+				data = search("/es/templated/_search/template", `{"source": "{\"query\": {\"query_string\": {\"query\": \"tag:({{#join}}tags{{/join}})\"}}}", "params": {"tags": ["a", "b"]}}`)
+				So(data.Hits.Total.Value, ShouldEqual, 2)
+
+				data = search("/es/templated/_search/template", `{"source": "{\"query\": {{#level}}{\"term\": {\"level\": \"{{level}}\"}}{{/level}}{{^level}}{\"match_all\": {}}{{/level}}}", "params": {}}`)
+				So(data.Hits.Total.Value, ShouldEqual, 3)
+			})
+			Convey("POST /es/_render/template", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"params": {"level": "info"}}`)
+				resp := request("POST", "/es/_render/template/bylevel", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldEqual, `{"template_output":{"query":{"term":{"level":"info"}},"size":10}}`)
+
+				body.Reset()
+				body.WriteString(`{"source": {"query": {"term": {"message": "{{text}}"}}}, "params": {"text": "a \\ \"b\""}}`)
+				resp = request("POST", "/es/_render/template", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldEqual, `{"template_output":{"query":{"term":{"message":"a \\ \"b\""}}}}`)
+
+				body.Reset()
+				body.WriteString(`{"source": "{\"query\": {{query}}", "params": {}}`)
+				resp = request("POST", "/es/_render/template", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(resp.Body.String(), ShouldContainSubstring, "x_content_parse_exception")
+			})
+			Convey("POST /es/:target/_msearch/template", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{}
+{"id": "bylevel", "params": {"level": "error"}}
+{"index": "templated"}
+{"id": "bylevel", "params": {"level": "info"}}
+{}
+{"id": "notexist"}
+`)
+				resp := request("POST", "/es/templated/_msearch/template", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				data := new(struct {
+					Responses []*meta.SearchResponse `json:"responses"`
+				})
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				So(data.Responses, ShouldHaveLength, 3)
+				So(data.Responses[0].Hits.Total.Value, ShouldEqual, 2)
+				So(data.Responses[1].Hits.Total.Value, ShouldEqual, 1)
+				So(data.Responses[2].Error, ShouldNotBeEmpty)
+			})
+			Convey("DELETE /es/_scripts/:id", func() {
+				resp := request("DELETE", "/es/_scripts/bylevel", nil)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				resp = request("GET", "/es/_scripts/bylevel", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+				resp = request("DELETE", "/es/_scripts/bylevel", nil)
+				So(resp.Code, ShouldEqual, http.StatusNotFound)
+			})
+		})
+		Convey("POST /es/:target/_search with sort options", func() {
+			Convey("init data for sort options", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"mappings": {"properties": {"n": {"type": "numeric"}, "at": {"type": "date", "format": "2006-01-02"}, "name": {"type": "keyword"}}}}`)
+				resp := request("PUT", "/es/sorts/_mapping", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				body.Reset()
+				body.WriteString(`{"index": {"_index": "sorts", "_id": "1"}}
+{"n": [1, 9], "at": "2022-01-03", "name": "a"}
+{"index": {"_index": "sorts", "_id": "2"}}
+{"n": [4, 5], "at": "2022-01-01", "name": "b"}
+{"index": {"_index": "sorts", "_id": "3"}}
+{"name": "c"}
+`)
+				resp = request("POST", "/es/_bulk", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+				So(resp.Body.String(), ShouldNotContainSubstring, `"errors":true`)
+			})
+			search := func(query string) *meta.SearchResponse {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(query)
+				resp := request("POST", "/es/sorts/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusOK)
+
+				data := new(meta.SearchResponse)
+				err := json.Unmarshal(resp.Body.Bytes(), data)
+				So(err, ShouldBeNil)
+				return data
+			}
+			ids := func(data *meta.SearchResponse) []string {
+				ids := make([]string, 0, len(data.Hits.Hits))
+				for _, hit := range data.Hits.Hits {
+					ids = append(ids, hit.ID)
+				}
+				return ids
+			}
+			Convey("sort with missing", func() {
+				So(ids(search(`{"sort": [{"at": "asc"}]}`)), ShouldResemble, []string{"2", "1", "3"})
+				So(ids(search(`{"sort": [{"at": {"order": "desc", "missing": "_first"}}]}`)), ShouldResemble, []string{"3", "1", "2"})
+				So(ids(search(`{"sort": [{"n": {"order": "asc", "missing": 3}}]}`)), ShouldResemble, []string{"1", "3", "2"})
+			})
+			Convey("sort with mode", func() {
+				data := search(`{"sort": [{"n": "asc"}]}`)
+				So(ids(data), ShouldResemble, []string{"1", "2", "3"})
+				So(data.Hits.Hits[0].Sort, ShouldResemble, []interface{}{float64(1)})
+				So(data.Hits.Hits[2].Sort, ShouldResemble, []interface{}{nil})
+
+				data = search(`{"sort": [{"n": "desc"}]}`)
+				So(ids(data), ShouldResemble, []string{"1", "2", "3"})
+				So(data.Hits.Hits[0].Sort, ShouldResemble, []interface{}{float64(9)})
+				data = search(`{"sort": [{"n": {"order": "asc", "mode": "avg"}}]}`)
+				So(ids(data), ShouldResemble, []string{"2", "1", "3"})
+				So(data.Hits.Hits[0].Sort, ShouldResemble, []interface{}{4.5})
+				So(ids(search(`{"sort": [{"n": {"order": "desc", "mode": "min"}}]}`)), ShouldResemble, []string{"2", "1", "3"})
+			})
+			Convey("sort with format and _doc", func() {
+				data := search(`{"sort": [{"at": {"order": "asc", "format": "2006-01-02"}}, "_doc"]}`)
+				So(data.Hits.Hits[0].Sort, ShouldHaveLength, 2)
+				So(data.Hits.Hits[0].Sort[0], ShouldEqual, "2022-01-01")
+
+				data = search(`{"sort": [{"at": {"order": "asc", "format": "2006-01-02"}}], "search_after": ["2022-01-01"]}`)
+				So(ids(data), ShouldResemble, []string{"1", "3"})
+
+				data = search(`{"sort": [{"at": {"order": "asc", "format": "epoch_millis"}}]}`)
+				So(data.Hits.Hits[0].Sort, ShouldResemble, []interface{}{float64(1640995200000)})
+			})
+			Convey("sort with unmapped_type", func() {
+				So(search(`{"sort": [{"nothing": {"order": "asc", "unmapped_type": "long"}}]}`).Hits.Hits, ShouldHaveLength, 3)
+
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"sort": [{"nothing": "asc"}]}`)
+				resp := request("POST", "/es/sorts/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(resp.Body.String(), ShouldContainSubstring, "No mapping found for [nothing] in order to sort on")
+			})
+			Convey("sort on an unsortable field", func() {
+				body := bytes.NewBuffer(nil)
+				body.WriteString(`{"sort": [{"name": "asc"}]}`)
+				resp := request("POST", "/es/sorts/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+				So(resp.Body.String(), ShouldContainSubstring, "is not sortable")
+
+				body.Reset()
+				body.WriteString(`{"sort": [{"n": {"order": "up"}}]}`)
+				resp = request("POST", "/es/sorts/_search", body)
+				So(resp.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
 	})
 }